@@ -15,6 +15,8 @@ package tar
 import (
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path"
 	"time"
@@ -60,6 +62,124 @@ type Header struct {
 	AccessTime time.Time // access time
 	ChangeTime time.Time // status change time
 	Xattrs     map[string]string
+
+	// SparseHoles represents a sequence of holes in a sparse file.
+	//
+	// The holes must be sorted in ascending order, must not overlap
+	// and must lie within the logical Size of the file.
+	// Reader.Next populates SparseHoles for sparse files in any of the
+	// GNU or PAX sparse formats. If SparseHoles is non-empty, the Writer
+	// stores only the data fragments of the file; the contents written
+	// to the holes must be all zeros.
+	SparseHoles []SparseEntry
+
+	// Format specifies the format of the tar header.
+	//
+	// This is set by Reader.Next as a best-effort guess at the format.
+	// Since the Reader liberally reads some non-compliant files,
+	// it is possible for this to be FormatUnknown.
+	//
+	// When writing, FormatUnknown lets the Writer pick a format that can
+	// encode the header, while FormatUSTAR, FormatPAX and FormatGNU force
+	// that format and cause WriteHeader to fail if the header cannot be
+	// encoded in it. AccessTime and ChangeTime are only written in the
+	// PAX and GNU formats, and sub-second timestamps only in the PAX format.
+	//
+	// The Format set by Reader.Next is not forced unless it is changed,
+	// so that a header can be read, modified and written again: a header
+	// read in the USTAR or PAX format is kept in it if it can still be
+	// encoded, and otherwise written as if Format were FormatUnknown.
+	Format Format
+
+	readFormat Format // Format as set by Reader.Next
+}
+
+// SparseEntry represents a Length-sized fragment at Offset in the file.
+type SparseEntry struct{ Offset, Length int64 }
+
+func (s SparseEntry) endOffset() int64 { return s.Offset + s.Length }
+
+// validateSparseHoles reports whether holes is a valid list of holes
+// for a file of the given size.
+func validateSparseHoles(holes []SparseEntry, size int64) bool {
+	var pos int64
+	for _, h := range holes {
+		switch {
+		case h.Offset < 0 || h.Length < 0:
+			return false // Negative values are never okay
+		case h.Offset > math.MaxInt64-h.Length:
+			return false // Integer overflow with large length
+		case h.Offset < pos:
+			return false // Holes can't overlap and must be in order
+		case h.endOffset() > size:
+			return false // Hole extends beyond the "real" size
+		}
+		pos = h.endOffset()
+	}
+	return true
+}
+
+// invertSparseEntries converts a validated sparse map of data fragments
+// into the list of holes between them in a file of the given size.
+func invertSparseEntries(sp []sparseEntry, size int64) []SparseEntry {
+	var holes []SparseEntry
+	var pos int64
+	for _, s := range sp {
+		if s.offset > pos {
+			holes = append(holes, SparseEntry{Offset: pos, Length: s.offset - pos})
+		}
+		pos = s.offset + s.numBytes
+	}
+	if size > pos {
+		holes = append(holes, SparseEntry{Offset: pos, Length: size - pos})
+	}
+	return holes
+}
+
+// sparseDataFragments converts a valid list of holes into the sparse map of
+// data fragments of a file of the given size. As done by GNU tar, the map
+// always ends with a fragment that reaches the end of the file, even if
+// that fragment is empty.
+func sparseDataFragments(holes []SparseEntry, size int64) []sparseEntry {
+	var sp []sparseEntry
+	var pos int64
+	for _, h := range holes {
+		if h.Offset > pos {
+			sp = append(sp, sparseEntry{offset: pos, numBytes: h.Offset - pos})
+		}
+		pos = h.endOffset()
+	}
+	return append(sp, sparseEntry{offset: pos, numBytes: size - pos})
+}
+
+// sysSparseDetect, if non-nil, returns the holes of the file f
+// using system-dependent facilities.
+var sysSparseDetect func(f *os.File) ([]SparseEntry, error)
+
+// DetectSparseHoles sets h.SparseHoles to the holes of the regular file f.
+//
+// Holes are found with the SEEK_DATA and SEEK_HOLE extensions of lseek.
+// If the operating system or the file system does not support them,
+// h.SparseHoles is left empty and the file is archived densely.
+// The file offset of f is restored before returning.
+func (h *Header) DetectSparseHoles(f *os.File) error {
+	h.SparseHoles = nil
+	if sysSparseDetect == nil {
+		return nil
+	}
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	holes, err := sysSparseDetect(f)
+	if _, serr := f.Seek(pos, io.SeekStart); err == nil {
+		err = serr
+	}
+	if err != nil {
+		return err
+	}
+	h.SparseHoles = holes
+	return nil
 }
 
 // FileInfo returns an os.FileInfo for the Header.
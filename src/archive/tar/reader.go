@@ -113,6 +113,7 @@ func (tr *Reader) Next() (*Header, error) {
 
 func (tr *Reader) next() (*Header, error) {
 	var extHdrs map[string]string
+	var paxHdrs bool // whether a PAX extended header was read
 
 	// Externally, Next iterates through the tar archive as if it is a series of
 	// files. Internally, the tar format often uses fake "files" to add meta
@@ -139,6 +140,7 @@ loop:
 			if err != nil {
 				return nil, err
 			}
+			paxHdrs = true
 			continue loop // This is a meta header affecting the next header
 		case TypeGNULongName, TypeGNULongLink:
 			realname, err := ioutil.ReadAll(tr)
@@ -168,6 +170,9 @@ loop:
 			if err := mergePAX(hdr, extHdrs); err != nil {
 				return nil, err
 			}
+			if paxHdrs && hdr.Format == FormatUSTAR {
+				hdr.Format = FormatPAX
+			}
 
 			// The extended headers may have updated the size.
 			// Thus, setup the regFileReader again after merging PAX headers.
@@ -180,6 +185,7 @@ loop:
 			if err := tr.handleSparseFile(hdr, rawHdr, extHdrs); err != nil {
 				return nil, err
 			}
+			hdr.readFormat = hdr.Format
 			return hdr, nil // This is a file, so stop
 		}
 	}
@@ -223,6 +229,9 @@ func (tr *Reader) handleSparseFile(hdr *Header, rawHdr *block, extHdrs map[strin
 	// Note that it is possible for len(sp) to be zero.
 	if sp != nil {
 		tr.curr, err = newSparseFileReader(tr.curr, sp, hdr.Size)
+		if err == nil {
+			hdr.SparseHoles = invertSparseEntries(sp, hdr.Size)
+		}
 	}
 	return err
 }
@@ -445,7 +454,7 @@ func (tr *Reader) readHeader() (*Header, *block, error) {
 
 	// Verify the header matches a known format.
 	format := tr.blk.GetFormat()
-	if format == formatUnknown {
+	if format == FormatUnknown {
 		return nil, nil, ErrHeader
 	}
 
@@ -495,7 +504,8 @@ func (tr *Reader) readHeader() (*Header, *block, error) {
 
 		var prefix string
 		switch format {
-		case formatUSTAR:
+		case FormatUSTAR:
+			hdr.Format = FormatUSTAR
 			ustar := tr.blk.USTAR()
 			prefix = p.parseString(ustar.Prefix())
 		case formatSTAR:
@@ -503,7 +513,8 @@ func (tr *Reader) readHeader() (*Header, *block, error) {
 			prefix = p.parseString(star.Prefix())
 			hdr.AccessTime = time.Unix(p.parseNumeric(star.AccessTime()), 0)
 			hdr.ChangeTime = time.Unix(p.parseNumeric(star.ChangeTime()), 0)
-		case formatGNU:
+		case FormatGNU:
+			hdr.Format = FormatGNU
 			gnu := tr.blk.GNU()
 			hdr.AccessTime = tryParseTime(gnu.AccessTime())
 			hdr.ChangeTime = tryParseTime(gnu.ChangeTime())
@@ -527,7 +538,7 @@ func (tr *Reader) readOldGNUSparseMap(hdr *Header, blk *block) ([]sparseEntry, e
 	// Make sure that the input format is GNU.
 	// Unfortunately, the STAR format also has a sparse header format that uses
 	// the same type flag but has a completely different layout.
-	if blk.GetFormat() != formatGNU {
+	if blk.GetFormat() != FormatGNU {
 		return nil, ErrHeader
 	}
 
@@ -19,6 +19,14 @@ import (
 )
 
 func TestReader(t *testing.T) {
+	// The sparse files in sparse-formats.tar alternate one-byte holes and
+	// one-byte data fragments, and end with a larger hole.
+	var sparseHoles []SparseEntry
+	for i := int64(0); i < 190; i += 2 {
+		sparseHoles = append(sparseHoles, SparseEntry{i, 1})
+	}
+	sparseHoles = append(sparseHoles, SparseEntry{190, 10})
+
 	vectors := []struct {
 		file    string    // Test input file
 		headers []*Header // Expected output headers
@@ -36,6 +44,7 @@ func TestReader(t *testing.T) {
 			Typeflag: '0',
 			Uname:    "dsymonds",
 			Gname:    "eng",
+			Format:   FormatGNU,
 		}, {
 			Name:     "small2.txt",
 			Mode:     0640,
@@ -46,6 +55,7 @@ func TestReader(t *testing.T) {
 			Typeflag: '0',
 			Uname:    "dsymonds",
 			Gname:    "eng",
+			Format:   FormatGNU,
 		}},
 		chksums: []string{
 			"e38b27eaccb4391bdec553a7f3ae6b2f",
@@ -54,57 +64,65 @@ func TestReader(t *testing.T) {
 	}, {
 		file: "testdata/sparse-formats.tar",
 		headers: []*Header{{
-			Name:     "sparse-gnu",
-			Mode:     420,
-			Uid:      1000,
-			Gid:      1000,
-			Size:     200,
-			ModTime:  time.Unix(1392395740, 0),
-			Typeflag: 0x53,
-			Linkname: "",
-			Uname:    "david",
-			Gname:    "david",
-			Devmajor: 0,
-			Devminor: 0,
+			Name:        "sparse-gnu",
+			Mode:        420,
+			Uid:         1000,
+			Gid:         1000,
+			Size:        200,
+			ModTime:     time.Unix(1392395740, 0),
+			Typeflag:    0x53,
+			Linkname:    "",
+			Uname:       "david",
+			Gname:       "david",
+			Devmajor:    0,
+			Devminor:    0,
+			SparseHoles: sparseHoles,
+			Format:      FormatGNU,
 		}, {
-			Name:     "sparse-posix-0.0",
-			Mode:     420,
-			Uid:      1000,
-			Gid:      1000,
-			Size:     200,
-			ModTime:  time.Unix(1392342187, 0),
-			Typeflag: 0x30,
-			Linkname: "",
-			Uname:    "david",
-			Gname:    "david",
-			Devmajor: 0,
-			Devminor: 0,
+			Name:        "sparse-posix-0.0",
+			Mode:        420,
+			Uid:         1000,
+			Gid:         1000,
+			Size:        200,
+			ModTime:     time.Unix(1392342187, 0),
+			Typeflag:    0x30,
+			Linkname:    "",
+			Uname:       "david",
+			Gname:       "david",
+			Devmajor:    0,
+			Devminor:    0,
+			SparseHoles: sparseHoles,
+			Format:      FormatPAX,
 		}, {
-			Name:     "sparse-posix-0.1",
-			Mode:     420,
-			Uid:      1000,
-			Gid:      1000,
-			Size:     200,
-			ModTime:  time.Unix(1392340456, 0),
-			Typeflag: 0x30,
-			Linkname: "",
-			Uname:    "david",
-			Gname:    "david",
-			Devmajor: 0,
-			Devminor: 0,
+			Name:        "sparse-posix-0.1",
+			Mode:        420,
+			Uid:         1000,
+			Gid:         1000,
+			Size:        200,
+			ModTime:     time.Unix(1392340456, 0),
+			Typeflag:    0x30,
+			Linkname:    "",
+			Uname:       "david",
+			Gname:       "david",
+			Devmajor:    0,
+			Devminor:    0,
+			SparseHoles: sparseHoles,
+			Format:      FormatPAX,
 		}, {
-			Name:     "sparse-posix-1.0",
-			Mode:     420,
-			Uid:      1000,
-			Gid:      1000,
-			Size:     200,
-			ModTime:  time.Unix(1392337404, 0),
-			Typeflag: 0x30,
-			Linkname: "",
-			Uname:    "david",
-			Gname:    "david",
-			Devmajor: 0,
-			Devminor: 0,
+			Name:        "sparse-posix-1.0",
+			Mode:        420,
+			Uid:         1000,
+			Gid:         1000,
+			Size:        200,
+			ModTime:     time.Unix(1392337404, 0),
+			Typeflag:    0x30,
+			Linkname:    "",
+			Uname:       "david",
+			Gname:       "david",
+			Devmajor:    0,
+			Devminor:    0,
+			SparseHoles: sparseHoles,
+			Format:      FormatPAX,
 		}, {
 			Name:     "end",
 			Mode:     420,
@@ -118,6 +136,7 @@ func TestReader(t *testing.T) {
 			Gname:    "david",
 			Devmajor: 0,
 			Devminor: 0,
+			Format:   FormatGNU,
 		}},
 		chksums: []string{
 			"6f53234398c2449fe67c1812d993012f",
@@ -186,6 +205,7 @@ func TestReader(t *testing.T) {
 			ChangeTime: time.Unix(1350244992, 23960108),
 			AccessTime: time.Unix(1350244992, 23960108),
 			Typeflag:   TypeReg,
+			Format:     FormatPAX,
 		}, {
 			Name:       "a/b",
 			Mode:       0777,
@@ -199,6 +219,7 @@ func TestReader(t *testing.T) {
 			AccessTime: time.Unix(1350266320, 910238425),
 			Typeflag:   TypeSymlink,
 			Linkname:   "123456789101112131415161718192021222324252627282930313233343536373839404142434445464748495051525354555657585960616263646566676869707172737475767778798081828384858687888990919293949596979899100",
+			Format:     FormatPAX,
 		}},
 	}, {
 		file: "testdata/pax-bad-hdr-file.tar",
@@ -218,6 +239,7 @@ func TestReader(t *testing.T) {
 			Typeflag: '0',
 			Uname:    "joetsai",
 			Gname:    "eng",
+			Format:   FormatGNU,
 		}},
 		chksums: []string{
 			"0afb597b283fe61b5d4879669a350556",
@@ -237,6 +259,7 @@ func TestReader(t *testing.T) {
 			Gname:    "eyefi",
 			Devmajor: 0,
 			Devminor: 0,
+			Format:   FormatGNU,
 		}},
 	}, {
 		file: "testdata/xattrs.tar",
@@ -258,6 +281,7 @@ func TestReader(t *testing.T) {
 				// Interestingly, selinux encodes the terminating null inside the xattr
 				"security.selinux": "unconfined_u:object_r:default_t:s0\x00",
 			},
+			Format: FormatPAX,
 		}, {
 			Name:       "small2.txt",
 			Mode:       0644,
@@ -273,6 +297,7 @@ func TestReader(t *testing.T) {
 			Xattrs: map[string]string{
 				"security.selinux": "unconfined_u:object_r:default_t:s0\x00",
 			},
+			Format: FormatPAX,
 		}},
 	}, {
 		// Matches the behavior of GNU, BSD, and STAR tar utilities.
@@ -282,6 +307,7 @@ func TestReader(t *testing.T) {
 			Linkname: "GNU4/GNU4/long-linkpath-name",
 			ModTime:  time.Unix(0, 0),
 			Typeflag: '2',
+			Format:   FormatGNU,
 		}},
 	}, {
 		// GNU tar file with atime and ctime fields set.
@@ -300,6 +326,7 @@ func TestReader(t *testing.T) {
 			Gname:      "dsnet",
 			AccessTime: time.Unix(1441974501, 0),
 			ChangeTime: time.Unix(1441973436, 0),
+			Format:     FormatGNU,
 		}, {
 			Name:       "test2/foo",
 			Mode:       33188,
@@ -312,18 +339,21 @@ func TestReader(t *testing.T) {
 			Gname:      "dsnet",
 			AccessTime: time.Unix(1441974501, 0),
 			ChangeTime: time.Unix(1441973436, 0),
+			Format:     FormatGNU,
 		}, {
-			Name:       "test2/sparse",
-			Mode:       33188,
-			Uid:        1000,
-			Gid:        1000,
-			Size:       536870912,
-			ModTime:    time.Unix(1441973427, 0),
-			Typeflag:   'S',
-			Uname:      "rawr",
-			Gname:      "dsnet",
-			AccessTime: time.Unix(1441991948, 0),
-			ChangeTime: time.Unix(1441973436, 0),
+			Name:        "test2/sparse",
+			Mode:        33188,
+			Uid:         1000,
+			Gid:         1000,
+			Size:        536870912,
+			ModTime:     time.Unix(1441973427, 0),
+			Typeflag:    'S',
+			Uname:       "rawr",
+			Gname:       "dsnet",
+			AccessTime:  time.Unix(1441991948, 0),
+			ChangeTime:  time.Unix(1441973436, 0),
+			SparseHoles: []SparseEntry{{0, 536870912}},
+			Format:      FormatGNU,
 		}},
 	}, {
 		// Matches the behavior of GNU and BSD tar utilities.
@@ -333,6 +363,7 @@ func TestReader(t *testing.T) {
 			Linkname: "PAX4/PAX4/long-linkpath-name",
 			ModTime:  time.Unix(0, 0),
 			Typeflag: '2',
+			Format:   FormatPAX,
 		}},
 	}, {
 		file: "testdata/neg-size.tar",
@@ -391,6 +422,11 @@ func TestReader(t *testing.T) {
 					v.file, i, j, *hdr)
 				continue
 			}
+			if hdr.readFormat != hdr.Format {
+				t.Errorf("file %s, test %d, entry %d: readFormat = %v, want %v",
+					v.file, i, j, hdr.readFormat, hdr.Format)
+			}
+			hdr.readFormat = FormatUnknown
 			if !reflect.DeepEqual(*hdr, *v.headers[j]) {
 				t.Errorf("file %s, test %d, entry %d: incorrect header:\ngot  %+v\nwant %+v",
 					v.file, i, j, *hdr, *v.headers[j])
@@ -620,7 +656,7 @@ func TestReadOldGNUSparseMap(t *testing.T) {
 		t21 = "00000000002\x0000000000001\x00"
 	)
 
-	mkBlk := func(size, sp0, sp1, sp2, sp3, ext string, format int) *block {
+	mkBlk := func(size, sp0, sp1, sp2, sp3, ext string, format Format) *block {
 		var blk block
 		copy(blk.GNU().RealSize(), size)
 		copy(blk.GNU().Sparse().Entry(0), sp0)
@@ -628,7 +664,7 @@ func TestReadOldGNUSparseMap(t *testing.T) {
 		copy(blk.GNU().Sparse().Entry(2), sp2)
 		copy(blk.GNU().Sparse().Entry(3), sp3)
 		copy(blk.GNU().Sparse().IsExtended(), ext)
-		if format != formatUnknown {
+		if format != FormatUnknown {
 			blk.SetFormat(format)
 		}
 		return &blk
@@ -640,20 +676,20 @@ func TestReadOldGNUSparseMap(t *testing.T) {
 		want   []sparseEntry // Expected sparse entries to be outputted
 		err    error         // Expected error to be returned
 	}{
-		{"", mkBlk("", "", "", "", "", "", formatUnknown), nil, ErrHeader},
-		{"", mkBlk("1234", "fewa", "", "", "", "", formatGNU), nil, ErrHeader},
-		{"", mkBlk("0031", "", "", "", "", "", formatGNU), nil, nil},
-		{"", mkBlk("1234", t00, t11, "", "", "", formatGNU),
+		{"", mkBlk("", "", "", "", "", "", FormatUnknown), nil, ErrHeader},
+		{"", mkBlk("1234", "fewa", "", "", "", "", FormatGNU), nil, ErrHeader},
+		{"", mkBlk("0031", "", "", "", "", "", FormatGNU), nil, nil},
+		{"", mkBlk("1234", t00, t11, "", "", "", FormatGNU),
 			[]sparseEntry{{0, 0}, {1, 1}}, nil},
-		{"", mkBlk("1234", t11, t12, t21, t11, "", formatGNU),
+		{"", mkBlk("1234", t11, t12, t21, t11, "", FormatGNU),
 			[]sparseEntry{{1, 1}, {1, 2}, {2, 1}, {1, 1}}, nil},
-		{"", mkBlk("1234", t11, t12, t21, t11, "\x80", formatGNU),
+		{"", mkBlk("1234", t11, t12, t21, t11, "\x80", FormatGNU),
 			[]sparseEntry{}, io.ErrUnexpectedEOF},
 		{t11 + t11,
-			mkBlk("1234", t11, t12, t21, t11, "\x80", formatGNU),
+			mkBlk("1234", t11, t12, t21, t11, "\x80", FormatGNU),
 			[]sparseEntry{}, io.ErrUnexpectedEOF},
 		{t11 + t21 + strings.Repeat("\x00", 512),
-			mkBlk("1234", t11, t12, t21, t11, "\x80", formatGNU),
+			mkBlk("1234", t11, t12, t21, t11, "\x80", FormatGNU),
 			[]sparseEntry{{1, 1}, {1, 2}, {2, 1}, {1, 1}, {1, 1}, {2, 1}}, nil},
 	}
 
@@ -0,0 +1,59 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin dragonfly freebsd openbsd netbsd solaris
+
+package tar
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+func init() {
+	sysSparseDetect = sparseDetectUnix
+}
+
+func sparseDetectUnix(f *os.File) ([]SparseEntry, error) {
+	// SEEK_DATA and SEEK_HOLE originated in Solaris and have since been
+	// adopted by most other Unix systems. Darwin swaps their values.
+	seekData, seekHole := 3, 4
+	if runtime.GOOS == "darwin" {
+		seekData, seekHole = 4, 3
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Systems and file systems report a lack of support for SEEK_HOLE
+	// with different errno values, so treat any error as no support.
+	if _, err := f.Seek(0, seekHole); err != nil {
+		return nil, nil
+	}
+
+	var holes []SparseEntry
+	for pos := int64(0); pos < end; {
+		hole, err := f.Seek(pos, seekHole)
+		if err != nil {
+			return nil, err
+		}
+		if hole >= end {
+			break // The implicit hole at the end of the file
+		}
+		data, err := f.Seek(hole, seekData)
+		if err != nil {
+			if pe, ok := err.(*os.PathError); !ok || pe.Err != syscall.ENXIO {
+				return nil, err
+			}
+			data = end // SEEK_DATA fails with ENXIO in a trailing hole
+		}
+		holes = append(holes, SparseEntry{Offset: hole, Length: data - hole})
+		pos = data
+	}
+	return holes, nil
+}
@@ -236,6 +236,37 @@ func TestParsePAXTime(t *testing.T) {
 	}
 }
 
+func TestFormatPAXTime(t *testing.T) {
+	vectors := []struct {
+		sec, nsec int64
+		want      string
+	}{
+		{1350244992, 0, "1350244992"},
+		{1350244992, 300000000, "1350244992.3"},
+		{1350244992, 23960100, "1350244992.0239601"},
+		{1350244992, 23960108, "1350244992.023960108"},
+		{+1, +1, "1.000000001"},
+		{0, 0, "0"},
+		{-1, 0, "-1"},
+		{-1, +1e9 - 1, "-0.000000001"},
+		{-1, +1e9 - 1e8, "-0.1"},
+		{-2, +1e9 - 1e8, "-1.1"},
+		{-1350244992, 300000000, "-1350244991.7"},
+	}
+
+	for _, v := range vectors {
+		ts := time.Unix(v.sec, v.nsec)
+		got := formatPAXTime(ts)
+		if got != v.want {
+			t.Errorf("formatPAXTime(%ds, %dns): got %q, want %q",
+				v.sec, v.nsec, got, v.want)
+		}
+		if ts2, err := parsePAXTime(got); err != nil || !ts2.Equal(ts) {
+			t.Errorf("parsePAXTime(%q): got (%v, %v), want (%v, nil)", got, ts2, err, ts)
+		}
+	}
+}
+
 func TestParsePAXRecord(t *testing.T) {
 	medName := strings.Repeat("CD", 50)
 	longName := strings.Repeat("AB", 100)
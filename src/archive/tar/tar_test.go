@@ -6,6 +6,7 @@ package tar
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -103,6 +104,7 @@ func TestRoundTrip(t *testing.T) {
 		// and would otherwise break the round-trip check
 		// below.
 		ModTime: time.Now().AddDate(0, 0, 0).Round(1 * time.Second),
+		Format:  FormatGNU,
 	}
 	if err := tw.WriteHeader(hdr); err != nil {
 		t.Fatalf("tw.WriteHeader: %v", err)
@@ -120,6 +122,7 @@ func TestRoundTrip(t *testing.T) {
 	if err != nil {
 		t.Fatalf("tr.Next: %v", err)
 	}
+	rHdr.readFormat = FormatUnknown
 	if !reflect.DeepEqual(rHdr, hdr) {
 		t.Errorf("Header mismatch.\n got %+v\nwant %+v", rHdr, hdr)
 	}
@@ -314,3 +317,52 @@ func TestHeaderRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectSparseHoles(t *testing.T) {
+	f, err := ioutil.TempFile("", "tar-sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// Write a file with a large hole followed by some data and a trailing
+	// hole; file systems allocate in blocks, so keep the sizes generous.
+	const size = 8 << 20
+	if _, err := f.WriteAt([]byte("hello, world"), 4<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	hdr := &Header{Name: "sparse", Size: size}
+	if err := hdr.DetectSparseHoles(f); err != nil {
+		t.Fatalf("unexpected DetectSparseHoles error: %v", err)
+	}
+	if pos, _ := f.Seek(0, io.SeekCurrent); pos != 5 {
+		t.Errorf("file offset = %d, want 5", pos)
+	}
+	if !validateSparseHoles(hdr.SparseHoles, size) {
+		t.Fatalf("invalid sparse holes: %v", hdr.SparseHoles)
+	}
+	if len(hdr.SparseHoles) == 0 {
+		t.Skip("file system does not report holes")
+	}
+
+	// Every byte within a reported hole must be zero.
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hdr.SparseHoles {
+		for i := h.Offset; i < h.endOffset(); i++ {
+			if data[i] != 0 {
+				t.Fatalf("non-zero byte at offset %d in hole %v", i, h)
+			}
+		}
+	}
+}
@@ -24,6 +24,10 @@ var (
 	ErrFieldTooLong    = errors.New("archive/tar: header field too long")
 	ErrWriteAfterClose = errors.New("archive/tar: write after close")
 	errInvalidHeader   = errors.New("archive/tar: header field too long or contains invalid values")
+
+	errInvalidSparseHoles = errors.New("archive/tar: invalid sparse holes")
+	errSparseNonRegular   = errors.New("archive/tar: sparse holes in a file that is not regular")
+	errWriteHole          = errors.New("archive/tar: write non-NUL byte in sparse hole")
 )
 
 // A Writer provides sequential writing of a tar archive in POSIX.1 format.
@@ -40,6 +44,17 @@ type Writer struct {
 	preferPax  bool  // use PAX header instead of binary numeric header
 	hdrBuff    block // buffer to use in writeHeader when writing a regular header
 	paxHdrBuff block // buffer to use in writeHeader when writing a PAX header
+
+	// sparse is non-nil while the data of a sparse file entry is written.
+	sparse *sparseFileWriter
+}
+
+// A sparseFileWriter tracks the logical position in a sparse file so that
+// only its data fragments are written to the archive.
+type sparseFileWriter struct {
+	sp    []sparseEntry // The remaining data fragments of the file
+	pos   int64         // Keeps track of file position
+	total int64         // Total size of the file
 }
 
 // NewWriter creates a new Writer writing to w.
@@ -47,6 +62,10 @@ func NewWriter(w io.Writer) *Writer { return &Writer{w: w} }
 
 // Flush finishes writing the current file (optional).
 func (tw *Writer) Flush() error {
+	if sw := tw.sparse; sw != nil && sw.pos < sw.total {
+		tw.err = fmt.Errorf("archive/tar: missed writing %d bytes", sw.total-sw.pos)
+		return tw.err
+	}
 	if tw.nb > 0 {
 		tw.err = fmt.Errorf("archive/tar: missed writing %d bytes", tw.nb)
 		return tw.err
@@ -64,6 +83,7 @@ func (tw *Writer) Flush() error {
 	}
 	tw.nb = 0
 	tw.pad = 0
+	tw.sparse = nil
 	return tw.err
 }
 
@@ -76,8 +96,36 @@ var (
 // WriteHeader writes hdr and prepares to accept the file's contents.
 // WriteHeader calls Flush if it is not the first header.
 // Calling after a Close will return ErrWriteAfterClose.
+//
+// If hdr.Format is FormatUnknown, the header is written in the USTAR
+// format, falling back to PAX records for long or non-ASCII strings and
+// to GNU binary fields for large numbers. Otherwise the header is written
+// in the requested format, and an error is returned if some field cannot
+// be represented in it. A Format set by Reader.Next and left unchanged
+// is not forced; see Header.Format.
+//
+// If hdr.SparseHoles is non-empty, or hdr.Typeflag is TypeGNUSparse, the
+// entry is written as a sparse file: Write then expects the hdr.Size bytes
+// of the logical file, including the zeros of its holes, and only the
+// data fragments are stored in the archive.
 func (tw *Writer) WriteHeader(hdr *Header) error {
-	return tw.writeHeader(hdr, true)
+	if hdr.Format == FormatUnknown || hdr.Format != hdr.readFormat {
+		return tw.writeHeader(hdr, true)
+	}
+
+	// The format is the one the header was read in, not one requested
+	// by the caller. Keep the USTAR or PAX format if the header still
+	// fits, so that it is written as it was read; GNU headers are written
+	// like new ones, which only use the GNU format where they must.
+	h := *hdr
+	if h.Format == FormatUSTAR || h.Format == FormatPAX {
+		err := tw.writeHeader(&h, true)
+		if _, ok := err.(*formatError); !ok {
+			return err
+		}
+	}
+	h.Format = FormatUnknown
+	return tw.writeHeader(&h, true)
 }
 
 // WriteHeader writes hdr and prepares to accept the file's contents.
@@ -96,12 +144,29 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 		return tw.err
 	}
 
+	format := hdr.Format
+	switch format {
+	case FormatUnknown, FormatUSTAR, FormatPAX, FormatGNU:
+	default:
+		return fmt.Errorf("archive/tar: cannot write header in %v format", format)
+	}
+
 	// a map to hold pax header records, if any are needed
 	paxHeaders := make(map[string]string)
 
-	// TODO(dsnet): we might want to use PAX headers for
-	// subsecond time resolution, but for now let's just capture
-	// too long fields or non ascii characters
+	// Fields that cannot be represented in an explicitly requested format
+	// are recorded in unencodable, and names that need a GNU long name
+	// header in longName and longLink.
+	var unencodable []string
+	var longName, longLink string
+	cannotEncode := func(field string) {
+		for _, f := range unencodable {
+			if f == field {
+				return
+			}
+		}
+		unencodable = append(unencodable, field)
+	}
 
 	// We need to select which scratch buffer to use carefully,
 	// since this method is called recursively to write PAX headers.
@@ -116,23 +181,73 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 
 	// Wrappers around formatter that automatically sets paxHeaders if the
 	// argument extends beyond the capacity of the input byte slice.
+	// The field is the name of the Header field being written, for errors.
 	var f formatter
-	var formatString = func(b []byte, s string, paxKeyword string) {
-		needsPaxHeader := paxKeyword != paxNone && len(s) > len(b) || !isASCII(s)
-		if needsPaxHeader {
-			paxHeaders[paxKeyword] = s
-			return
+	var formatString = func(b []byte, s string, paxKeyword, field string) {
+		switch format {
+		case FormatUSTAR:
+			if len(s) > len(b) || !isASCII(s) {
+				cannotEncode(field)
+				return
+			}
+		case FormatPAX:
+			if len(s) > len(b) || !isASCII(s) {
+				if paxKeyword == paxNone {
+					cannotEncode(field)
+					return
+				}
+				paxHeaders[paxKeyword] = s
+				return
+			}
+		case FormatGNU:
+			if len(s) > len(b) || !isASCII(s) {
+				switch paxKeyword {
+				case paxPath:
+					longName = s
+					copy(b, s) // Truncated copy, like GNU tar
+				case paxLinkpath:
+					longLink = s
+					copy(b, s)
+				default:
+					cannotEncode(field)
+				}
+				return
+			}
+		default:
+			needsPaxHeader := paxKeyword != paxNone && len(s) > len(b) || !isASCII(s)
+			if needsPaxHeader {
+				paxHeaders[paxKeyword] = s
+				return
+			}
 		}
 		f.formatString(b, s)
 	}
-	var formatNumeric = func(b []byte, x int64, paxKeyword string) {
-		// Try octal first.
+	var formatNumeric = func(b []byte, x int64, paxKeyword, field string) {
+		// Try octal first. Only the default format writes negative
+		// values in octal, for compatibility with earlier versions.
 		s := strconv.FormatInt(x, 8)
-		if len(s) < len(b) {
+		if len(s) < len(b) && (x >= 0 || format == FormatUnknown) {
 			f.formatOctal(b, x)
 			return
 		}
 
+		switch format {
+		case FormatUSTAR:
+			cannotEncode(field)
+			return
+		case FormatPAX:
+			if paxKeyword == paxNone {
+				cannotEncode(field)
+				return
+			}
+			f.formatOctal(b, 0)
+			paxHeaders[paxKeyword] = strconv.FormatInt(x, 10)
+			return
+		case FormatGNU:
+			f.formatNumeric(b, x)
+			return
+		}
+
 		// If it is too long for octal, and PAX is preferred, use a PAX header.
 		if paxKeyword != paxNone && tw.preferPax {
 			f.formatOctal(b, 0)
@@ -150,25 +265,113 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 	if !hdr.ModTime.Before(minTime) && !hdr.ModTime.After(maxTime) {
 		modTime = hdr.ModTime.Unix()
 	}
+	switch format {
+	case FormatUSTAR:
+		if modTime != hdr.ModTime.Unix() {
+			cannotEncode("ModTime")
+		}
+	case FormatPAX:
+		if modTime != hdr.ModTime.Unix() || hdr.ModTime.Nanosecond() != 0 {
+			paxHeaders[paxMtime] = formatPAXTime(hdr.ModTime)
+		}
+		if !hdr.AccessTime.IsZero() {
+			paxHeaders[paxAtime] = formatPAXTime(hdr.AccessTime)
+		}
+		if !hdr.ChangeTime.IsZero() {
+			paxHeaders[paxCtime] = formatPAXTime(hdr.ChangeTime)
+		}
+	case FormatGNU:
+		modTime = hdr.ModTime.Unix()
+	}
+
+	// Sparse files store only their data fragments, described by a sparse
+	// map: in the header and extension blocks for the GNU format, and at
+	// the start of the data for the PAX format (GNU sparse version 1.0).
+	var sp []sparseEntry
+	var spMap []byte // sparse map preceding the data in the PAX format
+	size, typeflag := hdr.Size, hdr.Typeflag
+	gnuSparse := hdr.Typeflag == TypeGNUSparse
+	if allowPax && (len(hdr.SparseHoles) > 0 || gnuSparse) {
+		if !validateSparseHoles(hdr.SparseHoles, hdr.Size) {
+			return errInvalidSparseHoles
+		}
+		switch {
+		case format == FormatUSTAR || gnuSparse && format == FormatPAX:
+			cannotEncode("SparseHoles")
+		case !gnuSparse && hdr.Typeflag != TypeReg && hdr.Typeflag != TypeRegA:
+			return errSparseNonRegular
+		}
+		gnuSparse = gnuSparse || format == FormatGNU
+		sp = sparseDataFragments(hdr.SparseHoles, hdr.Size)
+		size = 0
+		for _, s := range sp {
+			size += s.numBytes
+		}
+		if gnuSparse {
+			typeflag = TypeGNUSparse
+		} else {
+			typeflag = TypeReg
+			spMap = formatGNUSparseMap1x0(sp)
+			paxHeaders[paxGNUSparseMajor] = "1"
+			paxHeaders[paxGNUSparseMinor] = "0"
+			paxHeaders[paxGNUSparseName] = hdr.Name
+			paxHeaders[paxGNUSparseRealSize] = strconv.FormatInt(hdr.Size, 10)
+		}
+	}
 
 	v7 := header.V7()
-	formatString(v7.Name(), hdr.Name, paxPath)
+	ustar := header.USTAR()
+	switch {
+	case spMap != nil:
+		// The real name is in the GNU.sparse.name record.
+		dir, file := path.Split(hdr.Name)
+		f.formatString(v7.Name(), paxHeaderName(path.Join(dir, "GNUSparseFile.0", file)))
+	case format == FormatUSTAR && len(hdr.Name) > nameSize:
+		prefix, suffix, ok := splitUSTARPath(hdr.Name)
+		if !ok {
+			cannotEncode("Name")
+			break
+		}
+		formatString(v7.Name(), suffix, paxNone, "Name")
+		formatString(ustar.Prefix(), prefix, paxNone, "Name")
+	default:
+		formatString(v7.Name(), hdr.Name, paxPath, "Name")
+	}
 	// TODO(dsnet): The GNU format permits the mode field to be encoded in
 	// base-256 format. Thus, we can use formatNumeric instead of formatOctal.
 	f.formatOctal(v7.Mode(), hdr.Mode)
-	formatNumeric(v7.UID(), int64(hdr.Uid), paxUid)
-	formatNumeric(v7.GID(), int64(hdr.Gid), paxGid)
-	formatNumeric(v7.Size(), hdr.Size, paxSize)
+	formatNumeric(v7.UID(), int64(hdr.Uid), paxUid, "Uid")
+	formatNumeric(v7.GID(), int64(hdr.Gid), paxGid, "Gid")
+	formatNumeric(v7.Size(), size+int64(len(spMap)), paxSize, "Size")
 	// TODO(dsnet): Consider using PAX for finer time granularity.
-	formatNumeric(v7.ModTime(), modTime, paxNone)
-	v7.TypeFlag()[0] = hdr.Typeflag
-	formatString(v7.LinkName(), hdr.Linkname, paxLinkpath)
-
-	ustar := header.USTAR()
-	formatString(ustar.UserName(), hdr.Uname, paxUname)
-	formatString(ustar.GroupName(), hdr.Gname, paxGname)
-	formatNumeric(ustar.DevMajor(), hdr.Devmajor, paxNone)
-	formatNumeric(ustar.DevMinor(), hdr.Devminor, paxNone)
+	formatNumeric(v7.ModTime(), modTime, paxNone, "ModTime")
+	v7.TypeFlag()[0] = typeflag
+	formatString(v7.LinkName(), hdr.Linkname, paxLinkpath, "Linkname")
+
+	formatString(ustar.UserName(), hdr.Uname, paxUname, "Uname")
+	formatString(ustar.GroupName(), hdr.Gname, paxGname, "Gname")
+	formatNumeric(ustar.DevMajor(), hdr.Devmajor, paxNone, "Devmajor")
+	formatNumeric(ustar.DevMinor(), hdr.Devminor, paxNone, "Devminor")
+
+	// The GNU format has fields for the access and change times and for
+	// the sparse map, where USTAR has its prefix field.
+	var spExt []block // extension blocks holding the rest of the GNU sparse map
+	if format == FormatGNU {
+		gnu := header.GNU()
+		if !hdr.AccessTime.IsZero() {
+			formatNumeric(gnu.AccessTime(), hdr.AccessTime.Unix(), paxNone, "AccessTime")
+		}
+		if !hdr.ChangeTime.IsZero() {
+			formatNumeric(gnu.ChangeTime(), hdr.ChangeTime.Unix(), paxNone, "ChangeTime")
+		}
+	}
+	if gnuSparse && sp != nil {
+		gnu := header.GNU()
+		formatNumeric(gnu.RealSize(), hdr.Size, paxNone, "Size")
+		spExt = formatOldGNUSparseMap(gnu.Sparse(), sp, func(b []byte, x int64) {
+			formatNumeric(b, x, paxNone, "SparseHoles")
+		})
+	}
 
 	// TODO(dsnet): The logic surrounding the prefix field is broken when trying
 	// to encode the header as GNU format. The challenge with the current logic
@@ -182,7 +385,8 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 	//
 	// As a short-term fix, we disable the logic to use the prefix field, which
 	// will force the badly generated GNU files to become encoded as being
-	// the PAX format.
+	// the PAX format. The prefix field is only used when the USTAR format
+	// is explicitly requested with Header.Format.
 	//
 	// As an alternative fix, we could hard-code preferPax to be true. However,
 	// this is problematic for the following reasons:
@@ -211,15 +415,31 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 			delete(paxHeaders, paxPath)
 
 			// Update the path fields
-			formatString(v7.Name(), suffix, paxNone)
-			formatString(ustar.Prefix(), prefix, paxNone)
+			formatString(v7.Name(), suffix, paxNone, "Name")
+			formatString(ustar.Prefix(), prefix, paxNone, "Name")
 		}
 	}
 
-	if tw.usedBinary {
-		header.SetFormat(formatGNU)
-	} else {
-		header.SetFormat(formatUSTAR)
+	switch {
+	case format != FormatUnknown:
+		header.SetFormat(format)
+	case tw.usedBinary || gnuSparse && sp != nil:
+		header.SetFormat(FormatGNU)
+	default:
+		header.SetFormat(FormatUSTAR)
+	}
+
+	if allowPax && len(hdr.Xattrs) > 0 {
+		if format == FormatUSTAR || format == FormatGNU {
+			cannotEncode("Xattrs")
+		}
+		for k, v := range hdr.Xattrs {
+			paxHeaders[paxXattr+k] = v
+		}
+	}
+
+	if len(unencodable) > 0 {
+		return &formatError{unencodable, format}
 	}
 
 	// Check if there were any formatting errors.
@@ -228,12 +448,6 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 		return tw.err
 	}
 
-	if allowPax {
-		for k, v := range hdr.Xattrs {
-			paxHeaders[paxXattr+k] = v
-		}
-	}
-
 	if len(paxHeaders) > 0 {
 		if !allowPax {
 			return errInvalidHeader
@@ -242,11 +456,84 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 			return err
 		}
 	}
-	tw.nb = hdr.Size
+	if longLink != "" {
+		if err := tw.writeGNULongHeader(TypeGNULongLink, longLink); err != nil {
+			return err
+		}
+	}
+	if longName != "" {
+		if err := tw.writeGNULongHeader(TypeGNULongName, longName); err != nil {
+			return err
+		}
+	}
+
+	if _, tw.err = tw.w.Write(header[:]); tw.err != nil {
+		return tw.err
+	}
+	for i := range spExt {
+		if _, tw.err = tw.w.Write(spExt[i][:]); tw.err != nil {
+			return tw.err
+		}
+	}
+	if len(spMap) > 0 {
+		if _, tw.err = tw.w.Write(spMap); tw.err != nil {
+			return tw.err
+		}
+	}
+
+	tw.nb = size
 	tw.pad = (blockSize - (tw.nb % blockSize)) % blockSize
+	if sp != nil {
+		tw.sparse = &sparseFileWriter{sp: sp, total: hdr.Size}
+	}
+	return nil
+}
 
-	_, tw.err = tw.w.Write(header[:])
-	return tw.err
+// A formatError lists the Header fields that cannot be encoded in the
+// format requested by Header.Format.
+type formatError struct {
+	fields []string
+	format Format
+}
+
+func (e *formatError) Error() string {
+	return fmt.Sprintf("archive/tar: cannot encode %s in %v format", strings.Join(e.fields, ", "), e.format)
+}
+
+// formatOldGNUSparseMap stores the sparse map sp in the sparse array of a
+// GNU header and returns the extension blocks needed for the entries that
+// do not fit in it.
+func formatOldGNUSparseMap(s sparseArray, sp []sparseEntry, formatNumeric func([]byte, int64)) []block {
+	var ext []block
+	for {
+		for i := 0; i < s.MaxEntries() && len(sp) > 0; i++ {
+			formatNumeric(s.Entry(i).Offset(), sp[0].offset)
+			formatNumeric(s.Entry(i).NumBytes(), sp[0].numBytes)
+			sp = sp[1:]
+		}
+		if len(sp) == 0 {
+			return ext
+		}
+		s.IsExtended()[0] = 1
+		ext = append(ext, block{})
+		s = ext[len(ext)-1].Sparse()
+	}
+}
+
+// formatGNUSparseMap1x0 formats the sparse map sp as stored at the start of
+// the data of a file in GNU's PAX sparse format version 1.0: the number of
+// entries followed by the offset and size of each, as newline-terminated
+// decimal numbers, padded with zeros to a multiple of the block size.
+func formatGNUSparseMap1x0(sp []sparseEntry) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d\n", len(sp))
+	for _, s := range sp {
+		fmt.Fprintf(&buf, "%d\n%d\n", s.offset, s.numBytes)
+	}
+	if n := buf.Len() % blockSize; n > 0 {
+		buf.Write(zeroBlock[n:])
+	}
+	return buf.Bytes()
 }
 
 // splitUSTARPath splits a path according to USTAR prefix and suffix rules.
@@ -284,13 +571,7 @@ func (tw *Writer) writePAXHeader(hdr *Header, paxHeaders map[string]string) erro
 	// for identical inputs. As such, the constant 0 is now used instead.
 	// golang.org/issue/12358
 	dir, file := path.Split(hdr.Name)
-	fullName := path.Join(dir, "PaxHeaders.0", file)
-
-	ascii := toASCII(fullName)
-	if len(ascii) > nameSize {
-		ascii = ascii[:nameSize]
-	}
-	ext.Name = ascii
+	ext.Name = paxHeaderName(path.Join(dir, "PaxHeaders.0", file))
 	// Construct the body
 	var buf bytes.Buffer
 
@@ -318,14 +599,50 @@ func (tw *Writer) writePAXHeader(hdr *Header, paxHeaders map[string]string) erro
 	return nil
 }
 
+// paxHeaderName returns the name for a special file used by the PAX format,
+// reduced to what fits in the name field of a USTAR header.
+func paxHeaderName(name string) string {
+	ascii := toASCII(name)
+	if len(ascii) > nameSize {
+		ascii = ascii[:nameSize]
+	}
+	return ascii
+}
+
+// writeGNULongHeader writes a GNU header of type flag holding the long
+// name or link name s of the next entry.
+func (tw *Writer) writeGNULongHeader(flag byte, s string) error {
+	ext := &Header{
+		Name:     "././@LongLink",
+		ModTime:  minTime,
+		Typeflag: flag,
+		Size:     int64(len(s)) + 1, // NUL-terminated
+		Format:   FormatGNU,
+	}
+	if err := tw.writeHeader(ext, false); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, s+"\x00"); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
 // Write writes to the current entry in the tar archive.
 // Write returns the error ErrWriteTooLong if more than
 // hdr.Size bytes are written after WriteHeader.
+//
+// For sparse files, b is part of the logical file data: the bytes
+// falling in holes are not stored, and Write returns an error if
+// any of them is not zero.
 func (tw *Writer) Write(b []byte) (n int, err error) {
 	if tw.closed {
 		err = ErrWriteAfterClose
 		return
 	}
+	if tw.sparse != nil {
+		return tw.writeSparse(b)
+	}
 	overwrite := false
 	if int64(len(b)) > tw.nb {
 		b = b[0:tw.nb]
@@ -341,6 +658,63 @@ func (tw *Writer) Write(b []byte) (n int, err error) {
 	return
 }
 
+// writeSparse writes the data fragments in b of the current sparse entry.
+func (tw *Writer) writeSparse(b []byte) (n int, err error) {
+	sw := tw.sparse
+	overwrite := false
+	if int64(len(b)) > sw.total-sw.pos {
+		b = b[:sw.total-sw.pos]
+		overwrite = true
+	}
+	for len(b) > 0 {
+		// Skip past all finished fragments.
+		for len(sw.sp) > 0 && sw.pos >= sw.sp[0].offset+sw.sp[0].numBytes {
+			sw.sp = sw.sp[1:]
+		}
+
+		// In front of a data fragment, so skip a hole.
+		holeEnd := sw.total
+		if len(sw.sp) > 0 {
+			holeEnd = sw.sp[0].offset
+		}
+		if sw.pos < holeEnd {
+			nh := holeEnd - sw.pos
+			if nh > int64(len(b)) {
+				nh = int64(len(b))
+			}
+			for _, c := range b[:nh] {
+				if c != 0 {
+					return n, errWriteHole
+				}
+			}
+			sw.pos += nh
+			n += int(nh)
+			b = b[nh:]
+			continue
+		}
+
+		// In a data fragment, so write to it.
+		nd := sw.sp[0].offset + sw.sp[0].numBytes - sw.pos
+		if nd > int64(len(b)) {
+			nd = int64(len(b))
+		}
+		var nw int
+		nw, err = tw.w.Write(b[:nd])
+		tw.nb -= int64(nw)
+		sw.pos += int64(nw)
+		n += nw
+		if err != nil {
+			tw.err = err
+			return n, err
+		}
+		b = b[nd:]
+	}
+	if overwrite {
+		return n, ErrWriteTooLong
+	}
+	return n, nil
+}
+
 // Close closes the tar archive, flushing any unwritten
 // data to the underlying writer.
 func (tw *Writer) Close() error {
@@ -631,7 +631,7 @@ func TestIssue12594(t *testing.T) {
 		if i := strings.IndexByte(prefix, 0); i >= 0 {
 			prefix = prefix[:i] // Truncate at the NUL terminator
 		}
-		if blk.GetFormat() == formatGNU && len(prefix) > 0 && strings.HasPrefix(name, prefix) {
+		if blk.GetFormat() == FormatGNU && len(prefix) > 0 && strings.HasPrefix(name, prefix) {
 			t.Errorf("test %d, found prefix in GNU format: %s", i, prefix)
 		}
 
@@ -645,3 +645,322 @@ func TestIssue12594(t *testing.T) {
 		}
 	}
 }
+
+func TestWriterFormats(t *testing.T) {
+	vectors := []struct {
+		hdr     Header
+		wantErr bool
+	}{{
+		hdr: Header{Name: strings.Repeat("a", 120) + "/file", Format: FormatUSTAR},
+	}, {
+		hdr:     Header{Name: strings.Repeat("a", 300), Format: FormatUSTAR},
+		wantErr: true,
+	}, {
+		hdr:     Header{Name: "☺", Format: FormatUSTAR},
+		wantErr: true,
+	}, {
+		hdr:     Header{Name: "file", Uid: 1 << 25, Format: FormatUSTAR},
+		wantErr: true,
+	}, {
+		hdr:     Header{Name: "file", Xattrs: map[string]string{"user.key": "value"}, Format: FormatUSTAR},
+		wantErr: true,
+	}, {
+		hdr:     Header{Name: "file", Xattrs: map[string]string{"user.key": "value"}, Format: FormatGNU},
+		wantErr: true,
+	}, {
+		hdr: Header{Name: strings.Repeat("a", 300), Uid: 1 << 25, Format: FormatGNU},
+	}, {
+		hdr: Header{Name: strings.Repeat("a", 300), Linkname: "☺", Typeflag: TypeSymlink, Format: FormatGNU},
+	}, {
+		hdr: Header{Name: "☺", Uid: 1 << 25, ModTime: time.Unix(1350244992, 23960108), Format: FormatPAX},
+	}, {
+		hdr: Header{Name: "file", ModTime: time.Unix(-1e10, 0), Format: FormatPAX},
+	}, {
+		hdr:     Header{Name: "file", ModTime: time.Unix(-1e10, 0), Format: FormatUSTAR},
+		wantErr: true,
+	}, {
+		hdr:     Header{Name: "file", Format: formatSTAR},
+		wantErr: true,
+	}}
+
+	for i, v := range vectors {
+		var b bytes.Buffer
+		tw := NewWriter(&b)
+		hdr := v.hdr
+		if hdr.ModTime.IsZero() {
+			hdr.ModTime = time.Unix(0, 0)
+		}
+		err := tw.WriteHeader(&hdr)
+		if gotErr := err != nil; gotErr != v.wantErr {
+			t.Errorf("test %d, WriteHeader() = %v, want error %v", i, err, v.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if err := tw.Close(); err != nil {
+			t.Errorf("test %d, unexpected Close error: %v", i, err)
+			continue
+		}
+
+		got, err := NewReader(&b).Next()
+		if err != nil {
+			t.Errorf("test %d, unexpected Next error: %v", i, err)
+			continue
+		}
+		if got.Format != hdr.Format {
+			t.Errorf("test %d, Format = %v, want %v", i, got.Format, hdr.Format)
+		}
+		if got.Name != hdr.Name || got.Linkname != hdr.Linkname || got.Uid != hdr.Uid {
+			t.Errorf("test %d, got (%q, %q, %d), want (%q, %q, %d)",
+				i, got.Name, got.Linkname, got.Uid, hdr.Name, hdr.Linkname, hdr.Uid)
+		}
+		if !got.ModTime.Equal(hdr.ModTime) {
+			t.Errorf("test %d, ModTime = %v, want %v", i, got.ModTime, hdr.ModTime)
+		}
+	}
+}
+
+func TestWriterFormatErrors(t *testing.T) {
+	vectors := []struct {
+		hdr  Header
+		want string
+	}{{
+		// The name fits in the name and prefix fields, but the
+		// prefix is not ASCII.
+		hdr:  Header{Name: strings.Repeat("☺", 40) + "/file", Format: FormatUSTAR},
+		want: "Name",
+	}, {
+		hdr:  Header{Name: "file", Devmajor: 1 << 25, Format: FormatUSTAR},
+		want: "Devmajor",
+	}, {
+		hdr:  Header{Name: "file", Devminor: 1 << 25, Format: FormatPAX},
+		want: "Devminor",
+	}, {
+		hdr:  Header{Name: "file", ModTime: time.Unix(-1e10, 0), Format: FormatUSTAR},
+		want: "ModTime",
+	}, {
+		hdr:  Header{Name: "☺", Uid: 1 << 25, Format: FormatUSTAR},
+		want: "Name, Uid",
+	}}
+
+	for i, v := range vectors {
+		hdr := v.hdr
+		if hdr.ModTime.IsZero() {
+			hdr.ModTime = time.Unix(0, 0)
+		}
+		err := NewWriter(ioutil.Discard).WriteHeader(&hdr)
+		want := fmt.Sprintf("archive/tar: cannot encode %s in %v format", v.want, hdr.Format)
+		if err == nil || err.Error() != want {
+			t.Errorf("test %d, WriteHeader() = %v, want %q", i, err, want)
+		}
+	}
+}
+
+// TestWriterReadFormat checks that headers returned by Reader.Next can be
+// modified and written again, even if they no longer fit the format
+// they were read in.
+func TestWriterReadFormat(t *testing.T) {
+	vectors := []struct {
+		file   string
+		modify func(*Header)
+		want   Format // format of the header written
+		ok     bool
+	}{
+		{"testdata/ustar.tar", func(*Header) {}, FormatUSTAR, true},
+		{"testdata/ustar.tar", func(h *Header) { h.Name = strings.Repeat("a", 200) }, FormatPAX, true},
+		{"testdata/ustar.tar", func(h *Header) { h.Uname = strings.Repeat("u", 40) }, FormatPAX, true},
+		{"testdata/gnu.tar", func(*Header) {}, FormatUSTAR, true},
+		{"testdata/gnu.tar", func(h *Header) { h.Uname = strings.Repeat("u", 40) }, FormatPAX, true},
+		{"testdata/gnu.tar", func(h *Header) { h.Uid = 1 << 25 }, FormatGNU, true},
+		{"testdata/pax.tar", func(*Header) {}, FormatPAX, true},
+		{"testdata/pax.tar", func(h *Header) { h.Devmajor = 1 << 25 }, FormatGNU, true},
+
+		// A format set by the caller is still forced.
+		{"testdata/ustar.tar", func(h *Header) { h.Format = FormatGNU }, FormatGNU, true},
+		{"testdata/gnu.tar", func(h *Header) { h.Uname = strings.Repeat("u", 40); h.Format = FormatUSTAR }, 0, false},
+		{"testdata/pax.tar", func(h *Header) { h.Format = FormatUSTAR }, 0, false},
+	}
+
+	for i, v := range vectors {
+		f, err := os.Open(v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdr, err := NewReader(f).Next()
+		f.Close()
+		if err != nil {
+			t.Fatalf("test %d, unexpected Next error: %v", i, err)
+		}
+		v.modify(hdr)
+
+		var b bytes.Buffer
+		tw := NewWriter(&b)
+		err = tw.WriteHeader(hdr)
+		if ok := err == nil; ok != v.ok {
+			t.Errorf("test %d, WriteHeader() = %v, want success %v", i, err, v.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if _, err := tw.Write(make([]byte, hdr.Size)); err != nil {
+			t.Fatalf("test %d, unexpected Write error: %v", i, err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("test %d, unexpected Close error: %v", i, err)
+		}
+
+		got, err := NewReader(&b).Next()
+		if err != nil {
+			t.Errorf("test %d, unexpected Next error: %v", i, err)
+			continue
+		}
+		if got.Format != v.want {
+			t.Errorf("test %d, Format = %v, want %v", i, got.Format, v.want)
+		}
+		if got.Name != hdr.Name || got.Uname != hdr.Uname || got.Uid != hdr.Uid {
+			t.Errorf("test %d, got (%q, %q, %d), want (%q, %q, %d)", i,
+				got.Name, got.Uname, got.Uid, hdr.Name, hdr.Uname, hdr.Uid)
+		}
+		if v.want == FormatPAX && !got.ModTime.Equal(hdr.ModTime) {
+			t.Errorf("test %d, ModTime = %v, want %v", i, got.ModTime, hdr.ModTime)
+		}
+	}
+}
+
+func TestWriterSparse(t *testing.T) {
+	// Many small holes require GNU extension blocks and a large PAX map.
+	var manyHoles []SparseEntry
+	for i := int64(0); i < 100; i++ {
+		manyHoles = append(manyHoles, SparseEntry{2 * i, 1})
+	}
+
+	vectors := []struct {
+		holes []SparseEntry
+		size  int64
+	}{
+		{nil, 10},
+		{[]SparseEntry{{0, 5}}, 10},
+		{[]SparseEntry{{5, 5}}, 10},
+		{[]SparseEntry{{2, 3}, {7, 1}}, 10},
+		{[]SparseEntry{{0, 1 << 20}}, 1 << 20},
+		{manyHoles, 205},
+	}
+
+	for i, v := range vectors {
+		// Fill the data fragments with non-zero bytes.
+		want := make([]byte, v.size)
+		for j := range want {
+			want[j] = byte('a' + j%26)
+		}
+		for _, h := range v.holes {
+			for j := h.Offset; j < h.Offset+h.Length; j++ {
+				want[j] = 0
+			}
+		}
+
+		for _, format := range []Format{FormatGNU, FormatPAX} {
+			if v.holes == nil && format == FormatPAX {
+				continue // TypeGNUSparse is only valid in the GNU format
+			}
+			var b bytes.Buffer
+			tw := NewWriter(&b)
+			hdr := &Header{
+				Name:        "sparse.db",
+				Mode:        0644,
+				Size:        v.size,
+				ModTime:     time.Unix(0, 0),
+				Typeflag:    TypeReg,
+				SparseHoles: v.holes,
+				Format:      format,
+			}
+			if v.holes == nil {
+				hdr.Typeflag = TypeGNUSparse // Sparse file without any holes
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Errorf("test %d, %v: unexpected WriteHeader error: %v", i, format, err)
+				continue
+			}
+			if _, err := tw.Write(want); err != nil {
+				t.Errorf("test %d, %v: unexpected Write error: %v", i, format, err)
+				continue
+			}
+			if err := tw.Close(); err != nil {
+				t.Errorf("test %d, %v: unexpected Close error: %v", i, format, err)
+				continue
+			}
+
+			tr := NewReader(&b)
+			got, err := tr.Next()
+			if err != nil {
+				t.Errorf("test %d, %v: unexpected Next error: %v", i, format, err)
+				continue
+			}
+			if got.Name != hdr.Name || got.Size != v.size || got.Format != format {
+				t.Errorf("test %d, %v: got (%q, %d, %v), want (%q, %d, %v)",
+					i, format, got.Name, got.Size, got.Format, hdr.Name, v.size, format)
+			}
+			if len(got.SparseHoles) != len(v.holes) || (len(v.holes) > 0 && !reflect.DeepEqual(got.SparseHoles, v.holes)) {
+				t.Errorf("test %d, %v: SparseHoles = %v, want %v", i, format, got.SparseHoles, v.holes)
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Errorf("test %d, %v: unexpected ReadAll error: %v", i, format, err)
+				continue
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("test %d, %v: data mismatch", i, format)
+			}
+			if _, err := tr.Next(); err != io.EOF {
+				t.Errorf("test %d, %v: Next() = %v, want io.EOF", i, format, err)
+			}
+		}
+	}
+}
+
+func TestWriterSparseErrors(t *testing.T) {
+	newWriter := func(hdr *Header) (*Writer, error) {
+		tw := NewWriter(ioutil.Discard)
+		return tw, tw.WriteHeader(hdr)
+	}
+
+	// Holes must be sorted, non-overlapping and within the file size.
+	for _, holes := range [][]SparseEntry{
+		{{5, 5}, {0, 1}},
+		{{0, 5}, {4, 2}},
+		{{8, 5}},
+		{{-1, 2}},
+	} {
+		_, err := newWriter(&Header{Name: "f", Size: 10, SparseHoles: holes})
+		if err != errInvalidSparseHoles {
+			t.Errorf("WriteHeader(holes: %v) = %v, want %v", holes, err, errInvalidSparseHoles)
+		}
+	}
+
+	_, err := newWriter(&Header{Name: "d", Typeflag: TypeDir, SparseHoles: []SparseEntry{{0, 0}}})
+	if err != errSparseNonRegular {
+		t.Errorf("WriteHeader(dir) = %v, want %v", err, errSparseNonRegular)
+	}
+
+	_, err = newWriter(&Header{Name: "f", Size: 10, SparseHoles: []SparseEntry{{0, 5}}, Format: FormatUSTAR})
+	if err == nil {
+		t.Errorf("WriteHeader(USTAR sparse) succeeded, want error")
+	}
+
+	tw, err := newWriter(&Header{Name: "f", Size: 10, SparseHoles: []SparseEntry{{0, 5}}})
+	if err != nil {
+		t.Fatalf("unexpected WriteHeader error: %v", err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != errWriteHole {
+		t.Errorf("Write(hole) = %v, want %v", err, errWriteHole)
+	}
+
+	tw, err = newWriter(&Header{Name: "f", Size: 10, SparseHoles: []SparseEntry{{0, 5}}})
+	if err != nil {
+		t.Fatalf("unexpected WriteHeader error: %v", err)
+	}
+	if n, err := tw.Write(make([]byte, 11)); n != 10 || err != ErrWriteTooLong {
+		t.Errorf("Write(11 bytes) = (%d, %v), want (10, %v)", n, err, ErrWriteTooLong)
+	}
+}
@@ -41,10 +41,6 @@ type File struct {
 	headerOffset int64
 }
 
-func (f *File) hasDataDescriptor() bool {
-	return f.Flags&0x8 != 0
-}
-
 // OpenReader will open the Zip file specified by name and return a ReadCloser.
 func OpenReader(name string) (*ReadCloser, error) {
 	f, err := os.Open(name)
@@ -151,6 +147,18 @@ func (f *File) DataOffset() (offset int64, err error) {
 	return f.headerOffset + bodyOffset, nil
 }
 
+// OpenRaw returns a Reader that provides access to the File's
+// possibly-compressed data, without decompressing it or verifying
+// its checksum. Together with Writer.Copy or Writer.CreateRaw, it allows
+// entries to be moved between archives without recompressing them.
+func (f *File) OpenRaw() (io.Reader, error) {
+	bodyOffset, err := f.findBodyOffset()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(f.zipr, f.headerOffset+bodyOffset, int64(f.CompressedSize64)), nil
+}
+
 // Open returns a ReadCloser that provides access to the File's contents.
 // Multiple files may be read concurrently.
 func (f *File) Open() (io.ReadCloser, error) {
@@ -6,6 +6,7 @@ package zip
 
 import (
 	"compress/flate"
+	"compress/zstd"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -108,16 +109,18 @@ var (
 	compressors = map[uint16]Compressor{
 		Store:   func(w io.Writer) (io.WriteCloser, error) { return &nopCloser{w}, nil },
 		Deflate: func(w io.Writer) (io.WriteCloser, error) { return newFlateWriter(w), nil },
+		Zstd:    func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w), nil },
 	}
 
 	decompressors = map[uint16]Decompressor{
 		Store:   ioutil.NopCloser,
 		Deflate: newFlateReader,
+		Zstd:    func(r io.Reader) io.ReadCloser { return zstd.NewReader(r) },
 	}
 )
 
 // RegisterDecompressor allows custom decompressors for a specified method ID.
-// The common methods Store and Deflate are built in.
+// The common methods Store, Deflate and Zstd are built in.
 func RegisterDecompressor(method uint16, dcomp Decompressor) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -129,7 +132,7 @@ func RegisterDecompressor(method uint16, dcomp Decompressor) {
 }
 
 // RegisterCompressor registers custom compressors for a specified method ID.
-// The common methods Store and Deflate are built in.
+// The common methods Store, Deflate and Zstd are built in.
 func RegisterCompressor(method uint16, comp Compressor) {
 	mu.Lock()
 	defer mu.Unlock()
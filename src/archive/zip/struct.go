@@ -29,6 +29,7 @@ import (
 const (
 	Store   uint16 = 0
 	Deflate uint16 = 8
+	Zstd    uint16 = 93
 )
 
 const (
@@ -238,6 +239,12 @@ func (fh *FileHeader) isZip64() bool {
 	return fh.CompressedSize64 >= uint32max || fh.UncompressedSize64 >= uint32max
 }
 
+// hasDataDescriptor reports whether the file data is followed by a
+// data descriptor holding its CRC-32 and sizes.
+func (fh *FileHeader) hasDataDescriptor() bool {
+	return fh.Flags&0x8 != 0
+}
+
 func msdosModeToFileMode(m uint32) (mode os.FileMode) {
 	if m&msdosDir != 0 {
 		mode = os.ModeDir | 0777
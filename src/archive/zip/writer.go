@@ -31,6 +31,7 @@ type Writer struct {
 type header struct {
 	*FileHeader
 	offset uint64
+	raw    bool // data written verbatim by CreateRaw
 }
 
 // NewWriter returns a new Writer writing a zip file to w.
@@ -97,7 +98,7 @@ func (w *Writer) Close() error {
 			eb.uint64(h.UncompressedSize64)
 			eb.uint64(h.CompressedSize64)
 			eb.uint64(h.offset)
-			h.Extra = append(h.Extra, buf[:]...)
+			h.Extra = append(stripZip64Extra(h.Extra), buf[:]...)
 		} else {
 			b.uint32(h.CompressedSize)
 			b.uint32(h.UncompressedSize)
@@ -192,7 +193,7 @@ func (w *Writer) Close() error {
 // letter (e.g. C:) or leading slash, and only forward slashes are
 // allowed.
 // The file's contents must be written to the io.Writer before the next
-// call to Create, CreateHeader, or Close.
+// call to Create, CreateHeader, CreateRaw, Copy, or Close.
 func (w *Writer) Create(name string) (io.Writer, error) {
 	header := &FileHeader{
 		Name:   name,
@@ -206,17 +207,11 @@ func (w *Writer) Create(name string) (io.Writer, error) {
 // It returns a Writer to which the file contents should be written.
 //
 // The file's contents must be written to the io.Writer before the next
-// call to Create, CreateHeader, or Close. The provided FileHeader fh
-// must not be modified after a call to CreateHeader.
+// call to Create, CreateHeader, CreateRaw, Copy, or Close. The provided
+// FileHeader fh must not be modified after a call to CreateHeader.
 func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
-	if w.last != nil && !w.last.closed {
-		if err := w.last.close(); err != nil {
-			return nil, err
-		}
-	}
-	if len(w.dir) > 0 && w.dir[len(w.dir)-1].FileHeader == fh {
-		// See https://golang.org/issue/11144 confusion.
-		return nil, errors.New("archive/zip: invalid duplicate FileHeader")
+	if err := w.prepare(fh); err != nil {
+		return nil, err
 	}
 
 	fh.Flags |= 0x8 // we will write a data descriptor
@@ -247,7 +242,7 @@ func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
 	w.dir = append(w.dir, h)
 	fw.header = h
 
-	if err := writeHeader(w.cw, fh); err != nil {
+	if err := writeHeader(w.cw, h); err != nil {
 		return nil, err
 	}
 
@@ -255,7 +250,94 @@ func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
 	return fw, nil
 }
 
-func writeHeader(w io.Writer, h *FileHeader) error {
+// CreateRaw adds a file to the zip file using the provided FileHeader,
+// and returns a Writer to which the file's already compressed data
+// should be written. The data is stored verbatim: it must be compressed
+// with fh.Method, and fh.CRC32, fh.CompressedSize64 and
+// fh.UncompressedSize64 must describe it. If fh.Flags has the data
+// descriptor bit (0x8) set, a data descriptor is written after the data.
+//
+// The file's contents must be written to the io.Writer before the next
+// call to Create, CreateHeader, CreateRaw, Copy, or Close. The provided
+// FileHeader fh must not be modified after a call to CreateRaw.
+func (w *Writer) CreateRaw(fh *FileHeader) (io.Writer, error) {
+	if err := w.prepare(fh); err != nil {
+		return nil, err
+	}
+
+	if fh.isZip64() {
+		fh.CompressedSize = uint32max
+		fh.UncompressedSize = uint32max
+		fh.ReaderVersion = zipVersion45 // requires 4.5 - File uses ZIP64 format extensions
+		if !fh.hasDataDescriptor() {
+			// The sizes go in the local header, which then needs
+			// its own zip64 extra block.
+			var buf [20]byte // 2x uint16 + 2x uint64
+			eb := writeBuf(buf[:])
+			eb.uint16(zip64ExtraId)
+			eb.uint16(16) // size = 2x uint64
+			eb.uint64(fh.UncompressedSize64)
+			eb.uint64(fh.CompressedSize64)
+			fh.Extra = append(stripZip64Extra(fh.Extra), buf[:]...)
+		}
+	} else {
+		fh.CompressedSize = uint32(fh.CompressedSize64)
+		fh.UncompressedSize = uint32(fh.UncompressedSize64)
+	}
+
+	h := &header{
+		FileHeader: fh,
+		offset:     uint64(w.cw.count),
+		raw:        true,
+	}
+	w.dir = append(w.dir, h)
+	fw := &fileWriter{
+		header:    h,
+		zipw:      w.cw,
+		compCount: &countWriter{w: w.cw},
+	}
+
+	if err := writeHeader(w.cw, h); err != nil {
+		return nil, err
+	}
+
+	w.last = fw
+	return fw, nil
+}
+
+// Copy copies the file f, obtained from a Reader, into the zip file.
+// The compressed data and the metadata of f are copied verbatim,
+// without decompressing, recompressing or verifying them.
+func (w *Writer) Copy(f *File) error {
+	r, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	fh := f.FileHeader // don't modify the Reader's copy
+	fw, err := w.CreateRaw(&fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+// prepare finishes the previous file, if any, before a new file
+// described by fh is added.
+func (w *Writer) prepare(fh *FileHeader) error {
+	if w.last != nil && !w.last.closed {
+		if err := w.last.close(); err != nil {
+			return err
+		}
+	}
+	if len(w.dir) > 0 && w.dir[len(w.dir)-1].FileHeader == fh {
+		// See https://golang.org/issue/11144 confusion.
+		return errors.New("archive/zip: invalid duplicate FileHeader")
+	}
+	return nil
+}
+
+func writeHeader(w io.Writer, h *header) error {
 	var buf [fileHeaderLen]byte
 	b := writeBuf(buf[:])
 	b.uint32(uint32(fileHeaderSignature))
@@ -264,9 +346,15 @@ func writeHeader(w io.Writer, h *FileHeader) error {
 	b.uint16(h.Method)
 	b.uint16(h.ModifiedTime)
 	b.uint16(h.ModifiedDate)
-	b.uint32(0) // since we are writing a data descriptor crc32,
-	b.uint32(0) // compressed size,
-	b.uint32(0) // and uncompressed size should be zero
+	if h.raw && !h.hasDataDescriptor() {
+		b.uint32(h.CRC32)
+		b.uint32(h.CompressedSize)
+		b.uint32(h.UncompressedSize)
+	} else {
+		b.uint32(0) // since we are writing a data descriptor crc32,
+		b.uint32(0) // compressed size,
+		b.uint32(0) // and uncompressed size should be zero
+	}
 	b.uint16(uint16(len(h.Name)))
 	b.uint16(uint16(len(h.Extra)))
 	if _, err := w.Write(buf[:]); err != nil {
@@ -311,6 +399,9 @@ func (w *fileWriter) Write(p []byte) (int, error) {
 	if w.closed {
 		return 0, errors.New("zip: write to closed file")
 	}
+	if w.raw {
+		return w.compCount.Write(p)
+	}
 	w.crc32.Write(p)
 	return w.rawCount.Write(p)
 }
@@ -320,6 +411,12 @@ func (w *fileWriter) close() error {
 		return errors.New("zip: file closed twice")
 	}
 	w.closed = true
+	if w.raw {
+		if !w.hasDataDescriptor() {
+			return nil
+		}
+		return w.writeDataDescriptor()
+	}
 	if err := w.comp.Close(); err != nil {
 		return err
 	}
@@ -338,6 +435,11 @@ func (w *fileWriter) close() error {
 		fh.CompressedSize = uint32(fh.CompressedSize64)
 		fh.UncompressedSize = uint32(fh.UncompressedSize64)
 	}
+	return w.writeDataDescriptor()
+}
+
+func (w *fileWriter) writeDataDescriptor() error {
+	fh := w.header.FileHeader
 
 	// Write data descriptor. This is more complicated than one would
 	// think, see e.g. comments in zipfile.c:putextended() and
@@ -375,6 +477,25 @@ func (w *countWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// stripZip64Extra returns extra without its zip64 extended information
+// fields, so that an up to date one can be appended.
+func stripZip64Extra(extra []byte) []byte {
+	var out []byte
+	b := extra
+	for len(b) >= 4 {
+		tag := binary.LittleEndian.Uint16(b)
+		size := 4 + int(binary.LittleEndian.Uint16(b[2:]))
+		if size > len(b) {
+			break // malformed; keep the rest as is
+		}
+		if tag != zip64ExtraId {
+			out = append(out, b[:size]...)
+		}
+		b = b[size:]
+	}
+	return append(out, b...)
+}
+
 type nopCloser struct {
 	io.Writer
 }
@@ -6,6 +6,8 @@ package zip
 
 import (
 	"bytes"
+	"compress/flate"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -35,6 +37,12 @@ var writeTests = []WriteTest{
 		Method: Deflate,
 		Mode:   0644,
 	},
+	{
+		Name:   "zstd",
+		Data:   []byte("Zstandard compressed data, Zstandard compressed data"),
+		Method: Zstd,
+		Mode:   0644,
+	},
 	{
 		Name:   "setuid",
 		Data:   []byte("setuid file"),
@@ -140,6 +148,162 @@ func TestWriterFlush(t *testing.T) {
 	}
 }
 
+func TestWriterCopy(t *testing.T) {
+	largeData := make([]byte, 1<<17)
+	for i := range largeData {
+		largeData[i] = byte(rand.Int())
+	}
+	writeTests[1].Data = largeData
+	defer func() {
+		writeTests[1].Data = nil
+	}()
+
+	// write a zip file
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	for _, wt := range writeTests {
+		testCreate(t, w, &wt)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// copy all entries, in reverse order, into another zip file
+	buf2 := new(bytes.Buffer)
+	w2 := NewWriter(buf2)
+	for i := len(r.File) - 1; i >= 0; i-- {
+		if err := w2.Copy(r.File[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r2, err := NewReader(bytes.NewReader(buf2.Bytes()), int64(buf2.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r2.File) != len(writeTests) {
+		t.Fatalf("got %d files, want %d", len(r2.File), len(writeTests))
+	}
+	for i, f := range r2.File {
+		src := r.File[len(r.File)-1-i]
+		if f.CRC32 != src.CRC32 || f.CompressedSize64 != src.CompressedSize64 || f.Method != src.Method {
+			t.Errorf("%s: copied header %+v, want %+v", f.Name, f.FileHeader, src.FileHeader)
+		}
+		want, got := readRaw(t, src), readRaw(t, f)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: copied raw data differs", f.Name)
+		}
+		testReadFile(t, f, &writeTests[len(writeTests)-1-i])
+	}
+}
+
+func TestWriterCreateRaw(t *testing.T) {
+	data := bytes.Repeat([]byte("gophers and quolls "), 1000)
+	var comp bytes.Buffer
+	fw, err := flate.NewWriter(&comp, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	fw.Close()
+
+	headers := []*FileHeader{
+		{Name: "stored", Method: Store},
+		{Name: "deflated", Method: Deflate},
+		{Name: "descriptor", Method: Deflate, Flags: 0x8},
+		{Name: "badcrc", Method: Deflate},
+	}
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	for _, fh := range headers {
+		raw := comp.Bytes()
+		if fh.Method == Store {
+			raw = data
+		}
+		fh.CRC32 = crc32.ChecksumIEEE(data)
+		if fh.Name == "badcrc" {
+			fh.CRC32++
+		}
+		fh.CompressedSize64 = uint64(len(raw))
+		fh.UncompressedSize64 = uint64(len(data))
+		fw, err := w.CreateRaw(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range r.File {
+		if f.Name != headers[i].Name {
+			t.Fatalf("File name: got %q, want %q", f.Name, headers[i].Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s: opening: %v", f.Name, err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if f.Name == "badcrc" {
+			if err != ErrChecksum {
+				t.Errorf("%s: reading: got %v, want %v", f.Name, err, ErrChecksum)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: reading: %v", f.Name, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: contents differ", f.Name)
+		}
+	}
+}
+
+func TestStripZip64Extra(t *testing.T) {
+	tests := []struct {
+		in, want []byte
+	}{
+		{nil, nil},
+		{[]byte{1, 0, 0, 0}, nil},
+		{[]byte{1, 0, 2, 0, 9, 9, 7, 0, 1, 0, 5}, []byte{7, 0, 1, 0, 5}},
+		{[]byte{7, 0, 1, 0, 5, 1, 0, 1, 0, 9}, []byte{7, 0, 1, 0, 5}},
+		{[]byte{7, 0, 9, 0, 5}, []byte{7, 0, 9, 0, 5}}, // malformed
+		{[]byte{7, 0, 0, 0, 3}, []byte{7, 0, 0, 0, 3}}, // trailing garbage
+	}
+	for _, tt := range tests {
+		if got := stripZip64Extra(tt.in); !bytes.Equal(got, tt.want) {
+			t.Errorf("stripZip64Extra(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func readRaw(t *testing.T, f *File) []byte {
+	r, err := f.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
 func testCreate(t *testing.T, w *Writer, wt *WriteTest) {
 	header := &FileHeader{
 		Name:   wt.Name,
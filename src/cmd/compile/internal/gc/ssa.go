@@ -4261,6 +4261,10 @@ type SSAGenState struct {
 	// Map from GC safe points to stack map index, generated by
 	// liveness analysis.
 	stackMapIndex map[*ssa.Value]int
+
+	// unsafePoint reports whether the most recently emitted
+	// PCDATA_UnsafePoint value marks an unsafe-point.
+	unsafePoint bool
 }
 
 // Prog appends a new Prog.
@@ -4278,6 +4282,83 @@ func (s *SSAGenState) SetPos(pos src.XPos) {
 	s.pp.pos = pos
 }
 
+// setUnsafePoint emits a PCDATA_UnsafePoint instruction if the
+// unsafe-point state of the following instructions differs from
+// that of the preceding ones.
+func (s *SSAGenState) setUnsafePoint(unsafe bool) {
+	if unsafe == s.unsafePoint {
+		return
+	}
+	s.unsafePoint = unsafe
+	p := s.Prog(obj.APCDATA)
+	Addrconst(&p.From, obj.PCDATA_UnsafePoint)
+	if unsafe {
+		Addrconst(&p.To, obj.PCDATA_UnsafePointUnsafe)
+	} else {
+		Addrconst(&p.To, obj.PCDATA_UnsafePointSafe)
+	}
+}
+
+// markUnsafePoints finds the instructions of f at which the goroutine
+// must not be asynchronously preempted. These are the write barrier
+// tests: from the load of the write barrier flag through the branch
+// on it, and both arms of the branch up to where they rejoin. If the
+// goroutine were preempted in this window, the garbage collector could
+// change phase and the code would act on a stale flag.
+//
+// The results are indexed by value ID and block ID, respectively.
+// A block is marked if the control flow instructions at its end are
+// unsafe.
+func markUnsafePoints(f *ssa.Func) (values, blocks []bool) {
+	values = make([]bool, f.NumValues())
+	blocks = make([]bool, f.NumBlocks())
+	for _, b := range f.WBLoads {
+		if b.Kind == ssa.BlockInvalid || len(b.Succs) != 2 || b.Control == nil {
+			// The test was optimized away.
+			continue
+		}
+
+		// Find the values in b that the branch depends on.
+		// All architectures compute the flag test within
+		// the block, starting with the load of the flag.
+		deps := make(map[*ssa.Value]bool)
+		var walk func(v *ssa.Value)
+		walk = func(v *ssa.Value) {
+			if v.Block != b || deps[v] || v.Type.IsMemory() {
+				return
+			}
+			deps[v] = true
+			for _, a := range v.Args {
+				walk(a)
+			}
+		}
+		walk(b.Control)
+
+		// Mark everything from the first of them onward.
+		found := false
+		for _, v := range b.Values {
+			found = found || deps[v]
+			if found {
+				values[v.ID] = true
+			}
+		}
+		blocks[b.ID] = true
+
+		// Mark the two arms of the test.
+		for _, e := range b.Succs {
+			c := e.Block()
+			if len(c.Preds) != 1 {
+				continue
+			}
+			for _, v := range c.Values {
+				values[v.ID] = true
+			}
+			blocks[c.ID] = true
+		}
+	}
+	return values, blocks
+}
+
 // genssa appends entries to pp for each instruction in f.
 func genssa(f *ssa.Func, pp *Progs) {
 	var s SSAGenState
@@ -4308,12 +4389,15 @@ func genssa(f *ssa.Func, pp *Progs) {
 
 	s.ScratchFpMem = e.scratchFpMem
 
+	unsafeValues, unsafeBlocks := markUnsafePoints(f)
+
 	// Emit basic blocks
 	for i, b := range f.Blocks {
 		s.bstart[b.ID] = s.pp.next
 		// Emit values in block
 		thearch.SSAMarkMoves(&s, b)
 		for _, v := range b.Values {
+			s.setUnsafePoint(unsafeValues[v.ID])
 			x := s.pp.next
 			s.SetPos(v.Pos)
 
@@ -4354,6 +4438,7 @@ func genssa(f *ssa.Func, pp *Progs) {
 			// line numbers for otherwise empty blocks.
 			next = f.Blocks[i+1]
 		}
+		s.setUnsafePoint(unsafeBlocks[b.ID])
 		x := s.pp.next
 		s.SetPos(b.Pos)
 		thearch.SSAGenBlock(&s, b, next)
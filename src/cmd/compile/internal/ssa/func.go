@@ -40,6 +40,11 @@ type Func struct {
 	NoWB  bool     // write barrier is not allowed
 	WBPos src.XPos // line number of first write barrier
 
+	// WBLoads is the list of blocks that branch on the write
+	// barrier flag. Safe-points are disabled from the flag load
+	// through the write barrier test's two arms.
+	WBLoads []*Block
+
 	// when register allocation is done, maps value ids to locations
 	RegAlloc []Location
 
@@ -161,6 +161,7 @@ func writebarrier(f *Func) {
 		b.Kind = BlockIf
 		b.SetControl(flag)
 		b.Likely = BranchUnlikely
+		f.WBLoads = append(f.WBLoads, b)
 		b.Succs = b.Succs[:0]
 		b.AddEdgeTo(bThen)
 		b.AddEdgeTo(bElse)
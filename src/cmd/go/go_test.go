@@ -3847,3 +3847,39 @@ func main() {
 	tg.creatingTemp(exe)
 	tg.run("build", "-o", exe, "p")
 }
+
+func TestBuildInfo(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/dep/dep.go", `package dep; const Name = "dep"`)
+	tg.tempFile("src/prog/main.go", `package main
+import (
+	"dep"
+	"fmt"
+	"runtime/debug"
+)
+func main() {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		panic("no build info")
+	}
+	fmt.Print(dep.Name, "\n", bi)
+}
+`)
+	tg.setenv("GOPATH", tg.path("."))
+	exe := tg.path("prog" + exeSuffix)
+	tg.run("build", "-tags", "tag1", "-o", exe, "prog")
+
+	tg.run("version", "-m", exe)
+	tg.grepStdout(`^`+regexp.QuoteMeta(exe)+`: go`, "go version -m did not report the Go version")
+	tg.grepStdout(`^\tpath\tprog$`, "go version -m did not report the main package path")
+	tg.grepStdout(`^\tmod\tprog\t\(devel\)\th1:`, "go version -m did not report the main source tree")
+	tg.grepStdout(`^\tdep\tdep\t\(devel\)\th1:`, "go version -m did not report the dependency")
+	tg.grepStdout(`^\tbuild\t-tags=tag1$`, "go version -m did not report the build tags")
+	tg.grepStdout(`^\tbuild\tGOOS=`+runtime.GOOS+`$`, "go version -m did not report GOOS")
+
+	tg.run("run", "-tags", "tag1", tg.path("src/prog/main.go"))
+	tg.grepStdout(`^path\tcommand-line-arguments$`, "debug.ReadBuildInfo did not report the main package path")
+	tg.grepStdout(`^build\t-tags=tag1$`, "debug.ReadBuildInfo did not report the build tags")
+}
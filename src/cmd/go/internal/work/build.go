@@ -392,6 +392,7 @@ func BuildModeInit() {
 }
 
 func runBuild(cmd *base.Command, args []string) {
+	stampVCS = true
 	InstrumentInit()
 	BuildModeInit()
 	var b Builder
@@ -540,6 +541,7 @@ func InstallPackages(args []string, forGet bool) {
 		base.Fatalf("cannot install, GOBIN must be an absolute path")
 	}
 
+	stampVCS = true
 	InstrumentInit()
 	BuildModeInit()
 	pkgs := pkgsFilter(load.PackagesForBuild(args))
@@ -1368,6 +1370,15 @@ func (b *Builder) build(a *Action) (err error) {
 		}
 	}
 
+	// Record the build information in the binary.
+	if a.Link && cfg.BuildToolchainName == "gc" {
+		modFile := obj + "_gomod_.go"
+		if err := b.writeFile(a, modFile, modInfoProg(a.Package)); err != nil {
+			return err
+		}
+		gofiles = append(gofiles, modFile)
+	}
+
 	// Prepare Go import path list.
 	inc := b.includeArgs("-I", allArchiveActions(a))
 
@@ -1752,6 +1763,17 @@ func (b *Builder) copyFile(a *Action, dst, src string, perm os.FileMode, force b
 	return nil
 }
 
+// writeFile writes the text to file.
+func (b *Builder) writeFile(a *Action, file string, text []byte) error {
+	if cfg.BuildN || cfg.BuildX {
+		b.Showcmd("", "cat >%s << 'EOF' # internal\n%sEOF", file, text)
+		if cfg.BuildN {
+			return nil
+		}
+	}
+	return ioutil.WriteFile(file, text, 0666)
+}
+
 // Install the cgo export header file, if there is one.
 func (b *Builder) installHeader(a *Action) error {
 	src := a.Objdir + "_cgo_install.h"
@@ -0,0 +1,349 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package work
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+)
+
+// The build information for a binary is stored in the string variable
+// runtime.modinfo, where runtime/debug.ReadBuildInfo finds it. The go
+// command sets the variable from a generated file compiled into the
+// main package. The text is wrapped in 16-byte sentinels so that tools
+// such as debug/buildinfo can locate it in the binary's data.
+//
+// The sentinels are decoded at run time so that the go command's own
+// binary does not contain them except in its own build information.
+var (
+	infoStart, _ = hex.DecodeString("3077af0c9274080241e1c107e6d618e6")
+	infoEnd, _   = hex.DecodeString("f932433186182072008242104116d8f2")
+)
+
+// stampVCS reports whether to record version control information
+// in binaries. It is set by go build and go install; binaries built
+// by go run and go test are not stamped, to avoid running the
+// version control tools for every temporary executable.
+var stampVCS bool
+
+// modInfoProg returns the source of a file that, compiled into the
+// main package p, records the build information for the binary.
+func modInfoProg(p *load.Package) []byte {
+	info := string(infoStart) + buildInfo(p).String() + string(infoEnd)
+	return []byte(fmt.Sprintf(`package main
+import _ "unsafe"
+//go:linkname __debug_modinfo__ runtime.modinfo
+var __debug_modinfo__ = %s
+`, strconv.Quote(info)))
+}
+
+// buildInfo returns the build information for the main package p.
+func buildInfo(p *load.Package) *debug.BuildInfo {
+	info := &debug.BuildInfo{
+		GoVersion: runtime.Version(),
+		Path:      p.ImportPath,
+	}
+
+	// Group the packages in the build by the source tree
+	// containing them.
+	trees := make(map[string]*sourceTree)
+	var deps []*sourceTree
+	addPackage := func(p *load.Package) (t *sourceTree, isNew bool) {
+		path, dir, vcs := treeRoot(p)
+		t = trees[path]
+		if t == nil {
+			t = &sourceTree{path: path, dir: dir, vcs: vcs}
+			trees[path] = t
+			isNew = true
+		}
+		t.addFiles(p)
+		return t, isNew
+	}
+	main, _ := addPackage(p)
+	for _, p1 := range p.Internal.Deps {
+		if p1.Standard {
+			continue
+		}
+		if t, isNew := addPackage(p1); isNew {
+			deps = append(deps, t)
+		}
+	}
+	info.Main = main.module()
+	sort.Slice(deps, func(i, j int) bool { return deps[i].path < deps[j].path })
+	for _, t := range deps {
+		m := t.module()
+		info.Deps = append(info.Deps, &m)
+	}
+
+	appendSetting := func(key, value string) {
+		info.Settings = append(info.Settings, debug.BuildSetting{Key: key, Value: value})
+	}
+	if len(buildAsmflags) > 0 {
+		appendSetting("-asmflags", strings.Join(buildAsmflags, " "))
+	}
+	appendSetting("-buildmode", cfg.BuildBuildmode)
+	appendSetting("-compiler", cfg.BuildContext.Compiler)
+	if len(buildGcflags) > 0 {
+		appendSetting("-gcflags", strings.Join(buildGcflags, " "))
+	}
+	if len(cfg.BuildLdflags) > 0 {
+		appendSetting("-ldflags", strings.Join(cfg.BuildLdflags, " "))
+	}
+	if cfg.BuildMSan {
+		appendSetting("-msan", "true")
+	}
+	if cfg.BuildRace {
+		appendSetting("-race", "true")
+	}
+	if tags := cfg.BuildContext.BuildTags; len(tags) > 0 {
+		appendSetting("-tags", strings.Join(tags, ","))
+	}
+	cgo := "0"
+	if cfg.BuildContext.CgoEnabled {
+		cgo = "1"
+	}
+	appendSetting("CGO_ENABLED", cgo)
+	appendSetting("GOARCH", cfg.Goarch)
+	switch cfg.Goarch {
+	case "386":
+		if v := os.Getenv("GO386"); v != "" {
+			appendSetting("GO386", v)
+		}
+	case "arm":
+		if v := os.Getenv("GOARM"); v != "" {
+			appendSetting("GOARM", v)
+		}
+	}
+	appendSetting("GOOS", cfg.Goos)
+
+	if vcs := main.vcs; vcs != nil && stampVCS {
+		if st, err := vcs.status(main.dir); err == nil {
+			appendSetting("vcs", vcs.cmd)
+			if st.revision != "" {
+				appendSetting("vcs.revision", st.revision)
+			}
+			if !st.time.IsZero() {
+				appendSetting("vcs.time", st.time.UTC().Format(time.RFC3339))
+			}
+			appendSetting("vcs.modified", strconv.FormatBool(st.modified))
+		}
+	}
+	return info
+}
+
+// A sourceTree is a version control checkout, or a single package
+// directory if the package is not in a checkout, that contributes
+// packages to a build.
+type sourceTree struct {
+	path  string    // import path of the root
+	dir   string    // directory of the root
+	vcs   *vcsStamp // version control system for the tree, if any
+	files []string  // source files used in the build, absolute paths
+}
+
+// addFiles adds the source files of p to the tree.
+func (t *sourceTree) addFiles(p *load.Package) {
+	for _, list := range [][]string{
+		p.GoFiles, p.CgoFiles, p.CFiles, p.CXXFiles, p.MFiles, p.HFiles,
+		p.FFiles, p.SFiles, p.SwigFiles, p.SwigCXXFiles, p.SysoFiles,
+	} {
+		for _, f := range list {
+			t.files = append(t.files, filepath.Join(p.Dir, f))
+		}
+	}
+}
+
+// module returns the description of the tree for the build information.
+func (t *sourceTree) module() debug.Module {
+	m := debug.Module{
+		Path:    t.path,
+		Version: "(devel)",
+		Sum:     t.sum(),
+	}
+	if t.vcs != nil && stampVCS {
+		if st, err := t.vcs.status(t.dir); err == nil && st.revision != "" {
+			m.Version = st.revision
+		}
+	}
+	return m
+}
+
+// sum returns a hash of the tree's source files in the "h1:" format
+// also used for Go module checksums: a SHA-256 hash of a summary
+// listing the SHA-256 hash of each file, ordered by file name.
+// It returns the empty string if any file cannot be read.
+func (t *sourceTree) sum() string {
+	names := make(map[string]string)
+	var rel []string
+	for _, f := range t.files {
+		r, err := filepath.Rel(t.dir, f)
+		if err != nil {
+			r = f
+		}
+		r = filepath.ToSlash(r)
+		if _, ok := names[r]; !ok {
+			names[r] = f
+			rel = append(rel, r)
+		}
+	}
+	sort.Strings(rel)
+	h := sha256.New()
+	for _, r := range rel {
+		data, err := ioutil.ReadFile(names[r])
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%x  %s\n", sha256.Sum256(data), r)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// treeRoot returns the import path and directory of the source tree
+// containing p, along with its version control system. The root is
+// the nearest enclosing directory below $GOPATH/src holding a
+// version control checkout, or else p's own directory.
+func treeRoot(p *load.Package) (path, dir string, vcs *vcsStamp) {
+	if p.Root != "" && p.ImportPath != "command-line-arguments" && !p.Internal.Local {
+		srcRoot := filepath.Join(p.Root, "src")
+		for d := filepath.Clean(p.Dir); len(d) > len(srcRoot) && strings.HasPrefix(d, srcRoot); d = filepath.Dir(d) {
+			for _, v := range vcsStamps {
+				if _, err := os.Stat(filepath.Join(d, "."+v.cmd)); err == nil {
+					return filepath.ToSlash(d[len(srcRoot)+1:]), d, v
+				}
+			}
+		}
+	}
+	return p.ImportPath, p.Dir, nil
+}
+
+// A vcsStamp describes how to obtain the state of a checkout
+// from a version control system.
+type vcsStamp struct {
+	cmd string // name of binary to invoke; also the name of the metadata directory
+
+	// statusCmd prints the current revision and its commit time.
+	// parseStatus parses its output.
+	statusCmd   []string
+	parseStatus func(out string) (revision string, t time.Time, err error)
+
+	// modifiedCmd prints a non-empty listing if the checkout
+	// has uncommitted changes.
+	modifiedCmd []string
+}
+
+var vcsStamps = []*vcsStamp{
+	{
+		cmd:         "git",
+		statusCmd:   []string{"-c", "log.showsignature=false", "show", "-s", "--format=%H:%ct"},
+		parseStatus: parseRevTime,
+		modifiedCmd: []string{"status", "--porcelain"},
+	},
+	{
+		cmd:         "hg",
+		statusCmd:   []string{"log", "-r.", "-T", "{node}:{date|hgdate}"},
+		parseStatus: parseRevTime,
+		modifiedCmd: []string{"status"},
+	},
+}
+
+// parseRevTime parses output of the form "revision:seconds",
+// ignoring anything after the seconds.
+func parseRevTime(out string) (string, time.Time, error) {
+	out = strings.TrimSpace(out)
+	i := strings.Index(out, ":")
+	if i < 0 {
+		return "", time.Time{}, fmt.Errorf("unrecognized version control output %q", out)
+	}
+	rev, secs := out[:i], out[i+1:]
+	if j := strings.IndexAny(secs, " \t"); j >= 0 {
+		secs = secs[:j]
+	}
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("unrecognized version control output %q", out)
+	}
+	return rev, time.Unix(sec, 0), nil
+}
+
+// A vcsStatus is the state of a checkout.
+type vcsStatus struct {
+	revision string
+	time     time.Time
+	modified bool
+}
+
+var vcsStatusCache struct {
+	sync.Mutex
+	m map[string]vcsStatusResult
+}
+
+type vcsStatusResult struct {
+	st  vcsStatus
+	err error
+}
+
+// status returns the state of the checkout in dir.
+// Results are cached, since every binary built from
+// the same checkout records the same state.
+func (v *vcsStamp) status(dir string) (vcsStatus, error) {
+	vcsStatusCache.Lock()
+	defer vcsStatusCache.Unlock()
+	if r, ok := vcsStatusCache.m[dir]; ok {
+		return r.st, r.err
+	}
+	var r vcsStatusResult
+	r.st, r.err = v.status1(dir)
+	if vcsStatusCache.m == nil {
+		vcsStatusCache.m = make(map[string]vcsStatusResult)
+	}
+	vcsStatusCache.m[dir] = r
+	return r.st, r.err
+}
+
+func (v *vcsStamp) status1(dir string) (vcsStatus, error) {
+	var st vcsStatus
+	out, err := v.run(dir, v.statusCmd)
+	if err != nil {
+		return st, err
+	}
+	st.revision, st.time, err = v.parseStatus(out)
+	if err != nil {
+		return st, err
+	}
+	out, err = v.run(dir, v.modifiedCmd)
+	if err != nil {
+		return st, err
+	}
+	st.modified = strings.TrimSpace(out) != ""
+	return st, nil
+}
+
+func (v *vcsStamp) run(dir string, args []string) (string, error) {
+	cmd := exec.Command(v.cmd, args...)
+	cmd.Dir = dir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %v", v.cmd, strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
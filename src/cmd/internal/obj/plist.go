@@ -166,3 +166,47 @@ func (ctxt *Link) Globl(s *LSym, size int64, flag int) {
 		s.Type = STLSBSS
 	}
 }
+
+// MarkUnsafePoints inserts PCDATAs to mark the instructions at which
+// the goroutine must not be asynchronously preempted, as reported by
+// isUnsafePoint. It must be called once the instructions are laid
+// out, as the inserted PCDATAs take the Pc of the instruction that
+// follows them. Instructions already marked unsafe by the compiler
+// are left alone, and the compiler's marking is restored after each
+// sequence marked here.
+func MarkUnsafePoints(ctxt *Link, p0 *Prog, isUnsafePoint func(*Prog) bool) {
+	prev := p0
+	prevPcdata := int64(-1) // the value in effect at function entry
+	for p := prev.Link; p != nil; p, prev = p.Link, p {
+		if p.As == APCDATA && p.From.Offset == PCDATA_UnsafePoint {
+			prevPcdata = p.To.Offset
+			continue
+		}
+		if prevPcdata == PCDATA_UnsafePointUnsafe || !isUnsafePoint(p) {
+			continue
+		}
+		q := Appendp(ctxt, prev)
+		q.As = APCDATA
+		q.From.Type = TYPE_CONST
+		q.From.Offset = PCDATA_UnsafePoint
+		q.To.Type = TYPE_CONST
+		q.To.Offset = PCDATA_UnsafePointUnsafe
+		q.Pc = p.Pc
+
+		// Extend the sequence over the following unsafe points.
+		for p.Link != nil && isUnsafePoint(p.Link) {
+			p = p.Link
+		}
+		if p.Link == nil {
+			break
+		}
+		q = Appendp(ctxt, p)
+		q.As = APCDATA
+		q.From.Type = TYPE_CONST
+		q.From.Offset = PCDATA_UnsafePoint
+		q.To.Type = TYPE_CONST
+		q.To.Offset = prevPcdata
+		q.Pc = q.Link.Pc
+		p = q
+	}
+}
@@ -431,6 +431,17 @@ func spanz(ctxt *obj.Link, cursym *obj.LSym) {
 		loop++
 	}
 
+	// Mark nonpreemptible instruction sequences. asyncPreempt
+	// resumes the interrupted code through REGTMP, so preemption
+	// must not happen where REGTMP or REGTMP2 may hold a value.
+	obj.MarkUnsafePoints(ctxt, cursym.Text, func(p *obj.Prog) bool {
+		end := int64(len(buffer))
+		if p.Link != nil {
+			end = p.Link.Pc
+		}
+		return usesTmp(p, buffer[p.Pc:end])
+	})
+
 	cursym.Size = int64(len(buffer))
 	if cursym.Size%funcAlign != 0 {
 		cursym.Size += funcAlign - (cursym.Size % funcAlign)
@@ -439,6 +450,42 @@ func spanz(ctxt *obj.Link, cursym *obj.LSym) {
 	copy(cursym.P, buffer)
 }
 
+// usesTmp reports whether the code of p, whose machine code is
+// code, may use REGTMP or REGTMP2. That is the case if p names one
+// of them, or if the assembler expands p into several instructions,
+// which may pass values to each other through them.
+func usesTmp(p *obj.Prog, code []byte) bool {
+	isTmp := func(r int16) bool {
+		return r == REGTMP || r == REGTMP2
+	}
+	if isTmp(p.Reg) || isTmp(p.From.Reg) || isTmp(p.From.Index) ||
+		isTmp(p.To.Reg) || isTmp(p.To.Index) || isTmp(p.RegTo2) {
+		return true
+	}
+	if p.From3 != nil && (isTmp(p.From3.Reg) || isTmp(p.From3.Index)) {
+		return true
+	}
+
+	// The length of an instruction is given by the two high
+	// bits of its first byte.
+	n := 0
+	for len(code) > 0 {
+		size := 4
+		switch code[0] >> 6 {
+		case 0:
+			size = 2
+		case 3:
+			size = 6
+		}
+		if size > len(code) {
+			break
+		}
+		code = code[size:]
+		n++
+	}
+	return n > 1
+}
+
 func isint32(v int64) bool {
 	return int64(int32(v)) == v
 }
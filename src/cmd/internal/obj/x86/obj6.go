@@ -916,6 +916,15 @@ func preprocess(ctxt *obj.Link, cursym *obj.LSym) {
 			p.Spadj = -2
 			continue
 
+		case AADJSP:
+			// The prologue's ADJSP is already counted in
+			// deltasp; only track explicit adjustments.
+			if p.Spadj == 0 {
+				p.Spadj = int32(p.From.Offset)
+				deltasp += int32(p.From.Offset)
+			}
+			continue
+
 		case obj.ARET:
 			// do nothing
 		}
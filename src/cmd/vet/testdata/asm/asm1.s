@@ -313,3 +313,11 @@ TEXT ·f17584(SB), NOSPLIT, $12
 	MOVSS	y_real+4(FP), X0
 	MOVSS	y_imag+8(FP), X0
 	RET
+
+// SP writes the assembler cannot describe are marked with NOP SP.
+TEXT ·nopsp(SB), NOSPLIT, $0-0
+	ADJSP	$16
+	NOP	SP
+	MOVQ	AX, 8(SP)
+	ADJSP	$-16
+	RET
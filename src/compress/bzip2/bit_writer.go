@@ -0,0 +1,47 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bzip2
+
+// bitWriter packs values, most-significant bit first, into a byte slice.
+// The bits of an incomplete final byte are kept until more are written,
+// so that the output can be taken out a block at a time.
+type bitWriter struct {
+	out  []byte
+	n    uint64
+	bits uint
+}
+
+// WriteBits appends the low bits of v, which must be at most 32 bits long.
+func (bw *bitWriter) WriteBits(bits uint, v uint32) {
+	bw.n = bw.n<<bits | uint64(v)&(1<<bits-1)
+	bw.bits += bits
+	for bw.bits >= 8 {
+		bw.bits -= 8
+		bw.out = append(bw.out, byte(bw.n>>bw.bits))
+	}
+}
+
+func (bw *bitWriter) WriteBits64(bits uint, v uint64) {
+	if bits > 32 {
+		bw.WriteBits(bits-32, uint32(v>>32))
+		bits = 32
+	}
+	bw.WriteBits(bits, uint32(v))
+}
+
+func (bw *bitWriter) WriteBit(b bool) {
+	if b {
+		bw.WriteBits(1, 1)
+	} else {
+		bw.WriteBits(1, 0)
+	}
+}
+
+// Align pads the output with zero bits to a byte boundary.
+func (bw *bitWriter) Align() {
+	if bw.bits > 0 {
+		bw.WriteBits(8-bw.bits, 0)
+	}
+}
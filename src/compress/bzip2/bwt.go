@@ -0,0 +1,125 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bzip2
+
+// bwtSorter holds the arrays used by the forward Burrows-Wheeler
+// transform, so that they can be reused from block to block.
+type bwtSorter struct {
+	sa   []int32 // rotations in sorted order
+	rank []int32 // rank of each rotation by its first k bytes
+	tmp  []int32
+	cnt  []int32
+}
+
+// transform sets dst to the Burrows-Wheeler transform of src, the last
+// column of the sorted matrix of rotations of src, and returns origPtr,
+// the row of src itself. len(dst) must equal len(src), which must not
+// be zero.
+//
+// The rotations are sorted by prefix doubling: after the pass for k,
+// they are ordered by their first 2k bytes, from the ranks of the
+// rotations by their first k bytes. Each pass is a stable counting sort,
+// so the whole sort takes O(n log n) time even for repetitive input.
+func (s *bwtSorter) transform(dst, src []byte) (origPtr int) {
+	n := len(src)
+	s.sa = growInt32(s.sa, n)
+	s.rank = growInt32(s.rank, n)
+	s.tmp = growInt32(s.tmp, n)
+	if n < 256 {
+		s.cnt = growInt32(s.cnt, 256)
+	} else {
+		s.cnt = growInt32(s.cnt, n)
+	}
+	sa, rank, tmp, cnt := s.sa, s.rank, s.tmp, s.cnt
+
+	// Sort by the first byte.
+	for i := range cnt[:256] {
+		cnt[i] = 0
+	}
+	for _, c := range src {
+		cnt[c]++
+	}
+	sum := int32(0)
+	for i := range cnt[:256] {
+		sum += cnt[i]
+		cnt[i] = sum - cnt[i]
+	}
+	for i, c := range src {
+		sa[cnt[c]] = int32(i)
+		cnt[c]++
+	}
+	classes := int32(1)
+	rank[sa[0]] = 0
+	for j := 1; j < n; j++ {
+		if src[sa[j]] != src[sa[j-1]] {
+			classes++
+		}
+		rank[sa[j]] = classes - 1
+	}
+
+	for k := 1; k < n && int(classes) < n; k <<= 1 {
+		// sa is ordered by the first k bytes, so the rotations
+		// starting k bytes earlier are ordered by their second k bytes.
+		for j, i := range sa {
+			i -= int32(k)
+			if i < 0 {
+				i += int32(n)
+			}
+			tmp[j] = i
+		}
+		for i := range cnt[:classes] {
+			cnt[i] = 0
+		}
+		for _, i := range tmp {
+			cnt[rank[i]]++
+		}
+		sum := int32(0)
+		for i := range cnt[:classes] {
+			sum += cnt[i]
+			cnt[i] = sum
+		}
+		for j := n - 1; j >= 0; j-- {
+			i := tmp[j]
+			cnt[rank[i]]--
+			sa[cnt[rank[i]]] = i
+		}
+
+		// Rank the rotations by their first 2k bytes.
+		next := func(i int32) int32 {
+			i += int32(k)
+			if i >= int32(n) {
+				i -= int32(n)
+			}
+			return rank[i]
+		}
+		classes = 1
+		tmp[sa[0]] = 0
+		for j := 1; j < n; j++ {
+			a, b := sa[j-1], sa[j]
+			if rank[a] != rank[b] || next(a) != next(b) {
+				classes++
+			}
+			tmp[b] = classes - 1
+		}
+		rank, tmp = tmp, rank
+	}
+	s.rank, s.tmp = rank, tmp
+
+	for j, i := range sa {
+		if i == 0 {
+			origPtr = j
+			i = int32(n)
+		}
+		dst[j] = src[i-1]
+	}
+	return origPtr
+}
+
+func growInt32(b []int32, n int) []int32 {
+	if cap(b) < n {
+		return make([]int32, n)
+	}
+	return b[:n]
+}
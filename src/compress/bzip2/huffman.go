@@ -261,3 +261,92 @@ func buildHuffmanNode(t *huffmanTree, codes []huffmanCode, level uint32) (nodeIn
 
 	return
 }
+
+// huffmanCodeLengths sets lengths to the code lengths of a Huffman code
+// for symbols with the given frequencies, none of them longer than
+// maxLen. Every symbol gets a code, even those that don't occur, since
+// bzip2 stores a length for each of them. If the optimal code is too
+// long, the weights are flattened until it fits, as bzip2 does.
+func huffmanCodeLengths(lengths []uint8, freqs []int32, maxLen uint8) {
+	n := len(freqs)
+	if n < 2 {
+		panic("huffmanCodeLengths: too few symbols")
+	}
+
+	// Nodes 0 to n-1 are the leaves and the others are created by
+	// merging two nodes, so a parent comes after its children.
+	weight := make([]int64, 2*n-1)
+	parent := make([]int, 2*n-1)
+	depth := make([]uint8, 2*n-1)
+	leaves := make(huffmanLeaves, n)
+	for i, f := range freqs {
+		weight[i] = int64(f)
+		if f == 0 {
+			weight[i] = 1
+		}
+	}
+	for {
+		for i := range leaves {
+			leaves[i] = huffmanLeaf{weight[i], uint16(i)}
+		}
+		sort.Sort(leaves)
+
+		// The merged nodes are created in order of weight, so the
+		// lightest node is the first remaining one of either the
+		// sorted leaves or the merged nodes.
+		next, first, last := 0, n, n
+		lightest := func() int {
+			if next < n && (first == last || leaves[next].weight <= weight[first]) {
+				next++
+				return int(leaves[next-1].symbol)
+			}
+			first++
+			return first - 1
+		}
+		for ; last < len(weight); last++ {
+			a, b := lightest(), lightest()
+			weight[last] = weight[a] + weight[b]
+			parent[a], parent[b] = last, last
+		}
+
+		depth[len(depth)-1] = 0
+		tooLong := false
+		for i := len(depth) - 2; i >= 0; i-- {
+			depth[i] = depth[parent[i]] + 1
+			if i < n && depth[i] > maxLen {
+				tooLong = true
+			}
+		}
+		if !tooLong {
+			copy(lengths, depth[:n])
+			return
+		}
+		for i := range weight[:n] {
+			weight[i] = weight[i]/2 + 1
+		}
+	}
+}
+
+// huffmanLeaf is a symbol and its weight, used to build a Huffman code.
+type huffmanLeaf struct {
+	weight int64
+	symbol uint16
+}
+
+// huffmanLeaves is used to provide an interface for sorting.
+type huffmanLeaves []huffmanLeaf
+
+func (h huffmanLeaves) Len() int {
+	return len(h)
+}
+
+func (h huffmanLeaves) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+	return h[i].symbol < h[j].symbol
+}
+
+func (h huffmanLeaves) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
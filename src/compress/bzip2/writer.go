@@ -0,0 +1,444 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bzip2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// These constants are the valid compression levels. The level is the
+// block size in units of 100,000 bytes: larger blocks compress better
+// but take more memory to compress and decompress.
+const (
+	BestSpeed          = 1
+	BestCompression    = 9
+	DefaultCompression = BestCompression
+)
+
+var errWriterClosed = errors.New("bzip2: writer is closed")
+
+const (
+	maxCodeLen   = 17 // the longest Huffman code the Writer makes
+	groupSize    = 50 // the number of symbols coded with each selector
+	numIters     = 4  // the number of passes refining the Huffman tables
+	maxRunLength = 255
+)
+
+// A Writer is an io.WriteCloser.
+// Writes to a Writer are compressed and written to w.
+type Writer struct {
+	w           io.Writer
+	level       int
+	err         error
+	closed      bool
+	wroteHeader bool
+	fileCRC     uint32
+
+	// The current block, after the initial run-length encoding, and the
+	// run of equal bytes still to be added to it.
+	block    []byte
+	maxBlock int
+	blockCRC uint32
+	runByte  byte
+	runLen   int
+
+	bw     bitWriter
+	sorter bwtSorter
+	bwt    []byte
+	mtf    []uint16
+}
+
+// NewWriter returns a new Writer.
+// Writes to the returned writer are compressed and written to w.
+//
+// It is the caller's responsibility to call Close on the Writer when done.
+// Writes are buffered and not flushed until a block is full or Close
+// is called.
+func NewWriter(w io.Writer) *Writer {
+	z, _ := NewWriterLevel(w, DefaultCompression)
+	return z
+}
+
+// NewWriterLevel is like NewWriter but specifies the compression level
+// instead of assuming DefaultCompression.
+//
+// The compression level can be any integer value between BestSpeed and
+// BestCompression inclusive. The error returned will be nil if the
+// level is valid.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	if level < BestSpeed || level > BestCompression {
+		return nil, fmt.Errorf("bzip2: invalid compression level: %d", level)
+	}
+	z := &Writer{
+		w:     w,
+		level: level,
+		// Leave room for the longest run, as bzip2 does.
+		maxBlock: level*100*1000 - 19,
+	}
+	return z, nil
+}
+
+// Reset discards the Writer z's state and makes it equivalent to the
+// result of its original state from NewWriter or NewWriterLevel, but
+// writing to w instead. This permits reusing a Writer rather than
+// allocating a new one.
+func (z *Writer) Reset(w io.Writer) {
+	*z = Writer{
+		w:        w,
+		level:    z.level,
+		maxBlock: z.maxBlock,
+		block:    z.block[:0],
+		bw:       bitWriter{out: z.bw.out[:0]},
+		sorter:   z.sorter,
+		bwt:      z.bwt,
+		mtf:      z.mtf,
+	}
+}
+
+// Write writes a compressed form of p to the underlying io.Writer. The
+// compressed bytes are not necessarily flushed until the Writer is closed.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.closed {
+		return 0, errWriterClosed
+	}
+	for i, c := range p {
+		if z.runLen > 0 && c == z.runByte && z.runLen < maxRunLength {
+			z.runLen++
+			continue
+		}
+		if z.runLen > 0 {
+			z.addRun()
+			if len(z.block) >= z.maxBlock {
+				if z.err = z.writeBlock(); z.err != nil {
+					return i, z.err
+				}
+			}
+		}
+		z.runByte, z.runLen = c, 1
+	}
+	return len(p), nil
+}
+
+// Close closes the Writer, flushing any unwritten data to the underlying
+// io.Writer, but does not close the underlying io.Writer.
+func (z *Writer) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+	if z.runLen > 0 {
+		z.addRun()
+	}
+	if z.err = z.writeBlock(); z.err != nil {
+		return z.err
+	}
+	z.bw.WriteBits64(48, bzip2FinalMagic)
+	z.bw.WriteBits(32, z.fileCRC)
+	z.bw.Align()
+	_, z.err = z.w.Write(z.bw.out)
+	z.bw.out = z.bw.out[:0]
+	return z.err
+}
+
+// addRun adds the pending run to the block. Runs of four to 255 equal
+// bytes are stored as four bytes followed by the number of remaining
+// ones.
+func (z *Writer) addRun() {
+	crc := ^z.blockCRC
+	for i := 0; i < z.runLen; i++ {
+		crc = crctab[byte(crc>>24)^z.runByte] ^ (crc << 8)
+	}
+	z.blockCRC = ^crc
+
+	c := z.runByte
+	switch z.runLen {
+	case 1:
+		z.block = append(z.block, c)
+	case 2:
+		z.block = append(z.block, c, c)
+	case 3:
+		z.block = append(z.block, c, c, c)
+	default:
+		z.block = append(z.block, c, c, c, c, byte(z.runLen-4))
+	}
+	z.runLen = 0
+}
+
+// writeBlock compresses the current block, if it isn't empty, and writes
+// out all complete bytes of output.
+func (z *Writer) writeBlock() error {
+	if !z.wroteHeader {
+		z.wroteHeader = true
+		z.bw.out = append(z.bw.out, 'B', 'Z', 'h', byte('0'+z.level))
+	}
+	if len(z.block) > 0 {
+		z.fileCRC = (z.fileCRC<<1 | z.fileCRC>>31) ^ z.blockCRC
+		z.compressBlock()
+		z.block = z.block[:0]
+		z.blockCRC = 0
+	}
+	_, err := z.w.Write(z.bw.out)
+	z.bw.out = z.bw.out[:0]
+	return err
+}
+
+// compressBlock writes the current block to z.bw.
+func (z *Writer) compressBlock() {
+	bw := &z.bw
+	n := len(z.block)
+	if cap(z.bwt) < n {
+		z.bwt = make([]byte, n)
+	}
+	z.bwt = z.bwt[:n]
+	origPtr := z.sorter.transform(z.bwt, z.block)
+
+	// Only the byte values used in the block are given symbols.
+	var inUse [256]bool
+	for _, c := range z.block {
+		inUse[c] = true
+	}
+	var unseq [256]byte
+	numInUse := 0
+	for c, used := range inUse {
+		if used {
+			unseq[c] = byte(numInUse)
+			numInUse++
+		}
+	}
+
+	// The move-to-front transform turns the output of the BWT into
+	// mostly small numbers and runs of zeros. The runs are written with
+	// the RUNA and RUNB symbols as a bijective base-2 number, least
+	// significant digit first, and the other numbers are shifted up by
+	// one to make room.
+	alphaSize := numInUse + 2
+	eob := uint16(numInUse + 1)
+	var list [256]byte
+	for i := range list[:numInUse] {
+		list[i] = byte(i)
+	}
+	mtf := z.mtf[:0]
+	zeros := 0
+	for _, c := range z.bwt {
+		s := unseq[c]
+		if list[0] == s {
+			zeros++
+			continue
+		}
+		mtf = appendZeroRun(mtf, zeros)
+		zeros = 0
+		prev := list[0]
+		list[0] = s
+		j := 1
+		for ; list[j] != s; j++ {
+			list[j], prev = prev, list[j]
+		}
+		list[j] = prev
+		mtf = append(mtf, uint16(j+1))
+	}
+	mtf = appendZeroRun(mtf, zeros)
+	mtf = append(mtf, eob)
+	z.mtf = mtf
+
+	lengths, selectors := chooseTables(mtf, alphaSize)
+
+	bw.WriteBits64(48, bzip2BlockMagic)
+	bw.WriteBits(32, z.blockCRC)
+	bw.WriteBits(1, 0) // not randomized
+	bw.WriteBits(24, uint32(origPtr))
+
+	// The symbol map is a two-level, 16x16 bitmap.
+	var ranges uint32
+	for i := 0; i < 16; i++ {
+		for _, used := range inUse[16*i : 16*i+16] {
+			if used {
+				ranges |= 1 << uint(15-i)
+				break
+			}
+		}
+	}
+	bw.WriteBits(16, ranges)
+	for i := 0; i < 16; i++ {
+		if ranges&(1<<uint(15-i)) == 0 {
+			continue
+		}
+		var bits uint32
+		for j, used := range inUse[16*i : 16*i+16] {
+			if used {
+				bits |= 1 << uint(15-j)
+			}
+		}
+		bw.WriteBits(16, bits)
+	}
+
+	// The selectors are move-to-front transformed and written in unary.
+	bw.WriteBits(3, uint32(len(lengths)))
+	bw.WriteBits(15, uint32(len(selectors)))
+	tables := [6]uint8{0, 1, 2, 3, 4, 5}
+	for _, s := range selectors {
+		prev := tables[0]
+		tables[0] = s
+		j := 0
+		if prev != s {
+			j = 1
+			for ; tables[j] != s; j++ {
+				tables[j], prev = prev, tables[j]
+			}
+			tables[j] = prev
+		}
+		bw.WriteBits(uint(j+1), 1<<uint(j+1)-2)
+	}
+
+	// The code lengths are delta encoded from a 5-bit base value.
+	codes := make([][]uint32, len(lengths))
+	for t, lens := range lengths {
+		length := lens[0]
+		bw.WriteBits(5, uint32(length))
+		for _, l := range lens {
+			for ; length < l; length++ {
+				bw.WriteBits(2, 2)
+			}
+			for ; length > l; length-- {
+				bw.WriteBits(2, 3)
+			}
+			bw.WriteBits(1, 0)
+		}
+		codes[t] = canonicalCodes(lens)
+	}
+
+	for i, s := range mtf {
+		t := selectors[i/groupSize]
+		bw.WriteBits(uint(lengths[t][s]), codes[t][s])
+	}
+}
+
+// appendZeroRun appends the RUNA and RUNB symbols for a run of n zeros.
+func appendZeroRun(mtf []uint16, n int) []uint16 {
+	for n > 0 {
+		n--
+		mtf = append(mtf, uint16(n&1))
+		n >>= 1
+	}
+	return mtf
+}
+
+// chooseTables builds the Huffman tables for the symbols of a block and
+// selects one of them for each group of groupSize symbols. Like bzip2,
+// it starts with tables each covering a range of symbols of about equal
+// total frequency, then repeatedly assigns each group to the table
+// coding it in the fewest bits and rebuilds the tables from the symbol
+// frequencies of their groups.
+func chooseTables(mtf []uint16, alphaSize int) (lengths [][]uint8, selectors []uint8) {
+	var numTables int
+	switch n := len(mtf); {
+	case n < 200:
+		numTables = 2
+	case n < 600:
+		numTables = 3
+	case n < 1200:
+		numTables = 4
+	case n < 2400:
+		numTables = 5
+	default:
+		numTables = 6
+	}
+
+	freqs := make([]int32, alphaSize)
+	for _, s := range mtf {
+		freqs[s]++
+	}
+	lengths = make([][]uint8, numTables)
+	for t := range lengths {
+		lengths[t] = make([]uint8, alphaSize)
+	}
+	remaining := int32(len(mtf))
+	start := 0
+	for part := numTables; part > 0; part-- {
+		target := remaining / int32(part)
+		end := start - 1
+		sum := int32(0)
+		for sum < target && end < alphaSize-1 {
+			end++
+			sum += freqs[end]
+		}
+		if end > start && part != numTables && part != 1 && (numTables-part)%2 == 1 {
+			sum -= freqs[end]
+			end--
+		}
+		for s, lens := 0, lengths[part-1]; s < alphaSize; s++ {
+			if s >= start && s <= end {
+				lens[s] = 0
+			} else {
+				lens[s] = 15
+			}
+		}
+		start = end + 1
+		remaining -= sum
+	}
+
+	selectors = make([]uint8, (len(mtf)+groupSize-1)/groupSize)
+	tableFreqs := make([][]int32, numTables)
+	for t := range tableFreqs {
+		tableFreqs[t] = make([]int32, alphaSize)
+	}
+	for iter := 0; iter < numIters; iter++ {
+		for _, f := range tableFreqs {
+			for s := range f {
+				f[s] = 0
+			}
+		}
+		for g := range selectors {
+			group := mtf[g*groupSize:]
+			if len(group) > groupSize {
+				group = group[:groupSize]
+			}
+			var cost [6]int
+			for _, s := range group {
+				for t, lens := range lengths {
+					cost[t] += int(lens[s])
+				}
+			}
+			best := 0
+			for t := 1; t < numTables; t++ {
+				if cost[t] < cost[best] {
+					best = t
+				}
+			}
+			selectors[g] = uint8(best)
+			for _, s := range group {
+				tableFreqs[best][s]++
+			}
+		}
+		for t, lens := range lengths {
+			huffmanCodeLengths(lens, tableFreqs[t], maxCodeLen)
+		}
+	}
+	return lengths, selectors
+}
+
+// canonicalCodes returns the codes of the canonical Huffman code with
+// the given lengths, assigned in order of length and then of symbol.
+func canonicalCodes(lengths []uint8) []uint32 {
+	codes := make([]uint32, len(lengths))
+	code := uint32(0)
+	for l := uint8(1); l <= maxCodeLen; l++ {
+		for s, length := range lengths {
+			if length == l {
+				codes[s] = code
+				code++
+			}
+		}
+		code <<= 1
+	}
+	return codes
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bzip2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// writerInputs returns the inputs of the round trip tests.
+func writerInputs() map[string][]byte {
+	random := make([]byte, 200<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	bytes256 := make([]byte, 256)
+	for i := range bytes256 {
+		bytes256[i] = byte(i)
+	}
+	var runs []byte
+	for n := 1; n < 600; n += 7 {
+		runs = append(runs, bytes.Repeat([]byte{byte(n)}, n)...)
+		runs = append(runs, bytes.Repeat([]byte{'x'}, n%260)...)
+	}
+	return map[string][]byte{
+		"empty":  nil,
+		"short":  []byte("hello world\n"),
+		"one":    {'x'},
+		"same":   bytes.Repeat([]byte{'x'}, 300<<10),
+		"period": bytes.Repeat([]byte("abc"), 100<<10),
+		"runs":   runs,
+		"twain":  mustLoadFile("../testdata/Mark.Twain-Tom.Sawyer.txt"),
+		"e":      mustLoadFile("../testdata/e.txt"),
+		"random": random,
+		"bytes":  bytes.Repeat(bytes256, 10),
+	}
+}
+
+func compressBzip2(t *testing.T, level int, data []byte, chunk int) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriterLevel(&buf, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := data; len(p) > 0; {
+		n := chunk
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	for name, data := range writerInputs() {
+		for _, level := range []int{BestSpeed, 4, BestCompression} {
+			for _, chunk := range []int{1 << 20, 999} {
+				comp := compressBzip2(t, level, data, chunk)
+				got, err := ioutil.ReadAll(NewReader(bytes.NewReader(comp)))
+				if err != nil {
+					t.Errorf("%s, level %d, chunk %d: %v", name, level, chunk, err)
+					continue
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("%s, level %d, chunk %d: round trip mismatch:\ngot  %s\nwant %s", name, level, chunk, trim(got), trim(data))
+				}
+				if name == "twain" && len(comp) > len(data)/2 {
+					t.Errorf("%s, level %d: compressed to %d bytes", name, level, len(comp))
+				}
+			}
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	// This is the output of the bzip2 command for empty input.
+	want := mustDecodeHex("425a683917724538509000000000")
+	if got := compressBzip2(t, BestCompression, nil, 1); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestWriterClose(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write([]byte("hello world\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Errorf("no error writing to closed Writer")
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	data := []byte(strings.Repeat("hello world\n", 1000))
+	var buf1, buf2 bytes.Buffer
+	w, err := NewWriterLevel(&buf1, BestSpeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	w.Close()
+	w.Reset(&buf2)
+	w.Write(data)
+	w.Close()
+	if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+		t.Errorf("output after Reset differs")
+	}
+}
+
+func TestWriterLevel(t *testing.T) {
+	for _, level := range []int{-1, 0, 10} {
+		if _, err := NewWriterLevel(ioutil.Discard, level); err == nil {
+			t.Errorf("no error for level %d", level)
+		}
+	}
+}
+
+func TestBWT(t *testing.T) {
+	var vectors = []struct {
+		in, out string
+		origPtr int
+	}{
+		{"banana", "nnbaaa", 3},
+		{"abracadabra", "rdarcaaaabb", 2},
+		{"aaaa", "aaaa", 0},
+		{"x", "x", 0},
+	}
+	var s bwtSorter
+	for _, v := range vectors {
+		out := make([]byte, len(v.in))
+		origPtr := s.transform(out, []byte(v.in))
+		if string(out) != v.out || (v.in != "aaaa" && origPtr != v.origPtr) {
+			t.Errorf("transform(%q) = %q, %d, want %q, %d", v.in, out, origPtr, v.out, v.origPtr)
+		}
+	}
+}
+
+// TestWriterReference checks that the bzip2 command, if installed,
+// decompresses the output of the Writer.
+func TestWriterReference(t *testing.T) {
+	bzip2, err := exec.LookPath("bzip2")
+	if err != nil {
+		t.Skip("bzip2 command not found")
+	}
+	for name, data := range writerInputs() {
+		for _, level := range []int{BestSpeed, BestCompression} {
+			cmd := exec.Command(bzip2, "-d", "-c")
+			cmd.Stdin = bytes.NewReader(compressBzip2(t, level, data, 1<<20))
+			out, err := cmd.Output()
+			if err != nil {
+				t.Errorf("%s, level %d: %v", name, level, err)
+				continue
+			}
+			if !bytes.Equal(out, data) {
+				t.Errorf("%s, level %d: round trip mismatch", name, level)
+			}
+		}
+	}
+}
+
+func benchmarkEncode(b *testing.B, data []byte) {
+	w := NewWriter(ioutil.Discard)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Reset(ioutil.Discard)
+		w.Write(data)
+		w.Close()
+	}
+}
+
+func BenchmarkEncodeDigits(b *testing.B) { benchmarkEncode(b, mustLoadFile("../testdata/e.txt")) }
+func BenchmarkEncodeTwain(b *testing.B) {
+	benchmarkEncode(b, mustLoadFile("../testdata/Mark.Twain-Tom.Sawyer.txt"))
+}
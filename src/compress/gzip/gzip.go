@@ -36,6 +36,13 @@ type Writer struct {
 	closed      bool
 	buf         [10]byte
 	err         error
+
+	// Concurrent compression, see SetConcurrency.
+	blockSize int
+	blocks    int
+	cur       *block   // block being filled
+	queue     []*block // blocks in flight, oldest first
+	tail      []byte   // the last bytes of the input started
 }
 
 // NewWriter returns a new Writer.
@@ -78,6 +85,8 @@ func (z *Writer) init(w io.Writer, level int) {
 		w:          w,
 		level:      level,
 		compressor: compressor,
+		blockSize:  z.blockSize,
+		blocks:     z.blocks,
 	}
 }
 
@@ -187,10 +196,14 @@ func (z *Writer) Write(p []byte) (int, error) {
 				return n, z.err
 			}
 		}
-		if z.compressor == nil {
+		if z.compressor == nil && z.blocks == 0 {
 			z.compressor, _ = flate.NewWriter(z.w, z.level)
 		}
 	}
+	if z.blocks > 0 {
+		n, z.err = z.writeBlocks(p)
+		return n, z.err
+	}
 	z.size += uint32(len(p))
 	z.digest = crc32.Update(z.digest, crc32.IEEETable, p)
 	n, z.err = z.compressor.Write(p)
@@ -218,6 +231,10 @@ func (z *Writer) Flush() error {
 			return z.err
 		}
 	}
+	if z.blocks > 0 {
+		z.err = z.flushBlocks(false)
+		return z.err
+	}
 	z.err = z.compressor.Flush()
 	return z.err
 }
@@ -238,7 +255,11 @@ func (z *Writer) Close() error {
 			return z.err
 		}
 	}
-	z.err = z.compressor.Close()
+	if z.blocks > 0 {
+		z.err = z.flushBlocks(true)
+	} else {
+		z.err = z.compressor.Close()
+	}
 	if z.err != nil {
 		return z.err
 	}
@@ -7,7 +7,9 @@ package gzip
 import (
 	"bufio"
 	"bytes"
+	"hash/crc32"
 	"io/ioutil"
+	"os/exec"
 	"reflect"
 	"testing"
 	"time"
@@ -233,3 +235,138 @@ func TestWriterReset(t *testing.T) {
 		t.Errorf("buf2 %q != original buf of %q", buf2.String(), buf.String())
 	}
 }
+
+func TestCRC32Combine(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog.")
+	for i := 0; i <= len(data); i++ {
+		crc1 := crc32.ChecksumIEEE(data[:i])
+		crc2 := crc32.ChecksumIEEE(data[i:])
+		if got, want := crc32Combine(crc1, crc2, int64(len(data)-i)), crc32.ChecksumIEEE(data); got != want {
+			t.Errorf("split at %d: got %#x, want %#x", i, got, want)
+		}
+	}
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		level     int
+		blockSize int
+		blocks    int
+	}{
+		{DefaultCompression, 64 << 10, 4},
+		{DefaultCompression, 1000, 3},
+		{DefaultCompression, 1 << 20, 2},
+		{BestCompression, 100 << 10, 1},
+		{BestSpeed, 64 << 10, 4},
+		{HuffmanOnly, 64 << 10, 4},
+		{NoCompression, 64 << 10, 4},
+	}
+	for _, tt := range tests {
+		var serial bytes.Buffer
+		w, _ := NewWriterLevel(&serial, tt.level)
+		w.Write(data)
+		w.Close()
+
+		var buf bytes.Buffer
+		w, _ = NewWriterLevel(&buf, tt.level)
+		if err := w.SetConcurrency(tt.blockSize, tt.blocks); err != nil {
+			t.Fatal(err)
+		}
+		for p := data; len(p) > 0; {
+			n := 50000
+			if n > len(p) {
+				n = len(p)
+			}
+			w.Write(p[:n])
+			p = p[n:]
+			if len(p)%3 == 0 {
+				if err := w.Flush(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%+v: %v", tt, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%+v: round trip mismatch", tt)
+		}
+		if tt.blockSize >= 64<<10 && tt.level == DefaultCompression && buf.Len() > serial.Len()*21/20 {
+			t.Errorf("%+v: compressed to %d bytes, %d serially", tt, buf.Len(), serial.Len())
+		}
+	}
+}
+
+func TestWriterConcurrentEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetConcurrency(1<<20, 4)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadAll(r); len(got) != 0 || err != nil {
+		t.Errorf("ReadAll = %q, %v, want empty", got, err)
+	}
+
+	// The setting is kept by Reset, and cannot change once writing
+	// has started.
+	buf.Reset()
+	w.Reset(&buf)
+	w.Write([]byte("hello world"))
+	if err := w.SetConcurrency(1<<20, 4); err == nil {
+		t.Error("SetConcurrency after Write succeeded")
+	}
+	w.Close()
+	if w.blocks != 4 {
+		t.Errorf("Reset did not keep the concurrency setting")
+	}
+	if err := w.SetConcurrency(0, 4); err == nil {
+		t.Error("SetConcurrency with zero block size succeeded")
+	}
+}
+
+// TestWriterConcurrentGunzip checks that the system gunzip, if any,
+// decompresses the output of a concurrent Writer.
+func TestWriterConcurrentGunzip(t *testing.T) {
+	gunzip, err := exec.LookPath("gunzip")
+	if err != nil {
+		t.Skip("gunzip command not found")
+	}
+	data, err := ioutil.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetConcurrency(32<<10, 4)
+	w.Write(data)
+	w.Close()
+
+	cmd := exec.Command(gunzip, "-c")
+	cmd.Stdin = &buf
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("gunzip output differs from the input")
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+)
+
+// dictSize is the size of the DEFLATE window, the most of the data
+// before a block that its matches can refer to.
+const dictSize = 32 << 10
+
+// A block is a part of the input compressed concurrently with others.
+type block struct {
+	in   []byte
+	dict []byte // the data preceding in, for matches to refer to
+	last bool
+
+	// Set when done is closed.
+	out  bytes.Buffer
+	crc  uint32
+	err  error
+	done chan struct{}
+}
+
+// compress compresses b.in into b.out as a raw DEFLATE stream ending on
+// a byte boundary, with a final block if b is the last block.
+func (b *block) compress(level int) {
+	defer close(b.done)
+	b.crc = crc32.ChecksumIEEE(b.in)
+	fw, err := flate.NewWriterDict(&b.out, level, b.dict)
+	if err != nil {
+		b.err = err
+		return
+	}
+	if _, b.err = fw.Write(b.in); b.err != nil {
+		return
+	}
+	if b.last {
+		b.err = fw.Close()
+	} else {
+		b.err = fw.Flush()
+	}
+}
+
+// SetConcurrency makes z split its input into blocks of blockSize bytes
+// and compress up to blocks of them at once, each on its own goroutine.
+// The blocks are written out in order as a single GZIP member.
+//
+// Each block is compressed with the 32 KB of data preceding it as a
+// dictionary, so that the compression ratio is close to that of a
+// sequential Writer for large enough blocks, such as 1 MB. However, at
+// the BestSpeed, HuffmanOnly and NoCompression levels, the compressor
+// makes no use of dictionaries. Flush ends the current block.
+//
+// SetConcurrency must be called before the first call to Write, Flush,
+// or Close. The setting is kept by Reset.
+func (z *Writer) SetConcurrency(blockSize, blocks int) error {
+	if z.wroteHeader {
+		return errors.New("gzip: SetConcurrency called after writing")
+	}
+	if blockSize <= 0 || blocks <= 0 {
+		return errors.New("gzip: invalid concurrency settings")
+	}
+	z.blockSize = blockSize
+	z.blocks = blocks
+	return nil
+}
+
+// writeBlocks adds p to the current block, starting the compression
+// of each block that fills up.
+func (z *Writer) writeBlocks(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if z.cur == nil {
+			z.cur = &block{in: make([]byte, 0, z.blockSize)}
+		}
+		k := z.blockSize - len(z.cur.in)
+		if k > len(p) {
+			k = len(p)
+		}
+		z.cur.in = append(z.cur.in, p[:k]...)
+		z.size += uint32(k)
+		n += k
+		p = p[k:]
+		if len(z.cur.in) == z.blockSize {
+			if err := z.startBlock(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// startBlock starts the compression of the current block, which may be
+// empty. It then writes out the oldest blocks until fewer than
+// z.blocks are left in flight.
+func (z *Writer) startBlock(last bool) error {
+	b := z.cur
+	if b == nil {
+		b = new(block)
+	}
+	z.cur = nil
+	b.dict = z.tail
+	b.last = last
+	b.done = make(chan struct{})
+	go b.compress(z.level)
+	z.queue = append(z.queue, b)
+
+	// The input of a block is not modified once it is started, so the
+	// dictionary of the next block can refer to it.
+	if len(b.in) >= dictSize {
+		z.tail = b.in[len(b.in)-dictSize:]
+	} else if len(b.in) > 0 {
+		keep := z.tail
+		if len(keep)+len(b.in) > dictSize {
+			keep = keep[len(keep)+len(b.in)-dictSize:]
+		}
+		tail := make([]byte, 0, len(keep)+len(b.in))
+		tail = append(tail, keep...)
+		z.tail = append(tail, b.in...)
+	}
+
+	for len(z.queue) >= z.blocks {
+		if err := z.writeOldestBlock(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOldestBlock waits for the oldest block in flight and writes it.
+func (z *Writer) writeOldestBlock() error {
+	b := z.queue[0]
+	copy(z.queue, z.queue[1:])
+	z.queue[len(z.queue)-1] = nil
+	z.queue = z.queue[:len(z.queue)-1]
+
+	<-b.done
+	if b.err != nil {
+		return b.err
+	}
+	z.digest = crc32Combine(z.digest, b.crc, int64(len(b.in)))
+	_, err := z.w.Write(b.out.Bytes())
+	return err
+}
+
+// flushBlocks compresses the current block and writes out all blocks.
+func (z *Writer) flushBlocks(last bool) error {
+	if err := z.startBlock(last); err != nil {
+		return err
+	}
+	for len(z.queue) > 0 {
+		if err := z.writeOldestBlock(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crc32Combine returns the CRC-32 checksum of the concatenation of two
+// inputs with checksums crc1 and crc2, the second one being len2 bytes
+// long. It applies to crc1 the operator appending len2 zero bytes,
+// computed by repeated squaring in GF(2), as zlib does.
+func crc32Combine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+
+	// odd is the operator for one zero bit.
+	var even, odd [32]uint32
+	odd[0] = 0xedb88320 // the reversed IEEE polynomial
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	gf2MatrixSquare(&even, &odd) // two zero bits
+	gf2MatrixSquare(&odd, &even) // four zero bits
+
+	// The first squaring gives the operator for one zero byte.
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i++ {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+		vec >>= 1
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for n := range mat {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
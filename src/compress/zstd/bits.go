@@ -0,0 +1,147 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// A forwardBitReader reads bits from a byte slice, least significant
+// bit first. It is used for FSE table descriptions. Reading past the end
+// of the data yields zero bits; the caller checks overreads with used.
+type forwardBitReader struct {
+	b   []byte
+	pos uint // in bits
+}
+
+// peek returns the next n bits, n <= 25, without consuming them.
+func (br *forwardBitReader) peek(n uint) uint32 {
+	var v uint64
+	off := int(br.pos >> 3)
+	for i := 0; i < 5 && off+i < len(br.b); i++ {
+		v |= uint64(br.b[off+i]) << (8 * uint(i))
+	}
+	return uint32(v>>(br.pos&7)) & (1<<n - 1)
+}
+
+func (br *forwardBitReader) skip(n uint) { br.pos += n }
+
+func (br *forwardBitReader) read(n uint) uint32 {
+	v := br.peek(n)
+	br.pos += n
+	return v
+}
+
+// used returns the number of bytes holding the bits read so far,
+// or -1 if more bits were read than available.
+func (br *forwardBitReader) used() int {
+	n := int((br.pos + 7) >> 3)
+	if n > len(br.b) {
+		return -1
+	}
+	return n
+}
+
+// A backwardBitReader reads the bitstreams of Huffman coded literals
+// and FSE coded sequences. Such a bitstream is written forwards and read
+// backwards: reading starts at the highest set bit of the last byte,
+// which marks the end of the stream, and proceeds towards the first bit.
+type backwardBitReader struct {
+	b    []byte
+	off  int    // number of bytes of b not yet loaded
+	bits uint64 // the cnt low bits are unread; the next bit is the highest
+	cnt  uint
+	left int // number of bits left in the stream; negative after an overread
+}
+
+func (br *backwardBitReader) init(b []byte) error {
+	if len(b) == 0 {
+		return StructuralError("empty bitstream")
+	}
+	last := b[len(b)-1]
+	if last == 0 {
+		return StructuralError("missing bitstream end mark")
+	}
+	pad := uint(bits.LeadingZeros8(last)) + 1
+	br.b = b
+	br.off = len(b) - 1
+	br.bits = uint64(last)
+	br.cnt = 8 - pad
+	br.left = 8*len(b) - int(pad)
+	return nil
+}
+
+// fill loads bytes until at least 57 bits are available. Past the
+// beginning of the stream, zero bits are loaded.
+func (br *backwardBitReader) fill() {
+	for br.cnt <= 56 {
+		br.bits <<= 8
+		if br.off > 0 {
+			br.off--
+			br.bits |= uint64(br.b[br.off])
+		}
+		br.cnt += 8
+	}
+}
+
+// peek returns the next n bits, n <= 32, without consuming them.
+func (br *backwardBitReader) peek(n uint) uint32 {
+	if br.cnt < n {
+		br.fill()
+	}
+	return uint32(br.bits>>(br.cnt-n)) & (1<<n - 1)
+}
+
+func (br *backwardBitReader) skip(n uint) {
+	br.cnt -= n
+	br.left -= int(n)
+}
+
+func (br *backwardBitReader) read(n uint) uint32 {
+	if n == 0 {
+		return 0
+	}
+	v := br.peek(n)
+	br.skip(n)
+	return v
+}
+
+// A bitWriter writes bits least significant bit first. It produces
+// both FSE table descriptions and, closed with an end mark, the
+// bitstreams read by backwardBitReader.
+type bitWriter struct {
+	out   []byte
+	bits  uint64
+	nbits uint
+}
+
+// addBits writes the n low bits of v, n <= 32.
+func (w *bitWriter) addBits(v uint32, n uint) {
+	w.bits |= uint64(v&(1<<n-1)) << w.nbits
+	w.nbits += n
+	if w.nbits >= 32 {
+		w.out = append(w.out, byte(w.bits), byte(w.bits>>8), byte(w.bits>>16), byte(w.bits>>24))
+		w.bits >>= 32
+		w.nbits -= 32
+	}
+}
+
+// flush writes out all pending bits, padding the last byte with zeros.
+func (w *bitWriter) flush() {
+	for w.nbits > 0 {
+		w.out = append(w.out, byte(w.bits))
+		w.bits >>= 8
+		if w.nbits < 8 {
+			w.nbits = 0
+		} else {
+			w.nbits -= 8
+		}
+	}
+	w.bits = 0
+}
+
+// close terminates a bitstream with its end mark and flushes it.
+func (w *bitWriter) close() {
+	w.addBits(1, 1)
+	w.flush()
+}
@@ -0,0 +1,313 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "encoding/binary"
+
+// A seqTable is the decoding table of one kind of sequence code.
+type seqTable struct {
+	t   []fseDecEntry // nil if there is no previous table to repeat
+	log uint8
+	buf [1 << maxFSELog]fseDecEntry
+}
+
+// Decoding tables of the predefined distributions.
+var (
+	predefLitLenDec   [1 << predefLitLenLog]fseDecEntry
+	predefMatchLenDec [1 << predefMatchLenLog]fseDecEntry
+	predefOffsetDec   [1 << predefOffsetLog]fseDecEntry
+)
+
+func init() {
+	buildDecTable(predefLitLenDec[:], predefLitLenNorm, predefLitLenLog)
+	buildDecTable(predefMatchLenDec[:], predefMatchLenNorm, predefMatchLenLog)
+	buildDecTable(predefOffsetDec[:], predefOffsetNorm, predefOffsetLog)
+}
+
+// init builds st from the distribution norm.
+func (st *seqTable) init(norm []int16, log uint8) error {
+	if err := buildDecTable(st.buf[:], norm, log); err != nil {
+		return err
+	}
+	st.t = st.buf[:1<<log]
+	st.log = log
+	return nil
+}
+
+// read sets up st for a block, as described by mode and b. The
+// distribution norm has room for every symbol of the code. It returns
+// the number of bytes of b read.
+func (st *seqTable) read(mode uint8, b []byte, norm []int16, maxLog uint8, predef []fseDecEntry, predefLog uint8) (int, error) {
+	switch mode {
+	case modePredefined:
+		st.t = predef
+		st.log = predefLog
+		return 0, nil
+	case modeRLE:
+		if len(b) == 0 {
+			return 0, StructuralError("truncated sequences section")
+		}
+		if int(b[0]) >= len(norm) {
+			return 0, StructuralError("invalid RLE sequence code")
+		}
+		rleDecTable(st.buf[:], b[0])
+		st.t = st.buf[:1]
+		st.log = 0
+		return 1, nil
+	case modeCompressed:
+		log, n, err := readNorm(norm, b, maxLog)
+		if err != nil {
+			return 0, err
+		}
+		return n, st.init(norm, log)
+	}
+	if st.t == nil {
+		return 0, StructuralError("missing table to repeat")
+	}
+	return 0, nil
+}
+
+// decompressBlock decompresses the content of a compressed block,
+// appending it to hist.
+func (z *Reader) decompressBlock(b []byte) error {
+	lits, n, err := z.readLiterals(b)
+	if err != nil {
+		return err
+	}
+	return z.readSequences(b[n:], lits)
+}
+
+// readLiterals decodes the literals section at the start of b.
+// It returns the literals and the size of the section.
+func (z *Reader) readLiterals(b []byte) ([]byte, int, error) {
+	if len(b) == 0 {
+		return nil, 0, StructuralError("missing literals section")
+	}
+	typ := b[0] & 3
+	format := (b[0] >> 2) & 3
+
+	if typ == litsRaw || typ == litsRLE {
+		var size, hn int
+		switch format {
+		case 0, 2:
+			size, hn = int(b[0]>>3), 1
+		case 1:
+			if len(b) < 2 {
+				return nil, 0, StructuralError("truncated literals header")
+			}
+			size, hn = int(b[0]>>4)|int(b[1])<<4, 2
+		case 3:
+			if len(b) < 3 {
+				return nil, 0, StructuralError("truncated literals header")
+			}
+			size, hn = int(b[0]>>4)|int(b[1])<<4|int(b[2])<<12, 3
+		}
+		if size > z.blockMax {
+			return nil, 0, StructuralError("too many literals")
+		}
+		if typ == litsRaw {
+			if len(b) < hn+size {
+				return nil, 0, StructuralError("truncated literals")
+			}
+			return b[hn : hn+size], hn + size, nil
+		}
+		if len(b) <= hn {
+			return nil, 0, StructuralError("truncated literals")
+		}
+		z.lits = grow(z.lits[:0], size)
+		for i := range z.lits {
+			z.lits[i] = b[hn]
+		}
+		return z.lits, hn + 1, nil
+	}
+
+	// Huffman coded literals, in one or four streams.
+	hn, nbits := 3, uint(10)
+	switch format {
+	case 2:
+		hn, nbits = 4, 14
+	case 3:
+		hn, nbits = 5, 18
+	}
+	if len(b) < hn {
+		return nil, 0, StructuralError("truncated literals header")
+	}
+	var h uint64
+	for i := hn - 1; i >= 0; i-- {
+		h = h<<8 | uint64(b[i])
+	}
+	mask := uint64(1)<<nbits - 1
+	size := int(h >> 4 & mask)
+	compSize := int(h >> (4 + nbits) & mask)
+	if size > z.blockMax {
+		return nil, 0, StructuralError("too many literals")
+	}
+	if len(b) < hn+compSize {
+		return nil, 0, StructuralError("truncated literals")
+	}
+	data := b[hn : hn+compSize]
+	if typ == litsCompressed {
+		n, err := readHuffWeights(&z.weights, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		z.huff.init(&z.weights)
+		z.hasHuff = true
+		data = data[n:]
+	} else if !z.hasHuff {
+		return nil, 0, StructuralError("missing Huffman table to repeat")
+	}
+
+	z.lits = grow(z.lits[:0], size)
+	lits := z.lits
+	if format == 0 {
+		if err := z.huff.decode(lits, data); err != nil {
+			return nil, 0, err
+		}
+		return lits, hn + compSize, nil
+	}
+	if len(data) < 6 {
+		return nil, 0, StructuralError("truncated literals jump table")
+	}
+	var start [5]int
+	for i := 1; i < 4; i++ {
+		start[i] = start[i-1] + int(binary.LittleEndian.Uint16(data[2*i-2:]))
+	}
+	data = data[6:]
+	start[4] = len(data)
+	seg := (size + 3) / 4
+	if start[3] > len(data) || 3*seg > size {
+		return nil, 0, StructuralError("invalid literals jump table")
+	}
+	for i := 0; i < 4; i++ {
+		out := lits[i*seg:]
+		if i < 3 {
+			out = out[:seg]
+		}
+		if err := z.huff.decode(out, data[start[i]:start[i+1]]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return lits, hn + compSize, nil
+}
+
+// readSequences decodes the sequences section b and executes the
+// sequences, appending the decompressed data to hist.
+func (z *Reader) readSequences(b []byte, lits []byte) error {
+	if len(b) == 0 {
+		return StructuralError("missing sequences section")
+	}
+	nseq, n := int(b[0]), 1
+	switch {
+	case nseq == 0:
+		if len(b) > 1 {
+			return StructuralError("extraneous data after sequences")
+		}
+		if len(lits) > z.blockMax {
+			return StructuralError("block too large")
+		}
+		z.hist = append(z.hist, lits...)
+		return nil
+	case nseq < 128:
+	case nseq < 255:
+		if len(b) < 2 {
+			return StructuralError("truncated sequences header")
+		}
+		nseq, n = (nseq-128)<<8|int(b[1]), 2
+	default:
+		if len(b) < 3 {
+			return StructuralError("truncated sequences header")
+		}
+		nseq, n = int(b[1])|int(b[2])<<8+0x7f00, 3
+	}
+	if len(b) <= n {
+		return StructuralError("truncated sequences header")
+	}
+	modes := b[n]
+	b = b[n+1:]
+	if modes&3 != 0 {
+		return StructuralError("reserved sequences modes bits set")
+	}
+	n, err := z.litLen.read(modes>>6, b, z.norm[:maxLitLenCode+1], maxLitLenLog, predefLitLenDec[:], predefLitLenLog)
+	if err != nil {
+		return err
+	}
+	b = b[n:]
+	n, err = z.offset.read(modes>>4&3, b, z.norm[:maxOffsetCode+1], maxOffsetLog, predefOffsetDec[:], predefOffsetLog)
+	if err != nil {
+		return err
+	}
+	b = b[n:]
+	n, err = z.matchLen.read(modes>>2&3, b, z.norm[:maxMatchLenCode+1], maxMatchLenLog, predefMatchLenDec[:], predefMatchLenLog)
+	if err != nil {
+		return err
+	}
+	b = b[n:]
+
+	var br backwardBitReader
+	if err := br.init(b); err != nil {
+		return err
+	}
+	llTable, ofTable, mlTable := z.litLen.t, z.offset.t, z.matchLen.t
+	llState := br.read(uint(z.litLen.log))
+	ofState := br.read(uint(z.offset.log))
+	mlState := br.read(uint(z.matchLen.log))
+
+	out := z.hist
+	start := len(out)
+	if cap(out)-start < z.blockMax {
+		out = grow(out, z.blockMax)[:start]
+	}
+	for i := 0; i < nseq; i++ {
+		ll, of, ml := llTable[llState], ofTable[ofState], mlTable[mlState]
+
+		ofValue := uint32(1)<<of.sym + br.read(uint(of.sym))
+		mlCode := matchLenCodes[ml.sym]
+		matchLen := mlCode.base + br.read(uint(mlCode.nbits))
+		llCode := litLenCodes[ll.sym]
+		litLen := llCode.base + br.read(uint(llCode.nbits))
+		if i+1 < nseq {
+			llState = uint32(ll.base) + br.read(uint(ll.nbits))
+			mlState = uint32(ml.base) + br.read(uint(ml.nbits))
+			ofState = uint32(of.base) + br.read(uint(of.nbits))
+		}
+		if br.left < 0 {
+			return StructuralError("truncated sequences bitstream")
+		}
+
+		if int(litLen) > len(lits) {
+			return StructuralError("not enough literals")
+		}
+		if len(out)-start+int(litLen)+int(matchLen) > z.blockMax {
+			return StructuralError("block too large")
+		}
+		out = append(out, lits[:litLen]...)
+		lits = lits[litLen:]
+
+		offset := z.rep.resolve(ofValue, litLen)
+		if offset == 0 || int(offset) > len(out) {
+			return StructuralError("invalid match offset")
+		}
+		// The match may overlap the data it produces;
+		// copy it in chunks that double in size.
+		from := len(out) - int(offset)
+		for m := int(matchLen); m > 0; {
+			chunk := out[from:]
+			if len(chunk) > m {
+				chunk = chunk[:m]
+			}
+			out = append(out, chunk...)
+			m -= len(chunk)
+		}
+	}
+	if br.left != 0 {
+		return StructuralError("invalid sequences bitstream")
+	}
+	if len(out)-start+len(lits) > z.blockMax {
+		return StructuralError("block too large")
+	}
+	z.hist = append(out, lits...)
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "encoding/binary"
+
+// A dict is a parsed dictionary. Its content logically precedes the
+// data of each frame, and its entropy tables and repeat offsets are
+// the initial ones of the frame.
+//
+// A dictionary is either in the format built by the reference
+// implementation, starting with a magic number, or raw content.
+type dict struct {
+	id      uint32
+	content []byte
+	rep     repeatOffsets
+
+	hasTables bool
+	huff      huffWeights
+	litLen    []int16
+	litLenLog uint8
+	offset    []int16
+	offsetLog uint8
+	matchLen  []int16
+	matchLog  uint8
+}
+
+func parseDict(b []byte) (*dict, error) {
+	d := &dict{rep: initialRepeatOffsets}
+	if len(b) < 8 || binary.LittleEndian.Uint32(b) != dictMagic {
+		d.content = append([]byte(nil), b...)
+		return d, nil
+	}
+	d.id = binary.LittleEndian.Uint32(b[4:])
+	b = b[8:]
+
+	n, err := readHuffWeights(&d.huff, b)
+	if err != nil {
+		return nil, err
+	}
+	b = b[n:]
+	d.offset = make([]int16, maxOffsetCode+1)
+	if d.offsetLog, n, err = readNorm(d.offset, b, maxOffsetLog); err != nil {
+		return nil, err
+	}
+	b = b[n:]
+	d.matchLen = make([]int16, maxMatchLenCode+1)
+	if d.matchLog, n, err = readNorm(d.matchLen, b, maxMatchLenLog); err != nil {
+		return nil, err
+	}
+	b = b[n:]
+	d.litLen = make([]int16, maxLitLenCode+1)
+	if d.litLenLog, n, err = readNorm(d.litLen, b, maxLitLenLog); err != nil {
+		return nil, err
+	}
+	b = b[n:]
+	d.hasTables = true
+
+	if len(b) < 12 {
+		return nil, StructuralError("truncated dictionary")
+	}
+	for i := range d.rep {
+		d.rep[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	d.content = append([]byte(nil), b[12:]...)
+	for _, r := range d.rep {
+		if r == 0 || int64(r) > int64(len(d.content)) {
+			return nil, StructuralError("invalid dictionary repeat offset")
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "encoding/binary"
+
+// Encoding tables of the predefined distributions.
+var predefLitLenEnc, predefOffsetEnc, predefMatchLenEnc fseEncTable
+
+func init() {
+	predefLitLenEnc.init(predefLitLenNorm, predefLitLenLog)
+	predefOffsetEnc.init(predefOffsetNorm, predefOffsetLog)
+	predefMatchLenEnc.init(predefMatchLenNorm, predefMatchLenLog)
+}
+
+// A seqCoder chooses the table of one kind of sequence code for each
+// block.
+type seqCoder struct {
+	maxSym int
+	maxLog uint8
+	predef *fseEncTable
+	prev   *fseEncTable // table of the last compressed block, or nil
+	cur    *fseEncTable // table of the current block
+	mode   uint8
+	bufs   [2]fseEncTable
+	counts [maxMatchLenCode + 1]uint32
+	norm   [maxMatchLenCode + 1]int16
+	desc   []byte
+}
+
+func (c *seqCoder) init(maxSym int, maxLog uint8, predef *fseEncTable) {
+	c.maxSym = maxSym
+	c.maxLog = maxLog
+	c.predef = predef
+	c.prev = nil
+}
+
+// setPrev makes norm the table that the first block can repeat.
+func (c *seqCoder) setPrev(norm []int16, log uint8) {
+	c.bufs[0].init(norm, log)
+	c.prev = &c.bufs[0]
+}
+
+// choose picks the cheapest table for codes and appends its
+// description to dst.
+func (c *seqCoder) choose(dst []byte, codes []uint8) []byte {
+	counts := c.counts[:c.maxSym+1]
+	for i := range counts {
+		counts[i] = 0
+	}
+	maxSym := 0
+	for _, v := range codes {
+		counts[v]++
+		if int(v) > maxSym {
+			maxSym = int(v)
+		}
+	}
+	counts = counts[:maxSym+1]
+
+	free := &c.bufs[0]
+	if c.prev == free {
+		free = &c.bufs[1]
+	}
+	if int(counts[codes[0]]) == len(codes) {
+		free.initRLE(codes[0])
+		c.cur, c.mode = free, modeRLE
+		return append(dst, codes[0])
+	}
+
+	best := estimateBits(counts, c.predef.norm, c.predef.log)
+	c.cur, c.mode = c.predef, modePredefined
+	if c.prev != nil {
+		if n := estimateBits(counts, c.prev.norm, c.prev.log); n < best {
+			best = n
+			c.cur, c.mode = c.prev, modeRepeat
+		}
+	}
+	total := uint32(len(codes))
+	log := optimalLog(total, maxSym, c.maxLog)
+	norm := c.norm[:maxSym+1]
+	normalizeCounts(norm, counts, total, log)
+	c.desc = writeNorm(c.desc[:0], norm, log)
+	if n := estimateBits(counts, norm, log) + float64(8*len(c.desc)); n < best {
+		free.init(norm, log)
+		c.cur, c.mode = free, modeCompressed
+		dst = append(dst, c.desc...)
+	}
+	return dst
+}
+
+// An encoder codes the sequences and literals of blocks. Its state
+// carries over from one compressed block to the next.
+type encoder struct {
+	rep      repeatOffsets
+	huff     huffEncTable
+	hasHuff  bool
+	litLen   seqCoder
+	offset   seqCoder
+	matchLen seqCoder
+
+	// State of the current block, committed when it is emitted.
+	next    huffEncTable
+	newHuff bool
+
+	llCodes []uint8
+	ofCodes []uint8
+	mlCodes []uint8
+}
+
+// reset prepares e for a new frame using the dictionary d, if not nil.
+func (e *encoder) reset(d *dict) {
+	e.rep = initialRepeatOffsets
+	e.hasHuff = false
+	e.litLen.init(maxLitLenCode, maxLitLenLog, &predefLitLenEnc)
+	e.offset.init(maxOffsetCode, maxOffsetLog, &predefOffsetEnc)
+	e.matchLen.init(maxMatchLenCode, maxMatchLenLog, &predefMatchLenEnc)
+	if d == nil {
+		return
+	}
+	e.rep = d.rep
+	if d.hasTables {
+		e.huff.hw = d.huff
+		e.huff.init()
+		e.hasHuff = true
+		e.litLen.setPrev(d.litLen, d.litLenLog)
+		e.offset.setPrev(d.offset, d.offsetLog)
+		e.matchLen.setPrev(d.matchLen, d.matchLog)
+	}
+}
+
+// encodeBlock appends the content of a compressed block holding the
+// sequences and literals of s to dst.
+func (e *encoder) encodeBlock(dst []byte, s *blockSeqs) []byte {
+	dst = e.encodeLiterals(dst, s.lits)
+	return e.encodeSequences(dst, s.seqs)
+}
+
+// commit makes the tables of the block just encoded, and its repeat
+// offsets, those of the next block.
+func (e *encoder) commit(s *blockSeqs) {
+	e.rep = s.rep
+	if e.newHuff {
+		e.huff = e.next
+		e.hasHuff = true
+	}
+	if len(s.seqs) > 0 {
+		e.litLen.prev = e.litLen.cur
+		e.offset.prev = e.offset.cur
+		e.matchLen.prev = e.matchLen.cur
+	}
+}
+
+// appendLitsHeader appends the header of a raw or RLE literals section.
+func appendLitsHeader(dst []byte, typ uint8, n int) []byte {
+	switch {
+	case n < 1<<5:
+		return append(dst, byte(n<<3)|typ)
+	case n < 1<<12:
+		return append(dst, byte(n<<4)|1<<2|typ, byte(n>>4))
+	}
+	return append(dst, byte(n<<4)|3<<2|typ, byte(n>>4), byte(n>>12))
+}
+
+// encodeLiterals appends the literals section of lits to dst, Huffman
+// coding them with a new table or the previous one when that pays off.
+func (e *encoder) encodeLiterals(dst, lits []byte) []byte {
+	e.newHuff = false
+	n := len(lits)
+	if n == 0 {
+		return appendLitsHeader(dst, litsRaw, 0)
+	}
+	var counts [256]uint32
+	for _, b := range lits {
+		counts[b]++
+	}
+	if int(counts[lits[0]]) == n {
+		return append(appendLitsHeader(dst, litsRLE, n), lits[0])
+	}
+	if n < 64 {
+		return append(appendLitsHeader(dst, litsRaw, n), lits...)
+	}
+
+	streams, overhead := 1, 0
+	if n > 1023 {
+		streams, overhead = 4, 6
+	}
+	typ := uint8(litsRaw)
+	best := n
+	table := &e.huff
+	if e.hasHuff && e.huff.covers(&counts) {
+		if size := (e.huff.size(&counts)+7)/8 + overhead + streams; size < best {
+			typ, best = litsRepeat, size
+		}
+	}
+	e.next.build(&counts)
+	start := len(dst) + 5 // room for the largest header
+	out := append(dst, 0, 0, 0, 0, 0)
+	if desc, ok := e.next.appendWeights(out); ok {
+		if size := len(desc) - start + (e.next.size(&counts)+7)/8 + overhead + streams; size < best {
+			typ, best = litsCompressed, size
+			table = &e.next
+			out = desc
+		}
+	}
+	if typ == litsRaw {
+		return append(appendLitsHeader(dst, litsRaw, n), lits...)
+	}
+	if typ == litsRepeat {
+		out = out[:start]
+	}
+
+	if streams == 1 {
+		out = table.encode(out, lits)
+	} else {
+		jump := len(out)
+		out = append(out, 0, 0, 0, 0, 0, 0)
+		seg := (n + 3) / 4
+		for i := 0; i < 4; i++ {
+			s := lits[i*seg:]
+			if i < 3 {
+				s = s[:seg]
+			}
+			prev := len(out)
+			out = table.encode(out, s)
+			if i < 3 {
+				binary.LittleEndian.PutUint16(out[jump+2*i:], uint16(len(out)-prev))
+			}
+		}
+	}
+	size := len(out) - start
+	if size >= n {
+		return append(appendLitsHeader(dst, litsRaw, n), lits...)
+	}
+	e.newHuff = typ == litsCompressed
+
+	// Write the header, then move the data right after it.
+	var hn int
+	var h uint64
+	switch {
+	case streams == 1:
+		hn, h = 3, uint64(n)<<4|uint64(size)<<14
+	case n < 1<<10 && size < 1<<10:
+		hn, h = 3, 1<<2|uint64(n)<<4|uint64(size)<<14
+	case n < 1<<14 && size < 1<<14:
+		hn, h = 4, 2<<2|uint64(n)<<4|uint64(size)<<18
+	default:
+		hn, h = 5, 3<<2|uint64(n)<<4|uint64(size)<<22
+	}
+	h |= uint64(typ)
+	for i := 0; i < hn; i++ {
+		out[len(dst)+i] = byte(h >> (8 * uint(i)))
+	}
+	copy(out[len(dst)+hn:], out[start:])
+	return out[:len(dst)+hn+size]
+}
+
+// encodeSequences appends the sequences section of seqs to dst.
+func (e *encoder) encodeSequences(dst []byte, seqs []seq) []byte {
+	nseq := len(seqs)
+	switch {
+	case nseq < 128:
+		dst = append(dst, byte(nseq))
+	case nseq < 0x7f00:
+		dst = append(dst, byte(nseq>>8+128), byte(nseq))
+	default:
+		dst = append(dst, 255, byte(nseq-0x7f00), byte((nseq-0x7f00)>>8))
+	}
+	if nseq == 0 {
+		return dst
+	}
+
+	e.llCodes = e.llCodes[:0]
+	e.ofCodes = e.ofCodes[:0]
+	e.mlCodes = e.mlCodes[:0]
+	for _, s := range seqs {
+		e.llCodes = append(e.llCodes, litLenCode(s.litLen))
+		e.ofCodes = append(e.ofCodes, offsetCode(s.offValue))
+		e.mlCodes = append(e.mlCodes, matchLenCode(s.matchLen))
+	}
+	modes := len(dst)
+	dst = append(dst, 0)
+	dst = e.litLen.choose(dst, e.llCodes)
+	dst = e.offset.choose(dst, e.ofCodes)
+	dst = e.matchLen.choose(dst, e.mlCodes)
+	dst[modes] = e.litLen.mode<<6 | e.offset.mode<<4 | e.matchLen.mode<<2
+
+	// The sequences are written last to first, since they are read
+	// backwards.
+	ll, of, ml := e.litLen.cur, e.offset.cur, e.matchLen.cur
+	w := bitWriter{out: dst}
+	last := nseq - 1
+	llState := ll.initState(e.llCodes[last])
+	ofState := of.initState(e.ofCodes[last])
+	mlState := ml.initState(e.mlCodes[last])
+	e.addExtraBits(&w, seqs[last], last)
+	for i := last - 1; i >= 0; i-- {
+		of.encode(&w, &ofState, e.ofCodes[i])
+		ml.encode(&w, &mlState, e.mlCodes[i])
+		ll.encode(&w, &llState, e.llCodes[i])
+		e.addExtraBits(&w, seqs[i], i)
+	}
+	ml.flushState(&w, mlState)
+	of.flushState(&w, ofState)
+	ll.flushState(&w, llState)
+	w.close()
+	return w.out
+}
+
+// addExtraBits writes the bits completing the codes of sequence i.
+func (e *encoder) addExtraBits(w *bitWriter, s seq, i int) {
+	llc := litLenCodes[e.llCodes[i]]
+	w.addBits(s.litLen-llc.base, uint(llc.nbits))
+	mlc := matchLenCodes[e.mlCodes[i]]
+	w.addBits(s.matchLen-mlc.base, uint(mlc.nbits))
+	w.addBits(s.offValue, uint(e.ofCodes[i]))
+}
@@ -0,0 +1,34 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd_test
+
+import (
+	"bytes"
+	"compress/zstd"
+	"fmt"
+	"io"
+	"os"
+)
+
+func ExampleNewWriter() {
+	var b bytes.Buffer
+
+	w := zstd.NewWriter(&b)
+	w.Write([]byte("hello, world\n"))
+	w.Close()
+	fmt.Println(b.Bytes())
+	// Output: [40 181 47 253 4 88 105 0 0 104 101 108 108 111 44 32 119 111 114 108 100 10 76 31 249 241]
+}
+
+func ExampleNewReader() {
+	buff := []byte{40, 181, 47, 253, 4, 88, 105, 0, 0, 104, 101, 108, 108,
+		111, 44, 32, 119, 111, 114, 108, 100, 10, 76, 31, 249, 241}
+	b := bytes.NewReader(buff)
+
+	r := zstd.NewReader(b)
+	io.Copy(os.Stdout, r)
+	// Output: hello, world
+	r.Close()
+}
@@ -0,0 +1,427 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Finite State Entropy (FSE) coding is a form of tabled asymmetric
+// numeral systems. A table is described by the normalized counts of its
+// symbols, which add up to 1<<log; a count of -1 denotes a symbol less
+// probable than 1/(1<<log), which still takes one state.
+
+const (
+	minFSELog = 5
+	maxFSELog = 9
+)
+
+// readNorm reads an FSE table description from b into norm, whose length
+// is the number of possible symbols. It returns the accuracy log of the
+// table and the number of bytes read.
+func readNorm(norm []int16, b []byte, maxLog uint8) (log uint8, n int, err error) {
+	br := forwardBitReader{b: b}
+	log = uint8(br.read(4)) + minFSELog
+	if log > maxLog {
+		return 0, 0, StructuralError("FSE accuracy log too large")
+	}
+
+	// The number of states left to distribute, plus one, determines
+	// how many bits the next count is written with.
+	remaining := int32(1)<<log + 1
+	threshold := int32(1) << log
+	nbits := uint(log) + 1
+	sym := 0
+	prev0 := false
+	for remaining > 1 && sym < len(norm) {
+		if prev0 {
+			// A zero count is followed by a run of zero counts,
+			// as 2-bit repeat flags; 3 means 3 more and another flag.
+			zsym := sym
+			for br.peek(16) == 0xffff && br.used() >= 0 {
+				zsym += 24
+				br.skip(16)
+			}
+			for br.peek(2) == 3 {
+				zsym += 3
+				br.skip(2)
+			}
+			zsym += int(br.read(2))
+			if zsym >= len(norm) {
+				return 0, 0, StructuralError("FSE symbol index overflow")
+			}
+			for ; sym < zsym; sym++ {
+				norm[sym] = 0
+			}
+			prev0 = false
+			continue
+		}
+
+		// Small values use one bit less than large ones.
+		max := 2*threshold - 1 - remaining
+		v := int32(br.peek(nbits))
+		var count int32
+		if v&(threshold-1) < max {
+			count = v & (threshold - 1)
+			br.skip(nbits - 1)
+		} else {
+			count = v & (2*threshold - 1)
+			if count >= threshold {
+				count -= max
+			}
+			br.skip(nbits)
+		}
+		count-- // so that -1 denotes a low probability symbol
+		if count >= 0 {
+			remaining -= count
+		} else {
+			remaining--
+		}
+		if remaining < 1 {
+			return 0, 0, StructuralError("FSE counts too large")
+		}
+		norm[sym] = int16(count)
+		sym++
+		prev0 = count == 0
+		for remaining < threshold {
+			nbits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 {
+		return 0, 0, StructuralError("FSE counts do not add up")
+	}
+	for ; sym < len(norm); sym++ {
+		norm[sym] = 0
+	}
+	if n = br.used(); n < 0 {
+		return 0, 0, StructuralError("truncated FSE table")
+	}
+	return log, n, nil
+}
+
+// writeNorm appends the description of the FSE table norm to dst.
+// It is the inverse of readNorm.
+func writeNorm(dst []byte, norm []int16, log uint8) []byte {
+	w := bitWriter{out: dst}
+	w.addBits(uint32(log-minFSELog), 4)
+
+	// Trailing zero counts are implicit.
+	nsym := len(norm)
+	for nsym > 0 && norm[nsym-1] == 0 {
+		nsym--
+	}
+
+	remaining := int32(1)<<log + 1
+	threshold := int32(1) << log
+	nbits := uint(log) + 1
+	prev0 := false
+	for sym := 0; sym < nsym && remaining > 1; {
+		if prev0 {
+			start := sym
+			for norm[sym] == 0 {
+				sym++
+			}
+			for sym >= start+24 {
+				start += 24
+				w.addBits(0xffff, 16)
+			}
+			for sym >= start+3 {
+				start += 3
+				w.addBits(3, 2)
+			}
+			w.addBits(uint32(sym-start), 2)
+		}
+		count := int32(norm[sym])
+		sym++
+		max := 2*threshold - 1 - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		count++
+		if count >= threshold {
+			count += max
+		}
+		if count < max {
+			w.addBits(uint32(count), nbits-1)
+		} else {
+			w.addBits(uint32(count), nbits)
+		}
+		prev0 = count == 1
+		for remaining < threshold {
+			nbits--
+			threshold >>= 1
+		}
+	}
+	w.flush()
+	return w.out
+}
+
+// optimalLog returns the accuracy log for a table of symbols up to
+// maxSym, occurring total times, following the reference encoder.
+func optimalLog(total uint32, maxSym int, maxLog uint8) uint8 {
+	log := int(maxLog)
+	if maxBitsSrc := bits.Len32(total-1) - 3; maxBitsSrc < log {
+		log = maxBitsSrc
+	}
+	minBits := bits.Len32(total)
+	if minBitsSymbols := bits.Len32(uint32(maxSym)) + 1; minBitsSymbols < minBits {
+		minBits = minBitsSymbols
+	}
+	if minBits > log {
+		log = minBits
+	}
+	if log < minFSELog {
+		log = minFSELog
+	}
+	if log > int(maxLog) {
+		log = int(maxLog)
+	}
+	return uint8(log)
+}
+
+// normalizeCounts sets norm to the distribution of counts, which add up
+// to total, scaled to 1<<log.
+func normalizeCounts(norm []int16, counts []uint32, total uint32, log uint8) {
+	tableSize := int32(1) << log
+	sum := int32(0)
+	largest := 0
+	for s, c := range counts {
+		switch {
+		case c == 0:
+			norm[s] = 0
+			continue
+		case uint64(c)<<log < uint64(total):
+			norm[s] = -1
+			sum++
+		default:
+			n := int32((uint64(c)<<log + uint64(total)/2) / uint64(total))
+			norm[s] = int16(n)
+			sum += n
+		}
+		if c > counts[largest] {
+			largest = s
+		}
+	}
+	for diff := tableSize - sum; diff != 0; diff++ {
+		if diff > 0 {
+			norm[largest] += int16(diff)
+			break
+		}
+		// Take states away from the most probable symbols.
+		best := largest
+		for s, n := range norm {
+			if n > norm[best] {
+				best = s
+			}
+		}
+		norm[best]--
+	}
+}
+
+// estimateBits returns the approximate number of bits needed to code
+// counts with the table norm of accuracy log, or +Inf if some symbol
+// cannot be coded.
+func estimateBits(counts []uint32, norm []int16, log uint8) float64 {
+	var nbits float64
+	for s, c := range counts {
+		if c == 0 {
+			continue
+		}
+		if s >= len(norm) || norm[s] == 0 {
+			return math.Inf(1)
+		}
+		p := float64(norm[s])
+		if p < 0 {
+			p = 1
+		}
+		nbits += float64(c) * (float64(log) - math.Log2(p))
+	}
+	return nbits
+}
+
+// An fseDecEntry is a state of an FSE decoding table.
+type fseDecEntry struct {
+	sym   uint8
+	nbits uint8  // number of bits to read for the next state
+	base  uint16 // added to the bits read to get the next state
+}
+
+// buildDecTable fills t, of size 1<<log, from the distribution norm.
+func buildDecTable(t []fseDecEntry, norm []int16, log uint8) error {
+	tableSize := 1 << log
+	highThreshold := tableSize - 1
+	var next [256]uint16
+	for s, n := range norm {
+		if n == -1 {
+			t[highThreshold].sym = uint8(s)
+			highThreshold--
+			next[s] = 1
+		} else {
+			next[s] = uint16(n)
+		}
+	}
+
+	// Spread the symbols over the table.
+	pos := 0
+	step := tableSize>>1 + tableSize>>3 + 3
+	mask := tableSize - 1
+	for s, n := range norm {
+		for i := 0; i < int(n); i++ {
+			t[pos].sym = uint8(s)
+			pos = (pos + step) & mask
+			for pos > highThreshold {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	if pos != 0 {
+		return StructuralError("invalid FSE distribution")
+	}
+
+	for i := range t[:tableSize] {
+		s := t[i].sym
+		state := next[s]
+		next[s]++
+		nbits := log - uint8(bits.Len16(state)-1)
+		t[i].nbits = nbits
+		t[i].base = state<<nbits - uint16(tableSize)
+	}
+	return nil
+}
+
+// rleDecTable returns a decoding table always yielding sym.
+func rleDecTable(t []fseDecEntry, sym uint8) {
+	t[0] = fseDecEntry{sym: sym}
+}
+
+// An fseEncTable is an FSE encoding table. States are kept in the
+// range [1<<log, 2<<log).
+type fseEncTable struct {
+	log   uint8
+	norm  []int16
+	rle   bool // a single symbol, coded with no bits
+	state []uint16
+	trans []fseTransform
+}
+
+// An fseTransform holds the coding parameters of a symbol.
+type fseTransform struct {
+	deltaNbBits    uint32
+	deltaFindState int32
+}
+
+// init builds t from the distribution norm.
+func (t *fseEncTable) init(norm []int16, log uint8) {
+	tableSize := 1 << log
+	t.log = log
+	t.norm = append(t.norm[:0], norm...)
+	t.rle = false
+
+	// Spread the symbols as the decoder does.
+	symbols := make([]uint8, tableSize)
+	highThreshold := tableSize - 1
+	cumul := make([]int, len(norm)+1)
+	for s, n := range norm {
+		if n == -1 {
+			symbols[highThreshold] = uint8(s)
+			highThreshold--
+			cumul[s+1] = cumul[s] + 1
+		} else {
+			cumul[s+1] = cumul[s] + int(n)
+		}
+	}
+	pos := 0
+	step := tableSize>>1 + tableSize>>3 + 3
+	mask := tableSize - 1
+	for s, n := range norm {
+		for i := 0; i < int(n); i++ {
+			symbols[pos] = uint8(s)
+			pos = (pos + step) & mask
+			for pos > highThreshold {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+
+	if cap(t.state) < tableSize {
+		t.state = make([]uint16, tableSize)
+	}
+	t.state = t.state[:tableSize]
+	for u, s := range symbols {
+		t.state[cumul[s]] = uint16(tableSize + u)
+		cumul[s]++
+	}
+
+	if cap(t.trans) < len(norm) {
+		t.trans = make([]fseTransform, len(norm))
+	}
+	t.trans = t.trans[:len(norm)]
+	total := int32(0)
+	for s, n := range norm {
+		switch n {
+		case 0:
+			t.trans[s] = fseTransform{deltaNbBits: uint32(log+1)<<16 - uint32(tableSize)}
+		case -1, 1:
+			t.trans[s] = fseTransform{
+				deltaNbBits:    uint32(log)<<16 - uint32(tableSize),
+				deltaFindState: total - 1,
+			}
+			total++
+		default:
+			maxBitsOut := uint32(log) - uint32(bits.Len16(uint16(n-1))-1)
+			minStatePlus := uint32(n) << maxBitsOut
+			t.trans[s] = fseTransform{
+				deltaNbBits:    maxBitsOut<<16 - minStatePlus,
+				deltaFindState: total - int32(n),
+			}
+			total += int32(n)
+		}
+	}
+}
+
+// initRLE makes t a table for the single symbol sym.
+func (t *fseEncTable) initRLE(sym uint8) {
+	t.log = 0
+	t.norm = t.norm[:0]
+	for i := 0; i <= int(sym); i++ {
+		t.norm = append(t.norm, 0)
+	}
+	t.norm[sym] = 1
+	t.rle = true
+}
+
+// initState returns the initial state for coding the last symbol sym,
+// chosen so that it is flushed with the fewest bits.
+func (t *fseEncTable) initState(sym uint8) uint32 {
+	if t.rle {
+		return 0
+	}
+	tr := t.trans[sym]
+	nbitsOut := (tr.deltaNbBits + 1<<15) >> 16
+	v := nbitsOut<<16 - tr.deltaNbBits
+	return uint32(t.state[int32(v>>nbitsOut)+tr.deltaFindState])
+}
+
+// encode codes sym, transitioning from the state *s.
+func (t *fseEncTable) encode(w *bitWriter, s *uint32, sym uint8) {
+	if t.rle {
+		return
+	}
+	tr := t.trans[sym]
+	nbitsOut := (*s + tr.deltaNbBits) >> 16
+	w.addBits(*s, uint(nbitsOut))
+	*s = uint32(t.state[int32(*s>>nbitsOut)+tr.deltaFindState])
+}
+
+// flushState writes the final state s for the decoder to start from.
+func (t *fseEncTable) flushState(w *bitWriter, s uint32) {
+	if !t.rle {
+		w.addBits(s, uint(t.log))
+	}
+}
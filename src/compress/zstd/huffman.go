@@ -0,0 +1,432 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Literals are coded with canonical Huffman codes of at most 11 bits.
+// A Huffman table is described by the weights of its symbols: a symbol
+// of weight w > 0 has a code of maxBits+1-w bits. The weight of the
+// last symbol is implied, since the code is complete.
+
+const (
+	maxHuffLog       = 11
+	maxHuffWeightLog = 6 // accuracy log of FSE compressed weights
+)
+
+// huffWeights holds the weights of the symbols of a Huffman table.
+type huffWeights struct {
+	w    [256]uint8
+	nsym int // number of symbols, including the last one
+}
+
+// readHuffWeights reads a Huffman table description from b and returns
+// the number of bytes read.
+func readHuffWeights(hw *huffWeights, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, StructuralError("missing Huffman table")
+	}
+	hdr := int(b[0])
+	b = b[1:]
+	var n int // number of explicit weights
+	if hdr < 128 {
+		// FSE compressed weights; hdr is their size.
+		if hdr > len(b) {
+			return 0, StructuralError("truncated Huffman table")
+		}
+		var err error
+		if n, err = decodeWeights(hw.w[:], b[:hdr]); err != nil {
+			return 0, err
+		}
+	} else {
+		// Direct representation, four bits per weight.
+		n = hdr - 127
+		size := (n + 1) / 2
+		if size > len(b) {
+			return 0, StructuralError("truncated Huffman table")
+		}
+		for i := 0; i < n; i += 2 {
+			hw.w[i] = b[i/2] >> 4
+			hw.w[i+1] = b[i/2] & 15
+		}
+		hdr = size
+	}
+
+	// Complete the table with the last weight.
+	total := 0
+	for _, w := range hw.w[:n] {
+		if w > maxHuffLog {
+			return 0, StructuralError("invalid Huffman weight")
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 {
+		return 0, StructuralError("invalid Huffman table")
+	}
+	log := bits.Len(uint(total))
+	if log > maxHuffLog {
+		return 0, StructuralError("Huffman table too large")
+	}
+	rest := 1<<uint(log) - total
+	if rest&(rest-1) != 0 {
+		return 0, StructuralError("incomplete Huffman table")
+	}
+	if n >= len(hw.w) {
+		return 0, StructuralError("too many Huffman weights")
+	}
+	hw.w[n] = uint8(bits.Len(uint(rest)))
+	hw.nsym = n + 1
+	for i := hw.nsym; i < len(hw.w); i++ {
+		hw.w[i] = 0
+	}
+	return 1 + hdr, nil
+}
+
+// decodeWeights decodes FSE compressed Huffman weights from b into w
+// and returns their number. Two interleaved FSE states share a single
+// bitstream, which ends when a state cannot be updated.
+func decodeWeights(w []uint8, b []byte) (int, error) {
+	var norm [maxHuffLog + 1]int16
+	log, n, err := readNorm(norm[:], b, maxHuffWeightLog)
+	if err != nil {
+		return 0, err
+	}
+	var t [1 << maxHuffWeightLog]fseDecEntry
+	if err := buildDecTable(t[:], norm[:], log); err != nil {
+		return 0, err
+	}
+	var br backwardBitReader
+	if err := br.init(b[n:]); err != nil {
+		return 0, err
+	}
+	s1 := br.read(uint(log))
+	s2 := br.read(uint(log))
+	count := 0
+	for {
+		if count > len(w)-2 {
+			return 0, StructuralError("too many Huffman weights")
+		}
+		e := t[s1]
+		w[count] = e.sym
+		count++
+		if br.left < int(e.nbits) {
+			w[count] = t[s2].sym
+			count++
+			break
+		}
+		s1 = uint32(e.base) + br.read(uint(e.nbits))
+
+		e = t[s2]
+		w[count] = e.sym
+		count++
+		if br.left < int(e.nbits) {
+			w[count] = t[s1].sym
+			count++
+			break
+		}
+		s2 = uint32(e.base) + br.read(uint(e.nbits))
+	}
+	if br.left < 0 {
+		return 0, StructuralError("truncated Huffman weights")
+	}
+	return count, nil
+}
+
+// A huffDecEntry is an entry of a Huffman decoding table, indexed by
+// the next log bits of the stream.
+type huffDecEntry struct {
+	sym   uint8
+	nbits uint8
+}
+
+type huffDecTable struct {
+	log     uint8
+	entries [1 << maxHuffLog]huffDecEntry
+}
+
+// init builds the decoding table for the weights hw.
+func (t *huffDecTable) init(hw *huffWeights) {
+	total := 0
+	maxWeight := uint8(0)
+	for _, w := range hw.w[:hw.nsym] {
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+		if w > maxWeight {
+			maxWeight = w
+		}
+	}
+	t.log = uint8(bits.Len(uint(total)) - 1)
+
+	// Codes are assigned by increasing weight, then symbol.
+	pos := 0
+	for w := uint8(1); w <= maxWeight; w++ {
+		e := huffDecEntry{nbits: t.log + 1 - w}
+		n := 1 << (w - 1)
+		for s, sw := range hw.w[:hw.nsym] {
+			if sw != w {
+				continue
+			}
+			e.sym = uint8(s)
+			for i := pos; i < pos+n; i++ {
+				t.entries[i] = e
+			}
+			pos += n
+		}
+	}
+}
+
+// decode fills dst with symbols decoded from the bitstream b.
+func (t *huffDecTable) decode(dst, b []byte) error {
+	var br backwardBitReader
+	if err := br.init(b); err != nil {
+		return err
+	}
+	log := uint(t.log)
+	for i := range dst {
+		e := t.entries[br.peek(log)]
+		dst[i] = e.sym
+		br.skip(uint(e.nbits))
+	}
+	if br.left != 0 {
+		return StructuralError("invalid Huffman bitstream")
+	}
+	return nil
+}
+
+// A huffEncTable holds the codes of a Huffman table.
+type huffEncTable struct {
+	hw    huffWeights
+	code  [256]uint16
+	nbits [256]uint8
+}
+
+// init derives the canonical codes of t from its weights.
+func (t *huffEncTable) init() {
+	total := 0
+	maxWeight := uint8(0)
+	for _, w := range t.hw.w[:t.hw.nsym] {
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+		if w > maxWeight {
+			maxWeight = w
+		}
+	}
+	log := uint8(bits.Len(uint(total)) - 1)
+	t.nbits = [256]uint8{}
+	pos := 0
+	for w := uint8(1); w <= maxWeight; w++ {
+		for s, sw := range t.hw.w[:t.hw.nsym] {
+			if sw == w {
+				t.code[s] = uint16(pos >> (w - 1))
+				t.nbits[s] = log + 1 - w
+				pos += 1 << (w - 1)
+			}
+		}
+	}
+}
+
+// build makes t an optimal table for counts, with codes of at most
+// maxHuffLog bits. At least two symbols must occur.
+func (t *huffEncTable) build(counts *[256]uint32) {
+	var lengths [256]uint8
+	huffLengths(lengths[:], counts[:], maxHuffLog)
+	maxLen := uint8(0)
+	nsym := 0
+	for s, l := range lengths {
+		if l > 0 {
+			nsym = s + 1
+			if l > maxLen {
+				maxLen = l
+			}
+		}
+	}
+	t.hw = huffWeights{nsym: nsym}
+	for s, l := range lengths[:nsym] {
+		if l > 0 {
+			t.hw.w[s] = maxLen + 1 - l
+		}
+	}
+	t.init()
+}
+
+// covers reports whether t has a code for every symbol in counts.
+func (t *huffEncTable) covers(counts *[256]uint32) bool {
+	for s, c := range counts {
+		if c > 0 && t.nbits[s] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// size returns the number of bits needed to code counts with t.
+func (t *huffEncTable) size(counts *[256]uint32) int {
+	n := 0
+	for s, c := range counts {
+		n += int(c) * int(t.nbits[s])
+	}
+	return n
+}
+
+// appendWeights appends the description of t to dst, FSE compressing
+// the weights when that is smaller or when there are too many of them
+// to write directly. It reports false if no description is possible.
+func (t *huffEncTable) appendWeights(dst []byte) ([]byte, bool) {
+	n := t.hw.nsym - 1 // the last weight is implied
+	w := t.hw.w[:n]
+	var direct []byte
+	if n <= 128 {
+		direct = make([]byte, 1+(n+1)/2)
+		direct[0] = byte(127 + n)
+		for i, v := range w {
+			if i%2 == 0 {
+				direct[1+i/2] = v << 4
+			} else {
+				direct[1+i/2] |= v
+			}
+		}
+	}
+	if comp := encodeWeights(w); comp != nil && len(comp) < 128 && (direct == nil || len(comp)+1 < len(direct)) {
+		dst = append(dst, byte(len(comp)))
+		return append(dst, comp...), true
+	}
+	if direct == nil {
+		return dst, false
+	}
+	return append(dst, direct...), true
+}
+
+// encodeWeights FSE compresses the Huffman weights w, returning nil
+// if they cannot be compressed in a form decodeWeights reads back.
+func encodeWeights(w []uint8) []byte {
+	if len(w) < 2 {
+		return nil
+	}
+	var counts [maxHuffLog + 1]uint32
+	maxSym := 0
+	for _, v := range w {
+		counts[v]++
+		if int(v) > maxSym {
+			maxSym = int(v)
+		}
+	}
+	var norm [maxHuffLog + 1]int16
+	log := optimalLog(uint32(len(w)), maxSym, maxHuffWeightLog)
+	normalizeCounts(norm[:maxSym+1], counts[:maxSym+1], uint32(len(w)), log)
+	out := writeNorm(nil, norm[:maxSym+1], log)
+
+	var t fseEncTable
+	t.init(norm[:maxSym+1], log)
+	bw := bitWriter{out: out}
+	var s1, s2 uint32
+	i := len(w)
+	if i%2 == 1 {
+		s1 = t.initState(w[i-1])
+		s2 = t.initState(w[i-2])
+		t.encode(&bw, &s1, w[i-3])
+		i -= 3
+	} else {
+		s2 = t.initState(w[i-1])
+		s1 = t.initState(w[i-2])
+		i -= 2
+	}
+	for ; i > 0; i -= 2 {
+		t.encode(&bw, &s2, w[i-1])
+		t.encode(&bw, &s1, w[i-2])
+	}
+	t.flushState(&bw, s2)
+	t.flushState(&bw, s1)
+	bw.close()
+
+	// The end of the weights is only implied by the end of the
+	// bitstream, which can be ambiguous; check that it is not.
+	var check [256]uint8
+	if n, err := decodeWeights(check[:], bw.out); err != nil || n != len(w) || string(check[:n]) != string(w) {
+		return nil
+	}
+	return bw.out
+}
+
+// encode appends the Huffman coded bitstream of src to dst.
+func (t *huffEncTable) encode(dst, src []byte) []byte {
+	w := bitWriter{out: dst}
+	for i := len(src) - 1; i >= 0; i-- {
+		s := src[i]
+		w.addBits(uint32(t.code[s]), uint(t.nbits[s]))
+	}
+	w.close()
+	return w.out
+}
+
+// huffLengths sets lengths to the code lengths of an optimal prefix
+// code for counts, limited to maxLen bits, using the package-merge
+// algorithm.
+func huffLengths(lengths []uint8, counts []uint32, maxLen int) {
+	type node struct {
+		weight uint64
+		sym    int // -1 for a package of two nodes of the previous level
+	}
+	var leaves []node
+	for s, c := range counts {
+		lengths[s] = 0
+		if c > 0 {
+			leaves = append(leaves, node{weight: uint64(c), sym: s})
+		}
+	}
+	if len(leaves) < 2 {
+		for _, l := range leaves {
+			lengths[l.sym] = 1
+		}
+		return
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return leaves[i].weight < leaves[j].weight })
+
+	levels := [][]node{leaves}
+	for level := 1; level < maxLen; level++ {
+		prev := levels[level-1]
+		list := make([]node, 0, len(leaves)+len(prev)/2)
+		i := 0
+		for p := 0; p+1 < len(prev); p += 2 {
+			pkg := node{weight: prev[p].weight + prev[p+1].weight, sym: -1}
+			for i < len(leaves) && leaves[i].weight <= pkg.weight {
+				list = append(list, leaves[i])
+				i++
+			}
+			list = append(list, pkg)
+		}
+		list = append(list, leaves[i:]...)
+		levels = append(levels, list)
+	}
+
+	// Each occurrence of a leaf among the first 2n-2 nodes of the last
+	// level, directly or inside packages, adds a bit to its code.
+	var count func(level, n int)
+	count = func(level, n int) {
+		for _, nd := range levels[level][:n] {
+			if nd.sym >= 0 {
+				lengths[nd.sym]++
+			}
+		}
+		if level > 0 {
+			// The packages among the first n nodes are formed
+			// from a prefix of the previous level.
+			npkg := 0
+			for _, nd := range levels[level][:n] {
+				if nd.sym < 0 {
+					npkg++
+				}
+			}
+			count(level-1, 2*npkg)
+		}
+	}
+	count(len(levels)-1, 2*len(leaves)-2)
+}
@@ -0,0 +1,277 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// A seq is a sequence: litLen literals followed by a match of matchLen
+// bytes, whose offset is coded by offValue as in the format.
+type seq struct {
+	litLen   uint32
+	offValue uint32
+	matchLen uint32
+}
+
+// A blockSeqs accumulates the sequences and literals of a block.
+type blockSeqs struct {
+	seqs []seq
+	lits []byte
+	rep  repeatOffsets // the repeat offsets after the sequences
+}
+
+func (s *blockSeqs) reset(rep repeatOffsets) {
+	s.seqs = s.seqs[:0]
+	s.lits = s.lits[:0]
+	s.rep = rep
+}
+
+// add appends the sequence made of lits and a match at offset.
+func (s *blockSeqs) add(lits []byte, offset, matchLen int) {
+	litLen := uint32(len(lits))
+	s.lits = append(s.lits, lits...)
+	s.seqs = append(s.seqs, seq{
+		litLen:   litLen,
+		offValue: s.rep.encode(uint32(offset), litLen),
+		matchLen: uint32(matchLen),
+	})
+}
+
+const (
+	fastHashLog  = 16 // hash table of BestSpeed
+	longHashLog  = 17 // hash table of 8 byte prefixes of DefaultCompression
+	shortHashLog = 16 // hash table of 5 byte prefixes of DefaultCompression
+
+	prime4bytes = 2654435761
+	prime5bytes = 889523592379
+	prime8bytes = 0xcf1bbcdcb7a56463
+)
+
+func load32(b []byte, i int) uint32 { return binary.LittleEndian.Uint32(b[i:]) }
+func load64(b []byte, i int) uint64 { return binary.LittleEndian.Uint64(b[i:]) }
+
+func hash4(u uint32, h uint) uint32 { return (u * prime4bytes) >> (32 - h) }
+func hash5(u uint64, h uint) uint32 { return uint32(((u << 24) * prime5bytes) >> (64 - h)) }
+func hash8(u uint64, h uint) uint32 { return uint32((u * prime8bytes) >> (64 - h)) }
+
+// matchLen returns the length of the common prefix of a and b,
+// where len(a) <= len(b).
+func matchLen(a, b []byte) int {
+	n := 0
+	for len(a)-n >= 8 {
+		if x := load64(a, n) ^ load64(b, n); x != 0 {
+			return n + bits.TrailingZeros64(x)>>3
+		}
+		n += 8
+	}
+	for n < len(a) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// A matcher finds the sequences of the blocks of a frame. Its hash
+// tables hold positions in the history of the frame, or -1.
+type matcher struct {
+	level  int
+	window int
+	short  []int32
+	long   []int32
+}
+
+func (m *matcher) init(level, window int) {
+	m.level = level
+	m.window = window
+	if level == BestSpeed {
+		m.short = make([]int32, 1<<fastHashLog)
+		return
+	}
+	m.short = make([]int32, 1<<shortHashLog)
+	m.long = make([]int32, 1<<longHashLog)
+}
+
+// reset forgets all positions.
+func (m *matcher) reset() {
+	for i := range m.short {
+		m.short[i] = -1
+	}
+	for i := range m.long {
+		m.long[i] = -1
+	}
+}
+
+// shift adjusts the positions after the first n bytes of the history
+// have been dropped.
+func (m *matcher) shift(n int) {
+	shiftTable(m.short, int32(n))
+	shiftTable(m.long, int32(n))
+}
+
+func shiftTable(t []int32, n int32) {
+	for i, v := range t {
+		if v < n {
+			t[i] = -1
+		} else {
+			t[i] = v - n
+		}
+	}
+}
+
+// prime makes the positions of hist, a dictionary content, available
+// for matching.
+func (m *matcher) prime(hist []byte) {
+	for i := 0; i+8 <= len(hist); i++ {
+		if m.level == BestSpeed {
+			m.short[hash4(load32(hist, i), fastHashLog)] = int32(i)
+			continue
+		}
+		u := load64(hist, i)
+		m.short[hash5(u, shortHashLog)] = int32(i)
+		m.long[hash8(u, longHashLog)] = int32(i)
+	}
+}
+
+// valid reports whether position c can be matched from position p.
+func (m *matcher) valid(c, p int) bool {
+	return c >= 0 && p-c <= m.window
+}
+
+// find adds the sequences of hist[start:] to s. The trailing bytes
+// that are not part of a match are added to the literals of s.
+func (m *matcher) find(s *blockSeqs, hist []byte, start int) {
+	var anchor int
+	if m.level == BestSpeed {
+		anchor = m.fast(s, hist, start)
+	} else {
+		anchor = m.doubleFast(s, hist, start)
+	}
+	s.lits = append(s.lits, hist[anchor:]...)
+}
+
+// extendBack extends the match of length n at p, from c, backwards down
+// to anchor, and returns its new position and length.
+func extendBack(hist []byte, anchor, p, c, n int) (int, int) {
+	for p > anchor && c > 0 && hist[p-1] == hist[c-1] {
+		p--
+		c--
+		n++
+	}
+	return p, n
+}
+
+// fast looks up matches in a single hash table of 4 byte prefixes,
+// skipping ahead faster and faster in incompressible data. It returns
+// the end of the last match.
+func (m *matcher) fast(s *blockSeqs, hist []byte, start int) int {
+	const skipLog = 6
+	end := len(hist)
+	ilimit := end - 8
+	table := m.short
+	anchor, ip := start, start
+	for ip < ilimit {
+		h := hash4(load32(hist, ip), fastHashLog)
+		c := int(table[h])
+		table[h] = int32(ip)
+
+		if r := int(s.rep[0]); m.valid(ip+1-r, ip+1) && load32(hist, ip+1-r) == load32(hist, ip+1) {
+			p := ip + 1
+			n := 4 + matchLen(hist[p+4:], hist[p+4-r:])
+			s.add(hist[anchor:p], r, n)
+			ip = p + n
+			anchor = ip
+			continue
+		}
+		if !m.valid(c, ip) || load32(hist, c) != load32(hist, ip) {
+			ip += 1 + (ip-anchor)>>skipLog
+			continue
+		}
+		n := 4 + matchLen(hist[ip+4:], hist[c+4:])
+		p, n := extendBack(hist, anchor, ip, c, n)
+		s.add(hist[anchor:p], ip-c, n)
+		ip = p + n
+		anchor = ip
+		if ip < ilimit {
+			table[hash4(load32(hist, ip-2), fastHashLog)] = int32(ip - 2)
+		}
+	}
+	return anchor
+}
+
+// doubleFast looks up matches in two hash tables: one of 8 byte
+// prefixes, which finds long matches, and one of 5 byte prefixes.
+// After each match, it checks the second repeat offset for an
+// immediate match. It returns the end of the last match.
+func (m *matcher) doubleFast(s *blockSeqs, hist []byte, start int) int {
+	const skipLog = 8
+	end := len(hist)
+	ilimit := end - 8
+	anchor, ip := start, start
+	for ip < ilimit {
+		u := load64(hist, ip)
+		hl, hs := hash8(u, longHashLog), hash5(u, shortHashLog)
+		cl, cs := int(m.long[hl]), int(m.short[hs])
+		m.long[hl], m.short[hs] = int32(ip), int32(ip)
+
+		var p, offset, n int
+		if r := int(s.rep[0]); m.valid(ip+1-r, ip+1) && load32(hist, ip+1-r) == load32(hist, ip+1) {
+			p, offset = ip+1, r
+			n = 4 + matchLen(hist[p+4:], hist[p+4-r:])
+		} else if m.valid(cl, ip) && load64(hist, cl) == u {
+			offset = ip - cl
+			n = 8 + matchLen(hist[ip+8:], hist[cl+8:])
+			p, n = extendBack(hist, anchor, ip, cl, n)
+		} else if m.valid(cs, ip) && load32(hist, cs) == uint32(u) {
+			// Prefer a long match at the next position.
+			u1 := load64(hist, ip+1)
+			h1 := hash8(u1, longHashLog)
+			c1 := int(m.long[h1])
+			m.long[h1] = int32(ip + 1)
+			if m.valid(c1, ip+1) && load64(hist, c1) == u1 {
+				offset = ip + 1 - c1
+				n = 8 + matchLen(hist[ip+9:], hist[c1+8:])
+				p, n = extendBack(hist, anchor, ip+1, c1, n)
+			} else {
+				offset = ip - cs
+				n = 4 + matchLen(hist[ip+4:], hist[cs+4:])
+				p, n = extendBack(hist, anchor, ip, cs, n)
+			}
+		} else {
+			ip += 1 + (ip-anchor)>>skipLog
+			continue
+		}
+
+		s.add(hist[anchor:p], offset, n)
+		ip = p + n
+		anchor = ip
+		if ip >= ilimit {
+			break
+		}
+		m.insert(hist, p+2)
+		m.long[hash8(load64(hist, ip-2), longHashLog)] = int32(ip - 2)
+		m.short[hash5(load64(hist, ip-1), shortHashLog)] = int32(ip - 1)
+
+		for ip < ilimit {
+			r := int(s.rep[1])
+			if !m.valid(ip-r, ip) || load32(hist, ip-r) != load32(hist, ip) {
+				break
+			}
+			n := 4 + matchLen(hist[ip+4:], hist[ip+4-r:])
+			s.add(nil, r, n)
+			m.insert(hist, ip)
+			ip += n
+			anchor = ip
+		}
+	}
+	return anchor
+}
+
+// insert adds position i to both tables of doubleFast.
+func (m *matcher) insert(hist []byte, i int) {
+	u := load64(hist, i)
+	m.long[hash8(u, longHashLog)] = int32(i)
+	m.short[hash5(u, shortHashLog)] = int32(i)
+}
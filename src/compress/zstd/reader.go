@@ -0,0 +1,356 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var errReaderClosed = errors.New("zstd: reader is closed")
+
+// A Reader is an io.Reader that can be read to retrieve uncompressed
+// data from a Zstandard stream.
+//
+// The stream may consist of several frames, which are decompressed one
+// after the other; skippable frames are ignored.
+type Reader struct {
+	r    io.Reader
+	dict *dict
+	err  error
+
+	// State of the current frame.
+	inFrame     bool
+	lastBlock   bool
+	hasChecksum bool
+	hasSize     bool
+	size        uint64 // content size, if hasSize
+	produced    uint64
+	windowSize  int
+	blockMax    int
+	digest      xxhash64
+
+	// hist holds the data within reach of the matches of the next
+	// block: the dictionary content, then the decompressed data.
+	// Its tail, from off on, has not been returned by Read yet.
+	hist []byte
+	off  int
+
+	// Entropy state carried over from block to block.
+	rep      repeatOffsets
+	huff     huffDecTable
+	hasHuff  bool
+	litLen   seqTable
+	offset   seqTable
+	matchLen seqTable
+
+	scratch [16]byte
+	block   []byte
+	lits    []byte
+	weights huffWeights
+	norm    [maxMatchLenCode + 1]int16
+}
+
+// NewReader creates a new Reader reading the given stream.
+//
+// It is the caller's responsibility to call Close on the Reader when done.
+func NewReader(r io.Reader) *Reader {
+	z := new(Reader)
+	z.Reset(r)
+	return z
+}
+
+// NewReaderDict is like NewReader but decompresses with a dictionary.
+// The dictionary is either in the format built by the reference
+// implementation, whose ID frames must match, or raw content.
+func NewReaderDict(r io.Reader, dict []byte) (*Reader, error) {
+	d, err := parseDict(dict)
+	if err != nil {
+		return nil, err
+	}
+	z := &Reader{dict: d}
+	z.Reset(r)
+	return z, nil
+}
+
+// Reset discards the Reader z's state and makes it equivalent to the
+// result of its original state from NewReader or NewReaderDict, but
+// reading from r instead. This permits reusing a Reader rather than
+// allocating a new one.
+func (z *Reader) Reset(r io.Reader) {
+	z.r = r
+	z.err = nil
+	z.inFrame = false
+	z.hist = z.hist[:0]
+	z.off = 0
+}
+
+// Read implements io.Reader, reading uncompressed bytes from its
+// underlying Reader.
+func (z *Reader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for z.off == len(z.hist) {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.next()
+	}
+	n = copy(p, z.hist[z.off:])
+	z.off += n
+	return n, nil
+}
+
+// Close releases the memory held by z. It does not close the underlying
+// io.Reader. Reading from z after Close returns an error.
+func (z *Reader) Close() error {
+	z.err = errReaderClosed
+	z.hist, z.off = nil, 0
+	z.block = nil
+	z.lits = nil
+	return nil
+}
+
+// next decompresses the next part of the stream into hist.
+func (z *Reader) next() error {
+	switch {
+	case !z.inFrame:
+		return z.readFrameHeader()
+	case z.lastBlock:
+		return z.endFrame()
+	}
+	return z.readBlock()
+}
+
+// readFull reads exactly len(b) bytes, in the middle of a frame.
+func (z *Reader) readFull(b []byte) error {
+	_, err := io.ReadFull(z.r, b)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// skip discards the next n bytes, in the middle of a frame.
+func (z *Reader) skip(n int64) error {
+	for n > 0 {
+		k := n
+		if k > maxBlockSize {
+			k = maxBlockSize
+		}
+		z.block = grow(z.block[:0], int(k))
+		if err := z.readFull(z.block); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
+
+// readFrameHeader starts a new frame, skipping skippable frames.
+// It returns io.EOF at the end of the stream.
+func (z *Reader) readFrameHeader() error {
+	for {
+		if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+			return err
+		}
+		magic := binary.LittleEndian.Uint32(z.scratch[:])
+		if magic == frameMagic {
+			break
+		}
+		if magic&skippableFrameMask != skippableFrameMagic {
+			return StructuralError("invalid magic number")
+		}
+		if err := z.readFull(z.scratch[:4]); err != nil {
+			return err
+		}
+		if err := z.skip(int64(binary.LittleEndian.Uint32(z.scratch[:]))); err != nil {
+			return err
+		}
+	}
+
+	if err := z.readFull(z.scratch[:1]); err != nil {
+		return err
+	}
+	desc := z.scratch[0]
+	if desc&0x08 != 0 {
+		return StructuralError("reserved frame header bit set")
+	}
+	singleSegment := desc&0x20 != 0
+	z.hasChecksum = desc&0x04 != 0
+	dictIDSize := [4]int{0, 1, 2, 4}[desc&3]
+	sizeSize := [4]int{0, 2, 4, 8}[desc>>6]
+	if singleSegment && sizeSize == 0 {
+		sizeSize = 1
+	}
+	windowSize := 0
+	if !singleSegment {
+		windowSize = 1
+	}
+	b := z.scratch[:windowSize+dictIDSize+sizeSize]
+	if err := z.readFull(b); err != nil {
+		return err
+	}
+
+	var window uint64
+	if !singleSegment {
+		exp := uint(b[0] >> 3)
+		base := uint64(1) << (minWindowLog + exp)
+		window = base + base/8*uint64(b[0]&7)
+		b = b[1:]
+	}
+
+	var dictID uint32
+	for i := dictIDSize - 1; i >= 0; i-- {
+		dictID = dictID<<8 | uint32(b[i])
+	}
+	b = b[dictIDSize:]
+	if dictID != 0 && (z.dict == nil || z.dict.id != dictID) {
+		return ErrDictionary
+	}
+
+	z.hasSize = sizeSize > 0
+	switch sizeSize {
+	case 1:
+		z.size = uint64(b[0])
+	case 2:
+		z.size = uint64(binary.LittleEndian.Uint16(b)) + 256
+	case 4:
+		z.size = uint64(binary.LittleEndian.Uint32(b))
+	case 8:
+		z.size = binary.LittleEndian.Uint64(b)
+	}
+	if singleSegment {
+		window = z.size
+	}
+	if window > 1<<maxWindowLog {
+		return ErrWindowTooLarge
+	}
+	z.windowSize = int(window)
+	z.blockMax = maxBlockSize
+	if z.windowSize < z.blockMax {
+		z.blockMax = z.windowSize
+	}
+
+	z.inFrame = true
+	z.lastBlock = false
+	z.produced = 0
+	z.digest.reset()
+	z.rep = initialRepeatOffsets
+	z.hasHuff = false
+	z.litLen.t, z.offset.t, z.matchLen.t = nil, nil, nil
+	z.hist = z.hist[:0]
+	if d := z.dict; d != nil {
+		z.rep = d.rep
+		z.hist = append(z.hist, d.content...)
+		if d.hasTables {
+			z.huff.init(&d.huff)
+			z.hasHuff = true
+			if err := z.litLen.init(d.litLen, d.litLenLog); err != nil {
+				return err
+			}
+			if err := z.offset.init(d.offset, d.offsetLog); err != nil {
+				return err
+			}
+			if err := z.matchLen.init(d.matchLen, d.matchLog); err != nil {
+				return err
+			}
+		}
+	}
+	z.off = len(z.hist)
+	return nil
+}
+
+// endFrame checks the content size and checksum of the current frame.
+func (z *Reader) endFrame() error {
+	if z.hasSize && z.produced != z.size {
+		return StructuralError("frame content size mismatch")
+	}
+	if z.hasChecksum {
+		if err := z.readFull(z.scratch[:4]); err != nil {
+			return err
+		}
+		if binary.LittleEndian.Uint32(z.scratch[:]) != uint32(z.digest.sum64()) {
+			return ErrChecksum
+		}
+	}
+	z.inFrame = false
+	return nil
+}
+
+// readBlock decompresses the next block of the current frame.
+func (z *Reader) readBlock() error {
+	if err := z.readFull(z.scratch[:3]); err != nil {
+		return err
+	}
+	hdr := uint32(z.scratch[0]) | uint32(z.scratch[1])<<8 | uint32(z.scratch[2])<<16
+	z.lastBlock = hdr&1 != 0
+	size := int(hdr >> 3)
+	if size > z.blockMax {
+		return StructuralError("block too large")
+	}
+
+	// Drop the history out of reach of the window, amortizing the
+	// cost of the copy over at least a window of data. Matches may
+	// reach into the whole dictionary, even beyond a smaller window.
+	keep := z.windowSize
+	if z.dict != nil {
+		keep += len(z.dict.content)
+	}
+	if n := len(z.hist) - keep; n > 0 && n >= keep {
+		copy(z.hist, z.hist[n:])
+		z.hist = z.hist[:keep]
+		z.off = len(z.hist)
+	}
+
+	start := len(z.hist)
+	switch (hdr >> 1) & 3 {
+	case blockRaw:
+		z.hist = grow(z.hist, size)
+		if err := z.readFull(z.hist[start:]); err != nil {
+			return err
+		}
+	case blockRLE:
+		if err := z.readFull(z.scratch[:1]); err != nil {
+			return err
+		}
+		z.hist = grow(z.hist, size)
+		for i := start; i < len(z.hist); i++ {
+			z.hist[i] = z.scratch[0]
+		}
+	case blockCompressed:
+		z.block = grow(z.block[:0], size)
+		if err := z.readFull(z.block); err != nil {
+			return err
+		}
+		if err := z.decompressBlock(z.block); err != nil {
+			return err
+		}
+	default:
+		return StructuralError("reserved block type")
+	}
+
+	out := z.hist[start:]
+	if z.hasChecksum {
+		z.digest.write(out)
+	}
+	z.produced += uint64(len(out))
+	if z.hasSize && z.produced > z.size {
+		return StructuralError("frame content size mismatch")
+	}
+	return nil
+}
+
+// grow extends b by n bytes.
+func grow(b []byte, n int) []byte {
+	if cap(b)-len(b) < n {
+		nb := make([]byte, len(b), 2*cap(b)+n)
+		copy(nb, b)
+		b = nb
+	}
+	return b[:len(b)+n]
+}
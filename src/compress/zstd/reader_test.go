@@ -0,0 +1,318 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type readerTest struct {
+	desc string
+	raw  string
+	zstd []byte
+	err  error
+}
+
+var (
+	helloFrame = []byte{
+		0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x69, 0x00, 0x00, 0x68, 0x65, 0x6c,
+		0x6c, 0x6f, 0x2c, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0a, 0x4c, 0x1f,
+		0xf9, 0xf1,
+	}
+	skippableFrame = []byte{
+		0x5a, 0x2a, 0x4d, 0x18, 0x03, 0x00, 0x00, 0x00, 'a', 'b', 'c',
+	}
+)
+
+func concat(b ...[]byte) []byte {
+	return bytes.Join(b, nil)
+}
+
+var readerTests = []readerTest{
+	{
+		"empty stream",
+		"",
+		nil,
+		nil,
+	},
+	{
+		"single empty compressed block",
+		"",
+		[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 0x00, 0x15, 0x00, 0x00, 0x00, 0x00},
+		nil,
+	},
+	{
+		"raw block with checksum",
+		"hello, world\n",
+		helloFrame,
+		nil,
+	},
+	{
+		"raw block without checksum",
+		"hello, world\n",
+		[]byte{
+			0x28, 0xb5, 0x2f, 0xfd, 0x00, 0x58, 0x69, 0x00, 0x00, 0x68, 0x65, 0x6c,
+			0x6c, 0x6f, 0x2c, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0a,
+		},
+		nil,
+	},
+	{
+		"RLE literals and a long overlapping match",
+		strings.Repeat("a", 1000),
+		[]byte{
+			0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x4d, 0x00, 0x00, 0x10, 0x61, 0x61,
+			0x01, 0x00, 0xe3, 0x2b, 0x80, 0x05, 0x23, 0x42, 0xda, 0x2e,
+		},
+		nil,
+	},
+	{
+		"repeat offset",
+		"abcabcabcabcabcabcabcabcabcabc-abcabcabcabc\n",
+		[]byte{
+			0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x6d, 0x00, 0x00, 0x28, 0x61, 0x62,
+			0x63, 0x2d, 0x0a, 0x02, 0x00, 0x3c, 0x39, 0x61, 0xe8, 0x86, 0xe4, 0xee,
+			0xae, 0x5d,
+		},
+		nil,
+	},
+	{
+		"two frames",
+		"one\ntwo\n",
+		[]byte{
+			0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x21, 0x00, 0x00, 0x6f, 0x6e, 0x65,
+			0x0a, 0xdf, 0x16, 0x68, 0x09, 0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x21,
+			0x00, 0x00, 0x74, 0x77, 0x6f, 0x0a, 0x0f, 0x2d, 0x04, 0xf9,
+		},
+		nil,
+	},
+	{
+		"skippable frames",
+		"hello, world\n",
+		concat(skippableFrame, helloFrame, skippableFrame),
+		nil,
+	},
+	{
+		"bad checksum",
+		"hello, world\n",
+		concat(helloFrame[:len(helloFrame)-1], []byte{0}),
+		ErrChecksum,
+	},
+	{
+		"truncated frame",
+		"hello, world\n",
+		helloFrame[:len(helloFrame)-2],
+		io.ErrUnexpectedEOF,
+	},
+	{
+		"truncated skippable frame",
+		"",
+		skippableFrame[:len(skippableFrame)-1],
+		io.ErrUnexpectedEOF,
+	},
+	{
+		"trailing garbage",
+		"hello, world\n",
+		concat(helloFrame, []byte("garbage")),
+		StructuralError("invalid magic number"),
+	},
+	{
+		"reserved frame header bit",
+		"",
+		[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x08, 0x00, 0x01, 0x00, 0x00},
+		StructuralError("reserved frame header bit set"),
+	},
+	{
+		"window too large",
+		"",
+		[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 0x90, 0x01, 0x00, 0x00},
+		ErrWindowTooLarge,
+	},
+	{
+		"missing dictionary",
+		"",
+		[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x01, 0x00, 0x07, 0x01, 0x00, 0x00},
+		ErrDictionary,
+	},
+	{
+		"block larger than window",
+		"",
+		[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x20, 0x02, 0x19, 0x00, 0x00},
+		StructuralError("block too large"),
+	},
+	{
+		"content size mismatch",
+		"",
+		[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x20, 0x02, 0x01, 0x00, 0x00},
+		StructuralError("frame content size mismatch"),
+	},
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader(nil)
+	for _, tt := range readerTests {
+		r.Reset(bytes.NewReader(tt.zstd))
+		got, err := ioutil.ReadAll(r)
+		if err != tt.err {
+			t.Errorf("%s: got error %v, want %v", tt.desc, err, tt.err)
+		}
+		if tt.err == nil && string(got) != tt.raw {
+			t.Errorf("%s: got %q, want %q", tt.desc, got, tt.raw)
+		}
+	}
+}
+
+func TestReaderMatchOffset(t *testing.T) {
+	for _, offset := range []int{1, 2} {
+		var e encoder
+		e.reset(nil)
+		s := blockSeqs{rep: initialRepeatOffsets}
+		s.add([]byte("a"), offset, 10)
+		block := e.encodeBlock(nil, &s)
+		h := uint32(blockCompressed)<<1 | uint32(len(block))<<3 | 1
+		frame := concat([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 0x00, byte(h), byte(h >> 8), byte(h >> 16)}, block)
+
+		got, err := ioutil.ReadAll(NewReader(bytes.NewReader(frame)))
+		if offset == 1 {
+			if want := strings.Repeat("a", 11); err != nil || string(got) != want {
+				t.Errorf("offset 1: got %q, %v, want %q", got, err, want)
+			}
+		} else if err != StructuralError("invalid match offset") {
+			t.Errorf("offset %d: got error %v", offset, err)
+		}
+	}
+}
+
+func TestReaderFiles(t *testing.T) {
+	// The files of the reference decompression tests are named after
+	// the SHA-256 sum of their content.
+	files, err := filepath.Glob("testdata/*.*.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files")
+	}
+	for _, name := range files {
+		base := filepath.Base(name)
+		if len(base) < 9 || base[8] != '.' {
+			continue
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		sum := sha256.Sum256(got)
+		if s := hex.EncodeToString(sum[:4]); s != base[:8] {
+			t.Errorf("%s: content SHA-256 starts with %s", name, s)
+		}
+	}
+}
+
+func TestReaderLargeFile(t *testing.T) {
+	want, err := ioutil.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("testdata/Mark.Twain-Tom.Sawyer.txt.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes that differ from the %d bytes wanted", len(got), len(want))
+	}
+
+	// Read a byte at a time, then with corrupted data.
+	var buf [1]byte
+	r := NewReader(bytes.NewReader(data))
+	for i := range want {
+		if _, err := io.ReadFull(r, buf[:]); err != nil || buf[0] != want[i] {
+			t.Fatalf("byte %d: got %q, %v, want %q", i, buf[0], err, want[i])
+		}
+	}
+	if n, err := r.Read(buf[:]); n != 0 || err != io.EOF {
+		t.Errorf("Read at end = %d, %v, want 0, EOF", n, err)
+	}
+	for i := 100; i < len(data); i += len(data) / 7 {
+		bad := append([]byte(nil), data...)
+		bad[i] ^= 0x55
+		if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(bad))); err == nil {
+			t.Errorf("no error for data corrupted at byte %d", i)
+		}
+	}
+}
+
+func TestReaderDict(t *testing.T) {
+	dict, err := ioutil.ReadFile("testdata/Mark.Twain.dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("../testdata/gettysburg.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("testdata/gettysburg.txt.dict.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReaderDict(bytes.NewReader(data), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Reset keeps the dictionary.
+	r.Reset(bytes.NewReader(data))
+	if got, err = ioutil.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("after Reset: got %d bytes, %v", len(got), err)
+	}
+
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(data))); err != ErrDictionary {
+		t.Errorf("without dictionary: got error %v, want %v", err, ErrDictionary)
+	}
+	r, err = NewReaderDict(bytes.NewReader(data), []byte("raw content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err != ErrDictionary {
+		t.Errorf("with wrong dictionary: got error %v, want %v", err, ErrDictionary)
+	}
+
+	if _, err := NewReaderDict(nil, dict[:100]); err == nil {
+		t.Errorf("no error for truncated dictionary")
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	r := NewReader(bytes.NewReader(helloFrame))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err == nil {
+		t.Errorf("no error reading from closed Reader")
+	}
+}
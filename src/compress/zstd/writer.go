@@ -0,0 +1,277 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// These constants are copied from the flate package, so that code that
+// imports "compress/zstd" does not also have to import "compress/flate".
+const (
+	BestSpeed          = 1
+	DefaultCompression = 3
+)
+
+var errWriterClosed = errors.New("zstd: write to closed Writer")
+
+// A Writer is an io.WriteCloser.
+// Writes to a Writer are compressed and written to w.
+//
+// The Writer produces a single frame with a content checksum. It does
+// not record the size of the content, which is not known in advance.
+type Writer struct {
+	w         io.Writer
+	level     int
+	dict      *dict
+	windowLog uint
+	err       error
+	closed    bool
+
+	wroteHeader bool
+	digest      xxhash64
+
+	// hist holds the data within reach of the matches of the next
+	// block: the dictionary content, then the data written. Its tail,
+	// from pending on, has not been compressed yet.
+	hist    []byte
+	pending int
+
+	m    matcher
+	enc  encoder
+	seqs blockSeqs
+	out  []byte
+}
+
+// NewWriter returns a new Writer.
+// Writes to the returned writer are compressed and written to w.
+//
+// It is the caller's responsibility to call Close on the Writer when done.
+// Writes may be buffered and not flushed until Close.
+func NewWriter(w io.Writer) *Writer {
+	z, _ := NewWriterLevel(w, DefaultCompression)
+	return z
+}
+
+// NewWriterLevel is like NewWriter but specifies the compression level
+// instead of assuming DefaultCompression.
+//
+// The compression level can be DefaultCompression or BestSpeed.
+// The error returned will be nil if the level is valid.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	return NewWriterLevelDict(w, level, nil)
+}
+
+// NewWriterLevelDict is like NewWriterLevel but compresses with a
+// dictionary. The dictionary is either in the format built by the
+// reference implementation, whose ID is recorded in the frame, or raw
+// content. The same dictionary must be used to decompress the frame.
+func NewWriterLevelDict(w io.Writer, level int, dict []byte) (*Writer, error) {
+	z := &Writer{level: level}
+	switch level {
+	case BestSpeed:
+		z.windowLog = 19
+	case DefaultCompression:
+		z.windowLog = 21
+	default:
+		return nil, fmt.Errorf("zstd: invalid compression level: %d", level)
+	}
+	if len(dict) > 0 {
+		d, err := parseDict(dict)
+		if err != nil {
+			return nil, err
+		}
+		z.dict = d
+	}
+	z.m.init(level, 1<<z.windowLog)
+	z.Reset(w)
+	return z, nil
+}
+
+// Reset discards the Writer z's state and makes it equivalent to the
+// result of its original state from NewWriter, NewWriterLevel or
+// NewWriterLevelDict, but writing to w instead. This permits reusing
+// a Writer rather than allocating a new one.
+func (z *Writer) Reset(w io.Writer) {
+	z.w = w
+	z.err = nil
+	z.closed = false
+	z.wroteHeader = false
+	z.digest.reset()
+	z.hist = z.hist[:0]
+	z.m.reset()
+	z.enc.reset(z.dict)
+	if z.dict != nil {
+		z.hist = append(z.hist, z.dict.content...)
+		z.m.prime(z.hist)
+	}
+	z.pending = len(z.hist)
+}
+
+// Write writes a compressed form of p to the underlying io.Writer. The
+// compressed bytes are not necessarily flushed until the Writer is closed.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.closed {
+		return 0, errWriterClosed
+	}
+	n := len(p)
+	z.digest.write(p)
+	for len(p) > 0 {
+		// A full block is only compressed once more data comes,
+		// so that the last one can be marked as such by Close.
+		if len(z.hist)-z.pending == maxBlockSize {
+			if z.err = z.writeBlock(false); z.err != nil {
+				return n - len(p), z.err
+			}
+		}
+		k := maxBlockSize - (len(z.hist) - z.pending)
+		if k > len(p) {
+			k = len(p)
+		}
+		z.hist = append(z.hist, p[:k]...)
+		p = p[k:]
+	}
+	return n, nil
+}
+
+// Flush flushes any pending compressed data to the underlying writer.
+//
+// It is useful mainly in compressed network protocols, to ensure that
+// a remote reader has enough data to reconstruct a packet. Flush does
+// not return until the data has been written. If the underlying
+// writer returns an error, Flush returns that error.
+func (z *Writer) Flush() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed || z.wroteHeader && len(z.hist) == z.pending {
+		return nil
+	}
+	z.err = z.writeBlock(false)
+	return z.err
+}
+
+// Close closes the Writer by flushing any unwritten data to the
+// underlying io.Writer and writing the frame checksum. It does not
+// close the underlying io.Writer.
+func (z *Writer) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+	z.err = z.writeBlock(true)
+	return z.err
+}
+
+// writeBlock compresses the pending data as a block, preceded by the
+// frame header if needed and, for the last block, followed by the
+// checksum, and writes it out. A non-last block is only written if
+// there is pending data.
+func (z *Writer) writeBlock(last bool) error {
+	z.out = z.out[:0]
+	if !z.wroteHeader {
+		z.out = z.appendFrameHeader(z.out)
+		z.wroteHeader = true
+	}
+	if last || len(z.hist) > z.pending {
+		// Drop the history out of reach of the window, amortizing
+		// the cost of the copy over at least a window of data.
+		if window := 1 << z.windowLog; z.pending >= 2*window {
+			n := z.pending - window
+			copy(z.hist, z.hist[n:])
+			z.hist = z.hist[:len(z.hist)-n]
+			z.pending -= n
+			z.m.shift(n)
+		}
+		z.out = z.compressBlock(z.out, last)
+		z.pending = len(z.hist)
+	}
+	if last {
+		var sum [4]byte
+		binary.LittleEndian.PutUint32(sum[:], uint32(z.digest.sum64()))
+		z.out = append(z.out, sum[:]...)
+	}
+	_, err := z.w.Write(z.out)
+	return err
+}
+
+// appendFrameHeader appends the frame header to dst.
+func (z *Writer) appendFrameHeader(dst []byte) []byte {
+	dst = append(dst, 0x28, 0xb5, 0x2f, 0xfd)
+	var id uint32
+	if z.dict != nil {
+		id = z.dict.id
+	}
+	desc := byte(0x04) // content checksum
+	idSize := 0
+	switch {
+	case id == 0:
+	case id < 1<<8:
+		desc |= 1
+		idSize = 1
+	case id < 1<<16:
+		desc |= 2
+		idSize = 2
+	default:
+		desc |= 3
+		idSize = 4
+	}
+	dst = append(dst, desc, byte((z.windowLog-minWindowLog)<<3))
+	for i := 0; i < idSize; i++ {
+		dst = append(dst, byte(id>>(8*uint(i))))
+	}
+	return dst
+}
+
+// compressBlock appends the block holding the pending data to dst,
+// compressed if that makes it smaller.
+func (z *Writer) compressBlock(dst []byte, last bool) []byte {
+	src := z.hist[z.pending:]
+	hdr := len(dst)
+	dst = append(dst, 0, 0, 0)
+	typ, size := blockRaw, len(src)
+
+	switch {
+	case len(src) == 0:
+	case allSame(src):
+		typ = blockRLE
+		dst = append(dst, src[0])
+	default:
+		z.seqs.reset(z.enc.rep)
+		z.m.find(&z.seqs, z.hist, z.pending)
+		dst = z.enc.encodeBlock(dst, &z.seqs)
+		if n := len(dst) - hdr - 3; n < len(src) {
+			typ, size = blockCompressed, n
+			z.enc.commit(&z.seqs)
+		} else {
+			dst = append(dst[:hdr+3], src...)
+		}
+	}
+
+	h := uint32(typ)<<1 | uint32(size)<<3
+	if last {
+		h |= 1
+	}
+	dst[hdr], dst[hdr+1], dst[hdr+2] = byte(h), byte(h>>8), byte(h>>16)
+	return dst
+}
+
+func allSame(b []byte) bool {
+	for _, c := range b[1:] {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
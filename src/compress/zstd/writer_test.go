@@ -0,0 +1,277 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testInputs returns the inputs of the round trip tests.
+func testInputs(t *testing.T) map[string][]byte {
+	twain, err := ioutil.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ioutil.ReadFile("../testdata/e.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	var mixed []byte
+	for i := 0; i < 4; i++ {
+		mixed = append(mixed, random[i<<10:(i+50)<<10]...)
+		mixed = append(mixed, twain[i<<14:(i+3)<<14]...)
+	}
+	return map[string][]byte{
+		"empty":  nil,
+		"short":  []byte("hello, world\n"),
+		"same":   bytes.Repeat([]byte{'x'}, 300<<10),
+		"twain":  twain,
+		"e":      e,
+		"random": random,
+		"mixed":  mixed,
+	}
+}
+
+func compress(t *testing.T, level int, dict, data []byte, chunk int) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriterLevelDict(&buf, level, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := data; len(p) > 0; {
+		n := chunk
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decompress(t *testing.T, dict, data []byte) []byte {
+	r, err := NewReaderDict(bytes.NewReader(data), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	for name, data := range testInputs(t) {
+		for _, level := range []int{BestSpeed, DefaultCompression} {
+			for _, chunk := range []int{1 << 20, 5000} {
+				comp := compress(t, level, nil, data, chunk)
+				if got := decompress(t, nil, comp); !bytes.Equal(got, data) {
+					t.Errorf("%s, level %d, chunk %d: round trip mismatch", name, level, chunk)
+				}
+				if name == "twain" && len(comp) > len(data)/2 {
+					t.Errorf("%s, level %d: compressed to %d bytes", name, level, len(comp))
+				}
+			}
+		}
+	}
+}
+
+func TestWriterLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	// The input is longer than twice the window, so that the history
+	// of both levels is trimmed.
+	twain, err := ioutil.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	var data []byte
+	for len(data) < 5<<20 {
+		data = append(data, twain...)
+		data = append(data, random...)
+	}
+	for _, level := range []int{BestSpeed, DefaultCompression} {
+		comp := compress(t, level, nil, data, 1<<20)
+		if got := decompress(t, nil, comp); !bytes.Equal(got, data) {
+			t.Errorf("level %d: round trip mismatch", level)
+		}
+	}
+}
+
+func TestWriterDict(t *testing.T) {
+	dict, err := ioutil.ReadFile("testdata/Mark.Twain.dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("../testdata/gettysburg.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []byte(strings.Repeat("Four score and seven years ago ", 10))
+	for _, level := range []int{BestSpeed, DefaultCompression} {
+		for _, d := range [][]byte{dict, raw} {
+			plain := compress(t, level, nil, data, len(data))
+			comp := compress(t, level, d, data, len(data))
+			if got := decompress(t, d, comp); !bytes.Equal(got, data) {
+				t.Errorf("level %d, dictionary of %d bytes: round trip mismatch", level, len(d))
+			}
+			if len(comp) >= len(plain) {
+				t.Errorf("level %d, dictionary of %d bytes: compressed to %d bytes, %d without", level, len(d), len(comp), len(plain))
+			}
+		}
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	n := buf.Len()
+	if n == 0 {
+		t.Fatal("Flush did not write the frame header")
+	}
+	for _, s := range []string{"hello, ", "hello, ", "world\n"} {
+		w.Write([]byte(s))
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() == n {
+			t.Fatalf("Flush after writing %q wrote nothing", s)
+		}
+		n = buf.Len()
+	}
+	if err := w.Flush(); err != nil || buf.Len() != n {
+		t.Errorf("Flush with no pending data wrote %d bytes, %v", buf.Len()-n, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Errorf("no error writing to closed Writer")
+	}
+	if got := decompress(t, nil, buf.Bytes()); string(got) != "hello, hello, world\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	data := []byte(strings.Repeat("hello, world\n", 1000))
+	var buf1, buf2 bytes.Buffer
+	w, err := NewWriterLevel(&buf1, BestSpeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	w.Close()
+	w.Reset(&buf2)
+	w.Write(data)
+	w.Close()
+	if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+		t.Errorf("output after Reset differs")
+	}
+}
+
+func TestWriterLevel(t *testing.T) {
+	for _, level := range []int{-1, 0, 2, 4, 10} {
+		if _, err := NewWriterLevel(ioutil.Discard, level); err == nil {
+			t.Errorf("no error for level %d", level)
+		}
+	}
+}
+
+// TestWriterReference checks that the reference implementation, if
+// installed, decompresses the output of the Writer.
+func TestWriterReference(t *testing.T) {
+	zstd, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("zstd command not found")
+	}
+	dir, err := ioutil.TempDir("", "zstd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dict := filepath.Join(dir, "dict")
+	if err := ioutil.WriteFile(dict, []byte(strings.Repeat("abcdefgh", 100)), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range testInputs(t) {
+		for _, level := range []int{BestSpeed, DefaultCompression} {
+			for _, useDict := range []bool{false, true} {
+				var d []byte
+				args := []string{"-d", "-c", "-q"}
+				if useDict {
+					d, _ = ioutil.ReadFile(dict)
+					args = append(args, "-D", dict)
+				}
+				file := filepath.Join(dir, name+".zst")
+				if err := ioutil.WriteFile(file, compress(t, level, d, data, 1<<20), 0666); err != nil {
+					t.Fatal(err)
+				}
+				out, err := exec.Command(zstd, append(args, file)...).Output()
+				if err != nil {
+					t.Errorf("%s, level %d, dictionary %v: %v", name, level, useDict, err)
+					continue
+				}
+				if !bytes.Equal(out, data) {
+					t.Errorf("%s, level %d, dictionary %v: round trip mismatch", name, level, useDict)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	data, err := ioutil.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := NewWriter(ioutil.Discard)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Reset(ioutil.Discard)
+		w.Write(data)
+		w.Close()
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/Mark.Twain-Tom.Sawyer.txt.zst")
+	if err != nil {
+		b.Fatal(err)
+	}
+	r := NewReader(nil)
+	b.SetBytes(387851)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(bytes.NewReader(data))
+		ioutil.ReadAll(r)
+	}
+}
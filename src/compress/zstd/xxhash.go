@@ -0,0 +1,119 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// The content checksum of a frame is the low 32 bits of the
+// XXH64 hash, with a zero seed, of the decompressed data.
+// See https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md.
+
+const (
+	xxhPrime1 = 11400714785074694791
+	xxhPrime2 = 14029467366897019727
+	xxhPrime3 = 1609587929392839161
+	xxhPrime4 = 9650029242287828579
+	xxhPrime5 = 2870177450012600261
+)
+
+// xxhash64 computes the XXH64 hash of a stream of data.
+type xxhash64 struct {
+	v     [4]uint64
+	total uint64
+	mem   [32]byte
+	n     int // number of bytes in mem
+}
+
+func (h *xxhash64) reset() {
+	h.v[0] = xxhPrime1
+	h.v[0] += xxhPrime2
+	h.v[1] = xxhPrime2
+	h.v[2] = 0
+	h.v[3] = 0
+	h.v[3] -= xxhPrime1
+	h.total = 0
+	h.n = 0
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * xxhPrime2
+	return bits.RotateLeft64(acc, 31) * xxhPrime1
+}
+
+func xxhMergeRound(acc, val uint64) uint64 {
+	acc ^= xxhRound(0, val)
+	return acc*xxhPrime1 + xxhPrime4
+}
+
+func (h *xxhash64) write(p []byte) {
+	h.total += uint64(len(p))
+	if h.n+len(p) < len(h.mem) {
+		h.n += copy(h.mem[h.n:], p)
+		return
+	}
+	if h.n > 0 {
+		c := copy(h.mem[h.n:], p)
+		h.stripes(h.mem[:])
+		p = p[c:]
+		h.n = 0
+	}
+	if len(p) >= len(h.mem) {
+		n := len(p) &^ (len(h.mem) - 1)
+		h.stripes(p[:n])
+		p = p[n:]
+	}
+	h.n = copy(h.mem[:], p)
+}
+
+// stripes consumes p, whose length is a multiple of 32.
+func (h *xxhash64) stripes(p []byte) {
+	v0, v1, v2, v3 := h.v[0], h.v[1], h.v[2], h.v[3]
+	for ; len(p) >= 32; p = p[32:] {
+		v0 = xxhRound(v0, binary.LittleEndian.Uint64(p[0:]))
+		v1 = xxhRound(v1, binary.LittleEndian.Uint64(p[8:]))
+		v2 = xxhRound(v2, binary.LittleEndian.Uint64(p[16:]))
+		v3 = xxhRound(v3, binary.LittleEndian.Uint64(p[24:]))
+	}
+	h.v[0], h.v[1], h.v[2], h.v[3] = v0, v1, v2, v3
+}
+
+func (h *xxhash64) sum64() uint64 {
+	var s uint64
+	if h.total >= 32 {
+		s = bits.RotateLeft64(h.v[0], 1) + bits.RotateLeft64(h.v[1], 7) +
+			bits.RotateLeft64(h.v[2], 12) + bits.RotateLeft64(h.v[3], 18)
+		for _, v := range h.v {
+			s = xxhMergeRound(s, v)
+		}
+	} else {
+		s = h.v[2] + xxhPrime5 // the seed, which is zero
+	}
+	s += h.total
+
+	p := h.mem[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		s ^= xxhRound(0, binary.LittleEndian.Uint64(p))
+		s = bits.RotateLeft64(s, 27)*xxhPrime1 + xxhPrime4
+	}
+	if len(p) >= 4 {
+		s ^= uint64(binary.LittleEndian.Uint32(p)) * xxhPrime1
+		s = bits.RotateLeft64(s, 23)*xxhPrime2 + xxhPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		s ^= uint64(b) * xxhPrime5
+		s = bits.RotateLeft64(s, 11) * xxhPrime1
+	}
+
+	s ^= s >> 33
+	s *= xxhPrime2
+	s ^= s >> 29
+	s *= xxhPrime3
+	s ^= s >> 32
+	return s
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"strings"
+	"testing"
+)
+
+var xxhashTests = []struct {
+	in  string
+	sum uint64
+}{
+	{"", 0xef46db3751d8e999},
+	{"a", 0xd24ec4f1a98c6e5b},
+	{"abc", 0x44bc2cf5ad770999},
+}
+
+func TestXXHash(t *testing.T) {
+	var h xxhash64
+	for _, tt := range xxhashTests {
+		h.reset()
+		h.write([]byte(tt.in))
+		if got := h.sum64(); got != tt.sum {
+			t.Errorf("xxhash64(%q) = %#x, want %#x", tt.in, got, tt.sum)
+		}
+	}
+
+	// Writes of any size must add up to the same sum.
+	in := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	h.reset()
+	h.write([]byte(in))
+	want := h.sum64()
+	for _, n := range []int{1, 7, 31, 32, 33, 100} {
+		h.reset()
+		for p := in; len(p) > 0; {
+			k := n
+			if k > len(p) {
+				k = len(p)
+			}
+			h.write([]byte(p[:k]))
+			p = p[k:]
+		}
+		if got := h.sum64(); got != want {
+			t.Errorf("writes of %d bytes: sum %#x, want %#x", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package zstd implements reading and writing of Zstandard compressed data,
+// as specified in the Zstandard compression format document:
+// https://github.com/facebook/zstd/blob/master/doc/zstd_compression_format.md.
+//
+// A Zstandard stream is a sequence of frames. The Reader decompresses all
+// of them in turn, skipping skippable frames, and the Writer produces a
+// single frame. Both can use a dictionary, either in the format produced
+// by the reference implementation's dictionary builder or as raw content.
+package zstd
+
+import (
+	"errors"
+	"math/bits"
+)
+
+var (
+	// ErrChecksum is returned when reading a frame whose content
+	// checksum does not match the decompressed data.
+	ErrChecksum = errors.New("zstd: invalid checksum")
+
+	// ErrDictionary is returned when a frame requires a dictionary other
+	// than the one, if any, given to the Reader.
+	ErrDictionary = errors.New("zstd: wrong or missing dictionary")
+
+	// ErrWindowTooLarge is returned when a frame needs more memory than
+	// the Reader is willing to allocate for its history window.
+	ErrWindowTooLarge = errors.New("zstd: window size too large")
+)
+
+// A StructuralError is returned when the zstd data is found to be
+// syntactically invalid.
+type StructuralError string
+
+func (s StructuralError) Error() string {
+	return "zstd data invalid: " + string(s)
+}
+
+const (
+	frameMagic          = 0xfd2fb528
+	skippableFrameMagic = 0x184d2a50 // the low 4 bits are user defined
+	skippableFrameMask  = 0xfffffff0
+	dictMagic           = 0xec30a437
+
+	maxBlockSize = 128 << 10
+
+	minWindowLog = 10
+	// maxWindowLog limits the history a Reader keeps for a frame;
+	// it is the default limit of the reference decoder.
+	maxWindowLog = 27
+)
+
+// Block types.
+const (
+	blockRaw = iota
+	blockRLE
+	blockCompressed
+	blockReserved
+)
+
+// Literals section and sequence table compression modes.
+const (
+	litsRaw = iota
+	litsRLE
+	litsCompressed
+	litsRepeat // compressed with the previous Huffman table
+
+	modePredefined = 0
+	modeRLE        = 1
+	modeCompressed = 2
+	modeRepeat     = 3
+)
+
+// Limits of the three kinds of sequence codes.
+const (
+	maxLitLenCode   = 35
+	maxMatchLenCode = 52
+	maxOffsetCode   = 31
+
+	maxLitLenLog   = 9
+	maxMatchLenLog = 9
+	maxOffsetLog   = 8
+
+	minMatch = 3
+)
+
+// A codeInfo gives the baseline value and the number of extra bits of
+// a literal length or match length code.
+type codeInfo struct {
+	base  uint32
+	nbits uint8
+}
+
+var litLenCodes = [maxLitLenCode + 1]codeInfo{
+	{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0},
+	{8, 0}, {9, 0}, {10, 0}, {11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0},
+	{16, 1}, {18, 1}, {20, 1}, {22, 1}, {24, 2}, {28, 2}, {32, 3}, {40, 3},
+	{48, 4}, {64, 6}, {128, 7}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {4096, 12},
+	{8192, 13}, {16384, 14}, {32768, 15}, {65536, 16},
+}
+
+var matchLenCodes = [maxMatchLenCode + 1]codeInfo{
+	{3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0},
+	{11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 0}, {17, 0}, {18, 0},
+	{19, 0}, {20, 0}, {21, 0}, {22, 0}, {23, 0}, {24, 0}, {25, 0}, {26, 0},
+	{27, 0}, {28, 0}, {29, 0}, {30, 0}, {31, 0}, {32, 0}, {33, 0}, {34, 0},
+	{35, 1}, {37, 1}, {39, 1}, {41, 1}, {43, 2}, {47, 2}, {51, 3}, {59, 3},
+	{67, 4}, {83, 4}, {99, 5}, {131, 7}, {259, 8}, {515, 9}, {1027, 10}, {2051, 11},
+	{4099, 12}, {8195, 13}, {16387, 14}, {32771, 15}, {65539, 16},
+}
+
+// Default distributions of the sequence codes, used by the
+// predefined compression mode.
+var (
+	predefLitLenNorm = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+	predefMatchLenNorm = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+	predefOffsetNorm = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+)
+
+const (
+	predefLitLenLog   = 6
+	predefMatchLenLog = 6
+	predefOffsetLog   = 5
+)
+
+// litLenCode returns the code of the literal length v.
+func litLenCode(v uint32) uint8 {
+	if v < 16 {
+		return uint8(v)
+	}
+	if v >= 64 {
+		return uint8(bits.Len32(v) + 18)
+	}
+	c := uint8(16)
+	for c < 24 && litLenCodes[c+1].base <= v {
+		c++
+	}
+	return c
+}
+
+// matchLenCode returns the code of the match length v.
+func matchLenCode(v uint32) uint8 {
+	if v < 35 {
+		return uint8(v - minMatch)
+	}
+	if v >= 131 {
+		return uint8(bits.Len32(v-minMatch) + 35)
+	}
+	c := uint8(32)
+	for matchLenCodes[c+1].base <= v {
+		c++
+	}
+	return c
+}
+
+// offsetCode returns the code of the offset value v.
+func offsetCode(v uint32) uint8 {
+	return uint8(bits.Len32(v) - 1)
+}
+
+// repeatOffsets holds the three most recent match offsets.
+type repeatOffsets [3]uint32
+
+var initialRepeatOffsets = repeatOffsets{1, 4, 8}
+
+// resolve returns the match offset denoted by the offset value v of a
+// sequence with litLen literals, and updates the repeat offsets.
+// Offset values 1 to 3 select a repeat offset; larger values encode
+// the offset v-3. It returns 0 for an invalid repeat.
+func (rep *repeatOffsets) resolve(v, litLen uint32) uint32 {
+	if v > 3 {
+		rep[2], rep[1], rep[0] = rep[1], rep[0], v-3
+		return v - 3
+	}
+	idx := v - 1
+	if litLen == 0 {
+		idx++
+	}
+	var offset uint32
+	switch idx {
+	case 0:
+		return rep[0]
+	case 3:
+		offset = rep[0] - 1
+	default:
+		offset = rep[idx]
+	}
+	if idx == 1 {
+		rep[1], rep[0] = rep[0], offset
+	} else {
+		rep[2], rep[1], rep[0] = rep[1], rep[0], offset
+	}
+	return offset
+}
+
+// encode returns the offset value coding a match at offset after litLen
+// literals, preferring the repeat offsets, and updates rep as resolve
+// does.
+func (rep *repeatOffsets) encode(offset, litLen uint32) uint32 {
+	v := offset + 3
+	if litLen > 0 {
+		switch offset {
+		case rep[0]:
+			v = 1
+		case rep[1]:
+			v = 2
+		case rep[2]:
+			v = 3
+		}
+	} else {
+		switch offset {
+		case rep[1]:
+			v = 1
+		case rep[2]:
+			v = 2
+		case rep[0] - 1:
+			v = 3
+		}
+	}
+	rep.resolve(v, litLen)
+	return v
+}
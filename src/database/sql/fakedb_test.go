@@ -140,6 +140,32 @@ type fakeStmt struct {
 
 var fdriver driver.Driver = &fakeDriver{}
 
+// fakeConnector is a driver.Connector for a fakeDriver database.
+type fakeConnector struct {
+	name string
+}
+
+var _ driver.Connector = &fakeConnector{}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fdriver.Open(c.name)
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return fdriver
+}
+
+// fakeDriverCtx is a fakeDriver that implements driver.DriverContext.
+type fakeDriverCtx struct {
+	fakeDriver
+}
+
+var _ driver.DriverContext = &fakeDriverCtx{}
+
+func (cc *fakeDriverCtx) OpenConnector(name string) (driver.Connector, error) {
+	return &fakeConnector{name: name}, nil
+}
+
 func init() {
 	Register("test", fdriver)
 }
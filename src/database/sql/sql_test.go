@@ -874,7 +874,7 @@ func TestStatementClose(t *testing.T) {
 		msg  string
 	}{
 		{&Stmt{stickyErr: want}, "stickyErr not propagated"},
-		{&Stmt{tx: &Tx{}, txds: &driverStmt{Locker: &sync.Mutex{}, si: stubDriverStmt{want}}}, "driverStmt.Close() error not propagated"},
+		{&Stmt{cg: &Tx{}, cgds: &driverStmt{Locker: &sync.Mutex{}, si: stubDriverStmt{want}}}, "driverStmt.Close() error not propagated"},
 	}
 	for _, test := range tests {
 		if err := test.stmt.Close(); err != want {
@@ -995,6 +995,148 @@ func TestTxPrepare(t *testing.T) {
 	}
 }
 
+func TestConnQuery(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := conn.dc.ci
+
+	if err := conn.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	err = conn.QueryRowContext(ctx, "SELECT|people|name|age=?", 4).Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Dave" {
+		t.Errorf("name = %q; want Dave", name)
+	}
+	if conn.dc.ci != ci {
+		t.Error("Conn changed its underlying driver connection")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != ErrConnDone {
+		t.Errorf("second Close: err = %v; want %v", err, ErrConnDone)
+	}
+	if _, err := conn.ExecContext(ctx, "WIPE"); err != ErrConnDone {
+		t.Errorf("ExecContext after Close: err = %v; want %v", err, ErrConnDone)
+	}
+	if _, err := conn.QueryContext(ctx, "SELECT|people|name|"); err != ErrConnDone {
+		t.Errorf("QueryContext after Close: err = %v; want %v", err, ErrConnDone)
+	}
+}
+
+func TestConnTx(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.dc != conn.dc {
+		t.Error("transaction does not use the Conn's connection")
+	}
+	if _, err := tx.Exec("INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Conn is still usable after the transaction ends.
+	var age int
+	err = conn.QueryRowContext(ctx, "SELECT|people|age|name=?", "Dave").Scan(&age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if age != 4 {
+		t.Errorf("age = %d; want 4", age)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnStmt(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := conn.PrepareContext(ctx, "SELECT|people|name|age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := stmt.QueryRow(2).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Bob" {
+		t.Errorf("name = %q; want Bob", name)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.QueryRow(2).Scan(&name); err != ErrConnDone {
+		t.Errorf("QueryRow after Conn.Close: err = %v; want %v", err, ErrConnDone)
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenDB(t *testing.T) {
+	db := OpenDB(&fakeConnector{name: fakeDBName})
+	defer db.Close()
+	if db.Driver() != fdriver {
+		t.Error("Driver does not return the Connector's driver")
+	}
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t1|name=string")
+	exec(t, db, "INSERT|t1|name=?", "Alice")
+	var name string
+	if err := db.QueryRow("SELECT|t1|name|").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Alice" {
+		t.Errorf("name = %q; want Alice", name)
+	}
+}
+
+func TestOpenConnector(t *testing.T) {
+	Register("testctx", &fakeDriverCtx{})
+	db, err := Open("testctx", "people")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, ok := db.connector.(*fakeConnector); !ok {
+		t.Fatal("not using *fakeConnector")
+	}
+}
+
 func TestTxStmt(t *testing.T) {
 	db := newTestDB(t, "")
 	defer closeDB(t, db)
@@ -1592,7 +1734,7 @@ func TestIssue4902(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
 
-	driver := db.driver.(*fakeDriver)
+	driver := db.Driver().(*fakeDriver)
 	opens0 := driver.openCount
 
 	var stmt *Stmt
@@ -1685,7 +1827,7 @@ func TestMaxOpenConns(t *testing.T) {
 	db := newTestDB(t, "magicquery")
 	defer closeDB(t, db)
 
-	driver := db.driver.(*fakeDriver)
+	driver := db.Driver().(*fakeDriver)
 
 	// Force the number of open connections to 0 so we can get an accurate
 	// count for the test
@@ -1967,6 +2109,63 @@ func TestStats(t *testing.T) {
 	}
 }
 
+func TestStatsPool(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(2)
+	db.SetMaxIdleConns(1)
+
+	ctx := context.Background()
+	c1, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := db.Stats()
+	if got, want := stats.MaxOpenConnections, 2; got != want {
+		t.Errorf("stats.MaxOpenConnections = %d; want %d", got, want)
+	}
+	if got, want := stats.InUse, 2; got != want {
+		t.Errorf("stats.InUse = %d; want %d", got, want)
+	}
+	if got, want := stats.Idle, 0; got != want {
+		t.Errorf("stats.Idle = %d; want %d", got, want)
+	}
+
+	// The pool is exhausted, so the next request has to wait.
+	wctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	_, err = db.Conn(wctx)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Conn with exhausted pool: err = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	c1.Close()
+	c2.Close()
+	stats = db.Stats()
+	if got, want := stats.OpenConnections, 1; got != want {
+		t.Errorf("stats.OpenConnections = %d; want %d", got, want)
+	}
+	if got, want := stats.Idle, 1; got != want {
+		t.Errorf("stats.Idle = %d; want %d", got, want)
+	}
+	if got, want := stats.InUse, 0; got != want {
+		t.Errorf("stats.InUse = %d; want %d", got, want)
+	}
+	if got, want := stats.WaitCount, int64(1); got != want {
+		t.Errorf("stats.WaitCount = %d; want %d", got, want)
+	}
+	if stats.WaitDuration <= 0 {
+		t.Errorf("stats.WaitDuration = %v; want > 0", stats.WaitDuration)
+	}
+	if got, want := stats.MaxIdleClosed, int64(1); got != want {
+		t.Errorf("stats.MaxIdleClosed = %d; want %d", got, want)
+	}
+}
+
 func TestConnMaxLifetime(t *testing.T) {
 	t0 := time.Unix(1000000, 0)
 	offset := time.Duration(0)
@@ -1977,7 +2176,7 @@ func TestConnMaxLifetime(t *testing.T) {
 	db := newTestDB(t, "magicquery")
 	defer closeDB(t, db)
 
-	driver := db.driver.(*fakeDriver)
+	driver := db.Driver().(*fakeDriver)
 
 	// Force the number of open connections to 0 so we can get an accurate
 	// count for the test
@@ -2066,7 +2265,7 @@ func TestStmtCloseDeps(t *testing.T) {
 	db := newTestDB(t, "magicquery")
 	defer closeDB(t, db)
 
-	driver := db.driver.(*fakeDriver)
+	driver := db.Driver().(*fakeDriver)
 
 	driver.mu.Lock()
 	opens0 := driver.openCount
@@ -2865,7 +3064,7 @@ func TestIssue6081(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
 
-	drv := db.driver.(*fakeDriver)
+	drv := db.Driver().(*fakeDriver)
 	drv.mu.Lock()
 	opens0 := drv.openCount
 	closes0 := drv.closeCount
@@ -3035,7 +3234,7 @@ func TestConnectionLeak(t *testing.T) {
 	// Now we have defaultMaxIdleConns busy connections. Open
 	// a new one, but wait until the busy connections are released
 	// before returning control to DB.
-	drv := db.driver.(*fakeDriver)
+	drv := db.Driver().(*fakeDriver)
 	drv.waitCh = make(chan struct{}, 1)
 	drv.waitingCh = make(chan struct{}, 1)
 	var wg sync.WaitGroup
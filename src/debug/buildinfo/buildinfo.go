@@ -0,0 +1,188 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package buildinfo provides access to information embedded in a Go binary
+// about how it was built. This includes the Go toolchain version, and the
+// set of source trees and build settings used (for binaries built by the
+// go command).
+//
+// Build information is available for the currently running binary in
+// runtime/debug.ReadBuildInfo.
+//
+// Only ELF binaries are currently supported.
+package buildinfo
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+)
+
+// BuildInfo is the type of the build information provided
+// by this package. It is defined in runtime/debug.
+type BuildInfo = debug.BuildInfo
+
+var (
+	// errUnrecognizedFormat is returned when a given executable file
+	// doesn't appear to be in a known format, or it breaks the rules
+	// of that format, or when there are I/O errors reading the file.
+	errUnrecognizedFormat = errors.New("unrecognized file format")
+
+	// errNotGoExe is returned when a given executable file is valid but does
+	// not contain Go build information.
+	errNotGoExe = errors.New("not a Go executable")
+)
+
+// The go command wraps the build information recorded in a binary
+// in these sentinels. They are decoded at run time so that binaries
+// importing this package do not contain them by accident.
+var (
+	infoStart, _ = hex.DecodeString("3077af0c9274080241e1c107e6d618e6")
+	infoEnd, _   = hex.DecodeString("f932433186182072008242104116d8f2")
+)
+
+// ReadFile returns build information embedded in a Go binary
+// file at the given path.
+func ReadFile(name string) (info *BuildInfo, err error) {
+	defer func() {
+		if pathErr, ok := err.(*os.PathError); ok {
+			err = fmt.Errorf("could not read Go build info: %v", pathErr)
+		} else if err != nil {
+			err = fmt.Errorf("could not read Go build info from %s: %v", name, err)
+		}
+	}()
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read returns build information embedded in a Go binary file
+// accessed through the given ReaderAt.
+//
+// If the binary carries no build information from the go command
+// but its symbol table is intact, the returned BuildInfo holds
+// only the Go version.
+func Read(r io.ReaderAt) (*BuildInfo, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, errUnrecognizedFormat
+	}
+	x := &elfExe{f}
+
+	data, err := x.modinfo()
+	if err != nil {
+		return nil, err
+	}
+	if data != "" {
+		return debug.ParseBuildInfo(data)
+	}
+
+	// No build information from the go command.
+	// Fall back to the version recorded by the linker.
+	vers, err := x.buildVersion()
+	if err != nil {
+		return nil, err
+	}
+	return &BuildInfo{GoVersion: vers}, nil
+}
+
+// elfExe is the ELF implementation of the executable reader.
+type elfExe struct {
+	f *elf.File
+}
+
+// modinfo returns the build information text, without its sentinels,
+// found in the binary's data sections, or the empty string if there
+// is none.
+func (x *elfExe) modinfo() (string, error) {
+	for _, s := range x.f.Sections {
+		if s.Type != elf.SHT_PROGBITS || s.Flags&elf.SHF_ALLOC == 0 || s.Flags&elf.SHF_EXECINSTR != 0 {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			return "", errUnrecognizedFormat
+		}
+		i := bytes.Index(data, infoStart)
+		if i < 0 {
+			continue
+		}
+		data = data[i+len(infoStart):]
+		j := bytes.Index(data, infoEnd)
+		if j < 0 {
+			continue
+		}
+		return string(data[:j]), nil
+	}
+	return "", nil
+}
+
+// buildVersion returns the value of runtime.buildVersion,
+// located through the symbol table.
+func (x *elfExe) buildVersion() (string, error) {
+	syms, err := x.f.Symbols()
+	if err != nil {
+		return "", errNotGoExe
+	}
+	var addr uint64
+	found := false
+	for _, s := range syms {
+		if s.Name == "runtime.buildVersion" {
+			addr, found = s.Value, true
+			break
+		}
+	}
+	if !found {
+		return "", errNotGoExe
+	}
+
+	// The symbol holds a string header: a data pointer and a length.
+	ptrSize := 4
+	if x.f.Class == elf.ELFCLASS64 {
+		ptrSize = 8
+	}
+	hdr, err := x.readData(addr, uint64(2*ptrSize))
+	if err != nil {
+		return "", err
+	}
+	readPtr := func(b []byte) uint64 {
+		if ptrSize == 4 {
+			return uint64(x.f.ByteOrder.Uint32(b))
+		}
+		return x.f.ByteOrder.Uint64(b)
+	}
+	ptr, n := readPtr(hdr), readPtr(hdr[ptrSize:])
+	if n == 0 || n > 1<<10 {
+		return "", errNotGoExe
+	}
+	vers, err := x.readData(ptr, n)
+	if err != nil {
+		return "", err
+	}
+	return string(vers), nil
+}
+
+// readData reads size bytes at the given virtual address.
+func (x *elfExe) readData(addr, size uint64) ([]byte, error) {
+	for _, prog := range x.f.Progs {
+		if prog.Type != elf.PT_LOAD || addr < prog.Vaddr || addr+size > prog.Vaddr+prog.Filesz {
+			continue
+		}
+		b := make([]byte, size)
+		if _, err := prog.ReadAt(b, int64(addr-prog.Vaddr)); err != nil {
+			return nil, errUnrecognizedFormat
+		}
+		return b, nil
+	}
+	return nil, errUnrecognizedFormat
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package buildinfo_test
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"internal/testenv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+	switch runtime.GOOS {
+	case "darwin", "windows", "plan9":
+		t.Skipf("%s binaries are not ELF files", runtime.GOOS)
+	}
+
+	dir, err := ioutil.TempDir("", "buildinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "hello.go")
+	if err := ioutil.WriteFile(src, []byte("package main\nfunc main() {}\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(dir, "hello")
+	for _, ldflags := range []string{"", "-s"} {
+		cmd := exec.Command(testenv.GoToolPath(t), "build", "-ldflags="+ldflags, "-o", exe, src)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go build: %v\n%s", err, out)
+		}
+		info, err := buildinfo.ReadFile(exe)
+		if err != nil {
+			t.Fatalf("ldflags %q: %v", ldflags, err)
+		}
+		if info.GoVersion != runtime.Version() {
+			t.Errorf("ldflags %q: GoVersion = %q, want %q", ldflags, info.GoVersion, runtime.Version())
+		}
+		if info.Path != "command-line-arguments" {
+			t.Errorf("ldflags %q: Path = %q, want %q", ldflags, info.Path, "command-line-arguments")
+		}
+		found := false
+		for _, s := range info.Settings {
+			if s.Key == "GOOS" && s.Value == runtime.GOOS {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("ldflags %q: GOOS missing from settings: %+v", ldflags, info.Settings)
+		}
+	}
+}
+
+func TestReadNotExecutable(t *testing.T) {
+	_, err := buildinfo.Read(bytes.NewReader([]byte("not an executable")))
+	if err == nil {
+		t.Fatal("Read of non-executable data succeeded")
+	}
+}
+
+func TestReadFileMissing(t *testing.T) {
+	if _, err := buildinfo.ReadFile(filepath.Join("testdata", "missing")); err == nil {
+		t.Fatal("ReadFile of missing file succeeded")
+	}
+}
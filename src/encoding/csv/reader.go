@@ -63,12 +63,16 @@ import (
 // A ParseError is returned for parsing errors.
 // The first line is 1.  The first column is 0.
 type ParseError struct {
-	Line   int   // Line where the error occurred
-	Column int   // Column (rune index) where the error occurred
-	Err    error // The actual error
+	StartLine int   // Line where the record starts
+	Line      int   // Line where the error occurred
+	Column    int   // Column (rune index) where the error occurred
+	Err       error // The actual error
 }
 
 func (e *ParseError) Error() string {
+	if e.StartLine != 0 && e.StartLine != e.Line {
+		return fmt.Sprintf("record on line %d; parse error on line %d, column %d: %s", e.StartLine, e.Line, e.Column, e.Err)
+	}
 	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
 }
 
@@ -110,10 +114,16 @@ type Reader struct {
 	// If TrimLeadingSpace is true, leading white space in a field is ignored.
 	// This is done even if the field delimiter, Comma, is white space.
 	TrimLeadingSpace bool
-
-	line   int
-	column int
-	r      *bufio.Reader
+	// ReuseRecord controls whether calls to Read may return a slice sharing
+	// the backing array of the previous call's returned slice for performance.
+	// By default, each call to Read returns newly allocated memory owned by the caller.
+	ReuseRecord bool
+
+	line       int
+	column     int
+	recordLine int   // line where the current record starts
+	offset     int64 // number of bytes consumed from r
+	r          *bufio.Reader
 	// lineBuffer holds the unescaped fields read by readField, one after another.
 	// The fields can be accessed by using the indexes in fieldIndexes.
 	// Example: for the row `a,"b","c""d",e` lineBuffer will contain `abc"de` and
@@ -122,6 +132,20 @@ type Reader struct {
 	// Indexes of fields inside lineBuffer
 	// The i'th field starts at offset fieldIndexes[i] in lineBuffer.
 	fieldIndexes []int
+	// Positions of fields in the input, parallel to fieldIndexes.
+	fieldPositions []position
+	// fieldLine and fieldColumn hold the position of the field
+	// being read by parseField.
+	fieldLine, fieldColumn int
+
+	// lastRecord is the record returned by the previous call to Read,
+	// kept so its backing array can be reused if ReuseRecord is set.
+	lastRecord []string
+}
+
+// position is the line and column at which a field starts.
+type position struct {
+	line, col int
 }
 
 // NewReader returns a new Reader that reads from r.
@@ -135,9 +159,10 @@ func NewReader(r io.Reader) *Reader {
 // error creates a new ParseError based on err.
 func (r *Reader) error(err error) error {
 	return &ParseError{
-		Line:   r.line,
-		Column: r.column,
-		Err:    err,
+		StartLine: r.recordLine,
+		Line:      r.line,
+		Column:    r.column,
+		Err:       err,
 	}
 }
 
@@ -147,9 +172,45 @@ func (r *Reader) error(err error) error {
 // Except for that case, Read always returns either a non-nil
 // record or a non-nil error, but not both.
 // If there is no data left to be read, Read returns nil, io.EOF.
+// If ReuseRecord is true, the returned slice may be shared
+// between multiple calls to Read.
 func (r *Reader) Read() (record []string, err error) {
+	if r.ReuseRecord {
+		record, err = r.readRecord(r.lastRecord)
+		r.lastRecord = record
+	} else {
+		record, err = r.readRecord(nil)
+	}
+	return record, err
+}
+
+// FieldPos returns the line and column corresponding to the start of
+// the field with the given index in the slice most recently returned
+// by Read. As in ParseError, the first line is 1 and the first column
+// is 0, and columns count runes. A quoted field spanning several lines
+// is reported at the line where it starts.
+//
+// If this is called with an out-of-bounds index, it panics.
+func (r *Reader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(r.fieldPositions) {
+		panic("csv: out of range index passed to FieldPos")
+	}
+	p := &r.fieldPositions[field]
+	return p.line, p.col
+}
+
+// InputOffset returns the input stream byte offset of the current reader
+// position. The offset gives the location of the end of the most recently
+// read record and the beginning of the next one.
+func (r *Reader) InputOffset() int64 {
+	return r.offset
+}
+
+// readRecord reads the next record, reusing the backing array of dst
+// for it if possible.
+func (r *Reader) readRecord(dst []string) (record []string, err error) {
 	for {
-		record, err = r.parseRecord()
+		record, err = r.parseRecord(dst)
 		if record != nil {
 			break
 		}
@@ -160,8 +221,13 @@ func (r *Reader) Read() (record []string, err error) {
 
 	if r.FieldsPerRecord > 0 {
 		if len(record) != r.FieldsPerRecord {
-			r.column = 0 // report at start of record
-			return record, r.error(ErrFieldCount)
+			// Report at start of record.
+			return record, &ParseError{
+				StartLine: r.recordLine,
+				Line:      r.recordLine,
+				Column:    0,
+				Err:       ErrFieldCount,
+			}
 		}
 	} else if r.FieldsPerRecord == 0 {
 		r.FieldsPerRecord = len(record)
@@ -176,7 +242,7 @@ func (r *Reader) Read() (record []string, err error) {
 // reported.
 func (r *Reader) ReadAll() (records [][]string, err error) {
 	for {
-		record, err := r.Read()
+		record, err := r.readRecord(nil)
 		if err == io.EOF {
 			return records, nil
 		}
@@ -191,17 +257,20 @@ func (r *Reader) ReadAll() (records [][]string, err error) {
 // of how far into the line we have read.  r.column will point to the start
 // of this rune, not the end of this rune.
 func (r *Reader) readRune() (rune, error) {
-	r1, _, err := r.r.ReadRune()
+	r1, size, err := r.r.ReadRune()
+	r.offset += int64(size)
 
 	// Handle \r\n here. We make the simplifying assumption that
 	// anytime \r is followed by \n that it can be folded to \n.
 	// We will not detect files which contain both \r\n and bare \n.
 	if r1 == '\r' {
-		r1, _, err = r.r.ReadRune()
+		r1, size, err = r.r.ReadRune()
 		if err == nil {
 			if r1 != '\n' {
 				r.r.UnreadRune()
 				r1 = '\r'
+			} else {
+				r.offset += int64(size)
 			}
 		}
 	}
@@ -222,30 +291,34 @@ func (r *Reader) skip(delim rune) error {
 	}
 }
 
-// parseRecord reads and parses a single csv record from r.
-func (r *Reader) parseRecord() (fields []string, err error) {
+// parseRecord reads and parses a single csv record from r,
+// reusing the backing array of dst for the fields if possible.
+func (r *Reader) parseRecord(dst []string) (fields []string, err error) {
 	// Each record starts on a new line. We increment our line
 	// number (lines start at 1, not 0) and set column to -1
 	// so as we increment in readRune it points to the character we read.
 	r.line++
+	r.recordLine = r.line
 	r.column = -1
 
 	// Peek at the first rune. If it is an error we are done.
 	// If we support comments and it is the comment character
 	// then skip to the end of line.
 
-	r1, _, err := r.r.ReadRune()
+	r1, size, err := r.r.ReadRune()
 	if err != nil {
 		return nil, err
 	}
 
 	if r.Comment != 0 && r1 == r.Comment {
+		r.offset += int64(size)
 		return nil, r.skip('\n')
 	}
 	r.r.UnreadRune()
 
 	r.lineBuffer.Reset()
 	r.fieldIndexes = r.fieldIndexes[:0]
+	r.fieldPositions = r.fieldPositions[:0]
 
 	// At this point we have at least one field.
 	for {
@@ -254,6 +327,7 @@ func (r *Reader) parseRecord() (fields []string, err error) {
 		haveField, delim, err := r.parseField()
 		if haveField {
 			r.fieldIndexes = append(r.fieldIndexes, idx)
+			r.fieldPositions = append(r.fieldPositions, position{r.fieldLine, r.fieldColumn})
 		}
 
 		if delim == '\n' || err == io.EOF {
@@ -275,7 +349,11 @@ func (r *Reader) parseRecord() (fields []string, err error) {
 	// minimal and a tradeoff for better performance through the combined
 	// allocations.
 	line := r.lineBuffer.String()
-	fields = make([]string, fieldCount)
+	if cap(dst) >= fieldCount {
+		fields = dst[:fieldCount]
+	} else {
+		fields = make([]string, fieldCount)
+	}
 
 	for i, idx := range r.fieldIndexes {
 		if i == fieldCount-1 {
@@ -296,6 +374,7 @@ func (r *Reader) parseField() (haveField bool, delim rune, err error) {
 	for err == nil && r.TrimLeadingSpace && r1 != '\n' && unicode.IsSpace(r1) {
 		r1, err = r.readRune()
 	}
+	r.fieldLine, r.fieldColumn = r.line, r.column
 
 	if err == io.EOF && r.column != 0 {
 		return true, 0, err
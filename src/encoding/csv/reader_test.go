@@ -293,6 +293,126 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestReadMultiLineError(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\n\"c\nd\"e\",f\n"))
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	_, err := r.Read()
+	want := &ParseError{StartLine: 2, Line: 3, Column: 1, Err: ErrQuote}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("Read error = %#v, want %#v", err, want)
+	}
+	if got, wantMsg := err.Error(), `record on line 2; parse error on line 3, column 1: extraneous " in field`; got != wantMsg {
+		t.Errorf("Error() = %q, want %q", got, wantMsg)
+	}
+}
+
+func TestFieldPos(t *testing.T) {
+	const input = "a,bb,\"c\"\n\n# comment\n  x,\"multi\nline\",é,z\n\"\",,\n"
+	want := [][][2]int{
+		{{1, 0}, {1, 2}, {1, 5}},
+		{{4, 0}, {4, 4}, {5, 6}, {5, 8}},
+		{{6, 0}, {6, 3}, {6, 4}},
+	}
+	for _, trim := range []bool{false, true} {
+		r := NewReader(strings.NewReader(input))
+		r.Comment = '#'
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = trim
+		for i, fields := range want {
+			record, err := r.Read()
+			if err != nil {
+				t.Fatalf("record %d: %v", i, err)
+			}
+			if len(record) != len(fields) {
+				t.Fatalf("record %d: got %d fields, want %d", i, len(record), len(fields))
+			}
+			for j, pos := range fields {
+				if trim && i == 1 && j == 0 {
+					pos[1] = 2
+				}
+				line, col := r.FieldPos(j)
+				if line != pos[0] || col != pos[1] {
+					t.Errorf("trim=%v: FieldPos(%d) of record %d = %d:%d, want %d:%d", trim, j, i, line, col, pos[0], pos[1])
+				}
+			}
+		}
+	}
+}
+
+func TestFieldPosOutOfRange(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\n"))
+	r.Read()
+	defer func() {
+		if recover() == nil {
+			t.Error("FieldPos(2) did not panic")
+		}
+	}()
+	r.FieldPos(2)
+}
+
+func TestInputOffset(t *testing.T) {
+	const input = "a,b\r\n\"c\r\nd\",é\n\n#x\nlast"
+	want := []int64{5, 15, 23}
+	r := NewReader(strings.NewReader(input))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	for i, off := range want {
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if got := r.InputOffset(); got != off {
+			t.Errorf("after record %d, InputOffset() = %d, want %d", i, got, off)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read at end = %v, want io.EOF", err)
+	}
+	if got := r.InputOffset(); got != int64(len(input)) {
+		t.Errorf("at EOF, InputOffset() = %d, want %d", got, len(input))
+	}
+}
+
+func TestReuseRecord(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b,c\nd,e\nf,g,h,i\n"))
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	first, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"d", "e"}; !reflect.DeepEqual(second, want) {
+		t.Errorf("second record = %q, want %q", second, want)
+	}
+	if &first[0] != &second[0] {
+		t.Error("second record does not share the first record's backing array")
+	}
+	third, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"f", "g", "h", "i"}; !reflect.DeepEqual(third, want) {
+		t.Errorf("third record = %q, want %q", third, want)
+	}
+
+	// ReadAll never reuses records.
+	r = NewReader(strings.NewReader("a\nb\n"))
+	r.ReuseRecord = true
+	all, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"a"}, {"b"}}; !reflect.DeepEqual(all, want) {
+		t.Errorf("ReadAll = %q, want %q", all, want)
+	}
+}
+
 // nTimes is an io.Reader which yields the string s n times.
 type nTimes struct {
 	s   string
@@ -362,6 +482,10 @@ func BenchmarkReadWithoutFieldsPerRecord(b *testing.B) {
 	benchmarkRead(b, func(r *Reader) { r.FieldsPerRecord = -1 }, benchmarkCSVData)
 }
 
+func BenchmarkReadReuseRecord(b *testing.B) {
+	benchmarkRead(b, func(r *Reader) { r.ReuseRecord = true }, benchmarkCSVData)
+}
+
 func BenchmarkReadLargeFields(b *testing.B) {
 	benchmarkRead(b, nil, strings.Repeat(`xxxxxxxxxxxxxxxx,yyyyyyyyyyyyyyyy,zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz,wwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwww,vvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvv
 xxxxxxxxxxxxxxxxxxxxxxxx,yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy,zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz,wwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwwww,vvvv
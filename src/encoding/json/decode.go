@@ -91,6 +91,10 @@ import (
 // Instead, they are replaced by the Unicode replacement
 // character U+FFFD.
 //
+// A Decoder can be configured to reject unknown object keys, duplicate
+// object keys, case-insensitive key matches and invalid UTF-8; see
+// Decoder.DisallowUnknownFields and the related methods.
+//
 func Unmarshal(data []byte, v interface{}) error {
 	// Check for well-formedness.
 	// Avoids filling out half a data structure
@@ -153,6 +157,18 @@ type InvalidUnmarshalError struct {
 	Type reflect.Type
 }
 
+// A StrictError describes JSON input rejected by one of the
+// Decoder's strict decoding options.
+type StrictError struct {
+	Msg    string // description of the problem
+	Path   string // JSON Pointer (RFC 6901) to the offending value
+	Offset int64  // offset of the offending value from the start of the input
+}
+
+func (e *StrictError) Error() string {
+	return "json: " + e.Msg + " at " + strconv.Quote(e.Path) + " (offset " + strconv.FormatInt(e.Offset, 10) + ")"
+}
+
 func (e *InvalidUnmarshalError) Error() string {
 	if e.Type == nil {
 		return "json: Unmarshal(nil)"
@@ -274,6 +290,14 @@ type decodeState struct {
 	}
 	savedError error
 	useNumber  bool
+
+	// Strict decoding options; see the corresponding Decoder methods.
+	disallowUnknownFields  bool
+	disallowDuplicateNames bool
+	requireExactCase       bool
+	disallowInvalidUTF8    bool
+
+	path []string // JSON Pointer reference tokens, tracked only when strict
 }
 
 // errPhase is used for errors that should not happen unless
@@ -287,9 +311,71 @@ func (d *decodeState) init(data []byte) *decodeState {
 	d.savedError = nil
 	d.errorContext.Struct = ""
 	d.errorContext.Field = ""
+	d.path = d.path[:0]
 	return d
 }
 
+// strict reports whether any strict decoding option is in effect.
+func (d *decodeState) strict() bool {
+	return d.disallowUnknownFields || d.disallowDuplicateNames || d.requireExactCase || d.disallowInvalidUTF8
+}
+
+// pushName records that decoding has descended into the object member
+// with the given name. It is a no-op unless d is strict.
+func (d *decodeState) pushName(name []byte) {
+	if d.strict() {
+		d.path = append(d.path, string(name))
+	}
+}
+
+// pushIndex records that decoding has descended into the array element
+// with the given index. It is a no-op unless d is strict.
+func (d *decodeState) pushIndex(i int) {
+	if d.strict() {
+		d.path = append(d.path, strconv.Itoa(i))
+	}
+}
+
+// popPath undoes the most recent pushName or pushIndex.
+func (d *decodeState) popPath() {
+	if d.strict() {
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// pointer returns the JSON Pointer to the value being decoded.
+func (d *decodeState) pointer() string {
+	var b bytes.Buffer
+	for _, tok := range d.path {
+		b.WriteByte('/')
+		for i := 0; i < len(tok); i++ {
+			switch tok[i] {
+			case '~':
+				b.WriteString("~0")
+			case '/':
+				b.WriteString("~1")
+			default:
+				b.WriteByte(tok[i])
+			}
+		}
+	}
+	return b.String()
+}
+
+// strictError saves a StrictError for the value being decoded,
+// which starts at offset off.
+func (d *decodeState) strictError(msg string, off int) {
+	d.saveError(&StrictError{Msg: msg, Path: d.pointer(), Offset: int64(off)})
+}
+
+// checkString saves an error if d disallows invalid UTF-8 and
+// the quoted string literal item, which starts at offset off, contains it.
+func (d *decodeState) checkString(item []byte, off int) {
+	if d.disallowInvalidUTF8 && !validString(item) {
+		d.strictError("invalid UTF-8 in string", off)
+	}
+}
+
 // error aborts the decoding by panicking with err.
 func (d *decodeState) error(err error) {
 	panic(d.addErrorContext(err))
@@ -543,6 +629,7 @@ func (d *decodeState) array(v reflect.Value) {
 			}
 		}
 
+		d.pushIndex(i)
 		if i < v.Len() {
 			// Decode into element.
 			d.value(v.Index(i))
@@ -550,6 +637,7 @@ func (d *decodeState) array(v reflect.Value) {
 			// Ran out of fixed array: skip.
 			d.value(reflect.Value{})
 		}
+		d.popPath()
 		i++
 
 		// Next token must be , or ].
@@ -642,6 +730,7 @@ func (d *decodeState) object(v reflect.Value) {
 	}
 
 	var mapElem reflect.Value
+	var seen map[string]bool // keys decoded so far, if disallowing duplicates
 
 	for {
 		// Read opening " of string key or closing }.
@@ -662,6 +751,18 @@ func (d *decodeState) object(v reflect.Value) {
 		if !ok {
 			d.error(errPhase)
 		}
+		d.pushName(key)
+		d.checkString(item, start)
+		if d.disallowDuplicateNames {
+			if seen[string(key)] {
+				d.strictError("duplicate name "+strconv.Quote(string(key)), start)
+			} else {
+				if seen == nil {
+					seen = make(map[string]bool)
+				}
+				seen[string(key)] = true
+			}
+		}
 
 		// Figure out field corresponding to key.
 		var subv reflect.Value
@@ -684,10 +785,13 @@ func (d *decodeState) object(v reflect.Value) {
 					f = ff
 					break
 				}
-				if f == nil && ff.equalFold(ff.nameBytes, key) {
+				if f == nil && !d.requireExactCase && ff.equalFold(ff.nameBytes, key) {
 					f = ff
 				}
 			}
+			if f == nil && d.disallowUnknownFields {
+				d.strictError("unknown field "+strconv.Quote(string(key)), start)
+			}
 			if f != nil {
 				subv = v
 				destring = f.quoted
@@ -745,6 +849,7 @@ func (d *decodeState) object(v reflect.Value) {
 					n, err := strconv.ParseInt(s, 10, 64)
 					if err != nil || reflect.Zero(kt).OverflowInt(n) {
 						d.saveError(&UnmarshalTypeError{Value: "number " + s, Type: kt, Offset: int64(start + 1)})
+						d.popPath()
 						return
 					}
 					kv = reflect.ValueOf(n).Convert(kt)
@@ -753,6 +858,7 @@ func (d *decodeState) object(v reflect.Value) {
 					n, err := strconv.ParseUint(s, 10, 64)
 					if err != nil || reflect.Zero(kt).OverflowUint(n) {
 						d.saveError(&UnmarshalTypeError{Value: "number " + s, Type: kt, Offset: int64(start + 1)})
+						d.popPath()
 						return
 					}
 					kv = reflect.ValueOf(n).Convert(kt)
@@ -762,6 +868,7 @@ func (d *decodeState) object(v reflect.Value) {
 			}
 			v.SetMapIndex(kv, subv)
 		}
+		d.popPath()
 
 		// Next token must be , or }.
 		op = d.scanWhile(scanSkipSpace)
@@ -789,6 +896,9 @@ func (d *decodeState) literal(v reflect.Value) {
 	d.off--
 	d.scan.undo(op)
 
+	if d.data[start] == '"' {
+		d.checkString(d.data[start:d.off], start)
+	}
 	if v.IsValid() {
 		d.literalStore(d.data[start:d.off], v, false)
 	}
@@ -1032,7 +1142,9 @@ func (d *decodeState) arrayInterface() []interface{} {
 		d.off--
 		d.scan.undo(op)
 
+		d.pushIndex(len(v))
 		v = append(v, d.valueInterface())
+		d.popPath()
 
 		// Next token must be , or ].
 		op = d.scanWhile(scanSkipSpace)
@@ -1068,6 +1180,13 @@ func (d *decodeState) objectInterface() map[string]interface{} {
 		if !ok {
 			d.error(errPhase)
 		}
+		d.pushName([]byte(key))
+		d.checkString(item, start)
+		if d.disallowDuplicateNames {
+			if _, dup := m[key]; dup {
+				d.strictError("duplicate name "+strconv.Quote(key), start)
+			}
+		}
 
 		// Read : before value.
 		if op == scanSkipSpace {
@@ -1079,6 +1198,7 @@ func (d *decodeState) objectInterface() map[string]interface{} {
 
 		// Read value.
 		m[key] = d.valueInterface()
+		d.popPath()
 
 		// Next token must be , or }.
 		op = d.scanWhile(scanSkipSpace)
@@ -1111,6 +1231,7 @@ func (d *decodeState) literalInterface() interface{} {
 		return c == 't'
 
 	case '"': // string
+		d.checkString(item, start)
 		s, ok := unquote(item)
 		if !ok {
 			d.error(errPhase)
@@ -1142,6 +1263,35 @@ func getu4(s []byte) rune {
 	return rune(r)
 }
 
+// validString reports whether the quoted JSON string literal s is
+// valid UTF-8 and contains no unpaired UTF-16 surrogate escapes.
+func validString(s []byte) bool {
+	if !utf8.Valid(s) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			continue
+		}
+		i++
+		if i >= len(s) || s[i] != 'u' {
+			continue
+		}
+		rr := getu4(s[i-1:])
+		if rr < 0 {
+			return false
+		}
+		i += 4
+		if utf16.IsSurrogate(rr) {
+			if utf16.DecodeRune(rr, getu4(s[i+1:])) == unicode.ReplacementChar {
+				return false
+			}
+			i += 6
+		}
+	}
+	return true
+}
+
 // unquote converts a quoted JSON string literal s into an actual string t.
 // The rules are different than for Go, so cannot use strconv.Unquote.
 func unquote(s []byte) (t string, ok bool) {
@@ -17,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -2008,3 +2009,114 @@ func TestInvalidStringOption(t *testing.T) {
 		t.Fatalf("Unmarshal: %v", err)
 	}
 }
+
+type strictConfig struct {
+	Name  string
+	Ports []int
+	Inner struct {
+		Path string `json:"a/b~c"`
+	}
+}
+
+var strictTests = []struct {
+	in    string
+	ptr   interface{}
+	setup func(*Decoder)
+	err   *StrictError // nil if no error expected
+}{
+	// Lenient by default.
+	{`{"Name":"x","Nmae":"y","name":"z"}`, new(strictConfig), func(*Decoder) {}, nil},
+	{"{\"Name\":\"\xff\"}", new(strictConfig), func(*Decoder) {}, nil},
+
+	// Unknown fields.
+	{`{"Name":"x","Nmae":"y"}`, new(strictConfig), (*Decoder).DisallowUnknownFields,
+		&StrictError{`unknown field "Nmae"`, "/Nmae", 12}},
+	{`{"Inner":{"a/b~c":"p","x":1}}`, new(strictConfig), (*Decoder).DisallowUnknownFields,
+		&StrictError{`unknown field "x"`, "/Inner/x", 22}},
+	{`{"name":"x"}`, new(strictConfig), (*Decoder).DisallowUnknownFields, nil},
+	{`{"Name":"x","Nmae":"y"}`, new(map[string]string), (*Decoder).DisallowUnknownFields, nil},
+
+	// Exact case.
+	{`{"name":"x"}`, new(strictConfig), (*Decoder).RequireExactCase, nil},
+	{`{"name":"x"}`, new(strictConfig), func(dec *Decoder) {
+		dec.RequireExactCase()
+		dec.DisallowUnknownFields()
+	}, &StrictError{`unknown field "name"`, "/name", 1}},
+
+	// Duplicate names.
+	{`{"Ports":[1],"Ports":[2]}`, new(strictConfig), (*Decoder).DisallowDuplicateNames,
+		&StrictError{`duplicate name "Ports"`, "/Ports", 13}},
+	{`[{"a":1},{"a":2,"b":{"c":1,"c":2}}]`, new(interface{}), (*Decoder).DisallowDuplicateNames,
+		&StrictError{`duplicate name "c"`, "/1/b/c", 27}},
+	{`{"a":1,"a":2}`, new(map[string]int), (*Decoder).DisallowDuplicateNames,
+		&StrictError{`duplicate name "a"`, "/a", 7}},
+	{`{"a":{"a":1}}`, new(interface{}), (*Decoder).DisallowDuplicateNames, nil},
+
+	// Invalid UTF-8.
+	{"{\"Ports\":[1],\"Inner\":{\"a/b~c\":\"\xff\"}}", new(strictConfig), (*Decoder).DisallowInvalidUTF8,
+		&StrictError{"invalid UTF-8 in string", "/Inner/a~1b~0c", 30}},
+	{`["ok","\ud800"]`, new([]string), (*Decoder).DisallowInvalidUTF8,
+		&StrictError{"invalid UTF-8 in string", "/1", 6}},
+	{"{\"\xff\":1}", new(interface{}), (*Decoder).DisallowInvalidUTF8,
+		&StrictError{"invalid UTF-8 in string", "/�", 1}},
+	{"{\"Unknown\":[\"\xff\"]}", new(strictConfig), (*Decoder).DisallowInvalidUTF8,
+		&StrictError{"invalid UTF-8 in string", "/Unknown/0", 12}},
+	{`["😀","é"]`, new([]string), (*Decoder).DisallowInvalidUTF8, nil},
+}
+
+func TestDecoderStrict(t *testing.T) {
+	for i, tt := range strictTests {
+		dec := NewDecoder(strings.NewReader(tt.in))
+		tt.setup(dec)
+		err := dec.Decode(tt.ptr)
+		if tt.err == nil {
+			if err != nil {
+				t.Errorf("#%d: %s: unexpected error: %v", i, tt.in, err)
+			}
+			continue
+		}
+		se, ok := err.(*StrictError)
+		if !ok {
+			t.Errorf("#%d: %s: error = %v (%T), want *StrictError", i, tt.in, err, err)
+			continue
+		}
+		if !reflect.DeepEqual(se, tt.err) {
+			t.Errorf("#%d: %s: error = %#v, want %#v", i, tt.in, se, tt.err)
+		}
+	}
+}
+
+func TestDecoderStrictOffset(t *testing.T) {
+	in := `{"A":1} {"A":2,"B":3}` + "\n" + `{"A":3,"C":4}`
+	want := []int64{-1, 15, int64(strings.Index(in, `"C"`))}
+	for _, r := range []string{"full", "one byte"} {
+		var dec *Decoder
+		if r == "full" {
+			dec = NewDecoder(strings.NewReader(in))
+		} else {
+			dec = NewDecoder(iotest.OneByteReader(strings.NewReader(in)))
+		}
+		dec.DisallowUnknownFields()
+		for i, off := range want {
+			var v struct{ A int }
+			err := dec.Decode(&v)
+			if off < 0 {
+				if err != nil {
+					t.Errorf("%s, value %d: unexpected error: %v", r, i, err)
+				}
+				continue
+			}
+			if se, ok := err.(*StrictError); !ok || se.Offset != off {
+				t.Errorf("%s, value %d: error = %v, want offset %d", r, i, err, off)
+			}
+		}
+	}
+}
+
+func TestStrictErrorString(t *testing.T) {
+	err := &StrictError{Msg: `unknown field "x"`, Path: "/a/0/x", Offset: 12}
+	want := `json: unknown field "x" at "/a/0/x" (offset 12)`
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -8,16 +8,20 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 // A Decoder reads and decodes JSON values from an input stream.
 type Decoder struct {
-	r     io.Reader
-	buf   []byte
-	d     decodeState
-	scanp int // start of unread data in buf
-	scan  scanner
-	err   error
+	r       io.Reader
+	buf     []byte
+	d       decodeState
+	scanp   int   // start of unread data in buf
+	scanned int64 // amount of data already scanned, before buf
+	scan    scanner
+	err     error
 
 	tokenState int
 	tokenStack []int
@@ -35,6 +39,26 @@ func NewDecoder(r io.Reader) *Decoder {
 // Number instead of as a float64.
 func (dec *Decoder) UseNumber() { dec.d.useNumber = true }
 
+// DisallowUnknownFields causes the Decoder to return an error when the
+// destination is a struct and the input contains object keys which do
+// not match any non-ignored, exported field in the destination.
+func (dec *Decoder) DisallowUnknownFields() { dec.d.disallowUnknownFields = true }
+
+// DisallowDuplicateNames causes the Decoder to return an error when an
+// object in the input contains the same key more than once.
+func (dec *Decoder) DisallowDuplicateNames() { dec.d.disallowDuplicateNames = true }
+
+// RequireExactCase causes the Decoder to match object keys to struct
+// fields only when they are equal, not when they differ only in case.
+// A key that matches a field only case-insensitively is then treated
+// as an unknown field.
+func (dec *Decoder) RequireExactCase() { dec.d.requireExactCase = true }
+
+// DisallowInvalidUTF8 causes the Decoder to return an error when a string
+// in the input contains invalid UTF-8 or an unpaired UTF-16 surrogate
+// escape, rather than replacing it with U+FFFD.
+func (dec *Decoder) DisallowInvalidUTF8() { dec.d.disallowInvalidUTF8 = true }
+
 // Decode reads the next JSON-encoded value from its
 // input and stores it in the value pointed to by v.
 //
@@ -59,12 +83,17 @@ func (dec *Decoder) Decode(v interface{}) error {
 		return err
 	}
 	dec.d.init(dec.buf[dec.scanp : dec.scanp+n])
+	start := dec.scanned + int64(dec.scanp)
 	dec.scanp += n
 
 	// Don't save err from unmarshal into dec.err:
 	// the connection is still usable since we read a complete JSON
 	// object from it before the error happened.
 	err = dec.d.unmarshal(v)
+	if serr, ok := err.(*StrictError); ok {
+		// Make the offset relative to the start of the input.
+		serr.Offset += start
+	}
 
 	// fixup token streaming state
 	dec.tokenValueEnd()
@@ -135,6 +164,7 @@ func (dec *Decoder) refill() error {
 	// Make room to read more into the buffer.
 	// First slide down data already consumed.
 	if dec.scanp > 0 {
+		dec.scanned += int64(dec.scanp)
 		n := copy(dec.buf, dec.buf[dec.scanp:])
 		dec.buf = dec.buf[:n]
 		dec.scanp = 0
@@ -173,6 +203,9 @@ type Encoder struct {
 	indentBuf    *bytes.Buffer
 	indentPrefix string
 	indentValue  string
+
+	tokenState int
+	tokenStack []int
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -183,42 +216,55 @@ func NewEncoder(w io.Writer) *Encoder {
 // Encode writes the JSON encoding of v to the stream,
 // followed by a newline character.
 //
+// If WriteToken has opened an array or object that is not yet closed,
+// Encode instead writes v as the next element of the array or the value
+// of the pending object member, without a trailing newline.
+//
 // See the documentation for Marshal for details about the
 // conversion of Go values to JSON.
 func (enc *Encoder) Encode(v interface{}) error {
 	if enc.err != nil {
 		return enc.err
 	}
+	if !enc.tokenValueAllowed() {
+		return enc.tokenError("value")
+	}
 	e := newEncodeState()
 	err := e.marshal(v, encOpts{escapeHTML: enc.escapeHTML})
 	if err != nil {
 		return err
 	}
 
-	// Terminate each value with a newline.
+	// Terminate each top-level value with a newline.
 	// This makes the output look a little nicer
 	// when debugging, and some kind of space
 	// is required if the encoded value was a number,
 	// so that the reader knows there aren't more
 	// digits coming.
-	e.WriteByte('\n')
+	if enc.tokenState == tokenTopValue {
+		e.WriteByte('\n')
+	}
 
 	b := e.Bytes()
-	if enc.indentPrefix != "" || enc.indentValue != "" {
-		if enc.indentBuf == nil {
-			enc.indentBuf = new(bytes.Buffer)
-		}
-		enc.indentBuf.Reset()
-		err = Indent(enc.indentBuf, b, enc.indentPrefix, enc.indentValue)
-		if err != nil {
-			return err
+	if enc.indenting() || enc.tokenState != tokenTopValue {
+		buf := enc.buffer()
+		enc.tokenSeparator(buf)
+		if enc.indenting() {
+			prefix := enc.indentPrefix + strings.Repeat(enc.indentValue, len(enc.tokenStack))
+			err = Indent(buf, b, prefix, enc.indentValue)
+			if err != nil {
+				return err
+			}
+		} else {
+			buf.Write(b)
 		}
-		b = enc.indentBuf.Bytes()
+		b = buf.Bytes()
 	}
 	if _, err = enc.w.Write(b); err != nil {
 		enc.err = err
 	}
 	encodeStatePool.Put(e)
+	enc.tokenValueEnd()
 	return err
 }
 
@@ -241,6 +287,121 @@ func (enc *Encoder) SetEscapeHTML(on bool) {
 	enc.escapeHTML = on
 }
 
+// WriteToken writes the JSON token t to the stream.
+// It is the counterpart of Decoder.Token and accepts the same
+// token types: Delim, bool, float64, Number, string and nil.
+//
+// WriteToken inserts the commas and colons that separate array
+// elements and object members, and within an object it writes the
+// string token before each value as the member's key. Values between
+// a Delim('[') or Delim('{') and the matching closing Delim may also
+// be written with Encode. WriteToken returns an error, without writing
+// anything, if t would make the output malformed: for example, a
+// closing delimiter that does not match the innermost open one, or a
+// non-string token where an object key is expected. As with Encode,
+// each complete top-level value is followed by a newline.
+//
+// Tokens are written to the underlying writer as they are given,
+// so callers writing many small tokens may wish to wrap it in a
+// bufio.Writer.
+func (enc *Encoder) WriteToken(t Token) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	switch t := t.(type) {
+	case Delim:
+		return enc.writeDelim(t)
+	case string:
+		if enc.tokenState == tokenObjectStart || enc.tokenState == tokenObjectComma {
+			return enc.writeKey(t)
+		}
+	case nil, bool, float64, Number:
+	default:
+		return &UnsupportedTypeError{reflect.TypeOf(t)}
+	}
+	if !enc.tokenValueAllowed() {
+		return enc.tokenError("value")
+	}
+	return enc.Encode(t)
+}
+
+func (enc *Encoder) writeDelim(d Delim) error {
+	buf := enc.buffer()
+	switch d {
+	case '[', '{':
+		if !enc.tokenValueAllowed() {
+			return enc.tokenError(quoteChar(byte(d)))
+		}
+		enc.tokenSeparator(buf)
+		enc.tokenStack = append(enc.tokenStack, enc.tokenState)
+		if d == '[' {
+			enc.tokenState = tokenArrayStart
+		} else {
+			enc.tokenState = tokenObjectStart
+		}
+	case ']', '}':
+		empty, nonEmpty := tokenArrayStart, tokenArrayComma
+		if d == '}' {
+			empty, nonEmpty = tokenObjectStart, tokenObjectComma
+		}
+		switch enc.tokenState {
+		case empty:
+		case nonEmpty:
+			enc.tokenNewline(buf, len(enc.tokenStack)-1)
+		default:
+			return enc.tokenError(quoteChar(byte(d)))
+		}
+		enc.tokenState = enc.tokenStack[len(enc.tokenStack)-1]
+		enc.tokenStack = enc.tokenStack[:len(enc.tokenStack)-1]
+	default:
+		return &UnsupportedValueError{reflect.ValueOf(d), strconv.QuoteRune(rune(d))}
+	}
+	buf.WriteByte(byte(d))
+	if d == ']' || d == '}' {
+		if enc.tokenState == tokenTopValue {
+			buf.WriteByte('\n')
+		}
+		enc.tokenValueEnd()
+	}
+	return enc.write(buf.Bytes())
+}
+
+func (enc *Encoder) writeKey(key string) error {
+	buf := enc.buffer()
+	enc.tokenSeparator(buf)
+	e := newEncodeState()
+	e.string(key, enc.escapeHTML)
+	buf.Write(e.Bytes())
+	encodeStatePool.Put(e)
+	buf.WriteByte(':')
+	if enc.indenting() {
+		buf.WriteByte(' ')
+	}
+	enc.tokenState = tokenObjectValue
+	return enc.write(buf.Bytes())
+}
+
+func (enc *Encoder) write(b []byte) error {
+	if _, err := enc.w.Write(b); err != nil {
+		enc.err = err
+		return err
+	}
+	return nil
+}
+
+func (enc *Encoder) indenting() bool {
+	return enc.indentPrefix != "" || enc.indentValue != ""
+}
+
+// buffer returns enc's scratch buffer, emptied.
+func (enc *Encoder) buffer() *bytes.Buffer {
+	if enc.indentBuf == nil {
+		enc.indentBuf = new(bytes.Buffer)
+	}
+	enc.indentBuf.Reset()
+	return enc.indentBuf
+}
+
 // RawMessage is a raw encoded JSON value.
 // It implements Marshaler and Unmarshaler and can
 // be used to delay JSON decoding or precompute a JSON encoding.
@@ -336,6 +497,65 @@ func (dec *Decoder) tokenValueEnd() {
 	}
 }
 
+func (enc *Encoder) tokenValueAllowed() bool {
+	switch enc.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayComma, tokenObjectValue:
+		return true
+	}
+	return false
+}
+
+func (enc *Encoder) tokenValueEnd() {
+	switch enc.tokenState {
+	case tokenArrayStart:
+		enc.tokenState = tokenArrayComma
+	case tokenObjectValue:
+		enc.tokenState = tokenObjectComma
+	}
+}
+
+// tokenSeparator writes to buf what must precede the next array
+// element or object key: a comma if it is not the first and, when
+// indenting, a newline and indentation.
+func (enc *Encoder) tokenSeparator(buf *bytes.Buffer) {
+	switch enc.tokenState {
+	case tokenArrayComma, tokenObjectComma:
+		buf.WriteByte(',')
+	case tokenArrayStart, tokenObjectStart:
+	default:
+		return
+	}
+	enc.tokenNewline(buf, len(enc.tokenStack))
+}
+
+// tokenNewline writes a newline and depth levels of indentation
+// to buf if enc is indenting.
+func (enc *Encoder) tokenNewline(buf *bytes.Buffer, depth int) {
+	if !enc.indenting() {
+		return
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(enc.indentPrefix)
+	for i := 0; i < depth; i++ {
+		buf.WriteString(enc.indentValue)
+	}
+}
+
+func (enc *Encoder) tokenError(what string) error {
+	var context string
+	switch enc.tokenState {
+	case tokenTopValue:
+		context = " at top level"
+	case tokenArrayStart, tokenArrayComma:
+		context = " in array"
+	case tokenObjectStart, tokenObjectComma:
+		context = " looking for object key string"
+	case tokenObjectValue:
+		context = " looking for object value"
+	}
+	return &SyntaxError{"unexpected " + what + context, 0}
+}
+
 // A Delim is a JSON array or object delimiter, one of [ ] { or }.
 type Delim rune
 
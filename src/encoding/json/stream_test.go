@@ -123,6 +123,133 @@ func TestEncoderSetEscapeHTML(t *testing.T) {
 	}
 }
 
+var tokenEncoded = `{"s":"\u003c\u003e","list":[1,{"A":"x","B":"y"},null,[]],"empty":{},"n":2.5}
+true
+`
+
+var tokenEncodedIndent = `{
+>."s": "\u003c\u003e",
+>."list": [
+>..1,
+>..{
+>..."A": "x",
+>..."B": "y"
+>..},
+>..null,
+>..[]
+>.],
+>."empty": {},
+>."n": 2.5
+>}
+true
+`
+
+func TestEncoderWriteToken(t *testing.T) {
+	type pair struct{ A, B string }
+	for _, tt := range []struct {
+		prefix, indent string
+		want           string
+	}{
+		{"", "", tokenEncoded},
+		{">", ".", tokenEncodedIndent},
+	} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent(tt.prefix, tt.indent)
+		steps := []interface{}{
+			Delim('{'), "s", "<>", "list", Delim('['), Number("1"), pair{"x", "y"}, nil, Delim('['), Delim(']'), Delim(']'),
+			"empty", Delim('{'), Delim('}'), "n", 2.5, Delim('}'), true,
+		}
+		for i, step := range steps {
+			var err error
+			if p, ok := step.(pair); ok {
+				err = enc.Encode(p)
+			} else {
+				err = enc.WriteToken(step)
+			}
+			if err != nil {
+				t.Fatalf("indent %q: step %d (%v): %v", tt.indent, i, step, err)
+			}
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("indent %q: got %q, want %q", tt.indent, got, tt.want)
+		}
+	}
+}
+
+func TestEncoderWriteTokenRoundTrip(t *testing.T) {
+	const in = `{"a":[1,"two",{"b":null,"c":[true,false]}],"d":{},"e":[[]],"f":-1.5e3}`
+	for _, indent := range []string{"", "  "} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent("", indent)
+		dec := NewDecoder(strings.NewReader(in))
+		dec.UseNumber()
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Token: %v", err)
+			}
+			if err := enc.WriteToken(tok); err != nil {
+				t.Fatalf("WriteToken(%v): %v", tok, err)
+			}
+		}
+		var want bytes.Buffer
+		if indent == "" {
+			want.WriteString(in)
+		} else {
+			Indent(&want, []byte(in), "", indent)
+		}
+		want.WriteByte('\n')
+		if got := buf.String(); got != want.String() {
+			t.Errorf("indent %q: got %q, want %q", indent, got, want.String())
+		}
+	}
+}
+
+func TestEncoderWriteTokenErrors(t *testing.T) {
+	for _, tt := range []struct {
+		tokens []Token
+		bad    Token
+		err    string
+	}{
+		{nil, Delim(']'), `unexpected ']' at top level`},
+		{[]Token{Delim('[')}, Delim('}'), `unexpected '}' in array`},
+		{[]Token{Delim('{')}, Delim(']'), `unexpected ']' looking for object key string`},
+		{[]Token{Delim('{')}, 1.0, `unexpected value looking for object key string`},
+		{[]Token{Delim('{'), "k"}, Delim('}'), `unexpected '}' looking for object value`},
+		{nil, Delim('('), `json: unsupported value: '('`},
+		{nil, 1, `json: unsupported type: int`},
+	} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		for _, tok := range tt.tokens {
+			if err := enc.WriteToken(tok); err != nil {
+				t.Fatalf("WriteToken(%v): %v", tok, err)
+			}
+		}
+		n := buf.Len()
+		err := enc.WriteToken(tt.bad)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("after %v, WriteToken(%v) = %v, want %q", tt.tokens, tt.bad, err, tt.err)
+		}
+		if buf.Len() != n {
+			t.Errorf("after %v, WriteToken(%v) wrote %q", tt.tokens, tt.bad, buf.Bytes()[n:])
+		}
+	}
+
+	// Encode is a value, not a key.
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.WriteToken(Delim('{'))
+	if err := enc.Encode("k"); err == nil {
+		t.Errorf("Encode in object key position succeeded")
+	}
+}
+
 func TestDecoder(t *testing.T) {
 	for i := 0; i <= len(streamTest); i++ {
 		// Use stream without newlines as input,
@@ -145,6 +145,23 @@ func (enc *Encoder) Indent(prefix, indent string) {
 	enc.p.indent = indent
 }
 
+// SetPrefix sets the preferred prefix for the name space url.
+// When the encoder has to declare url, because no prefix in scope is
+// bound to it, it uses prefix rather than inventing one, provided prefix
+// is not bound to another name space at that point. Elements in url are
+// then written with prefix instead of declaring url as the default name
+// space. SetPrefix ignores prefixes that are not valid XML names or that
+// begin with "xml".
+func (enc *Encoder) SetPrefix(prefix, url string) {
+	if !isNameString(prefix) || strings.Contains(prefix, ":") || strings.HasPrefix(prefix, "xml") || url == "" {
+		return
+	}
+	if enc.p.preferred == nil {
+		enc.p.preferred = make(map[string]string)
+	}
+	enc.p.preferred[url] = prefix
+}
+
 // Encode writes the XML encoding of v to the stream.
 //
 // See the documentation for Marshal for details about the conversion
@@ -192,6 +209,13 @@ var (
 //
 // EncodeToken allows writing a ProcInst with Target set to "xml" only as the first token
 // in the stream.
+//
+// Name space declarations among the attributes of a StartElement, given as
+// Name{"xmlns", prefix} or Name{"", "xmlns"} as returned by Decoder.Token,
+// remain in effect until the matching EndElement. Element and attribute
+// names in a declared name space are written with its prefix, and
+// declarations already in effect are not repeated, so a stream of tokens
+// read by a Decoder is written back with the same prefixes.
 func (enc *Encoder) EncodeToken(t Token) error {
 
 	p := &enc.p
@@ -302,31 +326,63 @@ type printer struct {
 	depth      int
 	indentedIn bool
 	putNewline bool
-	attrNS     map[string]string // map prefix -> name space
+	attrNS     map[string]string // map prefix -> name space; "" is the default name space
 	attrPrefix map[string]string // map name space -> prefix
-	prefixes   []string
+	preferred  map[string]string // map name space -> prefix set by SetPrefix
+	prefixes   []nsUndo
 	tags       []Name
 }
 
-// createAttrPrefix finds the name space prefix attribute to use for the given name space,
-// defining a new prefix if necessary. It returns the prefix.
-func (p *printer) createAttrPrefix(url string) string {
-	if prefix := p.attrPrefix[url]; prefix != "" {
-		return prefix
+// An nsUndo records the previous value of an attrNS or attrPrefix entry,
+// so that the name space bindings made by an element can be undone at
+// its end tag. An nsUndo with a nil map marks the start of an element.
+type nsUndo struct {
+	m     map[string]string
+	key   string
+	value string // previous value; "" if there was none
+}
+
+// setNS sets m[key] to value, deleting it if value is "",
+// and records how to undo the change.
+func (p *printer) setNS(m map[string]string, key, value string) {
+	p.prefixes = append(p.prefixes, nsUndo{m, key, m[key]})
+	if value == "" {
+		delete(m, key)
+	} else {
+		m[key] = value
 	}
+}
 
+// bindPrefix binds prefix to the name space url until the end
+// of the current element.
+func (p *printer) bindPrefix(prefix, url string) {
+	if old := p.attrNS[prefix]; old != "" && p.attrPrefix[old] == prefix {
+		p.setNS(p.attrPrefix, old, "")
+	}
+	p.setNS(p.attrNS, prefix, url)
+	p.setNS(p.attrPrefix, url, prefix)
+}
+
+// prefixFor returns the prefix bound to the name space url,
+// or "" if there is none.
+func (p *printer) prefixFor(url string) string {
 	// The "http://www.w3.org/XML/1998/namespace" name space is predefined as "xml"
 	// and must be referred to that way.
 	// (The "http://www.w3.org/2000/xmlns/" name space is also predefined as "xmlns",
 	// but users should not be trying to use that one directly - that's our job.)
-	if url == xmlURL {
+	if url == xmlURL || url == "xml" {
 		return "xml"
 	}
+	return p.attrPrefix[url]
+}
 
-	// Need to define a new name space.
-	if p.attrPrefix == nil {
-		p.attrPrefix = make(map[string]string)
-		p.attrNS = make(map[string]string)
+// newPrefix binds a new prefix to the name space url and returns it.
+// The prefix is the one set by SetPrefix, if it is not already in use;
+// otherwise it is derived from url.
+func (p *printer) newPrefix(url string) string {
+	if prefix := p.preferred[url]; prefix != "" && p.attrNS[prefix] == "" {
+		p.bindPrefix(prefix, url)
+		return prefix
 	}
 
 	// Pick a name. We try to use the final element of the path
@@ -352,39 +408,82 @@ func (p *printer) createAttrPrefix(url string) string {
 		}
 	}
 
-	p.attrPrefix[url] = prefix
-	p.attrNS[prefix] = url
-
-	p.WriteString(`xmlns:`)
-	p.WriteString(prefix)
-	p.WriteString(`="`)
-	EscapeText(p, []byte(url))
-	p.WriteString(`" `)
-
-	p.prefixes = append(p.prefixes, prefix)
+	p.bindPrefix(prefix, url)
+	return prefix
+}
 
+// createAttrPrefix finds the name space prefix attribute to use for the given name space,
+// defining a new prefix if necessary. It returns the prefix.
+func (p *printer) createAttrPrefix(url string) string {
+	if prefix := p.prefixFor(url); prefix != "" {
+		return prefix
+	}
+	prefix := p.newPrefix(url)
+	p.writeNSDecl(prefix, url)
+	p.WriteByte(' ')
 	return prefix
 }
 
-// deleteAttrPrefix removes an attribute name space prefix.
-func (p *printer) deleteAttrPrefix(prefix string) {
-	delete(p.attrPrefix, p.attrNS[prefix])
-	delete(p.attrNS, prefix)
+// writeNSDecl writes the declaration of prefix as url,
+// or of the default name space if prefix is "".
+func (p *printer) writeNSDecl(prefix, url string) {
+	p.WriteString(`xmlns`)
+	if prefix != "" {
+		p.WriteByte(':')
+		p.WriteString(prefix)
+	}
+	p.WriteString(`="`)
+	EscapeText(p, []byte(url))
+	p.WriteByte('"')
 }
 
 func (p *printer) markPrefix() {
-	p.prefixes = append(p.prefixes, "")
+	if p.attrNS == nil {
+		p.attrNS = make(map[string]string)
+		p.attrPrefix = make(map[string]string)
+	}
+	p.prefixes = append(p.prefixes, nsUndo{})
 }
 
 func (p *printer) popPrefix() {
 	for len(p.prefixes) > 0 {
-		prefix := p.prefixes[len(p.prefixes)-1]
+		u := p.prefixes[len(p.prefixes)-1]
 		p.prefixes = p.prefixes[:len(p.prefixes)-1]
-		if prefix == "" {
+		if u.m == nil {
 			break
 		}
-		p.deleteAttrPrefix(prefix)
+		if u.value == "" {
+			delete(u.m, u.key)
+		} else {
+			u.m[u.key] = u.value
+		}
+	}
+}
+
+// nsDeclPrefix reports whether an attribute with the given name declares
+// a name space, either the default one (prefix "") or a prefix.
+func nsDeclPrefix(name Name) (prefix string, ok bool) {
+	switch {
+	case name.Space == "xmlns":
+		return name.Local, true
+	case name.Space == "" && name.Local == "xmlns":
+		return "", true
+	case name.Space == "" && strings.HasPrefix(name.Local, "xmlns:"):
+		return name.Local[len("xmlns:"):], true
+	}
+	return "", false
+}
+
+// qualifiedName returns the name to write in the tags of an element
+// with the given name, using the name space bindings in scope.
+func (p *printer) qualifiedName(name Name) string {
+	if name.Space == "" || name.Space == p.attrNS[""] {
+		return name.Local
+	}
+	if prefix := p.prefixFor(name.Space); prefix != "" {
+		return prefix + ":" + name.Local
 	}
+	return name.Local
 }
 
 var (
@@ -676,6 +775,12 @@ func (p *printer) marshalTextInterface(val encoding.TextMarshaler, start StartEl
 }
 
 // writeStart writes the given start element.
+//
+// Name space declarations among the attributes of start are written only
+// if they change the bindings in scope. The element name is written with
+// the default name space if that is its name space, or with a prefix
+// already bound to its name space; otherwise the default name space is
+// declared for it.
 func (p *printer) writeStart(start *StartElement) error {
 	if start.Name.Local == "" {
 		return fmt.Errorf("xml: start tag with no name")
@@ -684,14 +789,47 @@ func (p *printer) writeStart(start *StartElement) error {
 	p.tags = append(p.tags, start.Name)
 	p.markPrefix()
 
+	// Name space declarations given as attributes.
+	var decls []string // prefixes to declare; "" for the default name space
+	for _, attr := range start.Attr {
+		prefix, ok := nsDeclPrefix(attr.Name)
+		if !ok || p.attrNS[prefix] == attr.Value {
+			continue
+		}
+		if prefix == "" {
+			p.setNS(p.attrNS, "", attr.Value)
+		} else {
+			if attr.Value == "" || !isNameString(prefix) || strings.HasPrefix(prefix, "xml") {
+				// Prefixes cannot be undeclared, and xml and
+				// xmlns are predeclared.
+				continue
+			}
+			p.bindPrefix(prefix, attr.Value)
+		}
+		decls = append(decls, prefix)
+	}
+
+	// Name space of the element itself.
+	if space := start.Name.Space; space != "" && space != p.attrNS[""] && p.prefixFor(space) == "" {
+		switch {
+		case p.preferred[space] != "" && p.attrNS[p.preferred[space]] == "":
+			decls = append(decls, p.newPrefix(space))
+		case containsString(decls, ""):
+			// The default name space is taken by an explicit declaration.
+			decls = append(decls, p.newPrefix(space))
+		default:
+			p.setNS(p.attrNS, "", space)
+			decls = append([]string{""}, decls...)
+		}
+	}
+
 	p.writeIndent(1)
 	p.WriteByte('<')
-	p.WriteString(start.Name.Local)
+	p.WriteString(p.qualifiedName(start.Name))
 
-	if start.Name.Space != "" {
-		p.WriteString(` xmlns="`)
-		p.EscapeString(start.Name.Space)
-		p.WriteByte('"')
+	for _, prefix := range decls {
+		p.WriteByte(' ')
+		p.writeNSDecl(prefix, p.attrNS[prefix])
 	}
 
 	// Attributes
@@ -700,6 +838,9 @@ func (p *printer) writeStart(start *StartElement) error {
 		if name.Local == "" {
 			continue
 		}
+		if _, ok := nsDeclPrefix(name); ok {
+			continue
+		}
 		p.WriteByte(' ')
 		if name.Space != "" {
 			p.WriteString(p.createAttrPrefix(name.Space))
@@ -714,6 +855,15 @@ func (p *printer) writeStart(start *StartElement) error {
 	return nil
 }
 
+func containsString(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *printer) writeEnd(name Name) error {
 	if name.Local == "" {
 		return fmt.Errorf("xml: end tag with no name")
@@ -732,7 +882,7 @@ func (p *printer) writeEnd(name Name) error {
 	p.writeIndent(-1)
 	p.WriteByte('<')
 	p.WriteByte('/')
-	p.WriteString(name.Local)
+	p.WriteString(p.qualifiedName(name))
 	p.WriteByte('>')
 	p.popPrefix()
 	return nil
@@ -813,7 +813,7 @@ var marshalTests = []struct {
 			D1: "d1",
 		},
 		ExpectXML: `<top xmlns="space">` +
-			`<x><a>a</a><b>b</b><c xmlns="space">c</c>` +
+			`<x><a>a</a><b>b</b><c>c</c>` +
 			`<c xmlns="space1">c1</c>` +
 			`<d xmlns="space1">d1</d>` +
 			`</x>` +
@@ -2058,7 +2058,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "foo"}, "value"},
 		}},
 	},
-	want: `<local xmlns="space" xmlns:_xmlns="xmlns" _xmlns:x="space" xmlns:space="space" space:foo="value">`,
+	want: `<x:local xmlns:x="space" x:foo="value">`,
 }, {
 	desc: "start element with explicit namespace and colliding prefix",
 	toks: []Token{
@@ -2068,7 +2068,7 @@ var encodeTokenTests = []struct {
 			{Name{"x", "bar"}, "other"},
 		}},
 	},
-	want: `<local xmlns="space" xmlns:_xmlns="xmlns" _xmlns:x="space" xmlns:space="space" space:foo="value" xmlns:x="x" x:bar="other">`,
+	want: `<x:local xmlns:x="space" x:foo="value" xmlns:x_1="x" x_1:bar="other">`,
 }, {
 	desc: "start element using previously defined namespace",
 	toks: []Token{
@@ -2079,7 +2079,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "x"}, "y"},
 		}},
 	},
-	want: `<local xmlns:_xmlns="xmlns" _xmlns:x="space"><foo xmlns="space" xmlns:space="space" space:x="y">`,
+	want: `<local xmlns:x="space"><x:foo x:x="y">`,
 }, {
 	desc: "nested name space with same prefix",
 	toks: []Token{
@@ -2100,7 +2100,7 @@ var encodeTokenTests = []struct {
 			{Name{"space2", "b"}, "space2 value"},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:x="space1"><foo _xmlns:x="space2"><foo xmlns:space1="space1" space1:a="space1 value" xmlns:space2="space2" space2:b="space2 value"></foo></foo><foo xmlns:space1="space1" space1:a="space1 value" xmlns:space2="space2" space2:b="space2 value">`,
+	want: `<foo xmlns:x="space1"><foo xmlns:x="space2"><foo xmlns:space1="space1" space1:a="space1 value" x:b="space2 value"></foo></foo><foo x:a="space1 value" xmlns:space2="space2" space2:b="space2 value">`,
 }, {
 	desc: "start element defining several prefixes for the same name space",
 	toks: []Token{
@@ -2110,7 +2110,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "x"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns:_xmlns="xmlns" _xmlns:a="space" _xmlns:b="space" xmlns:space="space" space:x="value">`,
+	want: `<b:foo xmlns:a="space" xmlns:b="space" b:x="value">`,
 }, {
 	desc: "nested element redefines name space",
 	toks: []Token{
@@ -2122,7 +2122,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "a"}, "value"},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:x="space"><foo xmlns="space" _xmlns:y="space" xmlns:space="space" space:a="value">`,
+	want: `<foo xmlns:x="space"><y:foo xmlns:y="space" y:a="value">`,
 }, {
 	desc: "nested element creates alias for default name space",
 	toks: []Token{
@@ -2134,7 +2134,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "a"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo xmlns="space" xmlns:_xmlns="xmlns" _xmlns:y="space" xmlns:space="space" space:a="value">`,
+	want: `<foo xmlns="space"><foo xmlns:y="space" y:a="value">`,
 }, {
 	desc: "nested element defines default name space with existing prefix",
 	toks: []Token{
@@ -2146,7 +2146,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "a"}, "value"},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:x="space"><foo xmlns="space" xmlns="space" xmlns:space="space" space:a="value">`,
+	want: `<foo xmlns:x="space"><foo xmlns="space" x:a="value">`,
 }, {
 	desc: "nested element uses empty attribute name space when default ns defined",
 	toks: []Token{
@@ -2157,7 +2157,7 @@ var encodeTokenTests = []struct {
 			{Name{"", "attr"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo xmlns="space" attr="value">`,
+	want: `<foo xmlns="space"><foo attr="value">`,
 }, {
 	desc: "redefine xmlns",
 	toks: []Token{
@@ -2173,7 +2173,7 @@ var encodeTokenTests = []struct {
 			{Name{"xml", "xmlns"}, "space"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns:_xml="xml" _xml:xmlns="space">`,
+	want: `<foo xmlns="space" xml:xmlns="space">`,
 }, {
 	desc: "xmlns with explicit name space #2",
 	toks: []Token{
@@ -2189,7 +2189,7 @@ var encodeTokenTests = []struct {
 			{Name{"xmlns", "foo"}, ""},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:foo="">`,
+	want: `<foo>`,
 }, {
 	desc: "attribute with no name is ignored",
 	toks: []Token{
@@ -2218,7 +2218,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "x"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo xmlns="" x="value" xmlns:space="space" space:x="value">`,
+	want: `<foo xmlns="space"><foo xmlns="" x="value" xmlns:space="space" space:x="value">`,
 }, {
 	desc: "nested element requires empty default name space",
 	toks: []Token{
@@ -2227,7 +2227,7 @@ var encodeTokenTests = []struct {
 		}},
 		StartElement{Name{"", "foo"}, nil},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo>`,
+	want: `<foo xmlns="space"><foo>`,
 }, {
 	desc: "attribute uses name space from xmlns",
 	toks: []Token{
@@ -2249,7 +2249,7 @@ var encodeTokenTests = []struct {
 		EndElement{Name{"space", "baz"}},
 		EndElement{Name{"space", "foo"}},
 	},
-	want: `<foo xmlns="space" xmlns="space" xmlns:_xmlns="xmlns" _xmlns:bar="space" xmlns:space="space" space:baz="foo"><baz xmlns="space"></baz></foo>`,
+	want: `<foo xmlns="space" xmlns:bar="space" bar:baz="foo"><baz></baz></foo>`,
 }, {
 	desc: "default name space not used by attributes, not explicitly defined",
 	toks: []Token{
@@ -2261,7 +2261,7 @@ var encodeTokenTests = []struct {
 		EndElement{Name{"space", "baz"}},
 		EndElement{Name{"space", "foo"}},
 	},
-	want: `<foo xmlns="space" xmlns="space" xmlns:space="space" space:baz="foo"><baz xmlns="space"></baz></foo>`,
+	want: `<foo xmlns="space" xmlns:space="space" space:baz="foo"><baz></baz></foo>`,
 }, {
 	desc: "impossible xmlns declaration",
 	toks: []Token{
@@ -2272,7 +2272,44 @@ var encodeTokenTests = []struct {
 			{Name{"space", "attr"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space"><bar xmlns="space" xmlns:space="space" space:attr="value">`,
+	want: `<foo xmlns="space"><bar xmlns:space="space" space:attr="value">`,
+}, {
+	desc: "declarations in scope are not repeated",
+	toks: []Token{
+		StartElement{Name{"space", "foo"}, []Attr{
+			{Name{"", "xmlns"}, "space"},
+			{Name{"xmlns", "x"}, "other"},
+		}},
+		StartElement{Name{"space", "bar"}, []Attr{
+			{Name{"", "xmlns"}, "space"},
+			{Name{"xmlns", "x"}, "other"},
+			{Name{"other", "a"}, "value"},
+		}},
+		StartElement{Name{"other", "baz"}, nil},
+		EndElement{Name{"other", "baz"}},
+		EndElement{Name{"space", "bar"}},
+	},
+	want: `<foo xmlns="space" xmlns:x="other"><bar x:a="value"><x:baz></x:baz></bar>`,
+}, {
+	desc: "name space declaration with prefix in local name",
+	toks: []Token{
+		StartElement{Name{"space", "foo"}, []Attr{
+			{Name{"", "xmlns:s"}, "space"},
+		}},
+		EndElement{Name{"space", "foo"}},
+	},
+	want: `<s:foo xmlns:s="space"></s:foo>`,
+}, {
+	desc: "element name space conflicts with explicit default",
+	toks: []Token{
+		StartElement{Name{"space", "foo"}, []Attr{
+			{Name{"", "xmlns"}, "other"},
+		}},
+		StartElement{Name{"other", "bar"}, nil},
+		EndElement{Name{"other", "bar"}},
+		EndElement{Name{"space", "foo"}},
+	},
+	want: `<space:foo xmlns="other" xmlns:space="space"><bar></bar></space:foo>`,
 }}
 
 func TestEncodeToken(t *testing.T) {
@@ -2313,6 +2350,62 @@ loop:
 	}
 }
 
+func TestEncodeTokenRoundTrip(t *testing.T) {
+	const input = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<soap:Body><m:Price xmlns:m="https://example.com/prices" xsi:type="m:Price">` +
+		`<m:Item>Apple</m:Item><Note xmlns="https://example.com/notes" xml:lang="en">fresh</Note>` +
+		`</m:Price></soap:Body></soap:Envelope>`
+	dec := NewDecoder(strings.NewReader(input))
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if err := enc.EncodeToken(tok); err != nil {
+			t.Fatalf("EncodeToken(%#v): %v", tok, err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != input {
+		t.Errorf("round trip mismatch:\ngot  %s\nwant %s", got, input)
+	}
+}
+
+func TestEncoderSetPrefix(t *testing.T) {
+	type Body struct {
+		XMLName Name   `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+		Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+		ID      string `xml:"urn:ids id,attr"`
+	}
+	type Envelope struct {
+		XMLName Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+		Body    Body
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetPrefix("soap", "http://schemas.xmlsoap.org/soap/envelope/")
+	enc.SetPrefix("id", "urn:ids")
+	enc.SetPrefix("xmlfoo", "urn:ignored")
+	v := Envelope{Body: Body{Lang: "en", ID: "7"}}
+	for i := 0; i < 2; i++ {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const want = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<soap:Body xml:lang="en" xmlns:id="urn:ids" id:id="7"></soap:Body></soap:Envelope>`
+	if got := buf.String(); got != want+want {
+		t.Errorf("got  %s\nwant %s", got, want+want)
+	}
+}
+
 func TestProcInstEncodeToken(t *testing.T) {
 	var buf bytes.Buffer
 	enc := NewEncoder(&buf)
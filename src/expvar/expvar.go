@@ -13,6 +13,7 @@
 //
 //	cmdline   os.Args
 //	memstats  runtime.Memstats
+//	metrics   runtime/metrics, keyed by metric name
 //
 // The package is sometimes only imported for the side effect of
 // registering its HTTP handler and the above variables. To use it
@@ -30,6 +31,7 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"runtime/metrics"
 	"sort"
 	"strconv"
 	"sync"
@@ -359,8 +361,48 @@ func memstats() interface{} {
 	return *stats
 }
 
+// metricsHistogram is the JSON form of a metrics.Float64Histogram.
+// Buckets holds the lower bound of each bucket; the last bucket
+// has no upper bound.
+type metricsHistogram struct {
+	Counts  []uint64  `json:"counts"`
+	Buckets []float64 `json:"buckets"`
+}
+
+// runtimeMetrics reads all metrics supported by runtime/metrics.
+// Unlike memstats, it does not stop the world.
+func runtimeMetrics() interface{} {
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i := range samples {
+		samples[i].Name = descs[i].Name
+	}
+	metrics.Read(samples)
+
+	m := make(map[string]interface{}, len(samples))
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			m[s.Name] = s.Value.Uint64()
+		case metrics.KindFloat64:
+			m[s.Name] = s.Value.Float64()
+		case metrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			buckets := make([]float64, len(h.Counts))
+			copy(buckets, h.Buckets)
+			// JSON cannot represent infinities.
+			if len(buckets) > 0 && math.IsInf(buckets[0], -1) {
+				buckets[0] = -math.MaxFloat64
+			}
+			m[s.Name] = metricsHistogram{Counts: h.Counts, Buckets: buckets}
+		}
+	}
+	return m
+}
+
 func init() {
 	http.HandleFunc("/debug/vars", expvarHandler)
 	Publish("cmdline", Func(cmdline))
 	Publish("memstats", Func(memstats))
+	Publish("metrics", Func(runtimeMetrics))
 }
@@ -363,6 +363,27 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+func TestRuntimeMetrics(t *testing.T) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(Func(runtimeMetrics).String()), &m); err != nil {
+		t.Fatalf("metrics is not valid JSON: %v", err)
+	}
+	var goroutines uint64
+	if err := json.Unmarshal(m["/sched/goroutines:goroutines"], &goroutines); err != nil {
+		t.Fatal(err)
+	}
+	if goroutines < 1 {
+		t.Errorf("got %d goroutines, want at least 1", goroutines)
+	}
+	var pauses metricsHistogram
+	if err := json.Unmarshal(m["/gc/pauses:seconds"], &pauses); err != nil {
+		t.Fatal(err)
+	}
+	if len(pauses.Counts) == 0 || len(pauses.Counts) != len(pauses.Buckets) {
+		t.Errorf("got %d counts and %d buckets, want the same non-zero number", len(pauses.Counts), len(pauses.Buckets))
+	}
+}
+
 func BenchmarkRealworldExpvarUsage(b *testing.B) {
 	var (
 		bytesSent Int
@@ -174,12 +174,13 @@ var pkgDeps = map[string][]string{
 	"log": {"L1", "os", "fmt", "time"},
 
 	// Packages used by testing must be low-level (L2+fmt).
-	"regexp":         {"L2", "regexp/syntax"},
-	"regexp/syntax":  {"L2"},
-	"runtime/debug":  {"L2", "fmt", "io/ioutil", "os", "time"},
-	"runtime/pprof":  {"L2", "compress/gzip", "context", "encoding/binary", "fmt", "io/ioutil", "os", "text/tabwriter", "time"},
-	"runtime/trace":  {"L0"},
-	"text/tabwriter": {"L2"},
+	"regexp":          {"L2", "regexp/syntax"},
+	"regexp/syntax":   {"L2"},
+	"runtime/debug":   {"L2", "fmt", "io/ioutil", "os", "time"},
+	"runtime/metrics": {"L0", "math"},
+	"runtime/pprof":   {"L2", "compress/gzip", "context", "encoding/binary", "fmt", "io/ioutil", "os", "text/tabwriter", "time"},
+	"runtime/trace":   {"L0"},
+	"text/tabwriter":  {"L2"},
 
 	"testing":          {"L2", "flag", "fmt", "internal/race", "os", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
 	"testing/iotest":   {"L2", "log"},
@@ -225,16 +226,18 @@ var pkgDeps = map[string][]string{
 
 	// One of a kind.
 	"archive/tar":              {"L4", "OS", "syscall"},
-	"archive/zip":              {"L4", "OS", "compress/flate"},
+	"archive/zip":              {"L4", "OS", "compress/flate", "compress/zstd"},
 	"container/heap":           {"sort"},
 	"compress/bzip2":           {"L4"},
 	"compress/flate":           {"L4"},
 	"compress/gzip":            {"L4", "compress/flate"},
 	"compress/lzw":             {"L4"},
 	"compress/zlib":            {"L4", "compress/flate"},
+	"compress/zstd":            {"L4"},
 	"context":                  {"errors", "fmt", "reflect", "sync", "time"},
 	"database/sql":             {"L4", "container/list", "context", "database/sql/driver", "database/sql/internal"},
 	"database/sql/driver":      {"L4", "context", "time", "database/sql/internal"},
+	"debug/buildinfo":          {"L4", "OS", "debug/elf", "encoding/hex", "runtime/debug"},
 	"debug/dwarf":              {"L4"},
 	"debug/elf":                {"L4", "OS", "debug/dwarf", "compress/zlib"},
 	"debug/gosym":              {"L4"},
@@ -318,7 +321,7 @@ var pkgDeps = map[string][]string{
 
 	// Uses of networking.
 	"log/syslog":    {"L4", "OS", "net"},
-	"net/mail":      {"L4", "NET", "OS", "mime"},
+	"net/mail":      {"L4", "NET", "OS", "mime", "mime/multipart", "mime/quotedprintable"},
 	"net/textproto": {"L4", "OS", "net"},
 
 	// Core crypto.
@@ -380,8 +383,9 @@ var pkgDeps = map[string][]string{
 	"crypto/x509/pkix": {"L4", "CRYPTO-MATH"},
 
 	// Simple net+crypto-aware packages.
-	"mime/multipart": {"L4", "OS", "mime", "crypto/rand", "net/textproto", "mime/quotedprintable"},
-	"net/smtp":       {"L4", "CRYPTO", "NET", "crypto/tls"},
+	"mime/multipart":    {"L4", "OS", "mime", "crypto/rand", "net/textproto", "mime/quotedprintable"},
+	"net/smtp":          {"L4", "CRYPTO", "NET", "context", "crypto/tls"},
+	"net/smtp/smtptest": {"L4", "CRYPTO", "NET", "crypto/tls"},
 
 	// HTTP, kingpin of dependencies.
 	"net/http": {
@@ -404,18 +408,19 @@ var pkgDeps = map[string][]string{
 		"runtime/debug",
 	},
 	"net/http/internal":  {"L4"},
-	"net/http/httptrace": {"context", "crypto/tls", "internal/nettrace", "net", "reflect", "time"},
+	"net/http/httptrace": {"context", "crypto/tls", "internal/nettrace", "net", "net/textproto", "reflect", "time"},
 
 	// HTTP-using packages.
-	"expvar":             {"L4", "OS", "encoding/json", "net/http"},
+	"expvar":             {"L4", "OS", "encoding/json", "net/http", "runtime/metrics"},
 	"net/http/cgi":       {"L4", "NET", "OS", "crypto/tls", "net/http", "regexp"},
 	"net/http/cookiejar": {"L4", "NET", "net/http"},
 	"net/http/fcgi":      {"L4", "NET", "OS", "net/http", "net/http/cgi"},
-	"net/http/httptest":  {"L4", "NET", "OS", "crypto/tls", "flag", "net/http", "net/http/internal", "crypto/x509"},
-	"net/http/httputil":  {"L4", "NET", "OS", "context", "net/http", "net/http/internal"},
-	"net/http/pprof":     {"L4", "OS", "html/template", "net/http", "runtime/pprof", "runtime/trace"},
-	"net/rpc":            {"L4", "NET", "encoding/gob", "html/template", "net/http"},
+	"net/http/httptest":  {"L4", "NET", "OS", "CRYPTO-MATH", "crypto/tls", "flag", "net/http", "net/http/internal", "crypto/x509", "crypto/x509/pkix"},
+	"net/http/httputil":  {"L4", "NET", "OS", "context", "net/http", "net/http/internal", "golang_org/x/net/lex/httplex"},
+	"net/http/pprof":     {"L4", "OS", "html/template", "net/http", "runtime/metrics", "runtime/pprof", "runtime/trace"},
+	"net/rpc":            {"L4", "NET", "context", "encoding/gob", "html/template", "net/http"},
 	"net/rpc/jsonrpc":    {"L4", "NET", "encoding/json", "net/rpc"},
+	"net/rpc/jsonrpc2":   {"L4", "NET", "encoding/json", "net/rpc"},
 }
 
 // isMacro reports whether p is a package dependency macro
@@ -160,7 +160,7 @@ func (c *http2dialCall) dial(addr string) {
 // This code decides which ones live or die.
 // The return value used is whether c was used.
 // c is never closed.
-func (p *http2clientConnPool) addConnIfNeeded(key string, t *http2Transport, c *tls.Conn) (used bool, err error) {
+func (p *http2clientConnPool) addConnIfNeeded(key string, t *http2Transport, c net.Conn) (used bool, err error) {
 	p.mu.Lock()
 	for _, cc := range p.conns[key] {
 		if cc.CanTakeNewRequest() {
@@ -195,7 +195,7 @@ type http2addConnCall struct {
 	err  error
 }
 
-func (c *http2addConnCall) run(t *http2Transport, key string, tc *tls.Conn) {
+func (c *http2addConnCall) run(t *http2Transport, key string, tc net.Conn) {
 	cc, err := t.NewClientConn(tc)
 
 	p := c.p
@@ -262,6 +262,20 @@ func (p *http2clientConnPool) closeIdleConnections() {
 	}
 }
 
+// idleConnStates reports, for each connection in the pool, whether it
+// has no active streams.
+func (p *http2clientConnPool) idleConnStates() map[net.Conn]bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m := make(map[net.Conn]bool, len(p.keys))
+	for cc := range p.keys {
+		cc.mu.Lock()
+		m[cc.tconn] = len(cc.streams) == 0
+		cc.mu.Unlock()
+	}
+	return m
+}
+
 func http2filterOutClientConn(in []*http2ClientConn, exclude *http2ClientConn) []*http2ClientConn {
 	out := in[:0]
 	for _, v := range in {
@@ -307,10 +321,11 @@ func http2configureTransport(t1 *Transport) (*http2Transport, error) {
 	upgradeFn := func(authority string, c *tls.Conn) RoundTripper {
 		addr := http2authorityAddr("https", authority)
 		if used, err := connPool.addConnIfNeeded(addr, t2, c); err != nil {
+			t1.h2ConnClosed(c)
 			go c.Close()
 			return http2erringRoundTripper{err}
 		} else if !used {
-
+			t1.h2ConnClosed(c)
 			go c.Close()
 		}
 		return t2
@@ -325,6 +340,22 @@ func http2configureTransport(t1 *Transport) (*http2Transport, error) {
 	return t2, nil
 }
 
+// idleConnStates reports, for each connection the HTTP/1 Transport
+// handed to t, whether it has no active streams.
+func (t *http2Transport) idleConnStates() map[net.Conn]bool {
+	if p, ok := t.ConnPool.(http2noDialClientConnPool); ok {
+		return p.idleConnStates()
+	}
+	return nil
+}
+
+// connClosed tells the HTTP/1 Transport, if any, that t is done with c.
+func (t *http2Transport) connClosed(c net.Conn) {
+	if t.t1 != nil {
+		t.t1.h2ConnClosed(c)
+	}
+}
+
 // registerHTTPSProtocol calls Transport.RegisterProtocol but
 // convering panics into errors.
 func http2registerHTTPSProtocol(t *Transport, rt RoundTripper) (err error) {
@@ -2242,6 +2273,26 @@ func http2traceGotConn(req *Request, cc *http2ClientConn) {
 	trace.GotConn(ci)
 }
 
+func http2traceWroteHeaderField(trace *http2clientTrace, k, v string) {
+	if trace != nil && trace.WroteHeaderField != nil {
+		trace.WroteHeaderField(k, []string{v})
+	}
+}
+
+func http2traceGot1xxResponse(trace *http2clientTrace, code int, header Header) error {
+	if trace != nil && trace.Got1xxResponse != nil {
+		return trace.Got1xxResponse(code, textproto.MIMEHeader(header))
+	}
+	return nil
+}
+
+func http2traceRetry(req *Request, err error) {
+	trace := httptrace.ContextClientTrace(req.Context())
+	if trace != nil && trace.Retry != nil {
+		trace.Retry(httptrace.RetryInfo{Err: err})
+	}
+}
+
 func http2traceWroteHeaders(trace *http2clientTrace) {
 	if trace != nil && trace.WroteHeaders != nil {
 		trace.WroteHeaders()
@@ -2845,6 +2896,8 @@ func (e *http2httpError) Temporary() bool { return true }
 
 var http2errTimeout error = &http2httpError{msg: "http2: timeout awaiting response headers", timeout: true}
 
+var http2errDeadlineExceeded error = &http2httpError{msg: "http2: deadline exceeded", timeout: true}
+
 type http2connectionStater interface {
 	ConnectionState() tls.ConnectionState
 }
@@ -3223,6 +3276,22 @@ type http2ServeConnOpts struct {
 	// requests. If nil, BaseConfig.Handler is used. If BaseConfig
 	// or BaseConfig.Handler is nil, http.DefaultServeMux is used.
 	Handler Handler
+
+	// SawClientPreface reports whether the client connection
+	// preface has already been read from the connection.
+	SawClientPreface bool
+
+	// UpgradeRequest is an initial request received on a
+	// connection undergoing an h2c upgrade. It is served as
+	// stream 1. Its body must have been completely read from the
+	// connection, and the 101 Switching Protocols response
+	// written, before calling ServeConn.
+	UpgradeRequest *Request
+
+	// Settings are the client's settings from the HTTP2-Settings
+	// header of an h2c upgrade request. They are applied without
+	// being acknowledged.
+	Settings []http2Setting
 }
 
 func (o *http2ServeConnOpts) baseConfig() *Server {
@@ -3254,8 +3323,9 @@ func (o *http2ServeConnOpts) handler() Handler {
 // ConnectionState is used to verify the TLS ciphersuite and to set
 // the Request.TLS field in Handlers.
 //
-// ServeConn does not support h2c by itself. Any h2c support must be
-// implemented in terms of providing a suitably-behaving net.Conn.
+// ServeConn does not detect h2c by itself. The caller detects h2c
+// requests and describes what it has already read from c using the
+// SawClientPreface, UpgradeRequest and Settings options.
 //
 // The opts parameter is optional. If nil, default values are used.
 func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
@@ -3276,6 +3346,7 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 		wantStartPushCh:             make(chan http2startPushRequest, 8),
 		wroteFrameCh:                make(chan http2frameWriteResult, 1),
 		bodyReadCh:                  make(chan http2bodyReadMsg),
+		serveMsgCh:                  make(chan func(), 8),
 		doneServing:                 make(chan struct{}),
 		clientMaxStreams:            math.MaxUint32,
 		advMaxStreams:               s.maxConcurrentStreams(),
@@ -3329,6 +3400,19 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 	if hook := http2testHookGetServerConn; hook != nil {
 		hook(sc)
 	}
+
+	if opts != nil {
+		sc.sawClientPreface = opts.SawClientPreface
+		for _, st := range opts.Settings {
+			if err := sc.processSetting(st); err != nil {
+				sc.rejectConn(http2ErrCodeProtocol, "invalid HTTP2-Settings")
+				return
+			}
+		}
+		if opts.UpgradeRequest != nil {
+			sc.upgradeRequest(opts.UpgradeRequest)
+		}
+	}
 	sc.serve()
 }
 
@@ -3356,6 +3440,7 @@ type http2serverConn struct {
 	wroteFrameCh     chan http2frameWriteResult  // from writeFrameAsync -> serve, tickles more frame writes
 	bodyReadCh       chan http2bodyReadMsg       // from handlers -> serve
 	testHookCh       chan func(int)              // code to run on the serve loop
+	serveMsgCh       chan func()                 // from handlers -> serve; code to run on the serve loop
 	flow             http2flow                   // conn-wide (not stream-specific) outbound flow control
 	inflow           http2flow                   // conn-wide inbound flow control
 	tlsState         *tls.ConnectionState        // shared by all handlers, like net/http
@@ -3365,6 +3450,7 @@ type http2serverConn struct {
 	// Everything following is owned by the serve loop; use serveG.check():
 	serveG                      http2goroutineLock // used to verify funcs are on serve()
 	pushEnabled                 bool
+	sawClientPreface            bool // preface has already been read, used in h2c upgrade
 	sawFirstSettings            bool // got the initial SETTINGS frame after the preface
 	needToSendSettingsAck       bool
 	unackedSettings             int    // how many SETTINGS have we sent without ACKs?
@@ -3445,6 +3531,9 @@ type http2stream struct {
 
 	trailer    Header // accumulated trailers
 	reqTrailer Header // handler's Request.Trailer
+
+	readDeadline  *time.Timer // nil if no read deadline; set by the handler
+	writeDeadline *time.Timer // nil if no write deadline; set by the handler
 }
 
 func (sc *http2serverConn) Framer() *http2Framer { return sc.framer }
@@ -3728,6 +3817,8 @@ func (sc *http2serverConn) serve() {
 			sc.goAway(http2ErrCodeNo)
 		case fn := <-sc.testHookCh:
 			fn(loopNum)
+		case fn := <-sc.serveMsgCh:
+			fn()
 		}
 
 		if sc.inGoAway && sc.curOpenStreams() == 0 && !sc.needToSendGoAway && !sc.writingFrame {
@@ -3739,6 +3830,9 @@ func (sc *http2serverConn) serve() {
 // readPreface reads the ClientPreface greeting from the peer
 // or returns an error on timeout or an invalid greeting.
 func (sc *http2serverConn) readPreface() error {
+	if sc.sawClientPreface {
+		return nil
+	}
 	errc := make(chan error, 1)
 	go func() {
 
@@ -3828,6 +3922,16 @@ func (sc *http2serverConn) writeFrameFromHandler(wr http2FrameWriteRequest) erro
 	}
 }
 
+// sendServeMsg runs fn on the serve goroutine, unless the connection
+// is done serving.
+func (sc *http2serverConn) sendServeMsg(fn func()) {
+	sc.serveG.checkNotOn()
+	select {
+	case sc.serveMsgCh <- fn:
+	case <-sc.doneServing:
+	}
+}
+
 // writeFrame schedules a frame to write and sends it if there's nothing
 // already being written.
 //
@@ -4204,6 +4308,12 @@ func (sc *http2serverConn) closeStream(st *http2stream, err error) {
 		panic(fmt.Sprintf("invariant; can't close stream in state %v", st.state))
 	}
 	st.state = http2stateClosed
+	if st.readDeadline != nil {
+		st.readDeadline.Stop()
+	}
+	if st.writeDeadline != nil {
+		st.writeDeadline.Stop()
+	}
 	if st.isPushed() {
 		sc.curPushedStreams--
 	} else {
@@ -4689,6 +4799,12 @@ func (sc *http2serverConn) newWriterAndRequestNoBody(st *http2stream, rp http2re
 	}
 	req = http2requestWithContext(req, st.ctx)
 
+	rw := sc.newResponseWriter(st, req)
+	rw.rws.body = body
+	return rw, req, nil
+}
+
+func (sc *http2serverConn) newResponseWriter(st *http2stream, req *Request) *http2responseWriter {
 	rws := http2responseWriterStatePool.Get().(*http2responseWriterState)
 	bwSave := rws.bw
 	*rws = http2responseWriterState{}
@@ -4697,10 +4813,31 @@ func (sc *http2serverConn) newWriterAndRequestNoBody(st *http2stream, rp http2re
 	rws.bw.Reset(http2chunkWriter{rws})
 	rws.stream = st
 	rws.req = req
-	rws.body = body
+	return &http2responseWriter{rws: rws}
+}
 
-	rw := &http2responseWriter{rws: rws}
-	return rw, req, nil
+// upgradeRequest starts serving req, the HTTP/1 request that asked
+// to upgrade the connection to h2c, as stream 1. Its body has already
+// been read, so the stream starts half closed.
+func (sc *http2serverConn) upgradeRequest(req *Request) {
+	sc.serveG.check()
+	id := uint32(1)
+	sc.maxClientStreamID = id
+	st := sc.newStream(id, 0, http2stateHalfClosedRemote)
+	st.reqTrailer = req.Trailer
+	if st.reqTrailer != nil {
+		st.trailer = make(Header)
+	}
+	req = http2requestWithContext(req, st.ctx)
+	rw := sc.newResponseWriter(st, req)
+
+	// Disable any read deadline set by the net/http package
+	// prior to the upgrade.
+	if sc.hs.ReadTimeout != 0 {
+		sc.conn.SetReadDeadline(time.Time{})
+	}
+
+	go sc.runHandler(rw, req, sc.handler.ServeHTTP)
 }
 
 // Run on its own goroutine.
@@ -5094,21 +5231,97 @@ func (rws *http2responseWriterState) promoteUndeclaredTrailers() {
 }
 
 func (w *http2responseWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *http2responseWriter) FlushError() error {
 	rws := w.rws
 	if rws == nil {
 		panic("Header called after Handler finished")
 	}
+	var err error
 	if rws.bw.Buffered() > 0 {
-		if err := rws.bw.Flush(); err != nil {
-
-			return
-		}
+		err = rws.bw.Flush()
 	} else {
 
-		rws.writeChunk(nil)
+		_, err = rws.writeChunk(nil)
+	}
+	return err
+}
+
+func (w *http2responseWriter) SetReadDeadline(deadline time.Time) error {
+	st := w.rws.stream
+	if !deadline.IsZero() && deadline.Before(time.Now()) {
+		// A deadline in the past takes effect immediately, so
+		// reads after SetReadDeadline returns will fail.
+		st.onReadTimeout()
+		return nil
+	}
+	w.rws.conn.sendServeMsg(func() {
+		st.setDeadlineTimer(&st.readDeadline, deadline, st.onReadTimeout)
+	})
+	return nil
+}
+
+func (w *http2responseWriter) SetWriteDeadline(deadline time.Time) error {
+	st := w.rws.stream
+	if !deadline.IsZero() && deadline.Before(time.Now()) {
+		// A deadline in the past resets the stream immediately, so
+		// writes after SetWriteDeadline returns will fail.
+		st.onWriteTimeout()
+		return nil
+	}
+	w.rws.conn.sendServeMsg(func() {
+		st.setDeadlineTimer(&st.writeDeadline, deadline, st.onWriteTimeout)
+	})
+	return nil
+}
+
+func (w *http2responseWriter) EnableFullDuplex() error {
+	// We always support full duplex responses, so this is a no-op.
+	return nil
+}
+
+// setDeadlineTimer arranges for fn to be called at deadline, replacing
+// the timer in *t. A zero deadline removes it. It must be called on
+// the serve goroutine.
+func (st *http2stream) setDeadlineTimer(t **time.Timer, deadline time.Time, fn func()) {
+	st.sc.serveG.check()
+	if st.state == http2stateClosed {
+		return
+	}
+	if *t != nil && !(*t).Stop() {
+		// The timer has already fired; the deadline can't be extended.
+		return
+	}
+	switch {
+	case deadline.IsZero():
+		*t = nil
+	case *t == nil:
+		*t = time.AfterFunc(deadline.Sub(time.Now()), fn)
+	default:
+		(*t).Reset(deadline.Sub(time.Now()))
 	}
 }
 
+// onReadTimeout is run on its own goroutine (from time.AfterFunc)
+// when the stream's read deadline passes.
+func (st *http2stream) onReadTimeout() {
+	if st.body != nil {
+		st.body.CloseWithError(http2errDeadlineExceeded)
+	}
+}
+
+// onWriteTimeout is run on its own goroutine (from time.AfterFunc)
+// when the stream's write deadline passes.
+func (st *http2stream) onWriteTimeout() {
+	st.sc.writeFrameFromHandler(http2FrameWriteRequest{write: http2StreamError{
+		StreamID: st.id,
+		Code:     http2ErrCodeInternal,
+		Cause:    http2errDeadlineExceeded,
+	}})
+}
+
 func (w *http2responseWriter) CloseNotify() <-chan bool {
 	rws := w.rws
 	if rws == nil {
@@ -5683,9 +5896,10 @@ type http2clientStream struct {
 	done chan struct{} // closed when stream remove from cc.streams map; close calls guarded by cc.mu
 
 	// owned by clientConnReadLoop:
-	firstByte    bool // got the first response byte
-	pastHeaders  bool // got first MetaHeadersFrame (actual headers)
-	pastTrailers bool // got optional second MetaHeadersFrame (trailers)
+	firstByte    bool  // got the first response byte
+	pastHeaders  bool  // got first MetaHeadersFrame (actual headers)
+	pastTrailers bool  // got optional second MetaHeadersFrame (trailers)
+	num1xx       uint8 // number of 1xx responses seen
 
 	trailer    Header  // accumulated trailers
 	resTrailer *Header // client's Response.Trailer
@@ -5813,7 +6027,9 @@ func (t *http2Transport) RoundTripOpt(req *Request, opt http2RoundTripOpt) (*Res
 		http2traceGotConn(req, cc)
 		res, err := cc.RoundTrip(req)
 		if err != nil {
+			retryErr := err
 			if req, err = http2shouldRetryRequest(req, err); err == nil {
+				http2traceRetry(req, retryErr)
 				continue
 			}
 		}
@@ -6528,14 +6744,20 @@ func (cc *http2ClientConn) encodeHeaders(req *Request, addGzipHeader bool, trail
 		}
 	}
 
-	cc.writeHeader(":authority", host)
-	cc.writeHeader(":method", req.Method)
+	trace := http2requestTrace(req)
+	writeHeader := func(name, value string) {
+		cc.writeHeader(name, value)
+		http2traceWroteHeaderField(trace, name, value)
+	}
+
+	writeHeader(":authority", host)
+	writeHeader(":method", req.Method)
 	if req.Method != "CONNECT" {
-		cc.writeHeader(":path", path)
-		cc.writeHeader(":scheme", req.URL.Scheme)
+		writeHeader(":path", path)
+		writeHeader(":scheme", req.URL.Scheme)
 	}
 	if trailers != "" {
-		cc.writeHeader("trailer", trailers)
+		writeHeader("trailer", trailers)
 	}
 
 	var didUA bool
@@ -6560,17 +6782,17 @@ func (cc *http2ClientConn) encodeHeaders(req *Request, addGzipHeader bool, trail
 			}
 		}
 		for _, v := range vv {
-			cc.writeHeader(lowKey, v)
+			writeHeader(lowKey, v)
 		}
 	}
 	if http2shouldSendReqContentLength(req.Method, contentLength) {
-		cc.writeHeader("content-length", strconv.FormatInt(contentLength, 10))
+		writeHeader("content-length", strconv.FormatInt(contentLength, 10))
 	}
 	if addGzipHeader {
-		cc.writeHeader("accept-encoding", "gzip")
+		writeHeader("accept-encoding", "gzip")
 	}
 	if !didUA {
-		cc.writeHeader("user-agent", http2defaultUserAgent)
+		writeHeader("user-agent", http2defaultUserAgent)
 	}
 	return cc.hbuf.Bytes(), nil
 }
@@ -6705,6 +6927,7 @@ func http2isEOFOrNetReadError(err error) bool {
 
 func (rl *http2clientConnReadLoop) cleanup() {
 	cc := rl.cc
+	defer cc.t.connClosed(cc.tconn)
 	defer cc.tconn.Close()
 	defer cc.t.connPool().MarkDead(cc)
 	defer close(cc.readerDone)
@@ -6873,15 +7096,6 @@ func (rl *http2clientConnReadLoop) handleResponse(cs *http2clientStream, f *http
 		return nil, errors.New("malformed non-numeric status pseudo header")
 	}
 
-	if statusCode == 100 {
-		http2traceGot100Continue(cs.trace)
-		if cs.on100 != nil {
-			cs.on100()
-		}
-		cs.pastHeaders = false
-		return nil, nil
-	}
-
 	header := make(Header)
 	res := &Response{
 		Proto:      "HTTP/2.0",
@@ -6906,6 +7120,25 @@ func (rl *http2clientConnReadLoop) handleResponse(cs *http2clientStream, f *http
 		}
 	}
 
+	if statusCode >= 100 && statusCode <= 199 {
+		cs.num1xx++
+		const max1xxResponses = 5 // arbitrary bound on number of informational responses, same as net/http
+		if cs.num1xx > max1xxResponses {
+			return nil, errors.New("http2: too many 1xx informational responses")
+		}
+		if err := http2traceGot1xxResponse(cs.trace, statusCode, header); err != nil {
+			return nil, err
+		}
+		if statusCode == 100 {
+			http2traceGot100Continue(cs.trace)
+			if cs.on100 != nil {
+				cs.on100()
+			}
+		}
+		cs.pastHeaders = false
+		return nil, nil
+	}
+
 	streamEnded := f.StreamEnded()
 	isHead := cs.req.Method == "HEAD"
 	if !streamEnded || isHead {
@@ -0,0 +1,214 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/2 over cleartext TCP ("h2c"), RFC 7540 section 3.
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+
+	"golang_org/x/net/lex/httplex"
+)
+
+// maxH2CUpgradeBody is the largest request body an h2c upgrade
+// request may have. The body is read into memory before switching
+// protocols; requests with larger bodies are served using HTTP/1.
+const maxH2CUpgradeBody = 1 << 20
+
+// H2CHandler returns a handler that serves HTTP/2 over cleartext TCP
+// ("h2c") as well as HTTP/1, passing every request to h.
+//
+// The returned handler accepts both HTTP/2 connections from clients
+// with prior knowledge, which start with the HTTP/2 connection
+// preface, and HTTP/1.1 requests that ask to switch protocols with
+// an "Upgrade: h2c" header. It passes other requests to h unchanged.
+//
+// An h2c connection is hijacked from the Server that accepted it,
+// though it uses that Server's configuration, and Server.Shutdown and
+// Server.Close ask it to shut down gracefully.
+//
+// An upgrade request whose body is larger than 1MB, or of unknown
+// length, is served using HTTP/1.
+func H2CHandler(h Handler) Handler {
+	return &h2cHandler{h}
+}
+
+type h2cHandler struct {
+	h Handler
+}
+
+func (h *h2cHandler) ServeHTTP(w ResponseWriter, r *Request) {
+	switch {
+	case r.isH2Upgrade():
+		h.servePriorKnowledge(w, r)
+	case isH2CUpgrade(r):
+		h.serveUpgrade(w, r)
+	default:
+		h.h.ServeHTTP(w, r)
+	}
+}
+
+// servePriorKnowledge serves an HTTP/2 connection whose client sent
+// the connection preface straight away. The Server has parsed its
+// first part as a "PRI * HTTP/2.0" request.
+func (h *h2cHandler) servePriorKnowledge(w ResponseWriter, r *Request) {
+	hj, ok := w.(Hijacker)
+	if !ok {
+		Error(w, "h2c not supported", StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	const rest = "SM\r\n\r\n"
+	buf := make([]byte, len(rest))
+	if _, err := io.ReadFull(rw, buf); err != nil || string(buf) != rest {
+		conn.Close()
+		return
+	}
+	h.serveConn(h2cConn{conn, rw.Reader}, r, &http2ServeConnOpts{
+		SawClientPreface: true,
+	})
+}
+
+// serveUpgrade switches the connection of r, an HTTP/1.1 request with
+// an "Upgrade: h2c" header, to HTTP/2, and serves r as its first
+// stream.
+func (h *h2cHandler) serveUpgrade(w ResponseWriter, r *Request) {
+	settings, err := decodeH2CSettings(r.Header["Http2-Settings"])
+	if err != nil {
+		Error(w, err.Error(), StatusBadRequest)
+		return
+	}
+	hj, ok := w.(Hijacker)
+	if !ok || r.ContentLength < 0 || r.ContentLength > maxH2CUpgradeBody {
+		// Ignore the upgrade, as RFC 7540 allows.
+		h.h.ServeHTTP(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		Error(w, "error reading request body", StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.Header.Del("Upgrade")
+	r.Header.Del("Http2-Settings")
+	r.Header.Del("Connection")
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	h.serveConn(h2cConn{conn, rw.Reader}, r, &http2ServeConnOpts{
+		UpgradeRequest: r,
+		Settings:       settings,
+	})
+}
+
+// serveConn serves HTTP/2 on c, which was hijacked while serving r.
+func (h *h2cHandler) serveConn(c net.Conn, r *Request, opts *http2ServeConnOpts) {
+	s2 := new(http2Server)
+	if hs, ok := r.Context().Value(ServerContextKey).(*Server); ok {
+		http2configureServer18(hs, s2)
+		opts.BaseConfig = hs
+	}
+	opts.Handler = h.h
+	s2.ServeConn(c, opts)
+}
+
+// isH2CUpgrade reports whether r asks to upgrade the connection to h2c.
+func isH2CUpgrade(r *Request) bool {
+	return httplex.HeaderValuesContainsToken(r.Header["Upgrade"], "h2c") &&
+		httplex.HeaderValuesContainsToken(r.Header["Connection"], "HTTP2-Settings")
+}
+
+// decodeH2CSettings decodes the value of an HTTP2-Settings header,
+// the base64url encoded payload of a SETTINGS frame.
+func decodeH2CSettings(vv []string) ([]http2Setting, error) {
+	if len(vv) != 1 {
+		return nil, fmt.Errorf("h2c: expected 1 HTTP2-Settings header; got %d", len(vv))
+	}
+	b, err := base64.RawURLEncoding.DecodeString(vv[0])
+	if err != nil {
+		return nil, errors.New("h2c: malformed HTTP2-Settings header")
+	}
+	if len(b)%6 != 0 {
+		return nil, errors.New("h2c: malformed HTTP2-Settings header")
+	}
+	settings := make([]http2Setting, 0, len(b)/6)
+	for ; len(b) > 0; b = b[6:] {
+		settings = append(settings, http2Setting{
+			ID:  http2SettingID(binary.BigEndian.Uint16(b)),
+			Val: binary.BigEndian.Uint32(b[2:]),
+		})
+	}
+	return settings, nil
+}
+
+// h2cConn is a hijacked connection that reads first from the
+// Server's buffered reader.
+type h2cConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c h2cConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// useH2C reports whether requests using cm should be sent with h2c.
+func (t *Transport) useH2C(cm connectMethod) bool {
+	if t.UseH2C == nil || cm.targetScheme != "http" || cm.proxyURL != nil {
+		return false
+	}
+	if !t.UseH2C(cm.addr()) {
+		return false
+	}
+	t.h2cOnce.Do(t.onceSetH2CDefaults)
+	return true
+}
+
+func (t *Transport) onceSetH2CDefaults() {
+	connPool := new(http2clientConnPool)
+	t2 := &http2Transport{
+		ConnPool:  http2noDialClientConnPool{connPool},
+		AllowHTTP: true,
+		t1:        t,
+	}
+	connPool.t = t2
+	t.h2cTransport = t2
+}
+
+// addH2CConn hands c, a new connection to authority ("host:port"),
+// to the h2c client, and returns the RoundTripper to use for it.
+func (t *Transport) addH2CConn(authority string, c net.Conn) RoundTripper {
+	t2 := t.h2cTransport
+	addr := http2authorityAddr("http", authority)
+	connPool := t2.ConnPool.(http2noDialClientConnPool)
+	if used, err := connPool.addConnIfNeeded(addr, t2, c); err != nil {
+		t.h2ConnClosed(c)
+		go c.Close()
+		return http2erringRoundTripper{err}
+	} else if !used {
+		// Another connection to the same host was added
+		// concurrently.
+		t.h2ConnClosed(c)
+		go c.Close()
+	}
+	return t2
+}
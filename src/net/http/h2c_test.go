@@ -0,0 +1,121 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"golang_org/x/net/http2/hpack"
+)
+
+func TestDecodeH2CSettings(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    []http2Setting
+		wantErr bool
+	}{
+		{in: []string{""}, want: []http2Setting{}},
+		{in: []string{"AAQAAP__"}, want: []http2Setting{{http2SettingInitialWindowSize, 65535}}},
+		{in: []string{"AAMAAABkAAQAAP__"}, want: []http2Setting{
+			{http2SettingMaxConcurrentStreams, 100},
+			{http2SettingInitialWindowSize, 65535},
+		}},
+		{in: nil, wantErr: true},
+		{in: []string{"", ""}, wantErr: true},
+		{in: []string{"AAQAAP__="}, wantErr: true},
+		{in: []string{"AAQAAP8"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := decodeH2CSettings(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("decodeH2CSettings(%q) = %v; want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("decodeH2CSettings(%q): %v", tt.in, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("decodeH2CSettings(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{Handler: H2CHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s upgrade=%q", r.Method, body, r.Header.Get("Upgrade"))
+	}))}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "POST / HTTP/1.1\r\n"+
+		"Host: example.com\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\n"+
+		"HTTP2-Settings: AAQAAP__\r\n"+
+		"Content-Length: 5\r\n"+
+		"\r\n"+
+		"hello")
+	br := bufio.NewReader(c)
+	res, err := ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("got response %v %v; want 101 switching to h2c", res.Status, res.Header)
+	}
+
+	// The response to the upgrade request arrives on stream 1.
+	io.WriteString(c, http2ClientPreface)
+	fr := http2NewFramer(c, br)
+	fr.ReadMetaHeaders = hpack.NewDecoder(http2initialHeaderTableSize, nil)
+	if err := fr.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	var body strings.Builder
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Header().StreamID != 1 {
+			continue
+		}
+		switch f := f.(type) {
+		case *http2MetaHeadersFrame:
+			status = f.PseudoValue("status")
+		case *http2DataFrame:
+			body.Write(f.Data())
+		}
+		if sf, ok := f.(http2streamEnder); ok && sf.StreamEnded() {
+			break
+		}
+	}
+	if status != "200" {
+		t.Errorf("status = %q; want 200", status)
+	}
+	if got, want := body.String(), `POST hello upgrade=""`; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+}
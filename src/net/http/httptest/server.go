@@ -32,8 +32,20 @@ type Server struct {
 	// is called, existing fields are copied into the new config.
 	TLS *tls.Config
 
+	// EnableHTTP2 controls whether HTTP/2 is enabled on the
+	// server. It must be set between calling NewUnstartedServer
+	// and calling StartTLS. If set, the server offers HTTP/2 as
+	// well as HTTP/1.1, and Client uses HTTP/2.
+	EnableHTTP2 bool
+
+	// ClientCA, if set before calling StartTLS, makes the server
+	// require a client certificate issued by ClientCA. Client then
+	// presents a certificate from ClientCA, with the subject common
+	// name "httptest client".
+	ClientCA *CA
+
 	// Config may be changed after calling NewUnstartedServer and
-	// before Start or StartTLS.
+	// before Start, StartTLS or StartH2C.
 	Config *http.Server
 
 	// certificate is a parsed version of the TLS config certificate, if present.
@@ -85,8 +97,8 @@ func NewServer(handler http.Handler) *Server {
 
 // NewUnstartedServer returns a new Server but doesn't start it.
 //
-// After changing its configuration, the caller should call Start or
-// StartTLS.
+// After changing its configuration, the caller should call Start,
+// StartTLS or StartH2C.
 //
 // The caller should call Close when finished, to shut it down.
 func NewUnstartedServer(handler http.Handler) *Server {
@@ -130,7 +142,11 @@ func (s *Server) StartTLS() {
 		s.TLS = new(tls.Config)
 	}
 	if s.TLS.NextProtos == nil {
-		s.TLS.NextProtos = []string{"http/1.1"}
+		nextProtos := []string{"http/1.1"}
+		if s.EnableHTTP2 {
+			nextProtos = []string{"h2", "http/1.1"}
+		}
+		s.TLS.NextProtos = nextProtos
 	}
 	if len(s.TLS.Certificates) == 0 {
 		s.TLS.Certificates = []tls.Certificate{cert}
@@ -141,10 +157,17 @@ func (s *Server) StartTLS() {
 	}
 	certpool := x509.NewCertPool()
 	certpool.AddCert(s.certificate)
+	clientConfig := &tls.Config{
+		RootCAs: certpool,
+	}
+	if s.ClientCA != nil {
+		s.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+		s.TLS.ClientCAs = s.ClientCA.CertPool()
+		clientConfig.Certificates = []tls.Certificate{s.ClientCA.IssueClientCert("httptest client")}
+	}
 	s.client.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs: certpool,
-		},
+		TLSClientConfig:   clientConfig,
+		ForceAttemptHTTP2: s.EnableHTTP2,
 	}
 	s.Listener = tls.NewListener(s.Listener, s.TLS)
 	s.URL = "https://" + s.Listener.Addr().String()
@@ -152,6 +175,26 @@ func (s *Server) StartTLS() {
 	s.goServe()
 }
 
+// StartH2C starts a server from NewUnstartedServer that serves
+// HTTP/2 over cleartext TCP ("h2c"), as well as HTTP/1, by wrapping
+// its handler with http.H2CHandler. The client returned by Client
+// sends its requests to the server using h2c.
+func (s *Server) StartH2C() {
+	if s.URL != "" {
+		panic("Server already started")
+	}
+	h := s.Config.Handler
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	s.Config.Handler = http.H2CHandler(h)
+	addr := s.Listener.Addr().String()
+	s.client.Transport = &http.Transport{
+		UseH2C: func(a string) bool { return a == addr },
+	}
+	s.Start()
+}
+
 // NewTLSServer starts and returns a new Server using TLS.
 // The caller should call Close when finished, to shut it down.
 func NewTLSServer(handler http.Handler) *Server {
@@ -264,8 +307,9 @@ func (s *Server) Certificate() *x509.Certificate {
 }
 
 // Client returns an HTTP client configured for making requests to the server.
-// It is configured to trust the server's TLS test certificate and will
-// close its idle connections on Server.Close.
+// It is configured to trust the server's TLS test certificate, to present
+// a client certificate if ClientCA is set, and will close its idle
+// connections on Server.Close.
 func (s *Server) Client() *http.Client {
 	return s.client
 }
@@ -6,6 +6,8 @@ package httptest
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -162,3 +164,119 @@ func TestServerZeroValueClose(t *testing.T) {
 
 	ts.Close() // tests that it doesn't panic
 }
+
+func TestH2CServer(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.StartH2C()
+	defer ts.Close()
+
+	get := func(c *http.Client) string {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(got)
+	}
+	for i := 0; i < 2; i++ {
+		if got := get(ts.Client()); got != "HTTP/2.0" {
+			t.Errorf("request %d: got %q from server's client, want HTTP/2.0", i, got)
+		}
+	}
+	if got := get(&http.Client{Transport: &http.Transport{}}); got != "HTTP/1.1" {
+		t.Errorf("got %q from HTTP/1 client, want HTTP/1.1", got)
+	}
+}
+
+func TestTLSServerHTTP2(t *testing.T) {
+	for _, enable := range []bool{false, true} {
+		ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}))
+		ts.EnableHTTP2 = enable
+		ts.StartTLS()
+
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			ts.Close()
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "HTTP/1.1"
+		if enable {
+			want = "HTTP/2.0"
+		}
+		if string(got) != want {
+			t.Errorf("EnableHTTP2 = %v: got %q, want %q", enable, got, want)
+		}
+	}
+}
+
+func TestTLSServerClientCert(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			t.Error("request without client certificate")
+			return
+		}
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.ClientCA = NewCA()
+	ts.StartTLS()
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "httptest client" {
+		t.Errorf("got %q, want %q", got, "httptest client")
+	}
+
+	// A client with a certificate from another CA is refused.
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	c := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{NewCA().IssueClientCert("other")},
+		},
+	}}
+	if res, err := c.Get(ts.URL); err == nil {
+		res.Body.Close()
+		t.Error("request with untrusted client certificate succeeded")
+	}
+}
+
+func TestCAIssueClientCert(t *testing.T) {
+	ca := NewCA()
+	cert := ca.IssueClientCert("alice")
+	if cert.Leaf.Subject.CommonName != "alice" {
+		t.Errorf("CommonName = %q, want alice", cert.Leaf.Subject.CommonName)
+	}
+	_, err := cert.Leaf.Verify(x509.VerifyOptions{
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if !ca.Certificate().IsCA {
+		t.Error("CA certificate is not a CA")
+	}
+}
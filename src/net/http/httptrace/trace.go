@@ -11,6 +11,7 @@ import (
 	"crypto/tls"
 	"internal/nettrace"
 	"net"
+	"net/textproto"
 	"reflect"
 	"time"
 )
@@ -107,6 +108,14 @@ type ClientTrace struct {
 	// Continue" response.
 	Got100Continue func()
 
+	// Got1xxResponse is called for each 1xx informational response
+	// header returned before the final non-1xx response, such as
+	// "103 Early Hints". Got1xxResponse is called for "100 Continue"
+	// responses, even if Got100Continue is also defined. If it
+	// returns an error, the client request is aborted with that
+	// error value.
+	Got1xxResponse func(code int, header textproto.MIMEHeader) error
+
 	// DNSStart is called when a DNS lookup begins.
 	DNSStart func(DNSStartInfo)
 
@@ -135,6 +144,11 @@ type ClientTrace struct {
 	// failure.
 	TLSHandshakeDone func(tls.ConnectionState, error)
 
+	// WroteHeaderField is called after the Transport has written
+	// each request header. At the time of this call the values
+	// might be buffered and not yet written to the network.
+	WroteHeaderField func(key string, value []string)
+
 	// WroteHeaders is called after the Transport has written
 	// the request headers.
 	WroteHeaders func()
@@ -149,6 +163,13 @@ type ClientTrace struct {
 	// request and any body. It may be called multiple times
 	// in the case of retried requests.
 	WroteRequest func(WroteRequestInfo)
+
+	// Retry is called when the Transport is about to send the
+	// request again, on another connection, after an attempt on
+	// a reused connection failed in a way that made it safe to
+	// retry. GetConn and the hooks that follow it are called again
+	// for the new attempt.
+	Retry func(RetryInfo)
 }
 
 // WroteRequestInfo contains information provided to the WroteRequest
@@ -158,6 +179,13 @@ type WroteRequestInfo struct {
 	Err error
 }
 
+// RetryInfo contains information provided to the Retry hook.
+type RetryInfo struct {
+	// Err is the error from the failed attempt that caused
+	// the retry.
+	Err error
+}
+
 // compose modifies t such that it respects the previously-registered hooks in old,
 // subject to the composition policy requested in t.Compose.
 func (t *ClientTrace) compose(old *ClientTrace) {
@@ -186,9 +214,13 @@ func (t *ClientTrace) compose(old *ClientTrace) {
 		// creates a recursive call cycle and stack overflows)
 		tfCopy := reflect.ValueOf(tf.Interface())
 
-		// We need to call both tf and of in some order.
+		// We need to call both tf and of in some order. A hook
+		// that returns a non-nil error, such as Got1xxResponse,
+		// aborts the request, so don't call of after it.
 		newFunc := reflect.MakeFunc(hookType, func(args []reflect.Value) []reflect.Value {
-			tfCopy.Call(args)
+			if ret := tfCopy.Call(args); len(ret) == 1 && !ret[0].IsNil() {
+				return ret
+			}
 			return of.Call(args)
 		})
 		tv.Field(i).Set(newFunc)
@@ -7,6 +7,9 @@ package httptrace
 import (
 	"bytes"
 	"context"
+	"errors"
+	"net/textproto"
+	"reflect"
 	"testing"
 )
 
@@ -87,3 +90,33 @@ func TestCompose(t *testing.T) {
 	}
 
 }
+
+func TestComposeStopsOnError(t *testing.T) {
+	var calls []string
+	hook := func(name string, err error) func(int, textproto.MIMEHeader) error {
+		return func(int, textproto.MIMEHeader) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	wantErr := errors.New("stop")
+
+	tr := &ClientTrace{Got1xxResponse: hook("new", wantErr)}
+	tr.compose(&ClientTrace{Got1xxResponse: hook("old", nil)})
+	if err := tr.Got1xxResponse(103, nil); err != wantErr {
+		t.Errorf("Got1xxResponse = %v; want %v", err, wantErr)
+	}
+	if !reflect.DeepEqual(calls, []string{"new"}) {
+		t.Errorf("calls = %q; want only the new hook", calls)
+	}
+
+	calls = nil
+	tr = &ClientTrace{Got1xxResponse: hook("new", nil)}
+	tr.compose(&ClientTrace{Got1xxResponse: hook("old", wantErr)})
+	if err := tr.Got1xxResponse(103, nil); err != wantErr {
+		t.Errorf("Got1xxResponse = %v; want %v", err, wantErr)
+	}
+	if !reflect.DeepEqual(calls, []string{"new", "old"}) {
+		t.Errorf("calls = %q; want both hooks", calls)
+	}
+}
@@ -170,7 +170,9 @@ func TestReverseProxyStripHeadersPresentInConnection(t *testing.T) {
 	defer frontend.Close()
 
 	getReq, _ := http.NewRequest("GET", frontend.URL, nil)
-	getReq.Header.Set("Connection", "Upgrade, "+fakeConnectionToken)
+	// Upgrade is not listed in Connection, so this is not an upgrade
+	// request and the Upgrade header is stripped as hop-by-hop.
+	getReq.Header.Set("Connection", fakeConnectionToken)
 	getReq.Header.Set("Upgrade", "original value")
 	getReq.Header.Set(fakeConnectionToken, "should be deleted")
 	res, err := frontend.Client().Do(getReq)
@@ -668,6 +670,150 @@ func TestReverseProxy_CopyBuffer(t *testing.T) {
 	}
 }
 
+func TestReverseProxyErrorHandler(t *testing.T) {
+	var gotErr error
+	rproxy := &ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = "backend.example"
+		},
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("backend unreachable")
+		}),
+		ErrorHandler: func(rw http.ResponseWriter, req *http.Request, err error) {
+			gotErr = err
+			rw.WriteHeader(http.StatusTeapot)
+		},
+	}
+	rw := httptest.NewRecorder()
+	rproxy.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if rw.Code != http.StatusTeapot {
+		t.Errorf("status = %d; want %d", rw.Code, http.StatusTeapot)
+	}
+	if gotErr == nil || gotErr.Error() != "backend unreachable" {
+		t.Errorf("ErrorHandler got error %v; want backend unreachable", gotErr)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// upgradeBackend returns a handler that switches to a line echo
+// protocol named upType, whatever the client asked for.
+func upgradeBackend(t *testing.T, upType string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "" || r.Header.Get("Connection") != "Upgrade" {
+			t.Errorf("backend got Upgrade %q, Connection %q", r.Header.Get("Upgrade"), r.Header.Get("Connection"))
+			http.Error(w, "not an upgrade", http.StatusBadRequest)
+			return
+		}
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: "+upType+"\r\n\r\n")
+		bs := bufio.NewScanner(c)
+		if !bs.Scan() {
+			// The proxy gave up on the connection.
+			return
+		}
+		fmt.Fprintf(c, "backend got %q\n", bs.Text())
+	})
+}
+
+func TestReverseProxyWebSocket(t *testing.T) {
+	backendServer := httptest.NewServer(upgradeBackend(t, "websocket"))
+	defer backendServer.Close()
+
+	backURL, _ := url.Parse(backendServer.URL)
+	rproxy := NewSingleHostReverseProxy(backURL)
+	rproxy.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	rproxy.ModifyResponse = func(res *http.Response) error {
+		if _, ok := res.Body.(io.ReadWriteCloser); !ok {
+			t.Errorf("ModifyResponse got Body of type %T; want an io.ReadWriteCloser", res.Body)
+		}
+		res.Header.Add("X-Modified", "true")
+		return nil
+	}
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Header", "X-Value")
+		rproxy.ServeHTTP(rw, req)
+	})
+	frontendProxy := httptest.NewServer(handler)
+	defer frontendProxy.Close()
+
+	req, _ := http.NewRequest("GET", frontendProxy.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	c := frontendProxy.Client()
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %v; want 101", res.Status)
+	}
+	if got, want := res.Header.Get("X-Header"), "X-Value"; got != want {
+		t.Errorf("X-Header = %q; want %q", got, want)
+	}
+	if got, want := res.Header.Get("X-Modified"), "true"; got != want {
+		t.Errorf("X-Modified = %q; want %q", got, want)
+	}
+	if got, want := res.Header.Get("Upgrade"), "websocket"; got != want {
+		t.Errorf("Upgrade = %q; want %q", got, want)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("response body is of type %T; does not implement ReadWriteCloser", res.Body)
+	}
+	defer rwc.Close()
+
+	io.WriteString(rwc, "Hello\n")
+	bs := bufio.NewScanner(rwc)
+	if !bs.Scan() {
+		t.Fatalf("Scan: %v", bs.Err())
+	}
+	if got, want := bs.Text(), `backend got "Hello"`; got != want {
+		t.Errorf("got %#q; want %#q", got, want)
+	}
+}
+
+func TestReverseProxyUpgradeMismatch(t *testing.T) {
+	backendServer := httptest.NewServer(upgradeBackend(t, "other"))
+	defer backendServer.Close()
+
+	backURL, _ := url.Parse(backendServer.URL)
+	rproxy := NewSingleHostReverseProxy(backURL)
+	var gotErr error
+	rproxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		gotErr = err
+		rw.WriteHeader(http.StatusBadGateway)
+	}
+	frontendProxy := httptest.NewServer(rproxy)
+	defer frontendProxy.Close()
+
+	req, _ := http.NewRequest("GET", frontendProxy.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res, err := frontendProxy.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %v; want 502", res.Status)
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), `"other"`) {
+		t.Errorf("ErrorHandler got error %v; want protocol mismatch", gotErr)
+	}
+}
+
 type staticTransport struct {
 	res *http.Response
 }
@@ -37,6 +37,10 @@
 //
 //	wget http://localhost:6060/debug/pprof/trace?seconds=5
 //
+// Or to read the current values of all runtime/metrics metrics:
+//
+//	wget http://localhost:6060/debug/pprof/metrics
+//
 // To view all available profiles, open http://localhost:6060/debug/pprof/
 // in your browser.
 //
@@ -56,6 +60,7 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"runtime/metrics"
 	"runtime/pprof"
 	"runtime/trace"
 	"strconv"
@@ -66,6 +71,7 @@ import (
 func init() {
 	http.Handle("/debug/pprof/", http.HandlerFunc(Index))
 	http.Handle("/debug/pprof/cmdline", http.HandlerFunc(Cmdline))
+	http.Handle("/debug/pprof/metrics", http.HandlerFunc(Metrics))
 	http.Handle("/debug/pprof/profile", http.HandlerFunc(Profile))
 	http.Handle("/debug/pprof/symbol", http.HandlerFunc(Symbol))
 	http.Handle("/debug/pprof/trace", http.HandlerFunc(Trace))
@@ -209,6 +215,41 @@ func Symbol(w http.ResponseWriter, r *http.Request) {
 	w.Write(buf.Bytes())
 }
 
+// Metrics responds with the current value of every metric supported
+// by runtime/metrics, one per line, in the form "name value".
+// Histograms are written as one line per non-empty bucket, in the
+// form "name [lower, upper) count".
+// The package initialization registers it as /debug/pprof/metrics.
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i := range samples {
+		samples[i].Name = descs[i].Name
+	}
+	metrics.Read(samples)
+
+	var buf bytes.Buffer
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			fmt.Fprintf(&buf, "%s %d\n", s.Name, s.Value.Uint64())
+		case metrics.KindFloat64:
+			fmt.Fprintf(&buf, "%s %g\n", s.Name, s.Value.Float64())
+		case metrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			for i, c := range h.Counts {
+				if c == 0 {
+					continue
+				}
+				fmt.Fprintf(&buf, "%s [%g, %g) %d\n", s.Name, h.Buckets[i], h.Buckets[i+1], c)
+			}
+		}
+	}
+	w.Write(buf.Bytes())
+}
+
 // Handler returns an HTTP handler that serves the named profile.
 func Handler(name string) http.Handler {
 	return handler(name)
@@ -267,6 +308,7 @@ profiles:<br>
 </table>
 <br>
 <a href="goroutine?debug=2">full goroutine stack dump</a><br>
+<a href="metrics">runtime metrics</a><br>
 </body>
 </html>
 `))
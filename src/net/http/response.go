@@ -17,6 +17,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"golang_org/x/net/lex/httplex"
 )
 
 var respExcludeHeader = map[string]bool{
@@ -57,6 +59,12 @@ type Response struct {
 	//
 	// The Body is automatically dechunked if the server replied
 	// with a "chunked" Transfer-Encoding.
+	//
+	// On a successful "101 Switching Protocols" response, as used by
+	// WebSockets and HTTP/2's "h2c" mode, the Body returned by the
+	// Transport also implements io.Writer. Reading and writing it
+	// then use the underlying connection, which the caller now owns
+	// and must close by closing the Body.
 	Body io.ReadCloser
 
 	// ContentLength records the length of the associated content. The
@@ -214,6 +222,23 @@ func (r *Response) ProtoAtLeast(major, minor int) bool {
 		r.ProtoMajor == major && r.ProtoMinor >= minor
 }
 
+// isProtocolSwitch reports whether r is a response to a successful
+// protocol upgrade.
+func (r *Response) isProtocolSwitch() bool {
+	return r.StatusCode == StatusSwitchingProtocols &&
+		r.Header.Get("Upgrade") != "" &&
+		httplex.HeaderValuesContainsToken(r.Header["Connection"], "Upgrade")
+}
+
+// bodyIsWritable reports whether the Body supports writing. The
+// Transport returns writable bodies for 101 Switching Protocols
+// responses. Once the Transport has returned such a body, it is done
+// managing the connection.
+func (r *Response) bodyIsWritable() bool {
+	_, ok := r.Body.(io.Writer)
+	return ok
+}
+
 // Write writes r to w in the HTTP/1.x server response format,
 // including the status line, headers, body, and optional trailer.
 //
@@ -287,7 +312,7 @@ func (r *Response) Write(w io.Writer) error {
 	if err != nil {
 		return err
 	}
-	err = tw.WriteHeader(w)
+	err = tw.WriteHeader(w, nil)
 	if err != nil {
 		return err
 	}
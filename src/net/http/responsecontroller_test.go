@@ -0,0 +1,282 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wrappedResponseWriter struct {
+	ResponseWriter
+}
+
+func (w wrappedResponseWriter) Unwrap() ResponseWriter { return w.ResponseWriter }
+
+func TestResponseControllerFlush_h1(t *testing.T) { testResponseControllerFlush(t, h1Mode) }
+func TestResponseControllerFlush_h2(t *testing.T) { testResponseControllerFlush(t, h2Mode) }
+func testResponseControllerFlush(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	continuec := make(chan struct{})
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(wrappedResponseWriter{w})
+		io.WriteString(w, "one")
+		if err := ctl.Flush(); err != nil {
+			t.Errorf("ctl.Flush() = %v, want nil", err)
+			return
+		}
+		<-continuec
+		io.WriteString(w, "two")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		close(continuec)
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	buf := make([]byte, 16)
+	n, err := res.Body.Read(buf)
+	close(continuec)
+	if err != nil || string(buf[:n]) != "one" {
+		t.Fatalf("Body.Read = %q, %v, want %q, nil", buf[:n], err, "one")
+	}
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil || string(got) != "two" {
+		t.Fatalf("Body.Read = %q, %v, want %q, nil", got, err, "two")
+	}
+}
+
+func TestResponseControllerHijack(t *testing.T) {
+	defer afterTest(t)
+	const header = "X-Header"
+	const value = "set"
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(wrappedResponseWriter{w})
+		c, _, err := ctl.Hijack()
+		if err != nil {
+			t.Errorf("ctl.Hijack() = %v, want nil", err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.0 200 OK\r\n"+header+": "+value+"\r\nContent-Length: 0\r\n\r\n")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got := res.Header.Get(header); got != value {
+		t.Errorf("response header %q = %q, want %q", header, got, value)
+	}
+}
+
+func TestResponseControllerSetPastWriteDeadline_h1(t *testing.T) {
+	testResponseControllerSetPastWriteDeadline(t, h1Mode)
+}
+func TestResponseControllerSetPastWriteDeadline_h2(t *testing.T) {
+	testResponseControllerSetPastWriteDeadline(t, h2Mode)
+}
+func testResponseControllerSetPastWriteDeadline(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	errc := make(chan error, 1)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(w)
+		io.WriteString(w, "one")
+		if err := ctl.Flush(); err != nil {
+			errc <- err
+			return
+		}
+		if err := ctl.SetWriteDeadline(time.Now().Add(-10 * time.Second)); err != nil {
+			errc <- err
+			return
+		}
+		chunk := strings.Repeat("x", 1<<10)
+		for i := 0; i < 1<<12; i++ {
+			if _, err := io.WriteString(w, chunk); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- ctl.Flush()
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("handler wrote 4MB after write deadline without error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for handler")
+	}
+}
+
+func TestResponseControllerSetPastReadDeadline_h1(t *testing.T) {
+	testResponseControllerSetPastReadDeadline(t, h1Mode)
+}
+func TestResponseControllerSetPastReadDeadline_h2(t *testing.T) {
+	testResponseControllerSetPastReadDeadline(t, h2Mode)
+}
+func testResponseControllerSetPastReadDeadline(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	readc := make(chan struct{})
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		defer close(readc)
+		ctl := NewResponseController(w)
+		b := make([]byte, 3)
+		n, err := io.ReadFull(r.Body, b)
+		b = b[:n]
+		if err != nil || string(b) != "one" {
+			t.Errorf("before setting read deadline: Read = %v, %q, want nil, %q", err, string(b), "one")
+			return
+		}
+		if err := ctl.SetReadDeadline(time.Now()); err != nil {
+			t.Errorf("ctl.SetReadDeadline() = %v, want nil", err)
+			return
+		}
+		b, err = ioutil.ReadAll(r.Body)
+		if err == nil || string(b) != "" {
+			t.Errorf("after setting read deadline: Read = %q, nil, want error", string(b))
+		}
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Errorf("after setting read deadline: Read error = %v, want a timeout", err)
+		}
+		// Clear the deadline so finishing the request doesn't fail.
+		ctl.SetReadDeadline(time.Time{})
+	}))
+	defer cst.close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("one"))
+		<-readc
+		pw.Close()
+	}()
+	req, _ := NewRequest("POST", cst.ts.URL, pr)
+	res, err := cst.c.Do(req)
+	if err == nil {
+		res.Body.Close()
+	}
+	<-readc
+}
+
+// fullDuplexEchoHandler echoes each line of the request body as soon
+// as it is read.
+func fullDuplexEchoHandler(t *testing.T) Handler {
+	return HandlerFunc(func(w ResponseWriter, req *Request) {
+		ctl := NewResponseController(w)
+		if err := ctl.EnableFullDuplex(); err != nil {
+			t.Errorf("ctl.EnableFullDuplex() = %v, want nil", err)
+			return
+		}
+		w.WriteHeader(200)
+		ctl.Flush()
+		sc := bufio.NewScanner(req.Body)
+		for sc.Scan() {
+			io.WriteString(w, sc.Text()+"\n")
+			ctl.Flush()
+		}
+	})
+}
+
+// testFullDuplexEcho sends lines to a fullDuplexEchoHandler using
+// send, expecting each to be echoed in body before sending the next.
+func testFullDuplexEcho(t *testing.T, send func(line string), done func(), body io.Reader) {
+	br := bufio.NewReader(body)
+	for _, line := range []string{"one", "two", "three"} {
+		send(line + "\n")
+		got, err := br.ReadString('\n')
+		if err != nil || got != line+"\n" {
+			t.Fatalf("echo = %q, %v; want %q", got, err, line+"\n")
+		}
+	}
+	done()
+	if rest, err := ioutil.ReadAll(br); err != nil || len(rest) != 0 {
+		t.Errorf("remaining body = %q, %v; want empty", rest, err)
+	}
+}
+
+// The HTTP/1 Transport doesn't send a request's headers until it has
+// written the body, so this test talks to the server directly.
+func TestResponseControllerEnableFullDuplex_h1(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(fullDuplexEchoHandler(t))
+	defer ts.Close()
+
+	c, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "POST / HTTP/1.1\r\nHost: foo\r\nTransfer-Encoding: chunked\r\n\r\n")
+	res, err := ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	testFullDuplexEcho(t, func(line string) {
+		fmt.Fprintf(c, "%x\r\n%s\r\n", len(line), line)
+	}, func() {
+		io.WriteString(c, "0\r\n\r\n")
+	}, res.Body)
+}
+
+func TestResponseControllerEnableFullDuplex_h2(t *testing.T) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2Mode, fullDuplexEchoHandler(t))
+	defer cst.close()
+
+	pr, pw := io.Pipe()
+	req, _ := NewRequest("POST", cst.ts.URL, pr)
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	testFullDuplexEcho(t, func(line string) {
+		io.WriteString(pw, line)
+	}, func() {
+		pw.Close()
+	}, res.Body)
+}
+
+func TestResponseControllerNotSupported(t *testing.T) {
+	// A ResponseWriter that implements nothing beyond the interface.
+	type plainWriter struct{ ResponseWriter }
+	ctl := NewResponseController(plainWriter{})
+	if err := ctl.Flush(); err != ErrNotSupported {
+		t.Errorf("Flush = %v; want ErrNotSupported", err)
+	}
+	if _, _, err := ctl.Hijack(); err != ErrNotSupported {
+		t.Errorf("Hijack = %v; want ErrNotSupported", err)
+	}
+	if err := ctl.SetReadDeadline(time.Time{}); err != ErrNotSupported {
+		t.Errorf("SetReadDeadline = %v; want ErrNotSupported", err)
+	}
+	if err := ctl.SetWriteDeadline(time.Time{}); err != ErrNotSupported {
+		t.Errorf("SetWriteDeadline = %v; want ErrNotSupported", err)
+	}
+	if err := ctl.EnableFullDuplex(); err != ErrNotSupported {
+		t.Errorf("EnableFullDuplex = %v; want ErrNotSupported", err)
+	}
+}
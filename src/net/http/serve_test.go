@@ -461,6 +461,230 @@ func TestMuxRedirectLeadingSlashes(t *testing.T) {
 	}
 }
 
+func TestServeMuxPatterns(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	for _, pat := range []string{
+		"/",
+		"/{$}",
+		"GET /users/{id}",
+		"DELETE /users/{id}",
+		"GET /users/{id}/posts/{post}",
+		"GET /users/me",
+		"/files/{path...}",
+		"GET /static/",
+		"example.com/users/{id}",
+	} {
+		pat := pat
+		mux.HandleFunc(pat, func(w ResponseWriter, r *Request) {
+			w.Header().Set("Pattern", pat)
+		})
+	}
+	for _, tt := range []struct {
+		method string
+		host   string
+		path   string
+		code   int
+		want   string // pattern
+	}{
+		{"GET", "", "/", 200, "/{$}"},
+		{"GET", "", "/other", 200, "/"},
+		{"GET", "", "/users/42", 200, "GET /users/{id}"},
+		{"HEAD", "", "/users/42", 200, "GET /users/{id}"},
+		{"DELETE", "", "/users/42", 200, "DELETE /users/{id}"},
+		{"GET", "", "/users/me", 200, "GET /users/me"},
+		{"GET", "", "/users/42/posts/7", 200, "GET /users/{id}/posts/{post}"},
+		{"GET", "", "/files/a/b/c", 200, "/files/{path...}"},
+		{"POST", "", "/files/", 200, "/files/{path...}"},
+		{"GET", "", "/static/css/x.css", 200, "GET /static/"},
+		{"GET", "example.com", "/users/42", 200, "example.com/users/{id}"},
+		{"GET", "example.com:8080", "/users/42", 200, "example.com/users/{id}"},
+		{"GET", "", "/files", 301, "/files/{path...}"},
+		{"GET", "", "/static", 301, "GET /static/"},
+	} {
+		r := &Request{Method: tt.method, Host: tt.host, URL: &url.URL{Path: tt.path}}
+		h, pattern := mux.Handler(r)
+		if pattern != tt.want {
+			t.Errorf("%s %s%s: pattern = %q, want %q", tt.method, tt.host, tt.path, pattern, tt.want)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		if rr.Code != tt.code {
+			t.Errorf("%s %s%s: code = %d, want %d", tt.method, tt.host, tt.path, rr.Code, tt.code)
+		}
+	}
+}
+
+func TestServeMuxEscapedSlash(t *testing.T) {
+	setParallel(t)
+	handle := func(mux *ServeMux, pat string) {
+		mux.HandleFunc(pat, func(w ResponseWriter, r *Request) {
+			w.Header().Set("Pattern", pat)
+		})
+	}
+	for _, tt := range []struct {
+		wildcard string // additional pattern, if any
+		path     string
+		code     int
+		want     string // pattern
+	}{
+		// Without wildcards, escaped slashes match like slashes,
+		// as they did before patterns could have wildcards.
+		{"", "/a%2Fb", 200, "/a/b"},
+		{"", "/a%2fb", 200, "/a/b"},
+		{"", "/dir%2Ffile", 200, "/dir/"},
+		{"", "/a%2F..%2Fdir/", 301, "/dir/"},
+		{"", "/a%252Fb", 404, ""},
+
+		// A wildcard can match an escaped slash.
+		{"/x/{name}", "/x/a%2Fb", 200, "/x/{name}"},
+		{"/x/{name}", "/a%2Fb", 404, ""},
+		{"/x/{name}", "/dir%2Ffile", 404, ""},
+		{"/x/{name}", "/dir/file", 200, "/dir/"},
+		{"/a%2Fb", "/a%2Fb", 200, "/a%2Fb"},
+	} {
+		mux := NewServeMux()
+		handle(mux, "/a/b")
+		handle(mux, "/dir/")
+		if tt.wildcard != "" {
+			handle(mux, tt.wildcard)
+		}
+		u, err := url.Parse(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &Request{Method: "GET", URL: u}
+		h, pattern := mux.Handler(r)
+		if pattern != tt.want {
+			t.Errorf("with %q, %s: pattern = %q, want %q", tt.wildcard, tt.path, pattern, tt.want)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		if rr.Code != tt.code {
+			t.Errorf("with %q, %s: code = %d, want %d", tt.wildcard, tt.path, rr.Code, tt.code)
+		}
+	}
+}
+
+func TestServeMuxMethodNotAllowed(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("PUT /users/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("GET /other", func(w ResponseWriter, r *Request) {})
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+		allow        string
+	}{
+		{"POST", "/users/1", 405, "GET, HEAD, PUT"},
+		{"DELETE", "/other", 405, "GET, HEAD"},
+		{"GET", "/nothing", 404, ""},
+	} {
+		req := &Request{Method: tt.method, URL: &url.URL{Path: tt.path}}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != tt.code {
+			t.Errorf("%s %s: code = %d, want %d", tt.method, tt.path, rr.Code, tt.code)
+		}
+		if got := rr.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+	}
+}
+
+func TestServeMuxPathValue(t *testing.T) {
+	setParallel(t)
+	for _, tt := range []struct {
+		pattern string
+		path    string
+		want    map[string]string
+	}{
+		{
+			"/{a}/is/{b}/{c...}",
+			"/now/is/the/time/for/all",
+			map[string]string{
+				"a": "now",
+				"b": "the",
+				"c": "time/for/all",
+				"d": "",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/%2fjohn/address",
+			map[string]string{
+				"name":  "/john",
+				"other": "address",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/john%2Fdoe/there/is%2F/more",
+			map[string]string{
+				"name":  "john/doe",
+				"other": "there/is//more",
+			},
+		},
+	} {
+		mux := NewServeMux()
+		mux.HandleFunc(tt.pattern, func(w ResponseWriter, r *Request) {
+			for name, want := range tt.want {
+				if got := r.PathValue(name); got != want {
+					t.Errorf("%q, %q: PathValue(%q) = %q, want %q", tt.pattern, tt.path, name, got, want)
+				}
+			}
+			r.SetPathValue("a", "x")
+			if got := r.PathValue("a"); got != "x" {
+				t.Errorf("after SetPathValue: PathValue(%q) = %q, want %q", "a", got, "x")
+			}
+		})
+		u, err := url.Parse(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), &Request{Method: "GET", URL: u})
+	}
+}
+
+func TestServeMuxRegisterConflict(t *testing.T) {
+	for _, tt := range []struct {
+		p1, p2 string
+		want   string // substring of the panic value, or "" for no panic
+	}{
+		{"/a", "/a", "matches the same requests"},
+		{"/users/{id}", "/users/{name}", "matches the same requests"},
+		{"/a/{x}", "/{y}/b", "both match some paths"},
+		{"GET /", "/index.html", "matches more methods"},
+		{"/users/{id}", "/users/me", ""},
+		{"GET /a", "POST /a", ""},
+		{"/a", "example.com/a", ""},
+	} {
+		mux := NewServeMux()
+		mux.Handle(tt.p1, serve(200))
+		got := ""
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					got = fmt.Sprint(e)
+					if got == "" {
+						got = "empty panic"
+					}
+				}
+			}()
+			mux.Handle(tt.p2, serve(200))
+		}()
+		if tt.want == "" {
+			if got != "" {
+				t.Errorf("registering %q then %q: unexpected panic: %s", tt.p1, tt.p2, got)
+			}
+		} else if !strings.Contains(got, tt.want) {
+			t.Errorf("registering %q then %q: panic = %q, want it to contain %q", tt.p1, tt.p2, got, tt.want)
+		}
+	}
+}
+
 func BenchmarkServeMux(b *testing.B) {
 
 	type test struct {
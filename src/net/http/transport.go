@@ -21,6 +21,7 @@ import (
 	"log"
 	"net"
 	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
 	"os"
 	"strings"
@@ -61,6 +62,9 @@ const DefaultMaxIdleConnsPerHost = 2
 // This may leave many open connections when accessing many hosts.
 // This behavior can be managed using Transport's CloseIdleConnections method
 // and the MaxIdleConnsPerHost and DisableKeepAlives fields.
+// The number of connections to a single host can be limited with
+// MaxConnsPerHost, and ConnPoolStats reports how the connections to
+// each host are being used.
 //
 // Transports should be reused instead of created as needed.
 // Transports are safe for concurrent use by multiple goroutines.
@@ -83,6 +87,12 @@ type Transport struct {
 	reqMu       sync.Mutex
 	reqCanceler map[*Request]func(error)
 
+	// connsPerHostMu guards connsPerHost and h2Conns. When held
+	// together with idleMu or a persistConn's mu, it is acquired last.
+	connsPerHostMu sync.Mutex
+	connsPerHost   map[connectMethodKey]*hostConns
+	h2Conns        map[net.Conn]connectMethodKey // conns handed to the bundled HTTP/2 client
+
 	altMu    sync.Mutex   // guards changing altProto only
 	altProto atomic.Value // of nil or map[string]RoundTripper, key is URI scheme
 
@@ -148,6 +158,20 @@ type Transport struct {
 	// DefaultMaxIdleConnsPerHost is used.
 	MaxIdleConnsPerHost int
 
+	// MaxConnsPerHost optionally limits the total number of
+	// connections per host, including connections in the dialing,
+	// active, and idle states. On limit violation, requests wait
+	// for a connection to become idle or to close, and are served
+	// in the order in which they started waiting. A request stops
+	// waiting if its context is done or it is canceled.
+	//
+	// HTTP/2 connections made by the Transport count against the
+	// limit until the HTTP/2 client closes them. Requests waiting
+	// when an HTTP/2 connection is established share it.
+	//
+	// Zero means no limit.
+	MaxConnsPerHost int
+
 	// IdleConnTimeout is the maximum amount of time an idle
 	// (keep-alive) connection will remain idle before closing
 	// itself.
@@ -192,12 +216,29 @@ type Transport struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
+	// UseH2C optionally reports whether requests for "http" URLs
+	// to the host addr ("host:port") should use HTTP/2 over
+	// cleartext TCP ("h2c") instead of HTTP/1.1. The server must
+	// accept HTTP/2 connections with prior knowledge, such as a
+	// server using H2CHandler; the Transport does not try to
+	// upgrade HTTP/1.1 connections. Requests sent through a proxy
+	// always use HTTP/1.1.
+	UseH2C func(addr string) bool
+
+	// ForceAttemptHTTP2 controls whether HTTP/2 is enabled when a
+	// non-zero Dial or DialTLS func or TLSClientConfig is provided.
+	// By default, use of any of those fields conservatively disables
+	// HTTP/2. To use a custom dialer or TLS config and still attempt
+	// HTTP/2 upgrades, set this to true.
+	ForceAttemptHTTP2 bool
+
 	// nextProtoOnce guards initialization of TLSNextProto and
 	// h2transport (via onceSetNextProtoDefaults)
 	nextProtoOnce sync.Once
 	h2transport   *http2Transport // non-nil if http2 wired up
 
-	// TODO: tunable on max per-host TCP dials in flight (Issue 13957)
+	h2cOnce      sync.Once
+	h2cTransport *http2Transport // initialized by onceSetH2CDefaults
 }
 
 // onceSetNextProtoDefaults initializes TLSNextProto.
@@ -211,7 +252,7 @@ func (t *Transport) onceSetNextProtoDefaults() {
 		// Transport.
 		return
 	}
-	if t.TLSClientConfig != nil || t.Dial != nil || t.DialTLS != nil {
+	if !t.ForceAttemptHTTP2 && (t.TLSClientConfig != nil || t.Dial != nil || t.DialTLS != nil) {
 		// Be conservative and don't automatically enable
 		// http2 if they've specified a custom TLS config or
 		// custom dialers. Let them opt-in themselves via
@@ -388,6 +429,14 @@ func (t *Transport) RoundTrip(req *Request) (*Response, error) {
 			return nil, err
 		}
 
+		// Use an existing h2c connection if there is one, as
+		// the alternate protocol does above for HTTP/2 over TLS.
+		if t.useH2C(cm) {
+			if resp, err := (http2noDialH2RoundTripper{t.h2cTransport}).RoundTrip(req); err != ErrSkipAltProtocol {
+				return resp, err
+			}
+		}
+
 		// Get the cached or newly-created connection to either the
 		// host (for http or https), the http proxy, or the http proxy
 		// pre-CONNECTed to https server. In any case, we'll be ready
@@ -419,6 +468,17 @@ func (t *Transport) RoundTrip(req *Request) (*Response, error) {
 			return nil, err
 		}
 		testHookRoundTripRetried()
+		if trace != nil && trace.Retry != nil {
+			// Report the underlying error, not the wrappers
+			// that told shouldRetryRequest it was safe.
+			switch e := err.(type) {
+			case nothingWrittenError:
+				err = e.error
+			case transportReadFromServerError:
+				err = e.err
+			}
+			trace.Retry(httptrace.RetryInfo{Err: err})
+		}
 	}
 }
 
@@ -520,6 +580,10 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	if t.UseH2C != nil {
+		t.h2cOnce.Do(t.onceSetH2CDefaults)
+		t.h2cTransport.CloseIdleConnections()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -628,6 +692,7 @@ var (
 	errTooManyIdleHost    = errors.New("http: putIdleConn: too many idle connections for host")
 	errCloseIdleConns     = errors.New("http: CloseIdleConnections called")
 	errReadLoopExiting    = errors.New("http: persistConn.readLoop exiting")
+	errCallerOwnsConn     = errors.New("read loop ending; caller owns writable underlying conn")
 	errServerClosedIdle   = errors.New("http: server closed idle connection")
 	errIdleConnTimeout    = errors.New("http: idle connection timeout")
 	errNotCachingH2Conn   = errors.New("http: not caching alternate protocol's connections")
@@ -683,6 +748,12 @@ func (t *Transport) tryPutIdleConn(pconn *persistConn) error {
 	t.idleMu.Lock()
 	defer t.idleMu.Unlock()
 
+	// Requests waiting because of MaxConnsPerHost get the
+	// connection first, in the order they started waiting.
+	if t.deliverToConnWaiter(key, pconn) {
+		return nil
+	}
+
 	waitingDialer := t.idleConnCh[key]
 	select {
 	case waitingDialer <- pconn:
@@ -755,9 +826,13 @@ func (t *Transport) getIdleConnCh(cm connectMethod) chan *persistConn {
 }
 
 func (t *Transport) getIdleConn(cm connectMethod) (pconn *persistConn, idleSince time.Time) {
-	key := cm.key()
 	t.idleMu.Lock()
 	defer t.idleMu.Unlock()
+	return t.getIdleConnLocked(cm.key())
+}
+
+// t.idleMu must be held.
+func (t *Transport) getIdleConnLocked(key connectMethodKey) (pconn *persistConn, idleSince time.Time) {
 	for {
 		pconns, ok := t.idleConn[key]
 		if !ok {
@@ -827,6 +902,262 @@ func (t *Transport) removeIdleConnLocked(pconn *persistConn) {
 	}
 }
 
+// hostConns tracks a Transport's connections for one connectMethodKey.
+type hostConns struct {
+	n       int                 // open or dialing connections
+	dialing int                 // connections being dialed
+	waiting []chan *persistConn // requests waiting for a connection, oldest first
+}
+
+// hostConnsLocked returns the hostConns for key, creating it if necessary.
+// t.connsPerHostMu must be held.
+func (t *Transport) hostConnsLocked(key connectMethodKey) *hostConns {
+	hc := t.connsPerHost[key]
+	if hc == nil {
+		if t.connsPerHost == nil {
+			t.connsPerHost = make(map[connectMethodKey]*hostConns)
+		}
+		hc = new(hostConns)
+		t.connsPerHost[key] = hc
+	}
+	return hc
+}
+
+// reserveConn reserves a new connection for cm, to be dialed by the
+// caller, unless that would exceed MaxConnsPerHost.
+//
+// If the connection is reserved, reserveConn returns nil, nil.
+// If instead an idle connection is available, it returns that.
+// Otherwise it queues the caller and returns a channel on which
+// the caller receives either an idle connection, or nil once it
+// may dial. A caller that stops waiting must call cancelConnWait.
+func (t *Transport) reserveConn(cm connectMethod) (*persistConn, chan *persistConn) {
+	key := cm.key()
+	t.idleMu.Lock()
+	defer t.idleMu.Unlock()
+	if t.tryReserveConn(key) {
+		return nil, nil
+	}
+	// A connection may have become idle since getConn last looked.
+	// connsPerHostMu is not held here, as getIdleConnLocked takes
+	// the idle connections' mu.
+	if pc, _ := t.getIdleConnLocked(key); pc != nil {
+		return pc, nil
+	}
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	hc := t.hostConnsLocked(key)
+	if hc.n < t.MaxConnsPerHost && len(hc.waiting) == 0 {
+		// A connection closed in the meantime.
+		hc.n++
+		hc.dialing++
+		return nil, nil
+	}
+	w := make(chan *persistConn, 1)
+	hc.waiting = append(hc.waiting, w)
+	return nil, w
+}
+
+// tryReserveConn reserves a new connection for key and reports
+// whether it did so without exceeding MaxConnsPerHost.
+func (t *Transport) tryReserveConn(key connectMethodKey) bool {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	hc := t.hostConnsLocked(key)
+	if t.MaxConnsPerHost <= 0 || hc.n < t.MaxConnsPerHost && len(hc.waiting) == 0 {
+		hc.n++
+		hc.dialing++
+		return true
+	}
+	return false
+}
+
+// cancelConnWait removes w, as returned by reserveConn, from the wait
+// queue for key. If a connection or a reservation was already
+// delivered to w, it is passed on.
+func (t *Transport) cancelConnWait(key connectMethodKey, w chan *persistConn) {
+	t.connsPerHostMu.Lock()
+	if hc := t.connsPerHost[key]; hc != nil {
+		for i, v := range hc.waiting {
+			if v == w {
+				copy(hc.waiting[i:], hc.waiting[i+1:])
+				hc.waiting[len(hc.waiting)-1] = nil
+				hc.waiting = hc.waiting[:len(hc.waiting)-1]
+				t.connsPerHostMu.Unlock()
+				return
+			}
+		}
+	}
+	t.connsPerHostMu.Unlock()
+
+	// w was already dequeued, so a value was sent on it.
+	if pc := <-w; pc != nil {
+		t.putOrCloseIdleConn(pc)
+	} else {
+		t.dialDone(key, false)
+	}
+}
+
+// deliverToConnWaiter hands pconn to the oldest request waiting for
+// a connection to its host, and reports whether there was one.
+// t.idleMu must be held.
+func (t *Transport) deliverToConnWaiter(key connectMethodKey, pconn *persistConn) bool {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	hc := t.connsPerHost[key]
+	if hc == nil || len(hc.waiting) == 0 {
+		return false
+	}
+	w := hc.waiting[0]
+	hc.waiting[0] = nil
+	hc.waiting = hc.waiting[1:]
+	w <- pconn
+	return true
+}
+
+// deliverAltToConnWaiters hands alt, a multiplexed alternate protocol
+// connection, to every request waiting for a connection to key.
+func (t *Transport) deliverAltToConnWaiters(key connectMethodKey, alt *persistConn) {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	hc := t.connsPerHost[key]
+	if hc == nil {
+		return
+	}
+	for _, w := range hc.waiting {
+		w <- alt
+	}
+	hc.waiting = nil
+	if hc.n == 0 {
+		delete(t.connsPerHost, key)
+	}
+}
+
+// dialDone records that a dial reserved by reserveConn has finished.
+// If the dial failed, the reservation is released. A successful dial
+// must be recorded before the connection can be released.
+func (t *Transport) dialDone(key connectMethodKey, ok bool) {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	t.connsPerHost[key].dialing--
+	if !ok {
+		t.releaseConnLocked(key)
+	}
+}
+
+// releaseConn records that a connection for key has closed.
+func (t *Transport) releaseConn(key connectMethodKey) {
+	t.connsPerHostMu.Lock()
+	t.releaseConnLocked(key)
+	t.connsPerHostMu.Unlock()
+}
+
+// releaseConnLocked releases a connection for key, passing its
+// reservation on to the oldest waiting request, if any.
+// t.connsPerHostMu must be held.
+func (t *Transport) releaseConnLocked(key connectMethodKey) {
+	hc := t.connsPerHost[key]
+	if len(hc.waiting) > 0 {
+		w := hc.waiting[0]
+		hc.waiting[0] = nil
+		hc.waiting = hc.waiting[1:]
+		hc.dialing++
+		w <- nil
+		return
+	}
+	hc.n--
+	if hc.n == 0 {
+		delete(t.connsPerHost, key)
+	}
+}
+
+// addH2Conn records that c, reserved for key, is now owned by the
+// bundled HTTP/2 client, which calls h2ConnClosed when it is done.
+func (t *Transport) addH2Conn(c net.Conn, key connectMethodKey) {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if t.h2Conns == nil {
+		t.h2Conns = make(map[net.Conn]connectMethodKey)
+	}
+	t.h2Conns[c] = key
+}
+
+// h2ConnClosed is called by the bundled HTTP/2 client when it closes
+// or discards a connection that the Transport dialed.
+func (t *Transport) h2ConnClosed(c net.Conn) {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	key, ok := t.h2Conns[c]
+	if !ok {
+		return
+	}
+	delete(t.h2Conns, c)
+	t.releaseConnLocked(key)
+}
+
+// ConnPoolStats describes a Transport's connections to one host.
+type ConnPoolStats struct {
+	Active  int // connections in use by requests
+	Idle    int // idle (keep-alive) connections
+	Dialing int // connections being established
+	Waiting int // requests waiting for a connection because of MaxConnsPerHost
+}
+
+// ConnPoolStats returns a snapshot of the Transport's connections,
+// keyed by the "host:port" address of the host they are to, or by the
+// proxy URL for plain HTTP requests sent through a proxy.
+//
+// An HTTP/2 connection counts as active while it is carrying any
+// request, and as idle otherwise.
+func (t *Transport) ConnPoolStats() map[string]ConnPoolStats {
+	t.nextProtoOnce.Do(t.onceSetNextProtoDefaults)
+	if t.UseH2C != nil {
+		t.h2cOnce.Do(t.onceSetH2CDefaults)
+	}
+	h2Idle := make(map[net.Conn]bool)
+	for _, t2 := range []*http2Transport{t.h2transport, t.h2cTransport} {
+		if t2 == nil {
+			continue
+		}
+		for c, idle := range t2.idleConnStates() {
+			h2Idle[c] = idle
+		}
+	}
+
+	t.idleMu.Lock()
+	defer t.idleMu.Unlock()
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+
+	h2IdleCount := make(map[connectMethodKey]int)
+	for c, key := range t.h2Conns {
+		if h2Idle[c] {
+			h2IdleCount[key]++
+		}
+	}
+	stats := make(map[string]ConnPoolStats)
+	for key, hc := range t.connsPerHost {
+		idle := len(t.idleConn[key]) + h2IdleCount[key]
+		active := hc.n - hc.dialing - idle
+		if active < 0 {
+			// An idle connection that is closing but
+			// not yet released.
+			active = 0
+		}
+		host := key.addr
+		if host == "" {
+			host = key.proxy
+		}
+		st := stats[host]
+		st.Active += active
+		st.Idle += idle
+		st.Dialing += hc.dialing
+		st.Waiting += len(hc.waiting)
+		stats[host] = st
+	}
+	return stats
+}
+
 func (t *Transport) setReqCanceler(r *Request, fn func(error)) {
 	t.reqMu.Lock()
 	defer t.reqMu.Unlock()
@@ -921,8 +1252,44 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 	cancelc := make(chan error, 1)
 	t.setReqCanceler(req, func(err error) { cancelc <- err })
 
+	key := cm.key()
+	if pc, w := t.reserveConn(cm); pc != nil {
+		if trace != nil && trace.GotConn != nil {
+			trace.GotConn(pc.gotIdleConnTrace(pc.idleAt))
+		}
+		return pc, nil
+	} else if w != nil {
+		// MaxConnsPerHost connections are open or being dialed.
+		// Wait for one of them to become idle or to close.
+		select {
+		case pc := <-w:
+			if pc != nil {
+				if pc.alt == nil && trace != nil && trace.GotConn != nil {
+					trace.GotConn(httptrace.GotConnInfo{Conn: pc.conn, Reused: pc.isReused()})
+				}
+				return pc, nil
+			}
+			// A connection closed; dial a new one.
+		case <-req.Cancel:
+			t.cancelConnWait(key, w)
+			return nil, errRequestCanceledConn
+		case <-req.Context().Done():
+			t.cancelConnWait(key, w)
+			return nil, req.Context().Err()
+		case err := <-cancelc:
+			t.cancelConnWait(key, w)
+			if err == errRequestCanceled {
+				err = errRequestCanceledConn
+			}
+			return nil, err
+		}
+	}
+
 	go func() {
 		pc, err := t.dialConn(ctx, cm)
+		if err != nil {
+			t.dialDone(key, false)
+		}
 		dialc <- dialRes{pc, err}
 	}()
 
@@ -1159,12 +1526,37 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (*persistCon
 
 	if s := pconn.tlsState; s != nil && s.NegotiatedProtocolIsMutual && s.NegotiatedProtocol != "" {
 		if next, ok := t.TLSNextProto[s.NegotiatedProtocol]; ok {
+			t.dialDone(pconn.cacheKey, true)
+			if s.NegotiatedProtocol == "h2" && t.h2transport != nil {
+				// The connection stays counted against
+				// MaxConnsPerHost until the HTTP/2 client
+				// is done with it.
+				t.addH2Conn(pconn.conn, pconn.cacheKey)
+				alt := &persistConn{alt: next(cm.targetAddr, pconn.conn.(*tls.Conn))}
+				// Requests waiting for a connection can
+				// share this one instead.
+				t.deliverAltToConnWaiters(pconn.cacheKey, alt)
+				return alt, nil
+			}
+			// We can't tell when another protocol is done
+			// with the connection, so stop counting it.
+			t.releaseConn(pconn.cacheKey)
 			return &persistConn{alt: next(cm.targetAddr, pconn.conn.(*tls.Conn))}, nil
 		}
 	}
 
+	if t.useH2C(cm) {
+		t.dialDone(pconn.cacheKey, true)
+		t.addH2Conn(pconn.conn, pconn.cacheKey)
+		alt := &persistConn{alt: t.addH2CConn(cm.targetAddr, pconn.conn)}
+		t.deliverAltToConnWaiters(pconn.cacheKey, alt)
+		return alt, nil
+	}
+
 	pconn.br = bufio.NewReader(pconn)
 	pconn.bw = bufio.NewWriter(persistConnWriter{pconn})
+	t.dialDone(pconn.cacheKey, true)
+	pconn.reserved = true
 	go pconn.readLoop()
 	go pconn.writeLoop()
 	return pconn, nil
@@ -1330,6 +1722,7 @@ type persistConn struct {
 	writech   chan writeRequest   // written by roundTrip; read by writeLoop
 	closech   chan struct{}       // closed when conn closed
 	isProxy   bool
+	reserved  bool  // counted in t.connsPerHost until closed
 	sawEOF    bool  // whether we've seen EOF from conn; owned by readLoop
 	readLimit int64 // bytes allowed to be read; owned by readLoop
 	// writeErrCh passes the request write error (usually nil)
@@ -1567,6 +1960,7 @@ func (pc *persistConn) readLoop() {
 		pc.numExpectedResponses--
 		pc.mu.Unlock()
 
+		bodyWritable := resp.bodyIsWritable()
 		hasBody := rc.req.Method != "HEAD" && resp.ContentLength != 0
 
 		if resp.Close || rc.req.Close || resp.StatusCode <= 199 {
@@ -1576,7 +1970,7 @@ func (pc *persistConn) readLoop() {
 			alive = false
 		}
 
-		if !hasBody {
+		if !hasBody || bodyWritable {
 			pc.t.setReqCanceler(rc.req, nil)
 
 			// Put the idle conn back into the pool before we send the response
@@ -1590,6 +1984,10 @@ func (pc *persistConn) readLoop() {
 				pc.wroteRequest() &&
 				tryPutIdleConn(trace)
 
+			if bodyWritable {
+				closeErr = errCallerOwnsConn
+			}
+
 			select {
 			case rc.ch <- responseAndError{res: resp}:
 			case <-rc.callerGone:
@@ -1693,31 +2091,84 @@ func (pc *persistConn) readResponse(rc requestAndChan, trace *httptrace.ClientTr
 			trace.GotFirstResponseByte()
 		}
 	}
-	resp, err = ReadResponse(pc.br, rc.req)
-	if err != nil {
-		return
-	}
-	if rc.continueCh != nil {
-		if resp.StatusCode == 100 {
-			if trace != nil && trace.Got100Continue != nil {
-				trace.Got100Continue()
-			}
-			rc.continueCh <- struct{}{}
-		} else {
-			close(rc.continueCh)
-		}
-	}
-	if resp.StatusCode == 100 {
-		pc.readLimit = pc.maxHeaderResponseSize() // reset the limit
+	num1xx := 0               // number of informational 1xx headers received
+	const max1xxResponses = 5 // arbitrary bound on number of informational responses
+
+	continueCh := rc.continueCh
+	for {
 		resp, err = ReadResponse(pc.br, rc.req)
 		if err != nil {
 			return
 		}
+		resCode := resp.StatusCode
+		if continueCh != nil {
+			if resCode == 100 {
+				if trace != nil && trace.Got100Continue != nil {
+					trace.Got100Continue()
+				}
+				continueCh <- struct{}{}
+				continueCh = nil
+			} else if resCode >= 200 {
+				close(continueCh)
+				continueCh = nil
+			}
+		}
+		// 101 Switching Protocols is the final response.
+		if resCode < 100 || resCode > 199 || resCode == StatusSwitchingProtocols {
+			break
+		}
+		num1xx++
+		if num1xx > max1xxResponses {
+			return nil, errors.New("net/http: too many 1xx informational responses")
+		}
+		pc.readLimit = pc.maxHeaderResponseSize() // reset the limit
+		if trace != nil && trace.Got1xxResponse != nil {
+			if err := trace.Got1xxResponse(resCode, textproto.MIMEHeader(resp.Header)); err != nil {
+				// Return err itself to the caller, undecorated.
+				rc.treq.setError(err)
+				return nil, err
+			}
+		}
+	}
+	if resp.isProtocolSwitch() {
+		resp.Body = newReadWriteCloserBody(pc.br, pc.conn)
 	}
 	resp.TLS = pc.tlsState
 	return
 }
 
+func newReadWriteCloserBody(br *bufio.Reader, rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	body := &readWriteCloserBody{ReadWriteCloser: rwc}
+	if br.Buffered() != 0 {
+		body.br = br
+	}
+	return body
+}
+
+// readWriteCloserBody is the Response.Body type used when we want to
+// give users write access to the Body through the underlying
+// connection (TCP, unless using custom dialers). This is then
+// the concrete type for a Response.Body on the 101 Switching
+// Protocols response, as used by WebSockets, h2c, etc.
+type readWriteCloserBody struct {
+	br *bufio.Reader // used until empty
+	io.ReadWriteCloser
+}
+
+func (b *readWriteCloserBody) Read(p []byte) (n int, err error) {
+	if b.br != nil {
+		if n := b.br.Buffered(); len(p) > n {
+			p = p[:n]
+		}
+		n, err = b.br.Read(p)
+		if b.br.Buffered() == 0 {
+			b.br = nil
+		}
+		return n, err
+	}
+	return b.ReadWriteCloser.Read(p)
+}
+
 // waitForContinue returns the function to block until
 // any response, timeout or connection close. After any of them,
 // the function returns a bool which indicates if the body should be sent.
@@ -1820,8 +2271,9 @@ type responseAndError struct {
 }
 
 type requestAndChan struct {
-	req *Request
-	ch  chan responseAndError // unbuffered; always send in select on callerGone
+	treq *transportRequest
+	req  *Request
+	ch   chan responseAndError // unbuffered; always send in select on callerGone
 
 	// whether the Transport (as opposed to the user client code)
 	// added the Accept-Encoding gzip header. If the Transport
@@ -1947,6 +2399,7 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 
 	resc := make(chan responseAndError)
 	pc.reqch <- requestAndChan{
+		treq:       req,
 		req:        req.Request,
 		ch:         resc,
 		addedGzip:  requestedGzip,
@@ -2054,8 +2507,13 @@ func (pc *persistConn) closeLocked(err error) {
 			// freelist for http2. That's done by the
 			// alternate protocol's RoundTripper.
 		} else {
-			pc.conn.Close()
+			if err != errCallerOwnsConn {
+				pc.conn.Close()
+			}
 			close(pc.closech)
+			if pc.reserved {
+				pc.t.releaseConn(pc.cacheKey)
+			}
 		}
 	}
 	pc.mutateHeaderFunc = nil
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 )
 
 // Issue 15446: incorrect wrapping of errors when server closes an idle connection.
@@ -58,6 +59,56 @@ func TestTransportPersistConnReadLoopEOF(t *testing.T) {
 	}
 }
 
+// Tests that reserving a connection doesn't deadlock with an idle
+// connection to the same host being closed.
+func TestTransportReserveConnCloseRace(t *testing.T) {
+	cm := connectMethod{targetScheme: "http", targetAddr: "example.com:80"}
+	key := cm.key()
+	tr := &Transport{MaxConnsPerHost: 1}
+	c, _ := net.Pipe()
+	pc := &persistConn{
+		t:        tr,
+		conn:     c,
+		closech:  make(chan struct{}),
+		cacheKey: key,
+		reserved: true,
+	}
+	tr.hostConnsLocked(key).n = 1
+	tr.idleConn = map[connectMethodKey][]*persistConn{key: {pc}}
+	tr.idleLRU.add(pc)
+
+	// Start closing pc, and let reserveConn find it in the idle
+	// list while it is being closed.
+	pc.mu.Lock()
+	type result struct {
+		pc *persistConn
+		w  chan *persistConn
+	}
+	resc := make(chan result, 1)
+	go func() {
+		pc, w := tr.reserveConn(cm)
+		resc <- result{pc, w}
+	}()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pc.closeLocked(errors.New("closed"))
+		pc.mu.Unlock()
+	}()
+
+	select {
+	case res := <-resc:
+		if res.pc != nil || res.w != nil {
+			t.Fatalf("reserveConn = %p, %v; want a reservation", res.pc, res.w)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("reserveConn deadlocked")
+	}
+	tr.dialDone(key, false)
+	if n := len(tr.connsPerHost); n != 0 {
+		t.Errorf("%d hosts with connections left; want 0", n)
+	}
+}
+
 func isTransportReadFromServerError(err error) bool {
 	_, ok := err.(transportReadFromServerError)
 	return ok
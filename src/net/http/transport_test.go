@@ -30,6 +30,7 @@ import (
 	"net/http/httptrace"
 	"net/http/httputil"
 	"net/http/internal"
+	"net/textproto"
 	"net/url"
 	"os"
 	"reflect"
@@ -437,6 +438,150 @@ func TestTransportMaxPerHostIdleConns(t *testing.T) {
 	}
 }
 
+func TestTransportMaxConnsPerHost_h1(t *testing.T) { testTransportMaxConnsPerHost(t, h1Mode) }
+func TestTransportMaxConnsPerHost_h2(t *testing.T) { testTransportMaxConnsPerHost(t, h2Mode) }
+
+func testTransportMaxConnsPerHost(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	gotReq := make(chan bool, 3)
+	release := make(chan bool)
+	var conns int32
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		gotReq <- true
+		<-release
+	}), func(ts *httptest.Server) {
+		ts.Config.ConnState = func(c net.Conn, state ConnState) {
+			if state == StateNew {
+				atomic.AddInt32(&conns, 1)
+			}
+		}
+	}, func(tr *Transport) {
+		tr.MaxConnsPerHost = 1
+	})
+	defer cst.close()
+	host := cst.ts.Listener.Addr().String()
+
+	doReq := func(ctx context.Context) error {
+		req, _ := NewRequest("GET", cst.ts.URL, nil)
+		res, err := cst.c.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		_, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return err
+	}
+	waitStats := func(want ConnPoolStats) {
+		var got ConnPoolStats
+		if !waitCondition(5*time.Second, 10*time.Millisecond, func() bool {
+			got = cst.tr.ConnPoolStats()[host]
+			return got == want
+		}) {
+			t.Fatalf("ConnPoolStats()[%q] = %+v; want %+v", host, got, want)
+		}
+	}
+
+	// Occupy the only connection.
+	errc := make(chan error, 2)
+	go func() { errc <- doReq(context.Background()) }()
+	<-gotReq
+	waitStats(ConnPoolStats{Active: 1})
+
+	// The next request has to wait for it.
+	if h2 {
+		// HTTP/2 requests share the connection instead.
+		go func() { errc <- doReq(context.Background()) }()
+		<-gotReq
+	} else {
+		go func() { errc <- doReq(context.Background()) }()
+		waitStats(ConnPoolStats{Active: 1, Waiting: 1})
+
+		// A waiting request gives up when its context is done.
+		ctx, cancel := context.WithCancel(context.Background())
+		ctxErr := make(chan error, 1)
+		go func() { ctxErr <- doReq(ctx) }()
+		waitStats(ConnPoolStats{Active: 1, Waiting: 2})
+		cancel()
+		if err := <-ctxErr; err == nil {
+			t.Error("canceled request succeeded")
+		}
+		waitStats(ConnPoolStats{Active: 1, Waiting: 1})
+	}
+
+	release <- true
+	if !h2 {
+		<-gotReq
+	}
+	release <- true
+	for i := 0; i < 2; i++ {
+		if err := <-errc; err != nil {
+			t.Error(err)
+		}
+	}
+	waitStats(ConnPoolStats{Idle: 1})
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("server saw %d connections; want 1", n)
+	}
+
+	cst.tr.CloseIdleConnections()
+	if !waitCondition(5*time.Second, 10*time.Millisecond, func() bool {
+		return len(cst.tr.ConnPoolStats()) == 0
+	}) {
+		t.Errorf("after CloseIdleConnections, ConnPoolStats() = %v; want empty", cst.tr.ConnPoolStats())
+	}
+}
+
+func TestTransportResponseBodyWritableOnProtocolSwitch(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	done := make(chan struct{})
+	defer close(done)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, _, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: foo\r\n\r\nSome buffered data\n")
+		bs := bufio.NewScanner(conn)
+		bs.Scan()
+		fmt.Fprintf(conn, "%s\n", strings.ToUpper(bs.Text()))
+		<-done
+	}))
+	defer ts.Close()
+
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req.Header.Set("Upgrade", "foo")
+	req.Header.Set("Connection", "upgrade")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 {
+		t.Fatalf("expected 101 switching protocols; got %v, %v", res.Status, res.Header)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("expected a ReadWriteCloser; got a %T", res.Body)
+	}
+	defer rwc.Close()
+	bs := bufio.NewScanner(rwc)
+	if !bs.Scan() {
+		t.Fatalf("expected readable input")
+	}
+	if got, want := bs.Text(), "Some buffered data"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+	io.WriteString(rwc, "echo\n")
+	if !bs.Scan() {
+		t.Fatalf("expected another line")
+	}
+	if got, want := bs.Text(), "ECHO"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+}
+
 func TestTransportRemovesDeadIdleConnections(t *testing.T) {
 	setParallel(t)
 	defer afterTest(t)
@@ -2160,11 +2305,11 @@ Content-Length: %d
 	}
 
 	// And some other informational 1xx but non-100 responses, to test
-	// we return them but don't re-use the connection.
+	// we skip them and return the final response.
 	for i := 1; i <= numReqs; i++ {
 		req, _ := NewRequest("POST", "http://other.tld/", strings.NewReader(reqBody(i)))
 		req.Header.Set("X-Want-Response-Code", "123 Sesame Street")
-		testResponse(req, fmt.Sprintf("123, %d/%d", i, numReqs), 123)
+		testResponse(req, fmt.Sprintf("123, %d/%d", i, numReqs), 200)
 	}
 }
 
@@ -2657,8 +2802,15 @@ func TestRetryIdempotentRequestsOnError(t *testing.T) {
 	})
 	defer SetRoundTripRetried(nil)
 
+	trace := &httptrace.ClientTrace{
+		Retry: func(ri httptrace.RetryInfo) {
+			logf("Retry: %v", ri.Err)
+		},
+	}
 	for i := 0; i < 3; i++ {
-		res, err := c.Get("http://fake.golang/")
+		req, _ := NewRequest("GET", "http://fake.golang/", nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		res, err := c.Do(req)
 		if err != nil {
 			t.Fatalf("i=%d: Get = %v", i, err)
 		}
@@ -2673,6 +2825,7 @@ Write("GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\n
 Handler
 intentional write failure
 Retried.
+Retry: second write fails
 Dial
 Write("GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip\r\n\r\n")
 Handler
@@ -3379,6 +3532,13 @@ func testTransportEventTrace(t *testing.T, h2 bool, noHooks bool) {
 		},
 		Wait100Continue: func() { logf("Wait100Continue") },
 		Got100Continue:  func() { logf("Got100Continue") },
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			logf("Got1xxResponse: %d", code)
+			return nil
+		},
+		WroteHeaderField: func(key string, value []string) {
+			logf("WroteHeaderField: %s: %v", key, value)
+		},
 		WroteRequest: func(e httptrace.WroteRequestInfo) {
 			logf("WroteRequest: %+v", e)
 			close(gotWroteReqEvent)
@@ -3449,6 +3609,12 @@ func testTransportEventTrace(t *testing.T, h2 bool, noHooks bool) {
 	}
 	wantOnce("Wait100Continue")
 	wantOnce("Got100Continue")
+	wantOnce("Got1xxResponse: 100")
+	if h2 {
+		wantOnce("WroteHeaderField: expect: [100-continue]")
+	} else {
+		wantOnce("WroteHeaderField: Expect: [100-continue]")
+	}
 	wantOnce("WroteRequest: {Err:<nil>}")
 	if strings.Contains(got, " to udp ") {
 		t.Errorf("should not see UDP (DNS) connections")
@@ -3458,6 +3624,108 @@ func testTransportEventTrace(t *testing.T, h2 bool, noHooks bool) {
 	}
 }
 
+// earlyHintsHandler replies with two "103 Early Hints" responses
+// before the final one, which HTTP/1 handlers can't send otherwise.
+func earlyHintsHandler(t *testing.T) HandlerFunc {
+	return func(w ResponseWriter, r *Request) {
+		conn, bufrw, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		bufrw.WriteString("HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n")
+		bufrw.WriteString("HTTP/1.1 103 Early Hints\r\nLink: </script.js>; rel=preload\r\n\r\n")
+		bufrw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 5\r\nConnection: close\r\n\r\nHello")
+		bufrw.Flush()
+	}
+}
+
+func TestTransportGot1xxResponse(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(earlyHintsHandler(t))
+	defer ts.Close()
+
+	var links []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code != StatusEarlyHints {
+				t.Errorf("Got1xxResponse code = %d; want %d", code, StatusEarlyHints)
+			}
+			links = append(links, header.Get("Link"))
+			return nil
+		},
+	}
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || string(body) != "Hello" {
+		t.Errorf("got %v, %q; want 200 OK, %q", res.Status, body, "Hello")
+	}
+	want := []string{"</style.css>; rel=preload", "</script.js>; rel=preload"}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("Link headers = %q; want %q", links, want)
+	}
+}
+
+func TestTransportGot1xxResponseError(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(earlyHintsHandler(t))
+	defer ts.Close()
+
+	wantErr := errors.New("no early hints, thanks")
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			return wantErr
+		},
+	}
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := ts.Client().Do(req)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("unexpected success")
+	}
+	if ue, ok := err.(*url.Error); !ok || ue.Err != wantErr {
+		t.Errorf("Do error = %v; want %v", err, wantErr)
+	}
+}
+
+func TestTransportTooManyInformationalResponses(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, bufrw, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for i := 0; i < 6; i++ {
+			bufrw.WriteString("HTTP/1.1 103 Early Hints\r\n\r\n")
+		}
+		bufrw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n")
+		bufrw.Flush()
+	}))
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("unexpected success")
+	}
+	if !strings.Contains(err.Error(), "too many 1xx informational responses") {
+		t.Errorf("Get error = %v; want too many 1xx informational responses", err)
+	}
+}
+
 var (
 	isDNSHijackedOnce sync.Once
 	isDNSHijacked     bool
@@ -3,7 +3,7 @@
 // license that can be found in the LICENSE file.
 
 /*
-Package mail implements parsing of mail messages.
+Package mail implements parsing and composition of mail messages.
 
 For the most part, this package follows the syntax as specified by RFC 5322 and
 extended by RFC 6532.
@@ -0,0 +1,377 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mail
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLen is the length, excluding CRLF, to which
+// header fields are folded where possible (RFC 5322, section 2.1.1).
+const maxLineLen = 78
+
+// Address fields hold address lists, whose display names, but not the
+// addresses themselves, may be encoded (RFC 2047, section 5).
+var addressFields = map[string]bool{
+	"From":     true,
+	"Sender":   true,
+	"Reply-To": true,
+	"To":       true,
+	"Cc":       true,
+	"Bcc":      true,
+}
+
+// Set sets the header entries associated with key to the single element
+// value, replacing any existing values. The value is plain text; it is
+// encoded as needed when the message is written.
+func (h Header) Set(key, value string) {
+	textproto.MIMEHeader(h).Set(key, value)
+}
+
+// SetDate sets the Date header field to t.
+func (h Header) SetDate(t time.Time) {
+	h.Set("Date", t.Format(time.RFC1123Z))
+}
+
+// SetAddressList sets the named header field to the list of addresses.
+func (h Header) SetAddressList(key string, list []*Address) {
+	h.Set(key, formatAddressList(list))
+}
+
+func formatAddressList(list []*Address) string {
+	addrs := make([]string, len(list))
+	for i, a := range list {
+		addrs[i] = a.String()
+	}
+	return strings.Join(addrs, ", ")
+}
+
+// SetContentType sets the Content-Type header field to the media type t
+// with the parameters param. Parameter values that are not ASCII are
+// encoded as described in RFC 2231.
+func (h Header) SetContentType(t string, param map[string]string) {
+	h.Set("Content-Type", formatMediaType(t, param))
+}
+
+// formatMediaType is like mime.FormatMediaType but encodes non-ASCII
+// parameter values instead of failing.
+func formatMediaType(t string, param map[string]string) string {
+	plain := make(map[string]string)
+	var encoded []string
+	for k, v := range param {
+		if isASCII(v) {
+			plain[k] = v
+		} else {
+			encoded = append(encoded, k)
+		}
+	}
+	s := mime.FormatMediaType(t, plain)
+	if s == "" {
+		return ""
+	}
+	sort.Strings(encoded)
+	for _, k := range encoded {
+		s += "; " + strings.ToLower(k) + "*=utf-8''" + percentEncode(param[k])
+	}
+	return s
+}
+
+// percentEncode encodes s as an RFC 2231 extended value.
+func percentEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < utf8.RuneSelf && c > ' ' && !strings.ContainsRune("*'%()<>@,;:\\\"/[]?=", rune(c)) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xF])
+	}
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// formatField returns value as it should appear in the header field key:
+// encoded according to RFC 2047 if it is not ASCII, and folded.
+// The value of an address field must then be a valid address list.
+func formatField(key, value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", errors.New("mail: invalid value for header field " + key)
+	}
+	switch {
+	case isASCII(value):
+	case addressFields[key]:
+		list, err := ParseAddressList(value)
+		if err != nil {
+			return "", errors.New("mail: invalid address list in header field " + key + ": " + err.Error())
+		}
+		value = formatAddressList(list)
+	default:
+		value = mime.QEncoding.Encode("utf-8", value)
+	}
+	return fold(len(key)+len(": "), value), nil
+}
+
+// fold inserts line breaks before spaces in s, so that lines, the first of
+// which already holds n bytes, do not exceed maxLineLen where possible.
+// If even the first word does not fit, fold breaks the line before it,
+// making use of the space that follows the field name's colon.
+func fold(n int, s string) string {
+	var b strings.Builder
+	for i, word := range strings.Split(s, " ") {
+		if i == 0 && n+len(word) > maxLineLen {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		if i > 0 {
+			if n+1+len(word) > maxLineLen {
+				b.WriteString("\r\n")
+				n = 0
+			}
+			b.WriteByte(' ')
+			n++
+		}
+		b.WriteString(word)
+		n += len(word)
+	}
+	return b.String()
+}
+
+// formatHeader returns a copy of h with every value formatted for
+// writing, and the content fields set to their defaults if missing:
+// text/plain in UTF-8, sent quoted-printable if it is text and in base64
+// otherwise.
+func formatHeader(h Header) (textproto.MIMEHeader, error) {
+	f := make(textproto.MIMEHeader, len(h)+2)
+	for k, vv := range h {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		for _, v := range vv {
+			v, err := formatField(k, v)
+			if err != nil {
+				return nil, err
+			}
+			f[k] = append(f[k], v)
+		}
+	}
+	if f.Get("Content-Type") == "" {
+		f.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	if f.Get("Content-Transfer-Encoding") == "" {
+		enc := "base64"
+		switch {
+		case strings.HasPrefix(f.Get("Content-Type"), "multipart/"):
+			enc = ""
+		case strings.HasPrefix(f.Get("Content-Type"), "text/"):
+			enc = "quoted-printable"
+		}
+		if enc != "" {
+			f.Set("Content-Transfer-Encoding", enc)
+		}
+	}
+	return f, nil
+}
+
+// writeHeader writes h, followed by the blank line that ends it.
+func writeHeader(w io.Writer, h textproto.MIMEHeader) error {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range h[k] {
+			b.WriteString(k)
+			b.WriteString(": ")
+			b.WriteString(v)
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// NewWriter writes the header h of a single-part message to w and returns
+// a writer for the body. Values in h are plain text, which NewWriter
+// encodes and folds as needed; in address fields such as From and To,
+// only the display names are encoded, so non-ASCII values of those must
+// be valid address lists. NewWriter adds MIME-Version and, if they
+// are missing, a Content-Type of text/plain in UTF-8 and a
+// Content-Transfer-Encoding of quoted-printable for text or base64
+// otherwise. The body written is encoded accordingly; the caller must
+// close the returned writer to flush it. Closing it does not close w.
+func NewWriter(w io.Writer, h Header) (io.WriteCloser, error) {
+	f, err := formatHeader(h)
+	if err != nil {
+		return nil, err
+	}
+	f["MIME-Version"] = []string{"1.0"}
+	if err := writeHeader(w, f); err != nil {
+		return nil, err
+	}
+	return encoder(w, f.Get("Content-Transfer-Encoding")), nil
+}
+
+// A MultipartWriter writes a message, or a part of one,
+// whose body consists of several parts.
+type MultipartWriter struct {
+	mw *multipart.Writer
+}
+
+// NewMultipartWriter writes the header h of a multipart message to w,
+// encoded as for NewWriter and with a Content-Type of multipart/subtype,
+// and returns a MultipartWriter for its parts. The subtype is usually
+// "mixed", for a message with attachments, or "alternative", for the
+// same content in several forms such as plain text and HTML.
+func NewMultipartWriter(w io.Writer, h Header, subtype string) (*MultipartWriter, error) {
+	mw := multipart.NewWriter(w)
+	h = copyHeader(h)
+	h.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": mw.Boundary()}))
+	f, err := formatHeader(h)
+	if err != nil {
+		return nil, err
+	}
+	f["MIME-Version"] = []string{"1.0"}
+	if err := writeHeader(w, f); err != nil {
+		return nil, err
+	}
+	return &MultipartWriter{mw}, nil
+}
+
+func copyHeader(h Header) Header {
+	h2 := make(Header, len(h)+1)
+	for k, vv := range h {
+		h2[k] = vv
+	}
+	return h2
+}
+
+// CreatePart starts a new part with header h, encoded and given defaults
+// as for NewWriter, and returns a writer for its body. The caller must
+// close the returned writer before creating the next part.
+func (w *MultipartWriter) CreatePart(h Header) (io.WriteCloser, error) {
+	f, err := formatHeader(h)
+	if err != nil {
+		return nil, err
+	}
+	p, err := w.mw.CreatePart(f)
+	if err != nil {
+		return nil, err
+	}
+	return encoder(p, f.Get("Content-Transfer-Encoding")), nil
+}
+
+// CreateMultipart starts a new part that is itself a multipart body of
+// the given subtype, such as the "alternative" text and HTML forms of
+// a message with attachments. The caller must close it before creating
+// the next part.
+func (w *MultipartWriter) CreateMultipart(subtype string) (*MultipartWriter, error) {
+	boundary := multipart.NewWriter(nil).Boundary()
+	h := make(Header)
+	h.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": boundary}))
+	f, err := formatHeader(h)
+	if err != nil {
+		return nil, err
+	}
+	p, err := w.mw.CreatePart(f)
+	if err != nil {
+		return nil, err
+	}
+	mw := multipart.NewWriter(p)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	return &MultipartWriter{mw}, nil
+}
+
+// CreateAttachment starts a new base64-encoded part holding a file with
+// the given name and media type, to be presented as an attachment, and
+// returns a writer for its content. The caller must close the returned
+// writer before creating the next part.
+func (w *MultipartWriter) CreateAttachment(filename, contentType string) (io.WriteCloser, error) {
+	h := make(Header)
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Disposition", formatMediaType("attachment", map[string]string{"filename": filename}))
+	h.Set("Content-Transfer-Encoding", "base64")
+	return w.CreatePart(h)
+}
+
+// Close finishes the multipart body, writing the closing boundary.
+func (w *MultipartWriter) Close() error {
+	return w.mw.Close()
+}
+
+// encoder returns a writer that encodes its input
+// with the named content transfer encoding to w.
+func encoder(w io.Writer, encoding string) io.WriteCloser {
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		return quotedprintable.NewWriter(w)
+	case "base64":
+		return base64.NewEncoder(base64.StdEncoding, &lineBreaker{w: w})
+	}
+	return nopCloser{w}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// base64LineLen is the length of lines in base64 bodies
+// (RFC 2045, section 6.8).
+const base64LineLen = 76
+
+// A lineBreaker breaks its input into base64LineLen-byte lines.
+type lineBreaker struct {
+	w io.Writer
+	n int // bytes written on the current line
+}
+
+func (l *lineBreaker) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		if l.n == base64LineLen {
+			if _, err := io.WriteString(l.w, "\r\n"); err != nil {
+				return n, err
+			}
+			l.n = 0
+		}
+		chunk := b
+		if len(chunk) > base64LineLen-l.n {
+			chunk = chunk[:base64LineLen-l.n]
+		}
+		m, err := l.w.Write(chunk)
+		n += m
+		l.n += m
+		if err != nil {
+			return n, err
+		}
+		b = b[m:]
+	}
+	return n, nil
+}
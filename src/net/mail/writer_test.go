@@ -0,0 +1,251 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFold(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	folded := fold(len("Subject: "), long)
+	lines := strings.Split(folded, "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("fold did not fold %q", long)
+	}
+	for i, line := range lines {
+		if i == 0 {
+			line = "Subject: " + line
+		}
+		if len(line) > maxLineLen {
+			t.Errorf("line %d is %d bytes long: %q", i, len(line), line)
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space: %q", i, line)
+		}
+	}
+	if got := strings.Replace(folded, "\r\n", "", -1); got != long {
+		t.Errorf("unfolding gives %q, want %q", got, long)
+	}
+}
+
+func TestWriterInvalidHeader(t *testing.T) {
+	h := make(Header)
+	h.Set("Subject", "hi\r\nBcc: victim@example.com")
+	if _, err := NewWriter(ioutil.Discard, h); err == nil {
+		t.Error("NewWriter accepted a header value containing CRLF")
+	}
+}
+
+func TestWriterAddressField(t *testing.T) {
+	h := make(Header)
+	h.Set("From", `"Gøpher" <gopher@example.com>`)
+	h.Set("To", "Jörg Müller <jm@example.com>, plain@example.com")
+	h.Set("Cc", "nobody@example.com")
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	raw := buf.String()
+	if header := raw[:strings.Index(raw, "\r\n\r\n")]; !isASCII(header) {
+		t.Errorf("header is not ASCII:\n%s", header)
+	}
+	m, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		key  string
+		want []*Address
+	}{
+		{"From", []*Address{{Name: "Gøpher", Address: "gopher@example.com"}}},
+		{"To", []*Address{{Name: "Jörg Müller", Address: "jm@example.com"}, {Address: "plain@example.com"}}},
+		{"Cc", []*Address{{Address: "nobody@example.com"}}},
+	} {
+		if got, err := m.Header.AddressList(tt.key); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, %v; want %v", tt.key, got, err, tt.want)
+		}
+	}
+
+	h = make(Header)
+	h.Set("To", "Jörg <not an address")
+	if _, err := NewWriter(ioutil.Discard, h); err == nil {
+		t.Error("NewWriter accepted an invalid non-ASCII address list")
+	}
+}
+
+func TestWriterSinglePart(t *testing.T) {
+	from := []*Address{{Name: "Gøpher", Address: "gopher@example.com"}}
+	subject := "Grüße aus dem Büro, " + strings.Repeat("und noch mehr Text ", 5)
+	date := time.Date(2017, 5, 4, 3, 2, 1, 0, time.UTC)
+	body := "Hallo, Welt! = ÄÖÜ\n" + strings.Repeat("x", 100) + "\n"
+
+	h := make(Header)
+	h.SetAddressList("From", from)
+	h.SetAddressList("To", []*Address{{Address: "a@example.com"}, {Name: "B", Address: "b@example.com"}})
+	h.Set("Subject", subject)
+	h.SetDate(date)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, body)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := buf.String()
+	header := raw[:strings.Index(raw, "\r\n\r\n")]
+	for _, line := range strings.Split(header, "\r\n") {
+		if len(line) > maxLineLen {
+			t.Errorf("header line is %d bytes long: %q", len(line), line)
+		}
+		if !isASCII(line) {
+			t.Errorf("header line is not ASCII: %q", line)
+		}
+	}
+
+	m, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec mime.WordDecoder
+	if got, err := dec.DecodeHeader(m.Header.Get("Subject")); err != nil || got != subject {
+		t.Errorf("Subject = %q, %v; want %q", got, err, subject)
+	}
+	if got, err := m.Header.AddressList("From"); err != nil || !reflect.DeepEqual(got, from) {
+		t.Errorf("From = %v, %v; want %v", got, err, from)
+	}
+	if got, err := m.Header.AddressList("To"); err != nil || len(got) != 2 {
+		t.Errorf("To = %v, %v; want 2 addresses", got, err)
+	}
+	if got, err := m.Header.Date(); err != nil || !got.Equal(date) {
+		t.Errorf("Date = %v, %v; want %v", got, err, date)
+	}
+	if got := m.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q, want quoted-printable", got)
+	}
+	b, err := ioutil.ReadAll(quotedprintable.NewReader(m.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Replace(string(b), "\r\n", "\n", -1); got != body {
+		t.Errorf("body = %q, want %q", got, body)
+	}
+}
+
+func TestWriterMultipart(t *testing.T) {
+	const (
+		text     = "Plain text\n"
+		html     = "<p>HTML</p>\n"
+		filename = "Übersicht.pdf"
+	)
+	attachment := bytes.Repeat([]byte{0, 1, 2, 0xff}, 100)
+
+	h := make(Header)
+	h.SetAddressList("From", []*Address{{Address: "gopher@example.com"}})
+	h.Set("Subject", "Attachments")
+
+	var buf bytes.Buffer
+	mw, err := NewMultipartWriter(&buf, h, "mixed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt, err := mw.CreateMultipart("alternative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		ph := make(Header)
+		ph.Set("Content-Type", p.contentType)
+		w, err := alt.CreatePart(ph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, p.body)
+		w.Close()
+	}
+	if err := alt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := mw.CreateAttachment(filename, "application/pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(attachment)
+	w.Close()
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipartReader(t, m.Header.Get("Content-Type"), "multipart/mixed", m.Body)
+
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ar := multipartReader(t, p.Header.Get("Content-Type"), "multipart/alternative", p)
+	for _, want := range []string{text, html} {
+		ap, err := ar.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(ap) // quoted-printable is decoded by the reader
+		if got := strings.Replace(string(b), "\r\n", "\n", -1); got != want {
+			t.Errorf("alternative part = %q, want %q", got, want)
+		}
+	}
+	if _, err := ar.NextPart(); err != io.EOF {
+		t.Errorf("after alternative parts: got %v, want EOF", err)
+	}
+
+	p, err = mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.FileName(); got != filename {
+		t.Errorf("attachment file name = %q, want %q", got, filename)
+	}
+	b, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, attachment) {
+		t.Errorf("attachment = %x, want %x", b, attachment)
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("after attachment: got %v, want EOF", err)
+	}
+}
+
+func multipartReader(t *testing.T, contentType, want string, r io.Reader) *multipart.Reader {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != want {
+		t.Fatalf("Content-Type %q: got %s, %v; want %s", contentType, mediaType, err, want)
+	}
+	return multipart.NewReader(r, params["boundary"])
+}
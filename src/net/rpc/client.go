@@ -6,6 +6,7 @@ package rpc
 
 import (
 	"bufio"
+	"context"
 	"encoding/gob"
 	"errors"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"time"
 )
 
 // ServerError represents an error that has been returned from
@@ -32,6 +34,9 @@ type Call struct {
 	Reply         interface{} // The reply from the function (*struct).
 	Error         error       // After completion, the error status.
 	Done          chan *Call  // Strobes when call is complete.
+
+	seq     uint64        // sequence number assigned by send
+	timeout time.Duration // reported to the server in Request.Timeout
 }
 
 // Client represents an RPC Client.
@@ -83,11 +88,13 @@ func (client *Client) send(call *Call) {
 	seq := client.seq
 	client.seq++
 	client.pending[seq] = call
+	call.seq = seq
 	client.mutex.Unlock()
 
 	// Encode and send the request.
 	client.request.Seq = seq
 	client.request.ServiceMethod = call.ServiceMethod
+	client.request.Timeout = call.timeout
 	err := client.codec.WriteRequest(&client.request, call.Args)
 	if err != nil {
 		client.mutex.Lock()
@@ -131,7 +138,11 @@ func (client *Client) input() {
 			// We've got an error response. Give this to the request;
 			// any subsequent requests will get the ReadResponseBody
 			// error if there is one.
-			call.Error = ServerError(response.Error)
+			if err := response.Err(); err != nil {
+				call.Error = err
+			} else {
+				call.Error = ServerError(response.Error)
+			}
 			err = client.codec.ReadResponseBody(nil)
 			if err != nil {
 				err = errors.New("reading error body: " + err.Error())
@@ -317,3 +328,47 @@ func (client *Client) Call(serviceMethod string, args interface{}, reply interfa
 	call := <-client.Go(serviceMethod, args, reply, make(chan *Call, 1)).Done
 	return call.Error
 }
+
+// CallContext is like Call but gives up waiting for the reply when ctx is done,
+// returning ctx.Err(). If ctx has a deadline, the time remaining is sent to
+// the server with the request, where it bounds the context passed to service
+// methods that accept one. A reply arriving after CallContext has returned
+// is discarded.
+func (client *Client) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if ctx.Done() == nil {
+		return client.Call(serviceMethod, args, reply)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	call := &Call{
+		ServiceMethod: serviceMethod,
+		Args:          args,
+		Reply:         reply,
+		Done:          make(chan *Call, 1),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		call.timeout = time.Until(deadline)
+		if call.timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	client.send(call)
+	select {
+	case call = <-call.Done:
+		return call.Error
+	case <-ctx.Done():
+	}
+	client.mutex.Lock()
+	pending := client.pending[call.seq] == call
+	if pending {
+		delete(client.pending, call.seq)
+	}
+	client.mutex.Unlock()
+	if !pending {
+		// The reply won the race; it is being delivered now.
+		call = <-call.Done
+		return call.Error
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,234 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Args struct {
+	A, B int
+}
+
+type Reply struct {
+	C int
+}
+
+type Arith int
+
+var notified = make(chan Args, 10)
+
+func (t *Arith) Add(args *Args, reply *Reply) error {
+	reply.C = args.A + args.B
+	return nil
+}
+
+func (t *Arith) Div(args *Args, reply *Reply) error {
+	if args.B == 0 {
+		return &Error{Code: 1, Message: "divide by zero", Data: args.A}
+	}
+	reply.C = args.A / args.B
+	return nil
+}
+
+func (t *Arith) Fail(args *Args, reply *Reply) error {
+	return errors.New("failed")
+}
+
+func (t *Arith) Notify(args *Args, reply *Reply) error {
+	notified <- *args
+	return nil
+}
+
+func (t *Arith) Wait(ctx context.Context, args *Args, reply *Reply) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type BuiltinTypes struct{}
+
+func (BuiltinTypes) Double(i int, reply *int) error {
+	*reply = 2 * i
+	return nil
+}
+
+func init() {
+	rpc.Register(new(Arith))
+	rpc.Register(BuiltinTypes{})
+}
+
+// serve starts a server on one end of a pipe and returns the other.
+func serve() net.Conn {
+	cli, srv := net.Pipe()
+	go ServeConn(srv)
+	return cli
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		req  string
+		resp string // "" if no response is expected
+	}{
+		// by name and by position
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}, "id": 1}`,
+			`{"jsonrpc": "2.0", "result": {"C": 3}, "id": 1}`},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": [{"A": 3, "B": 4}], "id": "x"}`,
+			`{"jsonrpc": "2.0", "result": {"C": 7}, "id": "x"}`},
+		{`{"jsonrpc": "2.0", "method": "BuiltinTypes.Double", "params": [21], "id": null}`,
+			`{"jsonrpc": "2.0", "result": 42, "id": null}`},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "id": 2}`,
+			`{"jsonrpc": "2.0", "result": {"C": 0}, "id": 2}`},
+
+		// errors
+		{`{"jsonrpc": "2.0", "method": "Arith.Div", "params": {"A": 5}, "id": 3}`,
+			`{"jsonrpc": "2.0", "error": {"code": 1, "message": "divide by zero", "data": 5}, "id": 3}`},
+		{`{"jsonrpc": "2.0", "method": "Arith.Fail", "params": {}, "id": 4}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32000, "message": "failed"}, "id": 4}`},
+		{`{"jsonrpc": "2.0", "method": "Arith.Nope", "params": {}, "id": 5}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32601, "message": "rpc: can't find method Arith.Nope"}, "id": 5}`},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": [1, 2], "id": 6}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32602, "message": "invalid params", "data": "jsonrpc2: expected exactly one positional param"}, "id": 6}`},
+		{`{"jsonrpc": "1.0", "method": "Arith.Add", "params": {}, "id": 7}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid request"}, "id": 7}`},
+		{`{"jsonrpc": "2.0", "method": 1, "params": "bar"}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid request"}, "id": null}`},
+		{`[]`,
+			`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "empty batch"}, "id": null}`},
+
+		// notifications
+		{`{"jsonrpc": "2.0", "method": "Arith.Fail", "params": {}}`, ``},
+		{`[{"jsonrpc": "2.0", "method": "Arith.Nope"}, {"jsonrpc": "2.0", "method": "Arith.Add"}]`, ``},
+
+		// batches
+		{`[{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 1}, "id": 8},
+		  {"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 2, "B": 2}},
+		  1]`,
+			`[{"jsonrpc": "2.0", "result": {"C": 2}, "id": 8},
+			  {"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid request"}, "id": null}]`},
+	}
+
+	cli := serve()
+	defer cli.Close()
+	dec := json.NewDecoder(cli)
+	for _, tt := range tests {
+		fmt.Fprintln(cli, tt.req)
+		if tt.resp == "" {
+			continue
+		}
+		var got, want interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("%s: decoding response: %v", tt.req, err)
+		}
+		if err := json.Unmarshal([]byte(tt.resp), &want); err != nil {
+			t.Fatal(err)
+		}
+		// Batch responses may arrive in any order.
+		if g, ok := got.([]interface{}); ok && len(g) == 2 {
+			if g[0].(map[string]interface{})["id"] == nil {
+				g[0], g[1] = g[1], g[0]
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %v\nwant %v", tt.req, got, want)
+		}
+	}
+}
+
+func TestServerParseError(t *testing.T) {
+	cli := serve()
+	defer cli.Close()
+	fmt.Fprintln(cli, `{"jsonrpc": "2.0", "method": }`)
+	var resp map[string]interface{}
+	if err := json.NewDecoder(cli).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	code := resp["error"].(map[string]interface{})["code"]
+	if code != float64(CodeParseError) || resp["id"] != nil {
+		t.Errorf("got %v, want parse error with null id", resp)
+	}
+}
+
+func TestClient(t *testing.T) {
+	client := NewClient(serve())
+	defer client.Close()
+
+	var reply Reply
+	if err := client.Call("Arith.Add", &Args{7, 8}, &reply); err != nil {
+		t.Fatal("Add:", err)
+	}
+	if reply.C != 15 {
+		t.Errorf("Add: got %d, want 15", reply.C)
+	}
+	var n int
+	if err := client.Call("BuiltinTypes.Double", 4, &n); err != nil || n != 8 {
+		t.Errorf("Double: got %d, %v; want 8, nil", n, err)
+	}
+
+	err := client.Call("Arith.Div", &Args{7, 0}, &reply)
+	e, ok := err.(*Error)
+	if !ok || e.Code != 1 || e.Message != "divide by zero" || e.Data != float64(7) {
+		t.Errorf("Div: got %#v, want *Error with code 1 and data 7", err)
+	}
+	err = client.Call("Arith.Nope", &Args{}, &reply)
+	if e, ok := err.(*Error); !ok || e.Code != CodeMethodNotFound {
+		t.Errorf("Nope: got %#v, want *Error with code %d", err, CodeMethodNotFound)
+	}
+
+	if err := client.Notify("Arith.Notify", &Args{1, 2}); err != nil {
+		t.Fatal("Notify:", err)
+	}
+	select {
+	case args := <-notified:
+		if args != (Args{1, 2}) {
+			t.Errorf("Notify: server got %v, want {1 2}", args)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify: server not notified")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.CallContext(ctx, "Arith.Wait", &Args{}, &reply); err != context.DeadlineExceeded {
+		t.Errorf("Wait: got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientBatch(t *testing.T) {
+	client := NewClient(serve())
+	defer client.Close()
+
+	var sum, quo Reply
+	var double int
+	batch := []BatchElem{
+		{ServiceMethod: "Arith.Add", Args: &Args{1, 2}, Reply: &sum},
+		{ServiceMethod: "Arith.Div", Args: &Args{1, 0}, Reply: &quo},
+		{ServiceMethod: "BuiltinTypes.Double", Args: 5, Reply: &double},
+	}
+	if err := client.Batch(batch); err != nil {
+		t.Fatal("Batch:", err)
+	}
+	if batch[0].Error != nil || sum.C != 3 {
+		t.Errorf("Add: got %d, %v; want 3, nil", sum.C, batch[0].Error)
+	}
+	if e, ok := batch[1].Error.(*Error); !ok || e.Code != 1 {
+		t.Errorf("Div: got %#v, want *Error with code 1", batch[1].Error)
+	}
+	if batch[2].Error != nil || double != 10 {
+		t.Errorf("Double: got %d, %v; want 10, nil", double, batch[2].Error)
+	}
+
+	// The client can still be used on its own afterwards.
+	if err := client.Call("Arith.Add", &Args{2, 2}, &sum); err != nil || sum.C != 4 {
+		t.Errorf("Add after batch: got %d, %v; want 4, nil", sum.C, err)
+	}
+}
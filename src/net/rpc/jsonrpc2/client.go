@@ -0,0 +1,283 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jsonrpc2 implements a JSON-RPC 2.0 ClientCodec and ServerCodec
+// for the rpc package, including batch requests, notifications and
+// structured error objects.
+//
+// Requests carry their single argument either as a one-element params
+// array or, for values that encode as JSON objects, as named params.
+// The server accepts both forms.
+//
+// JSON-RPC 2.0 has no field for deadlines, so unlike the gob codec,
+// these codecs do not carry rpc.Request.Timeout to the server.
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+)
+
+type clientCodec struct {
+	dec *json.Decoder // for reading JSON values
+	c   io.Closer
+
+	encMutex sync.Mutex        // protects enc, batching, batch
+	enc      *json.Encoder     // for writing JSON values
+	batching bool              // requests are being collected in batch
+	batch    []json.RawMessage // requests to send as one batch
+
+	// temporary work space, used by the reading goroutine only
+	resp  clientResponse
+	queue []json.RawMessage // unread members of a batch response
+
+	// JSON-RPC responses include the request id but not the request method.
+	// Package rpc expects both.
+	// We save the request method in pending when sending a request
+	// and then look it up by request ID when filling out the rpc Response.
+	mutex   sync.Mutex        // protects pending
+	pending map[uint64]string // map request id to method name
+}
+
+func newClientCodec(conn io.ReadWriteCloser) *clientCodec {
+	return &clientCodec{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		pending: make(map[uint64]string),
+	}
+}
+
+// NewClientCodec returns a new rpc.ClientCodec using JSON-RPC 2.0 on conn.
+func NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return newClientCodec(conn)
+}
+
+type clientRequest struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	Id      *uint64     `json:"id,omitempty"`
+}
+
+// newRequest returns a request for method with param as its params.
+// The request is a notification until its Id is set.
+func newRequest(method string, param interface{}) (*clientRequest, error) {
+	req := &clientRequest{Version: "2.0", Method: method}
+	if param == nil {
+		return req, nil
+	}
+	b, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] == '{' {
+		req.Params = json.RawMessage(b)
+	} else {
+		req.Params = [1]json.RawMessage{b}
+	}
+	return req, nil
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
+	req, err := newRequest(r.ServiceMethod, param)
+	if err != nil {
+		return err
+	}
+	seq := r.Seq
+	req.Id = &seq
+	c.mutex.Lock()
+	c.pending[r.Seq] = r.ServiceMethod
+	c.mutex.Unlock()
+	return c.write(req)
+}
+
+func (c *clientCodec) notify(method string, param interface{}) error {
+	req, err := newRequest(method, param)
+	if err != nil {
+		return err
+	}
+	return c.write(req)
+}
+
+func (c *clientCodec) write(req *clientRequest) error {
+	c.encMutex.Lock()
+	defer c.encMutex.Unlock()
+	if c.batching {
+		b, err := json.Marshal(req)
+		if err == nil {
+			c.batch = append(c.batch, b)
+		}
+		return err
+	}
+	return c.enc.Encode(req)
+}
+
+// beginBatch causes requests to be collected until endBatch sends them.
+func (c *clientCodec) beginBatch() {
+	c.encMutex.Lock()
+	c.batching = true
+	c.encMutex.Unlock()
+}
+
+func (c *clientCodec) endBatch() error {
+	c.encMutex.Lock()
+	defer c.encMutex.Unlock()
+	batch := c.batch
+	c.batching = false
+	c.batch = nil
+	if len(batch) == 0 {
+		return nil
+	}
+	return c.enc.Encode(batch)
+}
+
+type clientResponse struct {
+	Version string          `json:"jsonrpc"`
+	Id      *uint64         `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+func (r *clientResponse) reset() {
+	r.Version = ""
+	r.Id = nil
+	r.Result = nil
+	r.Error = nil
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	for len(c.queue) == 0 {
+		var raw json.RawMessage
+		if err := c.dec.Decode(&raw); err != nil {
+			return err
+		}
+		if raw[0] != '[' {
+			c.queue = append(c.queue, raw)
+		} else if err := json.Unmarshal(raw, &c.queue); err != nil {
+			return err
+		}
+	}
+	raw := c.queue[0]
+	c.queue[0] = nil
+	c.queue = c.queue[1:]
+
+	c.resp.reset()
+	if err := json.Unmarshal(raw, &c.resp); err != nil {
+		return err
+	}
+	if c.resp.Id == nil {
+		// The server could not tell which request this answers,
+		// so it could not parse what we sent.
+		if c.resp.Error != nil {
+			return errors.New("jsonrpc2: server rejected request: " + c.resp.Error.Message)
+		}
+		return errors.New("jsonrpc2: response without id")
+	}
+
+	c.mutex.Lock()
+	r.ServiceMethod = c.pending[*c.resp.Id]
+	delete(c.pending, *c.resp.Id)
+	c.mutex.Unlock()
+
+	r.Error = ""
+	r.Seq = *c.resp.Id
+	switch {
+	case c.resp.Error != nil:
+		if c.resp.Error.Message == "" {
+			c.resp.Error.Message = "unspecified error"
+		}
+		r.SetErr(c.resp.Error)
+	case c.resp.Result == nil:
+		return errors.New("jsonrpc2: response has neither result nor error")
+	}
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	return json.Unmarshal(c.resp.Result, x)
+}
+
+func (c *clientCodec) Close() error {
+	return c.c.Close()
+}
+
+// Client is an rpc.Client using JSON-RPC 2.0, with additional methods
+// for sending notifications and batch requests.
+type Client struct {
+	*rpc.Client
+	codec *clientCodec
+
+	batchMutex sync.Mutex // serializes Batch
+}
+
+// NewClient returns a new Client to handle requests to the
+// set of services at the other end of the connection.
+func NewClient(conn io.ReadWriteCloser) *Client {
+	codec := newClientCodec(conn)
+	return &Client{Client: rpc.NewClientWithCodec(codec), codec: codec}
+}
+
+// Dial connects to a JSON-RPC 2.0 server at the specified network address.
+func Dial(network, address string) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Notify sends a notification: a request to which the server sends
+// no reply, not even to report an error.
+func (client *Client) Notify(serviceMethod string, args interface{}) error {
+	return client.codec.notify(serviceMethod, args)
+}
+
+// A BatchElem is a single call in a batch request.
+type BatchElem struct {
+	ServiceMethod string
+	Args          interface{}
+	Reply         interface{}
+	Error         error // set by Batch once the call completes
+}
+
+// Batch sends the calls in b to the server as a single batch request and
+// waits for all of them to complete. The outcome of each call is recorded
+// in its Error field. Batch returns an error only if the request could not
+// be written, in which case the client is closed.
+//
+// Requests made by other goroutines while the batch is being assembled
+// are sent as part of it.
+func (client *Client) Batch(b []BatchElem) error {
+	if len(b) == 0 {
+		return nil
+	}
+	client.batchMutex.Lock()
+	done := make(chan *rpc.Call, len(b))
+	calls := make([]*rpc.Call, len(b))
+	client.codec.beginBatch()
+	for i := range b {
+		calls[i] = client.Go(b[i].ServiceMethod, b[i].Args, b[i].Reply, done)
+	}
+	err := client.codec.endBatch()
+	client.batchMutex.Unlock()
+	if err != nil {
+		// Closing fails the calls that were never sent.
+		client.Close()
+	}
+	for range calls {
+		<-done
+	}
+	for i, call := range calls {
+		b[i].Error = call.Error
+	}
+	return err
+}
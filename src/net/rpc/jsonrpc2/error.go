@@ -0,0 +1,34 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeServerError is sent for errors returned by service methods
+	// that are not of type *Error.
+	CodeServerError = -32000
+)
+
+// Error is a JSON-RPC 2.0 error object.
+//
+// A service method may return an *Error to control the code and data
+// sent to the client; any other error is sent with CodeServerError and
+// its text as the message. On the client side, calls that fail on the
+// server report an *Error.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
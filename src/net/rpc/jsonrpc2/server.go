@@ -0,0 +1,273 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/rpc"
+	"sync"
+)
+
+var null = json.RawMessage("null")
+
+type serverCodec struct {
+	dec *json.Decoder // for reading JSON values
+	c   io.Closer
+
+	encMutex sync.Mutex    // protects enc
+	enc      *json.Encoder // for writing JSON values
+
+	// temporary work space, used by the reading goroutine only
+	req   serverRequest
+	cur   *pending          // request being read
+	queue []json.RawMessage // unread members of the current batch
+	batch *batch            // the current batch, if any
+
+	// JSON-RPC clients can use arbitrary json values as request IDs.
+	// Package rpc expects uint64 request IDs.
+	// We assign uint64 sequence numbers to incoming requests
+	// but save the original request in the pending map.
+	// When rpc responds, we use the sequence number in
+	// the response to find the original request.
+	mutex   sync.Mutex // protects seq, pending and batch counts
+	seq     uint64
+	pending map[uint64]*pending
+}
+
+// A pending describes a request that has not yet been responded to.
+type pending struct {
+	id    json.RawMessage // nil for a notification
+	batch *batch          // batch the request arrived in, if any
+	err   *Error          // error detected by the codec itself
+}
+
+// A batch collects the responses to a batch request until every member
+// has been handled, so that they can be sent together.
+type batch struct {
+	outstanding int
+	responses   []json.RawMessage
+}
+
+// NewServerCodec returns a new rpc.ServerCodec using JSON-RPC 2.0 on conn.
+func NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		pending: make(map[uint64]*pending),
+	}
+}
+
+type serverRequest struct {
+	Version string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  *json.RawMessage `json:"params"`
+	Id      json.RawMessage  `json:"id"`
+}
+
+func (r *serverRequest) reset() {
+	r.Version = ""
+	r.Method = ""
+	r.Params = nil
+	r.Id = nil
+}
+
+type serverResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	Id      json.RawMessage `json:"id"`
+}
+
+// validId reports whether id is acceptable as a request ID:
+// a string, a number or null.
+func validId(id json.RawMessage) bool {
+	switch c := id[0]; {
+	case c == '"', c == '-', '0' <= c && c <= '9':
+		return true
+	}
+	return string(id) == "null"
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	for len(c.queue) == 0 {
+		c.batch = nil
+		var raw json.RawMessage
+		if err := c.dec.Decode(&raw); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				c.write(serverResponse{Version: "2.0", Error: &Error{Code: CodeParseError, Message: "parse error"}, Id: null})
+			}
+			return err
+		}
+		if raw[0] != '[' {
+			c.queue = append(c.queue, raw)
+			break
+		}
+		if err := json.Unmarshal(raw, &c.queue); err != nil {
+			return err
+		}
+		if len(c.queue) == 0 {
+			c.write(serverResponse{Version: "2.0", Error: &Error{Code: CodeInvalidRequest, Message: "empty batch"}, Id: null})
+			continue
+		}
+		// Count every member now, so that early responses
+		// cannot complete the batch before it has been read.
+		c.batch = &batch{outstanding: len(c.queue)}
+	}
+	raw := c.queue[0]
+	c.queue[0] = nil
+	c.queue = c.queue[1:]
+
+	c.req.reset()
+	c.cur = &pending{batch: c.batch}
+	err := json.Unmarshal(raw, &c.req)
+	switch {
+	case err != nil:
+		c.cur.id = null
+		c.cur.err = &Error{Code: CodeInvalidRequest, Message: "invalid request"}
+	case c.req.Id != nil && !validId(c.req.Id):
+		c.cur.id = null
+		c.cur.err = &Error{Code: CodeInvalidRequest, Message: "invalid request id"}
+	case c.req.Version != "2.0" || c.req.Method == "":
+		c.cur.id = c.req.Id
+		if c.cur.id == nil {
+			c.cur.id = null
+		}
+		c.cur.err = &Error{Code: CodeInvalidRequest, Message: "invalid request"}
+	default:
+		c.cur.id = c.req.Id
+		r.ServiceMethod = c.req.Method
+	}
+	// An invalid request leaves r.ServiceMethod empty, which
+	// package rpc rejects; WriteResponse then reports c.cur.err.
+
+	c.mutex.Lock()
+	c.seq++
+	c.pending[c.seq] = c.cur
+	r.Seq = c.seq
+	c.mutex.Unlock()
+
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(x interface{}) error {
+	if x == nil || c.req.Params == nil {
+		// Params may be omitted; x is left as the zero value.
+		return nil
+	}
+	// JSON-RPC 2.0 params are either by position, an array,
+	// or by name, an object. RPC params is a single value.
+	// Accept a one-element array holding it, or the object itself.
+	params := *c.req.Params
+	var err error
+	switch params[0] {
+	case '[':
+		var args []json.RawMessage
+		if err = json.Unmarshal(params, &args); err == nil {
+			if len(args) != 1 {
+				err = errors.New("jsonrpc2: expected exactly one positional param")
+			} else {
+				err = json.Unmarshal(args[0], x)
+			}
+		}
+	case '{':
+		err = json.Unmarshal(params, x)
+	default:
+		err = errors.New("jsonrpc2: params must be an array or object")
+	}
+	if err != nil {
+		c.cur.err = &Error{Code: CodeInvalidParams, Message: "invalid params", Data: err.Error()}
+	}
+	return err
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, x interface{}) error {
+	c.mutex.Lock()
+	p, ok := c.pending[r.Seq]
+	if !ok {
+		c.mutex.Unlock()
+		return errors.New("invalid sequence number in response")
+	}
+	delete(c.pending, r.Seq)
+	c.mutex.Unlock()
+
+	if p.id == nil {
+		// Notifications are never answered, even on error.
+		return c.finish(p.batch, nil)
+	}
+	resp := serverResponse{Version: "2.0", Id: p.id}
+	switch {
+	case p.err != nil:
+		resp.Error = p.err
+	case r.Error == "":
+		resp.Result = x
+	default:
+		resp.Error = responseError(r)
+	}
+	if p.batch == nil {
+		return c.write(resp)
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		// Still answer, so the batch can complete.
+		resp.Result = nil
+		resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		b, _ = json.Marshal(resp)
+	}
+	return c.finish(p.batch, b)
+}
+
+// responseError converts the error reported in r to an error object.
+func responseError(r *rpc.Response) *Error {
+	switch err := r.Err().(type) {
+	case *Error:
+		return err
+	case nil:
+		// Besides the codec's own errors, which are reported
+		// separately, package rpc only fails requests naming
+		// a service or method it does not have.
+		return &Error{Code: CodeMethodNotFound, Message: r.Error}
+	default:
+		return &Error{Code: CodeServerError, Message: r.Error}
+	}
+}
+
+// finish records the response, if any, to a member of batch b and sends
+// the batch's responses once all of its members have been handled.
+func (c *serverCodec) finish(b *batch, resp json.RawMessage) error {
+	if b == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	if resp != nil {
+		b.responses = append(b.responses, resp)
+	}
+	b.outstanding--
+	done := b.outstanding == 0
+	c.mutex.Unlock()
+	if !done || len(b.responses) == 0 {
+		return nil
+	}
+	return c.write(b.responses)
+}
+
+func (c *serverCodec) write(v interface{}) error {
+	c.encMutex.Lock()
+	defer c.encMutex.Unlock()
+	return c.enc.Encode(v)
+}
+
+func (c *serverCodec) Close() error {
+	return c.c.Close()
+}
+
+// ServeConn runs the JSON-RPC 2.0 server on a single connection.
+// ServeConn blocks, serving the connection until the client hangs up.
+// The caller typically invokes ServeConn in a go statement.
+func ServeConn(conn io.ReadWriteCloser) {
+	rpc.ServeCodec(NewServerCodec(conn))
+}
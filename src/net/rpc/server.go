@@ -15,7 +15,8 @@
 
 		- the method's type is exported.
 		- the method is exported.
-		- the method has two arguments, both exported (or builtin) types.
+		- the method has two arguments, both exported (or builtin) types,
+		  optionally preceded by a context.Context.
 		- the method's second argument is a pointer.
 		- the method has return type error.
 
@@ -27,10 +28,19 @@
 	These requirements apply even if a different codec is used.
 	(In the future, these requirements may soften for custom codecs.)
 
+	A method may also take a context.Context before its two arguments:
+
+		func (t *T) MethodName(ctx context.Context, argType T1, replyType *T2) error
+
+	The context is canceled when the connection is closed and, if the client
+	made the call with Client.CallContext and a deadline, when the time the
+	client was willing to wait has elapsed.
+
 	The method's first argument represents the arguments provided by the caller; the
 	second argument represents the result parameters to be returned to the caller.
 	The method's return value, if non-nil, is passed back as a string that the client
-	sees as if created by errors.New.  If an error is returned, the reply parameter
+	sees as if created by errors.New, unless the codec in use is able to carry
+	the error value itself (see Response.Err).  If an error is returned, the reply parameter
 	will not be sent back to the client.
 
 	The server may handle requests on a single connection by calling ServeConn.  More
@@ -46,7 +56,8 @@
 
 	The Call method waits for the remote call to complete while the Go method
 	launches the call asynchronously and signals completion using the Call
-	structure's Done channel.
+	structure's Done channel. CallContext is like Call but stops waiting when
+	its context is done, and reports the context's deadline to the server.
 
 	Unless an explicit codec is set up, package encoding/gob is used to
 	transport the data.
@@ -128,6 +139,7 @@ package rpc
 
 import (
 	"bufio"
+	"context"
 	"encoding/gob"
 	"errors"
 	"io"
@@ -137,6 +149,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 )
@@ -151,12 +164,16 @@ const (
 // because Typeof takes an empty interface value. This is annoying.
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
+// Likewise for context.Context.
+var typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+
 type methodType struct {
-	sync.Mutex // protects counters
-	method     reflect.Method
-	ArgType    reflect.Type
-	ReplyType  reflect.Type
-	numCalls   uint
+	sync.Mutex  // protects counters
+	method      reflect.Method
+	ArgType     reflect.Type
+	ReplyType   reflect.Type
+	withContext bool // method takes a context.Context first
+	numCalls    uint
 }
 
 type service struct {
@@ -170,9 +187,10 @@ type service struct {
 // but documented here as an aid to debugging, such as when analyzing
 // network traffic.
 type Request struct {
-	ServiceMethod string   // format: "Service.Method"
-	Seq           uint64   // sequence number chosen by client
-	next          *Request // for free list in Server
+	ServiceMethod string        // format: "Service.Method"
+	Seq           uint64        // sequence number chosen by client
+	Timeout       time.Duration // time left until the client's deadline; zero if none
+	next          *Request      // for free list in Server
 }
 
 // Response is a header written before every RPC return. It is used internally
@@ -182,9 +200,28 @@ type Response struct {
 	ServiceMethod string    // echoes that of the Request
 	Seq           uint64    // echoes that of the request
 	Error         string    // error, if any.
+	err           error     // error value behind Error; not transmitted
 	next          *Response // for free list in Server
 }
 
+// Err returns the error value recorded by SetErr, or nil.
+// On the server side it is the error returned by the service method, which
+// codecs able to encode structured errors may use in place of Error.
+// Errors detected by the server itself, such as an unknown method,
+// are reported in Error alone.
+func (r *Response) Err() error {
+	return r.err
+}
+
+// SetErr sets Error to err.Error() and records err so that Err returns it.
+// On the client side, a codec that decodes a structured error may call SetErr
+// so that the Call reports err instead of a ServerError.
+// The error value itself is never encoded by the gob codec.
+func (r *Response) SetErr(err error) {
+	r.err = err
+	r.Error = err.Error()
+}
+
 // Server represents an RPC Server.
 type Server struct {
 	mu         sync.RWMutex // protects the serviceMap
@@ -222,7 +259,8 @@ func isExportedOrBuiltinType(t reflect.Type) bool {
 // Register publishes in the server the set of methods of the
 // receiver value that satisfy the following conditions:
 //	- exported method of exported type
-//	- two arguments, both of exported type
+//	- two arguments, both of exported type, optionally
+//	  preceded by a context.Context
 //	- the second argument is a pointer
 //	- one return value, of type error
 // It returns an error if the receiver is not an exported type or has
@@ -299,15 +337,21 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 		if method.PkgPath != "" {
 			continue
 		}
-		// Method needs three ins: receiver, *args, *reply.
-		if mtype.NumIn() != 3 {
+		// Method needs three ins: receiver, *args, *reply;
+		// or four, with a context.Context before *args.
+		in := 1
+		withContext := mtype.NumIn() == 4 && mtype.In(1) == typeOfContext
+		if withContext {
+			in++
+		}
+		if mtype.NumIn() != in+2 {
 			if reportErr {
 				log.Println("method", mname, "has wrong number of ins:", mtype.NumIn())
 			}
 			continue
 		}
 		// First arg need not be a pointer.
-		argType := mtype.In(1)
+		argType := mtype.In(in)
 		if !isExportedOrBuiltinType(argType) {
 			if reportErr {
 				log.Println(mname, "argument type not exported:", argType)
@@ -315,7 +359,7 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 			continue
 		}
 		// Second arg must be a pointer.
-		replyType := mtype.In(2)
+		replyType := mtype.In(in + 1)
 		if replyType.Kind() != reflect.Ptr {
 			if reportErr {
 				log.Println("method", mname, "reply type not a pointer:", replyType)
@@ -343,7 +387,7 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 			}
 			continue
 		}
-		methods[mname] = &methodType{method: method, ArgType: argType, ReplyType: replyType}
+		methods[mname] = &methodType{method: method, ArgType: argType, ReplyType: replyType, withContext: withContext}
 	}
 	return methods
 }
@@ -353,17 +397,20 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 // contains an error when it is used.
 var invalidRequest = struct{}{}
 
-func (server *Server) sendResponse(sending *sync.Mutex, req *Request, reply interface{}, codec ServerCodec, errmsg string) {
+func (server *Server) sendResponse(sending *sync.Mutex, req *Request, reply interface{}, codec ServerCodec, errmsg string, err error) {
 	resp := server.getResponse()
 	// Encode the response header
 	resp.ServiceMethod = req.ServiceMethod
-	if errmsg != "" {
+	if err != nil {
+		resp.SetErr(err)
+		reply = invalidRequest
+	} else if errmsg != "" {
 		resp.Error = errmsg
 		reply = invalidRequest
 	}
 	resp.Seq = req.Seq
 	sending.Lock()
-	err := codec.WriteResponse(resp, reply)
+	err = codec.WriteResponse(resp, reply)
 	if debugLog && err != nil {
 		log.Println("rpc: writing response:", err)
 	}
@@ -378,20 +425,29 @@ func (m *methodType) NumCalls() (n uint) {
 	return n
 }
 
-func (s *service) call(server *Server, sending *sync.Mutex, mtype *methodType, req *Request, argv, replyv reflect.Value, codec ServerCodec) {
+func (s *service) call(ctx context.Context, server *Server, sending *sync.Mutex, mtype *methodType, req *Request, argv, replyv reflect.Value, codec ServerCodec) {
 	mtype.Lock()
 	mtype.numCalls++
 	mtype.Unlock()
 	function := mtype.method.Func
 	// Invoke the method, providing a new value for the reply.
-	returnValues := function.Call([]reflect.Value{s.rcvr, argv, replyv})
+	var returnValues []reflect.Value
+	if mtype.withContext {
+		if req.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+			defer cancel()
+		}
+		returnValues = function.Call([]reflect.Value{s.rcvr, reflect.ValueOf(ctx), argv, replyv})
+	} else {
+		returnValues = function.Call([]reflect.Value{s.rcvr, argv, replyv})
+	}
 	// The return value for the method is an error.
-	errInter := returnValues[0].Interface()
-	errmsg := ""
-	if errInter != nil {
-		errmsg = errInter.(error).Error()
+	var err error
+	if errInter := returnValues[0].Interface(); errInter != nil {
+		err = errInter.(error)
 	}
-	server.sendResponse(sending, req, replyv.Interface(), codec, errmsg)
+	server.sendResponse(sending, req, replyv.Interface(), codec, "", err)
 	server.freeRequest(req)
 }
 
@@ -461,6 +517,9 @@ func (server *Server) ServeConn(conn io.ReadWriteCloser) {
 // ServeCodec is like ServeConn but uses the specified codec to
 // decode requests and encode responses.
 func (server *Server) ServeCodec(codec ServerCodec) {
+	// Calls still running when the client hangs up see their context canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	sending := new(sync.Mutex)
 	for {
 		service, mtype, req, argv, replyv, keepReading, err := server.readRequest(codec)
@@ -473,12 +532,12 @@ func (server *Server) ServeCodec(codec ServerCodec) {
 			}
 			// send a response if we actually managed to read a header.
 			if req != nil {
-				server.sendResponse(sending, req, invalidRequest, codec, err.Error())
+				server.sendResponse(sending, req, invalidRequest, codec, err.Error(), nil)
 				server.freeRequest(req)
 			}
 			continue
 		}
-		go service.call(server, sending, mtype, req, argv, replyv, codec)
+		go service.call(ctx, server, sending, mtype, req, argv, replyv, codec)
 	}
 	codec.Close()
 }
@@ -494,12 +553,12 @@ func (server *Server) ServeRequest(codec ServerCodec) error {
 		}
 		// send a response if we actually managed to read a header.
 		if req != nil {
-			server.sendResponse(sending, req, invalidRequest, codec, err.Error())
+			server.sendResponse(sending, req, invalidRequest, codec, err.Error(), nil)
 			server.freeRequest(req)
 		}
 		return err
 	}
-	service.call(server, sending, mtype, req, argv, replyv, codec)
+	service.call(context.Background(), server, sending, mtype, req, argv, replyv, codec)
 	return nil
 }
 
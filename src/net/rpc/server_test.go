@@ -5,6 +5,7 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -785,3 +786,75 @@ func BenchmarkEndToEndAsync(b *testing.B) {
 func BenchmarkEndToEndAsyncHTTP(b *testing.B) {
 	benchmarkEndToEndAsync(dialHTTP, b)
 }
+
+type Waiter struct{}
+
+func (Waiter) Deadline(ctx context.Context, args int, reply *time.Duration) error {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return errors.New("no deadline")
+	}
+	*reply = time.Until(d)
+	return nil
+}
+
+func (Waiter) Wait(ctx context.Context, args int, reply *int) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCallContext(t *testing.T) {
+	server := NewServer()
+	if err := server.Register(Waiter{}); err != nil {
+		t.Fatal(err)
+	}
+	l, addr := listenTCP()
+	defer l.Close()
+	go server.Accept(l)
+
+	client, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	defer client.Close()
+
+	// The deadline reaches the server.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var left time.Duration
+	if err := client.CallContext(ctx, "Waiter.Deadline", 0, &left); err != nil {
+		t.Fatal("Deadline:", err)
+	}
+	if left <= 0 || left > time.Minute {
+		t.Errorf("Deadline: server saw %v left, want in (0, 1m]", left)
+	}
+	err = client.Call("Waiter.Deadline", 0, &left)
+	if err == nil || err.Error() != "no deadline" {
+		t.Errorf("Deadline without context: got %v, want no deadline", err)
+	}
+
+	// The client stops waiting when the deadline passes,
+	// and so does the server.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var reply int
+	if err := client.CallContext(ctx, "Waiter.Wait", 0, &reply); err != context.DeadlineExceeded {
+		t.Errorf("Wait: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Canceling stops the client waiting; the late reply is discarded
+	// and the connection remains usable.
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	ctx2, cancel2 := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel2()
+	if err := client.CallContext(ctx2, "Waiter.Wait", 0, &reply); err != context.Canceled {
+		t.Errorf("Wait: got %v, want %v", err, context.Canceled)
+	}
+	time.Sleep(300 * time.Millisecond)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := client.CallContext(ctx, "Waiter.Deadline", 0, &left); err != nil {
+		t.Fatal("Deadline after cancel:", err)
+	}
+}
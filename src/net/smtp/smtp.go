@@ -4,18 +4,21 @@
 
 // Package smtp implements the Simple Mail Transfer Protocol as defined in RFC 5321.
 // It also implements the following extensions:
-//	8BITMIME  RFC 1652
-//	AUTH      RFC 2554
-//	STARTTLS  RFC 3207
+//	8BITMIME    RFC 1652
+//	PIPELINING  RFC 2920
+//	AUTH        RFC 2554
+//	STARTTLS    RFC 3207
+//	DSN         RFC 3461
+//	SMTPUTF8    RFC 6531
 // Additional extensions may be handled by clients.
 //
-// The smtp package is frozen and is not accepting new features.
 // Some external packages provide more functionality. See:
 //
 //   https://godoc.org/?q=smtp
 package smtp
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"errors"
@@ -24,6 +27,7 @@ import (
 	"net"
 	"net/textproto"
 	"strings"
+	"time"
 )
 
 // A Client represents a client connection to an SMTP server.
@@ -57,6 +61,63 @@ func Dial(addr string) (*Client, error) {
 	return NewClient(conn, host)
 }
 
+// DialContext is like Dial but uses ctx to bound both connecting to the
+// server and reading its greeting. Once the Client is returned, the
+// expiration of ctx does not affect it; see SetDeadline.
+func DialContext(ctx context.Context, addr string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	stop := watchContext(ctx, conn)
+	c, err := NewClient(conn, host)
+	if ctxErr := stop(); err != nil && ctxErr != nil {
+		err = ctxErr
+	}
+	return c, err
+}
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancelation of I/O.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// watchContext makes I/O on conn fail once ctx is done or its deadline
+// passes, until the returned stop function is called. stop clears
+// conn's deadline and returns ctx.Err() if ctx interrupted the I/O.
+func watchContext(ctx context.Context, conn net.Conn) (stop func() error) {
+	if ctx.Done() == nil {
+		return func() error { return nil }
+	}
+	if d, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(d)
+	}
+	done := make(chan struct{})
+	interrupted := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(aLongTimeAgo)
+			interrupted <- ctx.Err()
+		case <-done:
+			interrupted <- nil
+		}
+	}()
+	return func() error {
+		close(done)
+		err := <-interrupted
+		conn.SetDeadline(time.Time{})
+		if err == nil {
+			// The deadline set on conn may fire before ctx notices.
+			if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+				err = context.DeadlineExceeded
+			}
+		}
+		return err
+	}
+}
+
 // NewClient returns a new Client using an existing connection and host as a
 // server name to be used when authenticating.
 func NewClient(conn net.Conn, host string) (*Client, error) {
@@ -75,6 +136,13 @@ func (c *Client) Close() error {
 	return c.Text.Close()
 }
 
+// SetDeadline sets the read and write deadlines of the connection to the
+// server, as for net.Conn. A command that does not complete by then fails
+// with a timeout error. A zero value for t means commands will not time out.
+func (c *Client) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
 // hello runs a hello exchange if needed.
 func (c *Client) hello() error {
 	if !c.didHello {
@@ -232,32 +300,233 @@ func (c *Client) Auth(a Auth) error {
 	return err
 }
 
+// MailOptions holds the delivery status notification parameters
+// (RFC 3461) of a MAIL command. They are sent only if the server
+// supports the DSN extension.
+type MailOptions struct {
+	// Return is the RET parameter: "FULL" to have the whole message
+	// returned in failure notifications, or "HDRS" for only its headers.
+	// If empty, the server decides. Other values are an error.
+	Return string
+
+	// EnvelopeID is the ENVID parameter, an identifier
+	// that the server includes in any notifications.
+	EnvelopeID string
+}
+
+// RcptOptions holds the delivery status notification parameters
+// (RFC 3461) of a RCPT command. They are sent only if the server
+// supports the DSN extension.
+type RcptOptions struct {
+	// Notify is the NOTIFY parameter: either "NEVER" alone, or any
+	// of "SUCCESS", "FAILURE" and "DELAY", each at most once. If empty,
+	// the server decides. Other values are an error.
+	Notify []string
+
+	// OriginalRecipient is the ORCPT parameter, the address to which
+	// the message was originally sent, if it differs from the recipient.
+	OriginalRecipient string
+}
+
+// validate checks that the parameters in opts, which may be nil, are
+// valid, so that they can be sent as they are.
+func (opts *MailOptions) validate() error {
+	if opts == nil {
+		return nil
+	}
+	switch opts.Return {
+	case "", "FULL", "HDRS":
+		return nil
+	}
+	return fmt.Errorf("smtp: invalid RET parameter %q", opts.Return)
+}
+
+// validate checks that the parameters in opts, which may be nil, are
+// valid, so that they can be sent as they are.
+func (opts *RcptOptions) validate() error {
+	if opts == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, n := range opts.Notify {
+		switch n {
+		case "NEVER":
+			if len(opts.Notify) > 1 {
+				return errors.New("smtp: NOTIFY=NEVER combined with other values")
+			}
+		case "SUCCESS", "FAILURE", "DELAY":
+			if seen[n] {
+				return fmt.Errorf("smtp: duplicate NOTIFY value %q", n)
+			}
+			seen[n] = true
+		default:
+			return fmt.Errorf("smtp: invalid NOTIFY value %q", n)
+		}
+	}
+	return nil
+}
+
 // Mail issues a MAIL command to the server using the provided email address.
 // If the server supports the 8BITMIME extension, Mail adds the BODY=8BITMIME
-// parameter.
+// parameter. If the server supports the SMTPUTF8 extension, Mail adds the
+// SMTPUTF8 parameter.
 // This initiates a mail transaction and is followed by one or more Rcpt calls.
 func (c *Client) Mail(from string) error {
+	return c.MailWithOptions(from, nil)
+}
+
+// MailWithOptions is like Mail but also sends the DSN parameters in opts,
+// if the server supports them. A nil opts is the same as calling Mail.
+// If opts is invalid, MailWithOptions returns an error without sending
+// anything.
+func (c *Client) MailWithOptions(from string, opts *MailOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
 	if err := c.hello(); err != nil {
 		return err
 	}
-	cmdStr := "MAIL FROM:<%s>"
-	if c.ext != nil {
-		if _, ok := c.ext["8BITMIME"]; ok {
-			cmdStr += " BODY=8BITMIME"
+	_, _, err := c.cmd(250, "%s", c.mailCmd(from, opts))
+	return err
+}
+
+// mailCmd returns the MAIL command for from, with the parameters
+// the server supports.
+func (c *Client) mailCmd(from string, opts *MailOptions) string {
+	cmd := "MAIL FROM:<" + from + ">"
+	if c.ext == nil {
+		return cmd
+	}
+	if _, ok := c.ext["8BITMIME"]; ok {
+		cmd += " BODY=8BITMIME"
+	}
+	if _, ok := c.ext["SMTPUTF8"]; ok {
+		cmd += " SMTPUTF8"
+	}
+	if _, ok := c.ext["DSN"]; ok && opts != nil {
+		if opts.Return != "" {
+			cmd += " RET=" + opts.Return
+		}
+		if opts.EnvelopeID != "" {
+			cmd += " ENVID=" + xtext(opts.EnvelopeID)
 		}
 	}
-	_, _, err := c.cmd(250, cmdStr, from)
-	return err
+	return cmd
 }
 
 // Rcpt issues a RCPT command to the server using the provided email address.
 // A call to Rcpt must be preceded by a call to Mail and may be followed by
 // a Data call or another Rcpt call.
 func (c *Client) Rcpt(to string) error {
-	_, _, err := c.cmd(25, "RCPT TO:<%s>", to)
+	return c.RcptWithOptions(to, nil)
+}
+
+// RcptWithOptions is like Rcpt but also sends the DSN parameters in opts,
+// if the server supports them. A nil opts is the same as calling Rcpt.
+// If opts is invalid, RcptWithOptions returns an error without sending
+// anything.
+func (c *Client) RcptWithOptions(to string, opts *RcptOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	_, _, err := c.cmd(25, "%s", c.rcptCmd(to, opts))
 	return err
 }
 
+// rcptCmd returns the RCPT command for to, with the parameters
+// the server supports.
+func (c *Client) rcptCmd(to string, opts *RcptOptions) string {
+	cmd := "RCPT TO:<" + to + ">"
+	if _, ok := c.ext["DSN"]; ok && opts != nil {
+		if len(opts.Notify) > 0 {
+			cmd += " NOTIFY=" + strings.Join(opts.Notify, ",")
+		}
+		if opts.OriginalRecipient != "" {
+			cmd += " ORCPT=rfc822;" + xtext(opts.OriginalRecipient)
+		}
+	}
+	return cmd
+}
+
+// xtext encodes s as described in RFC 3461, section 4: characters
+// outside the printable ASCII range, '+' and '=' become "+XX".
+func xtext(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if '!' <= c && c <= '~' && c != '+' && c != '=' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('+')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xF])
+	}
+	return b.String()
+}
+
+// Envelope starts a mail transaction from the address from to each of the
+// addresses in to, as Mail followed by a Rcpt for each recipient would,
+// passing mopts and ropts as MailWithOptions and RcptWithOptions do.
+// If the server supports the PIPELINING extension, all of the commands
+// are sent before any reply is read, saving a round trip per recipient.
+//
+// Envelope returns the first error the server reports. With pipelining,
+// the remaining commands have been sent regardless, so some recipients
+// may have been accepted; the caller should Reset before reusing c.
+// If mopts or ropts is invalid, Envelope returns an error without
+// sending anything.
+func (c *Client) Envelope(from string, to []string, mopts *MailOptions, ropts *RcptOptions) error {
+	if err := mopts.validate(); err != nil {
+		return err
+	}
+	if err := ropts.validate(); err != nil {
+		return err
+	}
+	if err := c.hello(); err != nil {
+		return err
+	}
+	if _, ok := c.ext["PIPELINING"]; !ok {
+		if err := c.MailWithOptions(from, mopts); err != nil {
+			return err
+		}
+		for _, addr := range to {
+			if err := c.RcptWithOptions(addr, ropts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ids := make([]uint, 0, 1+len(to))
+	id, err := c.Text.Cmd("%s", c.mailCmd(from, mopts))
+	if err != nil {
+		return err
+	}
+	ids = append(ids, id)
+	for _, addr := range to {
+		id, err := c.Text.Cmd("%s", c.rcptCmd(addr, ropts))
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	var firstErr error
+	for i, id := range ids {
+		expectCode := 25
+		if i == 0 {
+			expectCode = 250
+		}
+		c.Text.StartResponse(id)
+		_, _, err := c.Text.ReadResponse(expectCode)
+		c.Text.EndResponse(id)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 type dataCloser struct {
 	c *Client
 	io.WriteCloser
@@ -309,6 +578,28 @@ func SendMail(addr string, a Auth, from string, to []string, msg []byte) error {
 		return err
 	}
 	defer c.Close()
+	return c.sendMail(a, from, to, msg)
+}
+
+// SendMailContext is like SendMail but gives up when ctx is done or its
+// deadline passes, whatever stage the exchange with the server is in.
+func SendMailContext(ctx context.Context, addr string, a Auth, from string, to []string, msg []byte) error {
+	c, err := DialContext(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	stop := watchContext(ctx, c.conn)
+	err = c.sendMail(a, from, to, msg)
+	if ctxErr := stop(); err != nil && ctxErr != nil {
+		err = ctxErr
+	}
+	return err
+}
+
+// sendMail implements SendMail on an established connection.
+func (c *Client) sendMail(a Auth, from string, to []string, msg []byte) error {
+	var err error
 	if err = c.hello(); err != nil {
 		return err
 	}
@@ -328,14 +619,9 @@ func SendMail(addr string, a Auth, from string, to []string, msg []byte) error {
 			}
 		}
 	}
-	if err = c.Mail(from); err != nil {
+	if err = c.Envelope(from, to, nil, nil); err != nil {
 		return err
 	}
-	for _, addr := range to {
-		if err = c.Rcpt(addr); err != nil {
-			return err
-		}
-	}
 	w, err := c.Data()
 	if err != nil {
 		return err
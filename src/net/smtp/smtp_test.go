@@ -7,11 +7,15 @@ package smtp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"internal/testenv"
 	"io"
+	"io/ioutil"
 	"net"
+	"net/smtp/smtptest"
 	"net/textproto"
 	"runtime"
 	"strings"
@@ -549,6 +553,280 @@ SendMail is working for me.
 QUIT
 `
 
+func TestEnvelope(t *testing.T) {
+	ts := smtptest.NewServer()
+	defer ts.Close()
+	ts.Hook = func(cmd string) *textproto.Error {
+		if strings.HasPrefix(cmd, "RCPT TO:<nobody@") {
+			return &textproto.Error{Code: 550, Msg: "No such user"}
+		}
+		return nil
+	}
+
+	c, err := Dial(ts.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	mopts := &MailOptions{Return: "HDRS", EnvelopeID: "id+1=2"}
+	ropts := &RcptOptions{Notify: []string{"FAILURE", "DELAY"}, OriginalRecipient: "a@example.com"}
+	err = c.Envelope("joe@example.com", []string{"a@example.com", "nobody@example.com", "b@example.com"}, mopts, ropts)
+	if e, ok := err.(*textproto.Error); !ok || e.Code != 550 {
+		t.Errorf("Envelope: got %v, want 550 error", err)
+	}
+	if err := c.Quit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// With PIPELINING, the commands go out without waiting for replies,
+	// although the server may answer each as soon as it reads it.
+	want := `C: EHLO localhost
+S: 250-smtptest
+S: 250-8BITMIME
+S: 250-PIPELINING
+S: 250-DSN
+S: 250 SMTPUTF8
+C: MAIL FROM:<joe@example.com> BODY=8BITMIME SMTPUTF8 RET=HDRS ENVID=id+2B1+3D2
+C: RCPT TO:<a@example.com> NOTIFY=FAILURE,DELAY ORCPT=rfc822;a@example.com
+C: RCPT TO:<nobody@example.com> NOTIFY=FAILURE,DELAY ORCPT=rfc822;a@example.com
+C: RCPT TO:<b@example.com> NOTIFY=FAILURE,DELAY ORCPT=rfc822;a@example.com
+S: 250 OK
+S: 250 OK
+S: 550 No such user
+S: 250 OK
+C: QUIT
+S: 221 Bye
+`
+	want = "S: 220 smtptest ESMTP ready\n" + want
+	want = strings.Replace(want, "\n", "\r\n", -1)
+	if got := ts.Transcripts()[0]; !transcriptMatches(got, want) {
+		t.Errorf("got transcript:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDSNOptionsInvalid(t *testing.T) {
+	mailTests := []*MailOptions{
+		{Return: "full"},
+		{Return: "NONE"},
+		{Return: "HDRS\r\nRCPT TO:<evil@example.com>"},
+		{Return: "FULL ENVID=x"},
+	}
+	rcptTests := [][]string{
+		{"NEVER", "FAILURE"},
+		{"FAILURE", "NEVER"},
+		{"DELAY", "DELAY"},
+		{"failure"},
+		{""},
+		{"FAILURE\r\nDATA"},
+		{"SUCCESS ORCPT=rfc822;evil@example.com"},
+	}
+
+	var cmdbuf bytes.Buffer
+	var fake faker
+	fake.ReadWriter = bufio.NewReadWriter(bufio.NewReader(strings.NewReader("")), bufio.NewWriter(&cmdbuf))
+	c := &Client{Text: textproto.NewConn(fake), localName: "localhost"}
+	for _, opts := range mailTests {
+		if err := c.MailWithOptions("joe@example.com", opts); err == nil {
+			t.Errorf("MailWithOptions(%+v): no error", opts)
+		}
+		if err := c.Envelope("joe@example.com", []string{"a@example.com"}, opts, nil); err == nil {
+			t.Errorf("Envelope with %+v: no error", opts)
+		}
+	}
+	for _, notify := range rcptTests {
+		opts := &RcptOptions{Notify: notify}
+		if err := c.RcptWithOptions("a@example.com", opts); err == nil {
+			t.Errorf("RcptWithOptions(%q): no error", notify)
+		}
+		if err := c.Envelope("joe@example.com", []string{"a@example.com"}, nil, opts); err == nil {
+			t.Errorf("Envelope with NOTIFY %q: no error", notify)
+		}
+	}
+	if cmdbuf.Len() != 0 {
+		t.Errorf("sent %q for invalid options", cmdbuf.String())
+	}
+
+	for _, opts := range []*MailOptions{nil, {}, {Return: "FULL"}, {Return: "HDRS"}} {
+		if err := opts.validate(); err != nil {
+			t.Errorf("%+v: %v", opts, err)
+		}
+	}
+	for _, notify := range [][]string{nil, {"NEVER"}, {"SUCCESS", "FAILURE", "DELAY"}, {"DELAY"}} {
+		if err := (&RcptOptions{Notify: notify}).validate(); err != nil {
+			t.Errorf("NOTIFY %q: %v", notify, err)
+		}
+	}
+}
+
+// transcriptMatches reports whether got matches want, allowing for the
+// server replying to each pipelined command as soon as it reads it.
+func transcriptMatches(got, want string) bool {
+	split := func(s string) (client, server []string) {
+		for _, line := range strings.Split(s, "\r\n") {
+			if strings.HasPrefix(line, "C: ") {
+				client = append(client, line)
+			} else {
+				server = append(server, line)
+			}
+		}
+		return
+	}
+	gc, gs := split(got)
+	wc, ws := split(want)
+	return strings.Join(gc, "\n") == strings.Join(wc, "\n") && strings.Join(gs, "\n") == strings.Join(ws, "\n")
+}
+
+func TestSendMailSMTPTest(t *testing.T) {
+	ts := smtptest.NewServer()
+	defer ts.Close()
+
+	msg := "Subject: test\r\n\r\n.howdy!\r\n"
+	if err := SendMail(ts.Addr, nil, "joe@example.com", []string{"a@example.com", "b@example.com"}, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	msgs := ts.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("server got %d messages, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.From != "joe@example.com" || strings.Join(m.To, " ") != "a@example.com b@example.com" {
+		t.Errorf("got envelope %s -> %v", m.From, m.To)
+	}
+	if want := "Subject: test\n\n.howdy!\n"; string(m.Data) != want {
+		t.Errorf("got data %q, want %q", m.Data, want)
+	}
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which
+// smtptest.Server supports in addition to PLAIN.
+type loginAuth struct {
+	username, password string
+}
+
+func (a *loginAuth) Start(server *ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected challenge %q", fromServer)
+}
+
+func TestAuthSMTPTest(t *testing.T) {
+	ts := smtptest.NewUnstartedServer()
+	ts.Username, ts.Password = "joe", "secret"
+	ts.Start()
+	defer ts.Close()
+	host, _, _ := net.SplitHostPort(ts.Addr)
+
+	tests := []struct {
+		auth Auth
+		ok   bool
+	}{
+		{PlainAuth("", "joe", "secret", host), true},
+		{PlainAuth("", "joe", "wrong", host), false},
+		{&loginAuth{"joe", "secret"}, true},
+		{&loginAuth{"jim", "secret"}, false},
+	}
+	for i, tt := range tests {
+		c, err := Dial(ts.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, mechs := c.Extension("AUTH"); !ok || mechs != "PLAIN LOGIN" {
+			t.Errorf("%d: AUTH extension %v, %q", i, ok, mechs)
+		}
+		err = c.Auth(tt.auth)
+		if tt.ok && err != nil {
+			t.Errorf("%d: Auth: %v", i, err)
+		}
+		if !tt.ok {
+			if e, ok := err.(*textproto.Error); !ok || e.Code != 535 {
+				t.Errorf("%d: Auth: got %v, want 535 error", i, err)
+			}
+		}
+		c.Close()
+	}
+
+	msg := []byte("Subject: test\r\n\r\nhowdy!\r\n")
+	if err := SendMail(ts.Addr, nil, "joe@example.com", []string{"a@example.com"}, msg); err == nil {
+		t.Errorf("SendMail without Auth: no error")
+	}
+	if err := SendMail(ts.Addr, PlainAuth("", "joe", "secret", host), "joe@example.com", []string{"a@example.com"}, msg); err != nil {
+		t.Errorf("SendMail: %v", err)
+	}
+	if n := len(ts.Messages()); n != 1 {
+		t.Errorf("server got %d messages, want 1", n)
+	}
+}
+
+func TestXtext(t *testing.T) {
+	tests := []struct{ in, out string }{
+		{"simple@example.com", "simple@example.com"},
+		{"a+b=c", "a+2Bb+3Dc"},
+		{"sp ace\x7f\xc3\xa9", "sp+20ace+7F+C3+A9"},
+	}
+	for _, tt := range tests {
+		if got := xtext(tt.in); got != tt.out {
+			t.Errorf("xtext(%q) = %q, want %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestSendMailContextTimeout(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+	go func() {
+		// Accept and then say nothing, as a stuck server would.
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.Copy(ioutil.Discard, c)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := SendMailContext(ctx, ln.Addr().String(), nil, "joe@example.com", []string{"a@example.com"}, []byte("howdy!"))
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientDeadline(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "220 hello\r\n")
+		io.Copy(ioutil.Discard, c)
+	}()
+
+	c, err := Dial(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	err = c.Hello("localhost")
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("Hello: got %v, want timeout", err)
+	}
+}
+
 func TestAuthFailed(t *testing.T) {
 	server := strings.Join(strings.Split(authFailedServer, "\n"), "\r\n")
 	client := strings.Join(strings.Split(authFailedClient, "\n"), "\r\n")
@@ -0,0 +1,423 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package smtptest provides a local SMTP server for testing mail clients.
+//
+// The server accepts any mail it is offered, optionally after the client
+// authenticates, and keeps it, together with a transcript of every
+// session, so that tests can check both what was delivered and exactly
+// how it was sent.
+package smtptest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// A Message is a mail received by a Server.
+type Message struct {
+	From string   // address given in MAIL FROM
+	To   []string // addresses given in RCPT TO
+	Data []byte   // content, without dot-stuffing and with \n line endings
+}
+
+// A Server is an SMTP server listening on a system-chosen port on the
+// local loopback interface, for use in end-to-end tests of mail clients.
+type Server struct {
+	Addr     string // address of form ipaddr:port
+	Listener net.Listener
+
+	// Extensions lists the extensions the server advertises in reply
+	// to EHLO, with their parameters if any, as in "SIZE 1000000".
+	// The server does not enforce them. NewServer sets
+	// 8BITMIME, PIPELINING, DSN and SMTPUTF8.
+	Extensions []string
+
+	// TLS, if set before Start, makes the server offer STARTTLS
+	// using this configuration.
+	TLS *tls.Config
+
+	// Username and Password, if set before Start, make the server offer
+	// the AUTH extension with the PLAIN and LOGIN mechanisms, accepting
+	// only these credentials, and require clients to authenticate
+	// before starting a mail transaction. If TLS is set, AUTH is only
+	// offered after STARTTLS.
+	Username string
+	Password string
+
+	// Hook, if set before Start, is called with each command line the
+	// server receives. If it returns a non-nil error, the server replies
+	// with its code and message instead of handling the command, which
+	// lets tests exercise the client's handling of failures.
+	Hook func(cmd string) *textproto.Error
+
+	wg sync.WaitGroup
+
+	mu          sync.Mutex // guards closed, conns, messages and transcripts
+	closed      bool
+	conns       map[net.Conn]bool
+	messages    []Message
+	transcripts []*strings.Builder
+}
+
+// NewServer starts and returns a new Server.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a new Server but doesn't start it.
+// After changing its configuration, the caller should call Start.
+// The caller should call Close when finished, to shut it down.
+func NewUnstartedServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		if l, err = net.Listen("tcp6", "[::1]:0"); err != nil {
+			panic(fmt.Sprintf("smtptest: failed to listen on a port: %v", err))
+		}
+	}
+	return &Server{
+		Listener:   l,
+		Extensions: []string{"8BITMIME", "PIPELINING", "DSN", "SMTPUTF8"},
+	}
+}
+
+// Start starts a server from NewUnstartedServer.
+func (s *Server) Start() {
+	if s.Addr != "" {
+		panic("Server already started")
+	}
+	s.Addr = s.Listener.Addr().String()
+	s.conns = make(map[net.Conn]bool)
+	s.wg.Add(1)
+	go s.serve()
+}
+
+// Close shuts down the server and blocks until all sessions have ended.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.Listener.Close()
+		for c := range s.conns {
+			c.Close()
+		}
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Messages returns the messages received so far, in order of receipt.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Transcripts returns a transcript of each session so far, in the order
+// the sessions began. Each line the client sent appears prefixed with
+// "C: " and each line the server sent with "S: ", with \r\n line endings;
+// message content is abbreviated to a single "C: <data>" line.
+// A session still in progress is included up to its last exchange.
+func (s *Server) Transcripts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ts := make([]string, len(s.transcripts))
+	for i, b := range s.transcripts {
+		ts[i] = b.String()
+	}
+	return ts
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.Listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = true
+		transcript := new(strings.Builder)
+		s.transcripts = append(s.transcripts, transcript)
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			sess := &session{srv: s, transcript: transcript}
+			sess.serve(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// A session is the state of one client connection.
+type session struct {
+	srv        *Server
+	transcript *strings.Builder // guarded by srv.mu
+
+	conn net.Conn
+	r    *textproto.Reader
+	w    *bufio.Writer
+	tls  bool
+	auth bool // whether the client has authenticated
+
+	// mail transaction in progress
+	from *string
+	to   []string
+}
+
+func (c *session) log(prefix, line string) {
+	c.srv.mu.Lock()
+	c.transcript.WriteString(prefix)
+	c.transcript.WriteString(line)
+	c.transcript.WriteString("\r\n")
+	c.srv.mu.Unlock()
+}
+
+func (c *session) setConn(conn net.Conn) {
+	c.conn = conn
+	c.r = textproto.NewReader(bufio.NewReader(conn))
+	c.w = bufio.NewWriter(conn)
+}
+
+// reply sends a possibly multi-line reply with the given code.
+func (c *session) reply(code int, lines ...string) error {
+	for i, line := range lines {
+		sep := '-'
+		if i == len(lines)-1 {
+			sep = ' '
+		}
+		s := fmt.Sprintf("%03d%c%s", code, sep, line)
+		c.log("S: ", s)
+		c.w.WriteString(s + "\r\n")
+	}
+	return c.w.Flush()
+}
+
+func (c *session) serve(conn net.Conn) {
+	defer conn.Close()
+	c.setConn(conn)
+	if c.reply(220, "smtptest ESMTP ready") != nil {
+		return
+	}
+	for {
+		line, err := c.r.ReadLine()
+		if err != nil {
+			return
+		}
+		c.log("C: ", line)
+		if c.srv.Hook != nil {
+			if e := c.srv.Hook(line); e != nil {
+				if c.reply(e.Code, e.Msg) != nil {
+					return
+				}
+				continue
+			}
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+		if quit, err := c.handle(strings.ToUpper(verb), arg); quit || err != nil {
+			return
+		}
+	}
+}
+
+// handle carries out a single command, reporting whether the session is over.
+func (c *session) handle(verb, arg string) (quit bool, err error) {
+	switch verb {
+	case "HELO":
+		c.reset()
+		return false, c.reply(250, "smtptest")
+	case "EHLO":
+		c.reset()
+		lines := append([]string{"smtptest"}, c.srv.Extensions...)
+		if c.srv.TLS != nil && !c.tls {
+			lines = append(lines, "STARTTLS")
+		}
+		if c.authAvailable() {
+			lines = append(lines, "AUTH PLAIN LOGIN")
+		}
+		return false, c.reply(250, lines...)
+	case "STARTTLS":
+		if c.srv.TLS == nil || c.tls {
+			return false, c.reply(502, "STARTTLS not available")
+		}
+		if err := c.reply(220, "Ready to start TLS"); err != nil {
+			return false, err
+		}
+		c.setConn(tls.Server(c.conn, c.srv.TLS))
+		c.tls = true
+		c.reset()
+		return false, nil
+	case "AUTH":
+		return false, c.authenticate(arg)
+	case "MAIL":
+		addr, ok := path(arg, "FROM:")
+		if !ok {
+			return false, c.reply(501, "Syntax: MAIL FROM:<address>")
+		}
+		if c.srv.Username != "" && !c.auth {
+			return false, c.reply(530, "Authentication required")
+		}
+		c.reset()
+		c.from = &addr
+		return false, c.reply(250, "OK")
+	case "RCPT":
+		addr, ok := path(arg, "TO:")
+		switch {
+		case !ok:
+			return false, c.reply(501, "Syntax: RCPT TO:<address>")
+		case c.from == nil:
+			return false, c.reply(503, "MAIL first")
+		}
+		c.to = append(c.to, addr)
+		return false, c.reply(250, "OK")
+	case "DATA":
+		if len(c.to) == 0 {
+			return false, c.reply(503, "RCPT first")
+		}
+		if err := c.reply(354, "End data with <CR><LF>.<CR><LF>"); err != nil {
+			return false, err
+		}
+		data, err := c.r.ReadDotBytes()
+		if err != nil {
+			return false, err
+		}
+		c.log("C: ", "<data>")
+		c.srv.mu.Lock()
+		c.srv.messages = append(c.srv.messages, Message{From: *c.from, To: c.to, Data: data})
+		c.srv.mu.Unlock()
+		c.reset()
+		return false, c.reply(250, "OK")
+	case "RSET":
+		c.reset()
+		return false, c.reply(250, "OK")
+	case "NOOP":
+		return false, c.reply(250, "OK")
+	case "VRFY":
+		return false, c.reply(252, "Cannot VRFY user")
+	case "QUIT":
+		return true, c.reply(221, "Bye")
+	}
+	return false, c.reply(502, "Command not implemented")
+}
+
+// authAvailable reports whether the client may authenticate now.
+func (c *session) authAvailable() bool {
+	return c.srv.Username != "" && !c.auth && (c.srv.TLS == nil || c.tls)
+}
+
+// authenticate carries out an AUTH command with argument arg.
+func (c *session) authenticate(arg string) error {
+	if !c.authAvailable() {
+		return c.reply(503, "AUTH not available")
+	}
+	mech, initial := arg, ""
+	if i := strings.IndexByte(arg, ' '); i >= 0 {
+		mech, initial = arg[:i], arg[i+1:]
+	}
+	var user, pass string
+	switch strings.ToUpper(mech) {
+	case "PLAIN":
+		// The response is the authorization identity, the user
+		// name and the password, separated by NUL bytes.
+		resp, ok, err := c.challenge(initial, "")
+		if !ok || err != nil {
+			return err
+		}
+		f := strings.Split(string(resp), "\x00")
+		if len(f) != 3 {
+			return c.reply(501, "Malformed PLAIN response")
+		}
+		user, pass = f[1], f[2]
+	case "LOGIN":
+		resp, ok, err := c.challenge(initial, "Username:")
+		if !ok || err != nil {
+			return err
+		}
+		user = string(resp)
+		resp, ok, err = c.challenge("", "Password:")
+		if !ok || err != nil {
+			return err
+		}
+		pass = string(resp)
+	default:
+		return c.reply(504, "Unrecognized authentication mechanism")
+	}
+	if user != c.srv.Username || pass != c.srv.Password {
+		return c.reply(535, "Authentication credentials invalid")
+	}
+	c.auth = true
+	return c.reply(235, "Authentication successful")
+}
+
+// challenge returns the decoded response of the client to an AUTH
+// challenge. If initial, the response sent with the AUTH command, is
+// empty, challenge first sends prompt and reads the response. If the
+// client cancels or the response is malformed, challenge replies with
+// an error itself and reports ok false.
+func (c *session) challenge(initial, prompt string) (resp []byte, ok bool, err error) {
+	line := initial
+	if line == "" {
+		if err := c.reply(334, base64.StdEncoding.EncodeToString([]byte(prompt))); err != nil {
+			return nil, false, err
+		}
+		if line, err = c.r.ReadLine(); err != nil {
+			return nil, false, err
+		}
+		c.log("C: ", line)
+	}
+	if line == "*" {
+		return nil, false, c.reply(501, "Authentication cancelled")
+	}
+	if line == "=" {
+		// An empty initial response.
+		return nil, true, nil
+	}
+	resp, err = base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return nil, false, c.reply(501, "Invalid base64 data")
+	}
+	return resp, true, nil
+}
+
+func (c *session) reset() {
+	c.from = nil
+	c.to = nil
+}
+
+// path parses the address in a MAIL or RCPT argument, such as
+// "FROM:<gopher@example.com> BODY=8BITMIME", ignoring any parameters.
+func path(arg, prefix string) (addr string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	arg = strings.TrimLeft(arg[len(prefix):], " ")
+	if !strings.HasPrefix(arg, "<") {
+		return "", false
+	}
+	i := strings.IndexByte(arg, '>')
+	if i < 0 {
+		return "", false
+	}
+	return arg[1:i], true
+}
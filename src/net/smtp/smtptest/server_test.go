@@ -0,0 +1,131 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smtptest
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	c, err := textproto.Dial("tcp", s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	steps := []struct {
+		cmd  string
+		code int
+	}{
+		{"", 220},
+		{"HELO client", 250},
+		{"RCPT TO:<a@example.com>", 503},
+		{"MAIL FROM:<joe@example.com> BODY=8BITMIME", 250},
+		{"RCPT TO:<a@example.com>", 250},
+		{"DATA", 354},
+		{"Subject: hi\r\n\r\n..dot\r\n.", 250},
+		{"BOGUS", 502},
+		{"QUIT", 221},
+	}
+	for _, step := range steps {
+		if step.cmd != "" {
+			if err := c.PrintfLine("%s", step.cmd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := c.ReadResponse(step.code); err != nil {
+			t.Fatalf("%q: %v", step.cmd, err)
+		}
+	}
+
+	msgs := s.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.From != "joe@example.com" || len(m.To) != 1 || m.To[0] != "a@example.com" || string(m.Data) != "Subject: hi\n\n.dot\n" {
+		t.Errorf("got message %+v", m)
+	}
+	ts := s.Transcripts()
+	if len(ts) != 1 || !strings.HasPrefix(ts[0], "S: 220 smtptest ESMTP ready\r\nC: HELO client\r\nS: 250 smtptest\r\n") {
+		t.Errorf("got transcripts %q", ts)
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	s := NewUnstartedServer()
+	s.Username, s.Password = "joe", "secret"
+	s.Start()
+	defer s.Close()
+
+	c, err := textproto.Dial("tcp", s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	steps := []struct {
+		cmd  string
+		code int
+	}{
+		{"", 220},
+		{"EHLO client", 250},
+		{"MAIL FROM:<joe@example.com>", 530},
+		{"AUTH CRAM-MD5", 504},
+		{"AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00joe\x00wrong")), 535},
+		{"AUTH PLAIN not-base64", 501},
+		{"AUTH LOGIN", 334},
+		{"*", 501},
+		{"AUTH LOGIN", 334},
+		{base64.StdEncoding.EncodeToString([]byte("joe")), 334},
+		{base64.StdEncoding.EncodeToString([]byte("secret")), 235},
+		{"AUTH PLAIN", 503},
+		{"MAIL FROM:<joe@example.com>", 250},
+		{"QUIT", 221},
+	}
+	for _, step := range steps {
+		if step.cmd != "" {
+			if err := c.PrintfLine("%s", step.cmd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := c.ReadResponse(step.code); err != nil {
+			t.Fatalf("%q: %v", step.cmd, err)
+		}
+	}
+	if ts := s.Transcripts(); len(ts) != 1 || !strings.Contains(ts[0], "S: 250 AUTH PLAIN LOGIN\r\n") {
+		t.Errorf("got transcripts %q", ts)
+	}
+}
+
+func TestServerAuthBeforeTLS(t *testing.T) {
+	s := NewUnstartedServer()
+	s.Username, s.Password = "joe", "secret"
+	s.TLS = new(tls.Config)
+	s.Start()
+	defer s.Close()
+
+	c, err := textproto.Dial("tcp", s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, _, err := c.ReadResponse(220); err != nil {
+		t.Fatal(err)
+	}
+	c.PrintfLine("EHLO client")
+	if _, msg, err := c.ReadResponse(250); err != nil || strings.Contains(msg, "AUTH") || !strings.Contains(msg, "STARTTLS") {
+		t.Errorf("EHLO: got %q, %v; want STARTTLS and no AUTH", msg, err)
+	}
+	c.PrintfLine("AUTH PLAIN")
+	if _, _, err := c.ReadResponse(503); err != nil {
+		t.Errorf("AUTH before STARTTLS: %v", err)
+	}
+}
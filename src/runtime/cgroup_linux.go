@@ -0,0 +1,262 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+// cgroupLimitSupported reports whether cgroupCPULimit can find
+// CPU bandwidth limits on this OS.
+const cgroupLimitSupported = true
+
+// cgroupCPULimit returns the CPU bandwidth limit, in CPUs, imposed on
+// the process by its cgroup (v1 cpu controller or v2 unified
+// hierarchy), and whether there is such a limit. If the cgroup and
+// several of its ancestors are limited, the lowest limit wins.
+//
+// root is prepended to every path read. It is empty except in tests,
+// which point it at a fake tree containing proc/self/cgroup,
+// proc/self/mountinfo, and the cgroup file systems.
+//
+// cgroupCPULimit allocates, so it must not be called from sysmon.
+func cgroupCPULimit(root string) (float64, bool) {
+	data := cgroupReadFile(root + "/proc/self/cgroup")
+	if data == nil {
+		return 0, false
+	}
+	path, v2, ok := parseProcSelfCgroup(string(data))
+	if !ok {
+		return 0, false
+	}
+
+	data = cgroupReadFile(root + "/proc/self/mountinfo")
+	if data == nil {
+		return 0, false
+	}
+	mnt, mntRoot, ok := findCgroupMount(string(data), v2)
+	if !ok {
+		return 0, false
+	}
+
+	// path is relative to the root of the hierarchy; the mount may
+	// only expose a subtree of it.
+	if mntRoot != "/" {
+		if path != mntRoot && !hasprefix(path, mntRoot+"/") {
+			return 0, false
+		}
+		path = path[len(mntRoot):]
+	}
+	if path == "/" {
+		path = ""
+	}
+
+	// Walk up from the process's cgroup to the mount point. A
+	// limit on any ancestor also applies to the process.
+	top := root + mnt
+	dir := top + path
+	limit, found := 0.0, false
+	for {
+		if l, ok := cgroupDirLimit(dir, v2); ok && (!found || l < limit) {
+			limit, found = l, true
+		}
+		if len(dir) <= len(top) {
+			break
+		}
+		i := len(dir) - 1
+		for i > len(top) && dir[i] != '/' {
+			i--
+		}
+		dir = dir[:i]
+	}
+	return limit, found
+}
+
+// parseProcSelfCgroup finds the cgroup controlling the CPU bandwidth
+// of the process in the contents of /proc/self/cgroup. Each line has
+// the form
+//
+//	hierarchy-ID:controller-list:cgroup-path
+//
+// A cgroup v1 hierarchy with the cpu controller takes precedence over
+// the v2 unified hierarchy ("0::path"), matching the kernel's choice
+// when the cpu controller is bound to v1.
+func parseProcSelfCgroup(data string) (path string, v2, ok bool) {
+	for data != "" {
+		var line string
+		line, data = cgroupNextLine(data)
+		i := index(line, ":")
+		if i < 0 {
+			continue
+		}
+		id, rest := line[:i], line[i+1:]
+		j := index(rest, ":")
+		if j < 0 {
+			continue
+		}
+		controllers, p := rest[:j], rest[j+1:]
+		if id == "0" && controllers == "" {
+			path, v2, ok = p, true, true
+			continue
+		}
+		if cgroupHasOption(controllers, "cpu") {
+			return p, false, true
+		}
+	}
+	return path, v2, ok
+}
+
+// findCgroupMount finds the mount point of the cgroup hierarchy in the
+// contents of /proc/self/mountinfo, along with the path of the
+// hierarchy that is mounted there. Each line has the form
+//
+//	id parent major:minor root mount-point options [optional...] - fstype source super-options
+//
+// See proc(5).
+func findCgroupMount(data string, v2 bool) (mnt, mntRoot string, ok bool) {
+	for data != "" {
+		var line string
+		line, data = cgroupNextLine(data)
+		sep := index(line, " - ")
+		if sep < 0 {
+			continue
+		}
+		pre, post := line[:sep], line[sep+len(" - "):]
+
+		fstype, post := cgroupNextField(post)
+		_, post = cgroupNextField(post) // source
+		superOpts, _ := cgroupNextField(post)
+		if v2 {
+			if fstype != "cgroup2" {
+				continue
+			}
+		} else if fstype != "cgroup" || !cgroupHasOption(superOpts, "cpu") {
+			continue
+		}
+
+		_, pre = cgroupNextField(pre) // id
+		_, pre = cgroupNextField(pre) // parent
+		_, pre = cgroupNextField(pre) // major:minor
+		mntRoot, pre = cgroupNextField(pre)
+		mnt, _ = cgroupNextField(pre)
+		if mntRoot == "" || mnt == "" {
+			continue
+		}
+		return mnt, mntRoot, true
+	}
+	return "", "", false
+}
+
+// cgroupDirLimit returns the CPU bandwidth limit set on the cgroup
+// directory dir, if any.
+func cgroupDirLimit(dir string, v2 bool) (float64, bool) {
+	var quota, period int
+	if v2 {
+		// cpu.max holds "$MAX $PERIOD", where $MAX is "max"
+		// if the cgroup is not limited.
+		data := cgroupReadFile(dir + "/cpu.max")
+		if data == nil {
+			return 0, false
+		}
+		q, rest := cgroupNextField(string(data))
+		p, _ := cgroupNextField(rest)
+		var ok1, ok2 bool
+		quota, ok1 = atoi(q)
+		period, ok2 = atoi(p)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+	} else {
+		// A quota of -1 means the cgroup is not limited.
+		data := cgroupReadFile(dir + "/cpu.cfs_quota_us")
+		if data == nil {
+			return 0, false
+		}
+		q, _ := cgroupNextField(string(data))
+		data = cgroupReadFile(dir + "/cpu.cfs_period_us")
+		if data == nil {
+			return 0, false
+		}
+		p, _ := cgroupNextField(string(data))
+		var ok1, ok2 bool
+		quota, ok1 = atoi(q)
+		period, ok2 = atoi(p)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+	}
+	if quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return float64(quota) / float64(period), true
+}
+
+// cgroupReadFile returns the contents of the named file, or nil if it
+// cannot be read.
+func cgroupReadFile(name string) []byte {
+	path := make([]byte, len(name)+1)
+	copy(path, name)
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return nil
+	}
+	buf := make([]byte, 0, 512)
+	for {
+		if len(buf) == cap(buf) {
+			nbuf := make([]byte, len(buf), 2*cap(buf))
+			copy(nbuf, buf)
+			buf = nbuf
+		}
+		n := read(fd, unsafe.Pointer(&buf[:cap(buf)][len(buf)]), int32(cap(buf)-len(buf)))
+		if n < 0 {
+			closefd(fd)
+			return nil
+		}
+		if n == 0 {
+			break
+		}
+		buf = buf[:len(buf)+int(n)]
+	}
+	closefd(fd)
+	return buf
+}
+
+// cgroupNextLine splits s into its first line and the rest.
+func cgroupNextLine(s string) (line, rest string) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// cgroupNextField splits s into its first space-separated field and
+// the rest, ignoring leading spaces and newlines.
+func cgroupNextField(s string) (field, rest string) {
+	for len(s) > 0 && (s[0] == ' ' || s[0] == '\n') {
+		s = s[1:]
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\n' {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// cgroupHasOption reports whether the comma-separated list opts
+// contains opt.
+func cgroupHasOption(opts, opt string) bool {
+	for opts != "" {
+		i := index(opts, ",")
+		if i < 0 {
+			return opts == opt
+		}
+		if opts[:i] == opt {
+			return true
+		}
+		opts = opts[i+1:]
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeCgroupTree creates a fake file system tree under a new
+// temporary directory. files maps slash-separated paths to contents.
+func writeCgroupTree(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+const (
+	v1MountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 22 0:26 / /sys/fs/cgroup/memory rw,nosuid shared:10 - cgroup cgroup rw,memory
+31 22 0:27 / /sys/fs/cgroup/cpu,cpuacct rw,nosuid shared:11 - cgroup cgroup rw,cpu,cpuacct
+`
+	v1Cgroup = `12:memory:/kubepods/pod1/ctr
+11:cpu,cpuacct:/kubepods/pod1/ctr
+0::/
+`
+	v2MountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 22 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec shared:4 - cgroup2 cgroup2 rw,nsdelegate
+`
+	v2Cgroup = "0::/system.slice/app.service\n"
+)
+
+var cgroupCPULimitTests = []struct {
+	name  string
+	files map[string]string
+	limit float64
+	ok    bool
+}{
+	{
+		name: "v1",
+		files: map[string]string{
+			"proc/self/cgroup":                                              v1Cgroup,
+			"proc/self/mountinfo":                                           v1MountInfo,
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_quota_us":  "150000\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_period_us": "100000\n",
+		},
+		limit: 1.5,
+		ok:    true,
+	},
+	{
+		name: "v1-unlimited",
+		files: map[string]string{
+			"proc/self/cgroup":                                              v1Cgroup,
+			"proc/self/mountinfo":                                           v1MountInfo,
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_quota_us":  "-1\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_period_us": "100000\n",
+		},
+	},
+	{
+		name: "v1-parent",
+		files: map[string]string{
+			"proc/self/cgroup":                                              v1Cgroup,
+			"proc/self/mountinfo":                                           v1MountInfo,
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_quota_us":  "-1\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_period_us": "100000\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/cpu.cfs_quota_us":      "400000\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/cpu.cfs_period_us":     "100000\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/cpu.cfs_quota_us":           "800000\n",
+			"sys/fs/cgroup/cpu,cpuacct/kubepods/cpu.cfs_period_us":          "100000\n",
+		},
+		limit: 4,
+		ok:    true,
+	},
+	{
+		// A container with its own cgroup namespace sees its
+		// cgroup as the root of the hierarchy, which is
+		// mounted from the middle of the host's hierarchy.
+		name: "v1-namespace",
+		files: map[string]string{
+			"proc/self/cgroup":                            "11:cpu,cpuacct:/kubepods/pod1/ctr\n",
+			"proc/self/mountinfo":                         "31 22 0:27 /kubepods/pod1/ctr /sys/fs/cgroup/cpu,cpuacct rw - cgroup cgroup rw,cpuacct,cpu\n",
+			"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+			"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+		},
+		limit: 0.5,
+		ok:    true,
+	},
+	{
+		name: "v2",
+		files: map[string]string{
+			"proc/self/cgroup":                               v2Cgroup,
+			"proc/self/mountinfo":                            v2MountInfo,
+			"sys/fs/cgroup/system.slice/app.service/cpu.max": "250000 100000\n",
+			"sys/fs/cgroup/system.slice/cpu.max":             "max 100000\n",
+		},
+		limit: 2.5,
+		ok:    true,
+	},
+	{
+		name: "v2-unlimited",
+		files: map[string]string{
+			"proc/self/cgroup":                               v2Cgroup,
+			"proc/self/mountinfo":                            v2MountInfo,
+			"sys/fs/cgroup/system.slice/app.service/cpu.max": "max 100000\n",
+		},
+	},
+	{
+		name: "v2-parent",
+		files: map[string]string{
+			"proc/self/cgroup":                               v2Cgroup,
+			"proc/self/mountinfo":                            v2MountInfo,
+			"sys/fs/cgroup/system.slice/app.service/cpu.max": "max 100000\n",
+			"sys/fs/cgroup/system.slice/cpu.max":             "300000 100000\n",
+		},
+		limit: 3,
+		ok:    true,
+	},
+	{
+		name: "no-cgroup",
+		files: map[string]string{
+			"proc/self/mountinfo": v2MountInfo,
+		},
+	},
+	{
+		name: "no-mount",
+		files: map[string]string{
+			"proc/self/cgroup":    v2Cgroup,
+			"proc/self/mountinfo": v1MountInfo,
+		},
+	},
+}
+
+func TestCgroupCPULimit(t *testing.T) {
+	for _, tt := range cgroupCPULimitTests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeCgroupTree(t, tt.files)
+			defer os.RemoveAll(root)
+			limit, ok := runtime.CgroupCPULimit(root)
+			if limit != tt.limit || ok != tt.ok {
+				t.Errorf("CgroupCPULimit() = %v, %v; want %v, %v", limit, ok, tt.limit, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDefaultGOMAXPROCS(t *testing.T) {
+	ncpu := runtime.NumCPU()
+	min := func(a, b int) int {
+		if a < b {
+			return a
+		}
+		return b
+	}
+	for _, tt := range []struct {
+		quota string
+		want  int
+	}{
+		{"max 100000\n", ncpu},
+		{"250000 100000\n", min(ncpu, 3)},
+		{"300000 100000\n", min(ncpu, 3)},
+		{"50000 100000\n", min(ncpu, 2)},
+		{"100000000 100000\n", ncpu},
+	} {
+		root := writeCgroupTree(t, map[string]string{
+			"proc/self/cgroup":                               v2Cgroup,
+			"proc/self/mountinfo":                            v2MountInfo,
+			"sys/fs/cgroup/system.slice/app.service/cpu.max": tt.quota,
+		})
+		if got := runtime.DefaultGOMAXPROCS(root); got != tt.want {
+			t.Errorf("cpu.max %q: DefaultGOMAXPROCS() = %d; want %d", tt.quota, got, tt.want)
+		}
+		os.RemoveAll(root)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package runtime
+
+const cgroupLimitSupported = false
+
+func cgroupCPULimit(root string) (float64, bool) {
+	return 0, false
+}
@@ -13,6 +13,9 @@ import (
 // simultaneously and returns the previous setting. If n < 1, it does not
 // change the current setting.
 // The number of logical CPUs on the local machine can be queried with NumCPU.
+//
+// Setting GOMAXPROCS with n > 0 stops the runtime from adjusting it to
+// changes in the cgroup CPU limit; see the package documentation.
 // This call will go away when the scheduler improves.
 func GOMAXPROCS(n int) int {
 	if n > _MaxGomaxprocs {
@@ -20,6 +23,9 @@ func GOMAXPROCS(n int) int {
 	}
 	lock(&sched.lock)
 	ret := int(gomaxprocs)
+	if n > 0 {
+		sched.customGOMAXPROCS = true
+	}
 	unlock(&sched.lock)
 	if n <= 0 || n == ret {
 		return ret
@@ -0,0 +1,283 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReadBuildInfo returns the build information embedded
+// in the running binary. The information is available only
+// in binaries built by the go command.
+func ReadBuildInfo() (info *BuildInfo, ok bool) {
+	data := modinfo()
+	if len(data) < 32 {
+		return nil, false
+	}
+	// The go command wraps the build information in 16-byte
+	// sentinels so that it can be found in a binary file.
+	data = data[16 : len(data)-16]
+	bi, err := ParseBuildInfo(data)
+	if err != nil {
+		return nil, false
+	}
+	return bi, true
+}
+
+// BuildInfo represents the build information read from a Go binary.
+type BuildInfo struct {
+	// GoVersion is the version of the Go toolchain that built the binary
+	// (for example, "go1.9").
+	GoVersion string
+
+	// Path is the import path of the main package for the binary
+	// (for example, "golang.org/x/tools/cmd/stringer").
+	Path string
+
+	// Main describes the source tree containing the main package.
+	Main Module
+
+	// Deps describes all the dependencies of the binary that
+	// are not part of the standard library.
+	Deps []*Module
+
+	// Settings describes the build settings used to build the binary.
+	Settings []BuildSetting
+}
+
+// Module describes a source tree included in a build.
+//
+// The go command identifies a source tree by the root of its
+// version control checkout, if any, and otherwise by the
+// import path of the package it contains.
+type Module struct {
+	Path    string // import path of the root of the source tree
+	Version string // VCS revision of the source tree, or "(devel)" if unknown
+	Sum     string // checksum of the source files used in the build
+}
+
+// A BuildSetting is a key-value pair describing one setting that influenced a build.
+//
+// Defined keys include:
+//
+//	-buildmode    the buildmode flag used (typically "exe")
+//	-compiler     the compiler toolchain flag used (typically "gc")
+//	CGO_ENABLED   the effective CGO_ENABLED environment variable
+//	GOARCH        the architecture target
+//	GOOS          the operating system target
+//	vcs           the version control system for the source tree of the main package
+//	vcs.revision  the revision identifier for the current commit or checkout
+//	vcs.time      the modification time associated with vcs.revision, in RFC3339 format
+//	vcs.modified  true or false indicating whether the source tree had local modifications
+//
+// Flags such as -gcflags, -ldflags, -tags, -race and -msan are recorded
+// under their flag names when they are set.
+type BuildSetting struct {
+	// Key and Value describe the build setting.
+	// Key must not contain an equals sign, space, tab, or newline.
+	// Value must not contain newlines ('\n').
+	Key, Value string
+}
+
+// quoteKey reports whether key is required to be quoted.
+func quoteKey(key string) bool {
+	return len(key) == 0 || strings.ContainsAny(key, "= \t\r\n\"`")
+}
+
+// quoteValue reports whether value is required to be quoted.
+func quoteValue(value string) bool {
+	return strings.ContainsAny(value, " \t\r\n\"`")
+}
+
+// String returns the build information in the textual form
+// accepted by ParseBuildInfo.
+func (bi *BuildInfo) String() string {
+	buf := new(bytes.Buffer)
+	if bi.GoVersion != "" {
+		fmt.Fprintf(buf, "go\t%s\n", bi.GoVersion)
+	}
+	if bi.Path != "" {
+		fmt.Fprintf(buf, "path\t%s\n", bi.Path)
+	}
+	formatMod := func(word string, m Module) {
+		buf.WriteString(word)
+		buf.WriteByte('\t')
+		buf.WriteString(m.Path)
+		buf.WriteByte('\t')
+		buf.WriteString(m.Version)
+		buf.WriteByte('\t')
+		buf.WriteString(m.Sum)
+		buf.WriteByte('\n')
+	}
+	if bi.Main != (Module{}) {
+		formatMod("mod", bi.Main)
+	}
+	for _, dep := range bi.Deps {
+		formatMod("dep", *dep)
+	}
+	for _, s := range bi.Settings {
+		key := s.Key
+		if quoteKey(key) {
+			key = strconv.Quote(key)
+		}
+		value := s.Value
+		if quoteValue(value) {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(buf, "build\t%s=%s\n", key, value)
+	}
+
+	return buf.String()
+}
+
+// ParseBuildInfo parses the textual form of build information
+// produced by BuildInfo.String.
+func ParseBuildInfo(data string) (bi *BuildInfo, err error) {
+	lineNum := 1
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("could not parse Go build info: line %d: %v", lineNum, err)
+		}
+	}()
+
+	var (
+		goLine    = "go\t"
+		pathLine  = "path\t"
+		modLine   = "mod\t"
+		depLine   = "dep\t"
+		buildLine = "build\t"
+		newline   = "\n"
+		tab       = "\t"
+	)
+
+	readModuleLine := func(elem []string) (Module, error) {
+		if len(elem) != 2 && len(elem) != 3 {
+			return Module{}, fmt.Errorf("expected 2 or 3 columns; got %d", len(elem))
+		}
+		version := elem[1]
+		sum := ""
+		if len(elem) == 3 {
+			sum = elem[2]
+		}
+		return Module{
+			Path:    elem[0],
+			Version: version,
+			Sum:     sum,
+		}, nil
+	}
+
+	bi = new(BuildInfo)
+	var line string
+	// Reverse of BuildInfo.String(), except for go version.
+	for len(data) > 0 {
+		i := strings.Index(data, newline)
+		if i < 0 {
+			break
+		}
+		line, data = data[:i], data[i+1:]
+		switch {
+		case strings.HasPrefix(line, goLine):
+			bi.GoVersion = line[len(goLine):]
+		case strings.HasPrefix(line, pathLine):
+			bi.Path = line[len(pathLine):]
+		case strings.HasPrefix(line, modLine):
+			elem := strings.Split(line[len(modLine):], tab)
+			bi.Main, err = readModuleLine(elem)
+			if err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, depLine):
+			elem := strings.Split(line[len(depLine):], tab)
+			dep := new(Module)
+			*dep, err = readModuleLine(elem)
+			if err != nil {
+				return nil, err
+			}
+			bi.Deps = append(bi.Deps, dep)
+		case strings.HasPrefix(line, buildLine):
+			kv := line[len(buildLine):]
+			if len(kv) < 1 {
+				return nil, fmt.Errorf("build line missing '='")
+			}
+
+			var key, rawValue string
+			switch kv[0] {
+			case '=':
+				return nil, fmt.Errorf("build line with missing key")
+
+			case '`', '"':
+				rawKey, err := quotedPrefix(kv)
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted key in build line")
+				}
+				if len(kv) == len(rawKey) {
+					return nil, fmt.Errorf("build line missing '=' after quoted key")
+				}
+				if c := kv[len(rawKey)]; c != '=' {
+					return nil, fmt.Errorf("unexpected character after quoted key: %q", c)
+				}
+				key, _ = strconv.Unquote(rawKey)
+				rawValue = kv[len(rawKey)+1:]
+
+			default:
+				i := strings.Index(kv, "=")
+				if i < 0 {
+					return nil, fmt.Errorf("build line missing '=' after key")
+				}
+				key, rawValue = kv[:i], kv[i+1:]
+				if quoteKey(key) {
+					return nil, fmt.Errorf("unquoted key %q must be quoted", key)
+				}
+			}
+
+			var value string
+			if len(rawValue) > 0 {
+				switch rawValue[0] {
+				case '`', '"':
+					var err error
+					value, err = strconv.Unquote(rawValue)
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted value in build line")
+					}
+
+				default:
+					value = rawValue
+					if quoteValue(value) {
+						return nil, fmt.Errorf("unquoted value %q must be quoted", value)
+					}
+				}
+			}
+
+			bi.Settings = append(bi.Settings, BuildSetting{Key: key, Value: value})
+		}
+		lineNum++
+	}
+	return bi, nil
+}
+
+// quotedPrefix returns the quoted string (as understood by
+// strconv.Unquote) at the beginning of s.
+func quotedPrefix(s string) (string, error) {
+	if len(s) == 0 {
+		return "", strconv.ErrSyntax
+	}
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return s[:i+1], nil
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case '\n':
+			return "", strconv.ErrSyntax
+		}
+	}
+	return "", strconv.ErrSyntax
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"reflect"
+	. "runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestParseBuildInfoRoundTrip(t *testing.T) {
+	for _, bi := range []*BuildInfo{
+		{},
+		{
+			GoVersion: "go1.9",
+			Path:      "example.com/cmd/hello",
+			Main:      Module{Path: "example.com", Version: "(devel)", Sum: "h1:aaaa"},
+			Deps: []*Module{
+				{Path: "example.com/dep", Version: "0123456789abcdef", Sum: "h1:bbbb"},
+				{Path: "other/dep", Version: "(devel)"},
+			},
+			Settings: []BuildSetting{
+				{Key: "-buildmode", Value: "default"},
+				{Key: "-gcflags", Value: "-N -l"},
+				{Key: "-ldflags", Value: `-X "main.v=a b"`},
+				{Key: "quoted=key", Value: "x"},
+				{Key: "empty", Value: ""},
+				{Key: "GOOS", Value: "linux"},
+			},
+		},
+	} {
+		s := bi.String()
+		got, err := ParseBuildInfo(s)
+		if err != nil {
+			t.Errorf("ParseBuildInfo(%q): %v", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, bi) {
+			t.Errorf("ParseBuildInfo(%q):\ngot  %+v\nwant %+v", s, got, bi)
+		}
+	}
+}
+
+func TestParseBuildInfoErrors(t *testing.T) {
+	for _, tt := range []struct {
+		data, err string
+	}{
+		{"mod\texample.com\n", "expected 2 or 3 columns"},
+		{"build\t=x\n", "missing key"},
+		{"build\tkey\n", "missing '='"},
+		{"build\t\"key=x\n", "invalid quoted key"},
+		{"build\tkey=a b\n", "must be quoted"},
+		{"go\tgo1.9\nbuild\t\"k\"x\n", "line 2: unexpected character"},
+	} {
+		_, err := ParseBuildInfo(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseBuildInfo(%q) = %v, want error containing %q", tt.data, err, tt.err)
+		}
+	}
+}
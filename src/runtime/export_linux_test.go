@@ -8,3 +8,9 @@ package runtime
 
 var NewOSProc0 = newosproc0
 var Mincore = mincore
+
+var CgroupCPULimit = cgroupCPULimit
+
+func DefaultGOMAXPROCS(root string) int {
+	return int(defaultGOMAXPROCS(root))
+}
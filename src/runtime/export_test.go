@@ -217,6 +217,8 @@ func BenchSetType(n int, x interface{}) {
 
 const PtrSize = sys.PtrSize
 
+const PreemptMSupported = preemptMSupported
+
 var ForceGCPeriod = &forcegcperiod
 
 // SetTracebackEnv is like runtime/debug.SetTraceback, but it raises
@@ -349,3 +351,52 @@ func blockOnSystemStackInternal() {
 	lock(&deadlock)
 	lock(&deadlock)
 }
+
+type TimeHistogram timeHistogram
+
+// Counts returns the counts for the given bucket, subBucket indices.
+// Returns true if the bucket was valid, otherwise returns the count of
+// overflowed durations and false.
+func (th *TimeHistogram) Count(bucket, subBucket uint) (uint64, bool) {
+	t := (*timeHistogram)(th)
+	i := bucket*TimeHistNumSubBuckets + subBucket
+	if i >= uint(len(t.counts)) {
+		return t.overflow, false
+	}
+	return t.counts[i], true
+}
+
+func (th *TimeHistogram) Record(duration int64) {
+	(*timeHistogram)(th).record(duration)
+}
+
+const (
+	TimeHistSubBucketBits   = timeHistSubBucketBits
+	TimeHistNumSubBuckets   = timeHistNumSubBuckets
+	TimeHistNumSuperBuckets = timeHistNumSuperBuckets
+)
+
+var TimeHistogramMetricsBuckets = timeHistogramMetricsBuckets
+
+func ReadMetricsSlow(memStats *MemStats, samplesp unsafe.Pointer, len, cap int) {
+	stopTheWorld("ReadMetricsSlow")
+
+	// Read memstats first. It's going to flush
+	// the mcaches which readMetrics does not do, so
+	// going the other way around may result in
+	// inconsistent statistics.
+	systemstack(func() {
+		readmemstats_m(memStats)
+	})
+
+	// Read metrics off the system stack.
+	//
+	// The only part of readMetrics that could allocate is
+	// the histogram allocation. Callers should populate the
+	// samples with a prior call to metrics.Read so that the
+	// histograms are reused and the heap doesn't change
+	// between the two reads.
+	readMetrics(samplesp, len, cap)
+
+	startTheWorld()
+}
@@ -27,6 +27,13 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	allocfreetrace: setting allocfreetrace=1 causes every allocation to be
 	profiled and a stack trace printed on each object's allocation and free.
 
+	asyncpreemptoff: setting asyncpreemptoff=1 disables signal-based
+	asynchronous goroutine preemption. This makes some loops
+	non-preemptible for long periods, which may delay GC and
+	goroutine scheduling. This is useful for debugging GC issues
+	because it also disables the conservative stack scanning used
+	for asynchronously preempted goroutines.
+
 	cgocheck: setting cgocheck=0 disables all checks for packages
 	using cgo to incorrectly pass Go pointers to non-Go code.
 	Setting cgocheck=1 (the default) enables relatively cheap
@@ -34,6 +41,10 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	expensive checks that should not miss any errors, but will
 	cause your program to run slower.
 
+	cpuquotaoff: setting cpuquotaoff=1 makes the default GOMAXPROCS ignore
+	the CPU bandwidth limit of the process's Linux cgroup, and disables
+	the periodic updates of GOMAXPROCS when that limit changes.
+
 	efence: setting efence=1 causes the allocator to run in a mode
 	where each object is allocated on a unique page and addresses are
 	never recycled.
@@ -127,6 +138,13 @@ that can be blocked in system calls on behalf of Go code; those do not count aga
 the GOMAXPROCS limit. This package's GOMAXPROCS function queries and changes
 the limit.
 
+If GOMAXPROCS is not set, the limit defaults to the number of logical CPUs
+available to the process. On Linux, if the process's cgroup (v1 or v2) has a
+CPU bandwidth limit (cpu.cfs_quota_us or cpu.max), the default is lowered to
+that limit, rounded up to a whole number of CPUs but not below 2. The runtime
+rechecks the limit periodically and adjusts GOMAXPROCS if it changes, unless
+GOMAXPROCS was set by the environment variable or by the GOMAXPROCS function.
+
 The GOTRACEBACK variable controls the amount of output generated when a Go
 program fails due to an unrecovered panic or an unexpected runtime condition.
 By default, a failure prints a stack trace for the current goroutine,
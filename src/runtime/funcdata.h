@@ -10,6 +10,7 @@
 
 #define PCDATA_StackMapIndex 0
 #define PCDATA_InlTreeIndex 1
+#define PCDATA_UnsafePoint 2
 
 #define FUNCDATA_ArgsPointerMaps 0 /* garbage collector blocks */
 #define FUNCDATA_LocalsPointerMaps 1
@@ -36,3 +36,21 @@ func TestBswap32(t *testing.T) {
 		t.Errorf("Bswap(%x)=%x, want 0x44332211", x, y)
 	}
 }
+
+func TestLen64(t *testing.T) {
+	for i := 0; i <= 64; i++ {
+		var x uint64
+		if i > 0 {
+			x = 1 << uint(i-1)
+		}
+		if got := sys.Len64(x); got != i {
+			t.Errorf("Len64(%#x)=%d, want %d", x, got, i)
+		}
+		if i > 1 {
+			x |= x - 1
+			if got := sys.Len64(x); got != i {
+				t.Errorf("Len64(%#x)=%d, want %d", x, got, i)
+			}
+		}
+	}
+}
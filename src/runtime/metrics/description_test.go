@@ -0,0 +1,47 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics_test
+
+import (
+	"io/ioutil"
+	"regexp"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDescriptionNameFormat(t *testing.T) {
+	r := regexp.MustCompile("^(?P<name>/[^:]+):(?P<unit>[^:*/]+(?:[*/][^:*/]+)*)$")
+	descriptions := metrics.All()
+	for _, desc := range descriptions {
+		if !r.MatchString(desc.Name) {
+			t.Errorf("metrics %q does not match regexp %s", desc.Name, r)
+		}
+		if desc.Kind == metrics.KindBad {
+			t.Errorf("metric %q has bad kind", desc.Name)
+		}
+	}
+	if !sort.SliceIsSorted(descriptions, func(i, j int) bool {
+		return descriptions[i].Name < descriptions[j].Name
+	}) {
+		t.Error("descriptions are not sorted by name")
+	}
+}
+
+func TestDocs(t *testing.T) {
+	b, err := ioutil.ReadFile("doc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := strings.Join(strings.Fields(string(b)), " ")
+	for _, desc := range metrics.All() {
+		// Compare modulo line breaks and indentation.
+		want := desc.Name + " " + desc.Description
+		if !strings.Contains(doc, want) {
+			t.Errorf("doc.go does not document metric %q as %q", desc.Name, desc.Description)
+		}
+	}
+}
@@ -788,6 +788,10 @@ const gcAssistTimeSlack = 5000
 // assist by pre-paying for this many bytes of future allocations.
 const gcOverAssistWork = 64 << 10
 
+// gcPauseDist is the distribution of individual stop-the-world
+// pauses performed by the garbage collector.
+var gcPauseDist timeHistogram
+
 var work struct {
 	full  lfstack                  // lock-free list of full blocks workbuf
 	empty lfstack                  // lock-free list of empty blocks workbuf
@@ -1202,6 +1206,7 @@ func gcStart(mode gcMode, trigger gcTrigger) {
 		systemstack(startTheWorldWithSema)
 		now = nanotime()
 		work.pauseNS += now - work.pauseStart
+		gcPauseDist.record(now - work.pauseStart)
 		work.tMark = now
 	} else {
 		t := nanotime()
@@ -1421,6 +1426,7 @@ func gcMarkTermination() {
 	sec, nsec, _ := time_now()
 	unixNow := sec*1e9 + int64(nsec)
 	work.pauseNS += now - work.pauseStart
+	gcPauseDist.record(now - work.pauseStart)
 	work.tEnd = now
 	atomic.Store64(&memstats.last_gc_unix, uint64(unixNow)) // must be Unix time to make sense to user
 	atomic.Store64(&memstats.last_gc_nanotime, uint64(now)) // monotonic time for us
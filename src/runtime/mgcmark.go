@@ -770,18 +770,25 @@ func scanstack(gp *g, gcw *gcWork) {
 
 	// Scan the stack.
 	var cache pcvalueCache
+	conservative := false
 	scanframe := func(frame *stkframe, unused unsafe.Pointer) bool {
-		scanframeworker(frame, &cache, gcw)
+		scanframeworker(frame, &cache, &conservative, gcw)
 		return true
 	}
 	gentraceback(^uintptr(0), ^uintptr(0), 0, gp, 0, nil, 0x7fffffff, scanframe, nil, 0)
+	conservative = false
 	tracebackdefers(gp, scanframe, nil)
 	gp.gcscanvalid = true
 }
 
 // Scan a stack frame: local variables and function arguments/results.
+//
+// If *conservative is set, the frame was stopped at an asynchronous
+// safe-point and has no usable stack map, so it is scanned
+// conservatively. scanframeworker updates *conservative for the next
+// frame.
 //go:nowritebarrier
-func scanframeworker(frame *stkframe, cache *pcvalueCache, gcw *gcWork) {
+func scanframeworker(frame *stkframe, cache *pcvalueCache, conservative *bool, gcw *gcWork) {
 
 	f := frame.fn
 	targetpc := frame.continpc
@@ -792,6 +799,29 @@ func scanframeworker(frame *stkframe, cache *pcvalueCache, gcw *gcWork) {
 	if _DebugGC > 1 {
 		print("scanframe ", funcname(f), "\n")
 	}
+
+	isAsyncPreempt := f.entry == asyncPreemptPC
+	if *conservative || isAsyncPreempt {
+		// Conservatively scan the frame. Unlike the precise
+		// case, this includes the outgoing argument space
+		// since we may have stopped while this function was
+		// setting up a call.
+		if frame.varp > frame.sp {
+			scanConservative(frame.sp, frame.varp-frame.sp, gcw)
+		}
+
+		// Scan arguments to this frame.
+		if frame.arglen != 0 {
+			scanConservative(frame.argp, frame.arglen, gcw)
+		}
+
+		// The asyncPreempt frame holds the registers of the
+		// asynchronously stopped parent frame, so the parent
+		// must be scanned conservatively too. We only want to
+		// scan those two frames conservatively.
+		*conservative = isAsyncPreempt
+		return
+	}
 	if targetpc != f.entry {
 		targetpc--
 	}
@@ -1191,6 +1221,46 @@ func scanobject(b uintptr, gcw *gcWork) {
 	gcw.scanWork += int64(i)
 }
 
+// scanConservative scans block [b, b+n) conservatively, treating any
+// pointer-like value in the block as a pointer.
+//
+// If the block is not in the heap, it must be otherwise protected
+// from concurrent modification, like a stopped goroutine's stack.
+//go:nowritebarrier
+func scanConservative(b, n uintptr, gcw *gcWork) {
+	if debugScanConservative {
+		print("conservatively scanning [", hex(b), ",", hex(b+n), ")\n")
+	}
+	for i := uintptr(0); i < n; i += sys.PtrSize {
+		val := *(*uintptr)(unsafe.Pointer(b + i))
+
+		// Check if val points into the heap.
+		if val < mheap_.arena_start || val >= mheap_.arena_used {
+			continue
+		}
+
+		// Check if val points to an allocated span.
+		span := mheap_.spans[(val-mheap_.arena_start)>>_PageShift]
+		if span == nil || span.state != mSpanInUse || val < span.base() || val >= span.limit {
+			continue
+		}
+
+		// Check if val points to an allocated object.
+		idx := span.objIndex(val)
+		if span.isFree(idx) {
+			continue
+		}
+
+		// val points to an allocated object. Mark it.
+		obj := span.base() + idx*span.elemsize
+		greyobject(obj, b, i, heapBitsForAddr(obj), span, gcw, idx)
+	}
+}
+
+// debugScanConservative enables debug logging for stack frames that
+// are scanned conservatively.
+const debugScanConservative = false
+
 // Shade the object if it isn't already.
 // The object is not nil and known to be in the heap.
 // Preemption must be disabled.
@@ -543,3 +543,12 @@ func osyield() {
 	}
 	osyield1()
 }
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+const preemptMSupported = false
+
+// signalM sends a signal to mp. It is not used on this system.
+func signalM(mp *m, sig int) {
+	// Not supported; preemptMSupported is false.
+}
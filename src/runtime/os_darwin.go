@@ -569,3 +569,12 @@ func sysargs(argc int32, argv **byte) {
 		executablePath = executablePath[len(prefix):]
 	}
 }
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+const preemptMSupported = false
+
+// signalM sends a signal to mp. It is not used on this system.
+func signalM(mp *m, sig int) {
+	// Not supported; preemptMSupported is false.
+}
@@ -379,6 +379,8 @@ func sigprocmask(how int32, new, old *sigset) {
 func getrlimit(kind int32, limit unsafe.Pointer) int32
 func raise(sig uint32)
 func raiseproc(sig uint32)
+func getpid() int
+func tgkill(tgid, tid, sig int)
 
 //go:noescape
 func sched_getaffinity(pid, len uintptr, buf *uintptr) int32
@@ -437,3 +439,18 @@ func setSignalstackSP(s *stackt, sp uintptr) {
 
 func (c *sigctxt) fixsigcode(sig uint32) {
 }
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+//
+// On mips, mipsle, ppc64, and ppc64le, asyncPreempt resumes the
+// interrupted code through a register (REGTMP, or CTR on ppc64) that
+// the toolchain may be using at any instruction, so preemption signals
+// are not sent there yet. On s390x, the assembler marks the
+// instructions that use REGTMP as unsafe-points.
+const preemptMSupported = GOARCH == "386" || GOARCH == "amd64" || GOARCH == "arm" || GOARCH == "arm64" || GOARCH == "s390x"
+
+// signalM sends a signal to mp.
+func signalM(mp *m, sig int) {
+	tgkill(getpid(), int(mp.procid), sig)
+}
@@ -299,3 +299,15 @@ int8 nacl_irt_thread_v0_1_str[] = "nacl-irt-thread-0.1";
 void *nacl_irt_thread_v0_1[3]; // thread_create, thread_exit, thread_nice
 int32 nacl_irt_thread_v0_1_size = sizeof(nacl_irt_thread_v0_1);
 */
+
+const sigPreempt = 16 // SIGURG
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+const preemptMSupported = false
+
+func preemptM(mp *m) {
+	// Not currently supported.
+	//
+	// TODO: Use a different signal? Or use SuspendThread?
+}
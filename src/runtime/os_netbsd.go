@@ -305,3 +305,12 @@ func sigdelset(mask *sigset, i int) {
 
 func (c *sigctxt) fixsigcode(sig uint32) {
 }
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+const preemptMSupported = false
+
+// signalM sends a signal to mp. It is not used on this system.
+func signalM(mp *m, sig int) {
+	// Not supported; preemptMSupported is false.
+}
@@ -487,3 +487,13 @@ func signame(sig uint32) string {
 	}
 	return sigtable[sig].name
 }
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+const preemptMSupported = false
+
+func preemptM(mp *m) {
+	// Not currently supported.
+	//
+	// TODO: Use a different signal? Or use SuspendThread?
+}
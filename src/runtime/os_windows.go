@@ -771,3 +771,13 @@ func setThreadCPUProfiler(hz int32) {
 func memlimit() uintptr {
 	return 0
 }
+
+// preemptMSupported reports whether preemptM can interrupt a running
+// thread.
+const preemptMSupported = false
+
+func preemptM(mp *m) {
+	// Not currently supported.
+	//
+	// TODO: Use a different signal? Or use SuspendThread?
+}
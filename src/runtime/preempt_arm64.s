@@ -0,0 +1,141 @@
+// Code generated by mkpreempt.go; DO NOT EDIT.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+TEXT ·asyncPreempt(SB),NOSPLIT,$-8-0
+	SUB $512, RSP
+	MOVD R30, 0(RSP)
+	MOVD R0, 8(RSP)
+	MOVD R1, 16(RSP)
+	MOVD R2, 24(RSP)
+	MOVD R3, 32(RSP)
+	MOVD R4, 40(RSP)
+	MOVD R5, 48(RSP)
+	MOVD R6, 56(RSP)
+	MOVD R7, 64(RSP)
+	MOVD R8, 72(RSP)
+	MOVD R9, 80(RSP)
+	MOVD R10, 88(RSP)
+	MOVD R11, 96(RSP)
+	MOVD R12, 104(RSP)
+	MOVD R13, 112(RSP)
+	MOVD R14, 120(RSP)
+	MOVD R15, 128(RSP)
+	MOVD R16, 136(RSP)
+	MOVD R17, 144(RSP)
+	MOVD R19, 152(RSP)
+	MOVD R20, 160(RSP)
+	MOVD R21, 168(RSP)
+	MOVD R22, 176(RSP)
+	MOVD R23, 184(RSP)
+	MOVD R24, 192(RSP)
+	MOVD R25, 200(RSP)
+	MOVD R26, 208(RSP)
+	MOVD R27, 216(RSP)
+	MOVD R29, 224(RSP)
+	WORD $0xd53b4200 // MRS NZCV, R0
+	MOVD R0, 232(RSP)
+	WORD $0xd53b4420 // MRS FPSR, R0
+	MOVD R0, 240(RSP)
+	FMOVD F0, 248(RSP)
+	FMOVD F1, 256(RSP)
+	FMOVD F2, 264(RSP)
+	FMOVD F3, 272(RSP)
+	FMOVD F4, 280(RSP)
+	FMOVD F5, 288(RSP)
+	FMOVD F6, 296(RSP)
+	FMOVD F7, 304(RSP)
+	FMOVD F8, 312(RSP)
+	FMOVD F9, 320(RSP)
+	FMOVD F10, 328(RSP)
+	FMOVD F11, 336(RSP)
+	FMOVD F12, 344(RSP)
+	FMOVD F13, 352(RSP)
+	FMOVD F14, 360(RSP)
+	FMOVD F15, 368(RSP)
+	FMOVD F16, 376(RSP)
+	FMOVD F17, 384(RSP)
+	FMOVD F18, 392(RSP)
+	FMOVD F19, 400(RSP)
+	FMOVD F20, 408(RSP)
+	FMOVD F21, 416(RSP)
+	FMOVD F22, 424(RSP)
+	FMOVD F23, 432(RSP)
+	FMOVD F24, 440(RSP)
+	FMOVD F25, 448(RSP)
+	FMOVD F26, 456(RSP)
+	FMOVD F27, 464(RSP)
+	FMOVD F28, 472(RSP)
+	FMOVD F29, 480(RSP)
+	FMOVD F30, 488(RSP)
+	FMOVD F31, 496(RSP)
+	CALL ·asyncPreempt2(SB)
+	FMOVD 496(RSP), F31
+	FMOVD 488(RSP), F30
+	FMOVD 480(RSP), F29
+	FMOVD 472(RSP), F28
+	FMOVD 464(RSP), F27
+	FMOVD 456(RSP), F26
+	FMOVD 448(RSP), F25
+	FMOVD 440(RSP), F24
+	FMOVD 432(RSP), F23
+	FMOVD 424(RSP), F22
+	FMOVD 416(RSP), F21
+	FMOVD 408(RSP), F20
+	FMOVD 400(RSP), F19
+	FMOVD 392(RSP), F18
+	FMOVD 384(RSP), F17
+	FMOVD 376(RSP), F16
+	FMOVD 368(RSP), F15
+	FMOVD 360(RSP), F14
+	FMOVD 352(RSP), F13
+	FMOVD 344(RSP), F12
+	FMOVD 336(RSP), F11
+	FMOVD 328(RSP), F10
+	FMOVD 320(RSP), F9
+	FMOVD 312(RSP), F8
+	FMOVD 304(RSP), F7
+	FMOVD 296(RSP), F6
+	FMOVD 288(RSP), F5
+	FMOVD 280(RSP), F4
+	FMOVD 272(RSP), F3
+	FMOVD 264(RSP), F2
+	FMOVD 256(RSP), F1
+	FMOVD 248(RSP), F0
+	MOVD 240(RSP), R0
+	WORD $0xd51b4420 // MSR R0, FPSR
+	MOVD 232(RSP), R0
+	WORD $0xd51b4200 // MSR R0, NZCV
+	MOVD 224(RSP), R29
+	MOVD 216(RSP), R27
+	MOVD 208(RSP), R26
+	MOVD 200(RSP), R25
+	MOVD 192(RSP), R24
+	MOVD 184(RSP), R23
+	MOVD 176(RSP), R22
+	MOVD 168(RSP), R21
+	MOVD 160(RSP), R20
+	MOVD 152(RSP), R19
+	MOVD 144(RSP), R17
+	MOVD 136(RSP), R16
+	MOVD 128(RSP), R15
+	MOVD 120(RSP), R14
+	MOVD 112(RSP), R13
+	MOVD 104(RSP), R12
+	MOVD 96(RSP), R11
+	MOVD 88(RSP), R10
+	MOVD 80(RSP), R9
+	MOVD 72(RSP), R8
+	MOVD 64(RSP), R7
+	MOVD 56(RSP), R6
+	MOVD 48(RSP), R5
+	MOVD 40(RSP), R4
+	MOVD 32(RSP), R3
+	MOVD 24(RSP), R2
+	MOVD 16(RSP), R1
+	MOVD 8(RSP), R0
+	MOVD 512(RSP), R30
+	MOVD 0(RSP), R18
+	ADD $528, RSP
+	JMP (R18)
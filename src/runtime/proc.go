@@ -12,6 +12,12 @@ import (
 
 var buildVersion = sys.TheVersion
 
+// modinfo holds the build information recorded by the go command,
+// wrapped in sentinel bytes. The go command sets it from a file
+// compiled into the main package; it is empty for binaries not
+// built by the go command.
+var modinfo string
+
 // Goroutine scheduler
 // The scheduler's job is to distribute ready-to-run goroutines over worker threads.
 //
@@ -233,6 +239,96 @@ func forcegchelper() {
 	}
 }
 
+// defaultGOMAXPROCS returns the value of GOMAXPROCS to use when it is
+// not set explicitly: the number of usable CPUs, lowered to the CPU
+// bandwidth limit of the process's cgroup, if any. Fractional limits
+// are rounded up, and a limit never lowers GOMAXPROCS below 2, so
+// that a goroutine can still run while another is in a system call.
+//
+// root is passed to cgroupCPULimit.
+func defaultGOMAXPROCS(root string) int32 {
+	procs := ncpu
+	if debug.cpuquotaoff != 0 {
+		return procs
+	}
+	limit, ok := cgroupCPULimit(root)
+	if !ok {
+		return procs
+	}
+	n := int32(limit)
+	if float64(n) < limit {
+		n++
+	}
+	if n < 2 {
+		n = 2
+	}
+	if n < procs {
+		procs = n
+	}
+	return procs
+}
+
+// maxprocsUpdatePeriod is the time in nanoseconds between checks of
+// the cgroup CPU limit by sysmon.
+var maxprocsUpdatePeriod int64 = 1e9
+
+// start GOMAXPROCS updater goroutine
+func init() {
+	lock(&sched.lock)
+	custom := sched.customGOMAXPROCS
+	unlock(&sched.lock)
+	if cgroupLimitSupported && !custom && debug.cpuquotaoff == 0 {
+		go updatemaxprocshelper()
+	}
+}
+
+// updatemaxprocshelper recomputes the default GOMAXPROCS each time it
+// is woken by sysmon, and applies it if it changed. It exits once
+// GOMAXPROCS has been set explicitly.
+func updatemaxprocshelper() {
+	updatemaxprocs.g = getg()
+	for {
+		lock(&updatemaxprocs.lock)
+		if updatemaxprocs.idle != 0 {
+			throw("updatemaxprocs: phase error")
+		}
+		atomic.Store(&updatemaxprocs.idle, 1)
+		goparkunlock(&updatemaxprocs.lock, "GOMAXPROCS updater (idle)", traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+
+		lock(&sched.lock)
+		custom := sched.customGOMAXPROCS
+		unlock(&sched.lock)
+		if custom {
+			return
+		}
+
+		// Read the limit before stopping the world; it
+		// allocates and does I/O.
+		procs := defaultGOMAXPROCS("")
+		if procs > _MaxGomaxprocs {
+			procs = _MaxGomaxprocs
+		}
+		lock(&sched.lock)
+		unchanged := procs == gomaxprocs
+		unlock(&sched.lock)
+		if unchanged {
+			continue
+		}
+
+		stopTheWorld("GOMAXPROCS")
+		// GOMAXPROCS may have been called while we
+		// were stopping the world.
+		lock(&sched.lock)
+		if !sched.customGOMAXPROCS {
+			// newprocs will be processed by startTheWorld
+			newprocs = procs
+		}
+		unlock(&sched.lock)
+		startTheWorld()
+	}
+}
+
 // Gosched yields the processor, allowing other goroutines to run. It does not
 // suspend the current goroutine, so execution resumes automatically.
 //go:nosplit
@@ -475,9 +571,12 @@ func schedinit() {
 	gcinit()
 
 	sched.lastpoll = uint64(nanotime())
-	procs := ncpu
+	var procs int32
 	if n, ok := atoi32(gogetenv("GOMAXPROCS")); ok && n > 0 {
 		procs = n
+		sched.customGOMAXPROCS = true
+	} else {
+		procs = defaultGOMAXPROCS("")
 	}
 	if procs > _MaxGomaxprocs {
 		procs = _MaxGomaxprocs
@@ -491,6 +590,11 @@ func schedinit() {
 		// to ensure runtime·buildVersion is kept in the resulting binary.
 		buildVersion = "unknown"
 	}
+	if len(modinfo) == 1 {
+		// Condition should never trigger. This code just serves
+		// to ensure runtime·modinfo is kept in the resulting binary.
+		modinfo = ""
+	}
 }
 
 func dumpgstatus(gp *g) {
@@ -785,8 +889,40 @@ func casgstatus(gp *g, oldval, newval uint32) {
 	if newval == _Grunning {
 		gp.gcscanvalid = false
 	}
+
+	// Track the time spent runnable for a sample of transitions,
+	// to feed the /sched/latencies:seconds metric. Stack copies
+	// move a runnable G out of and back into _Grunnable, so don't
+	// restart the clock for those.
+	switch newval {
+	case _Grunnable:
+		if oldval == _Gcopystack {
+			break
+		}
+		if gp.trackingSeq%gTrackingPeriod == 0 {
+			gp.tracking = true
+			gp.runnableStamp = nanotime()
+		}
+		gp.trackingSeq++
+	case _Grunning:
+		if gp.tracking {
+			schedLatencyDist.record(nanotime() - gp.runnableStamp)
+			gp.tracking = false
+		}
+	}
 }
 
+// gTrackingPeriod is the number of transitions to _Grunnable
+// between the ones that are timed for scheduling latency
+// statistics. Timing every transition would add two nanotime
+// calls to each trip through the scheduler.
+const gTrackingPeriod = 8
+
+// schedLatencyDist is the distribution of time goroutines spend
+// runnable before they are scheduled, sampled every
+// gTrackingPeriod transitions.
+var schedLatencyDist timeHistogram
+
 // casgstatus(gp, oldstatus, Gcopystack), assuming oldstatus is Gwaiting or Grunnable.
 // Returns old status. Cannot call casgstatus directly, because we are racing with an
 // async wakeup that might come in from netpoll. If we see Gwaiting from the readgstatus,
@@ -869,12 +1005,21 @@ loop:
 
 			// Ask for preemption and self scan.
 			if castogscanstatus(gp, _Grunning, _Gscanrunning) {
+				var mp *m
 				if !gp.gcscandone {
 					gp.preemptscan = true
 					gp.preempt = true
 					gp.stackguard0 = stackPreempt
+					mp = gp.m
 				}
 				casfrom_Gscanstatus(gp, _Gscanrunning, _Grunning)
+				if mp != nil && preemptMSupported && debug.asyncpreemptoff == 0 {
+					// gp may be in a loop without calls.
+					// Interrupt it so it stops at an
+					// asynchronous safe point and yields,
+					// after which we can scan its stack.
+					preemptM(mp)
+				}
 			}
 		}
 
@@ -3727,6 +3872,8 @@ func sysmon() {
 	lastscavenge := nanotime()
 	nscavenge := 0
 
+	lastmaxprocs := nanotime()
+
 	lasttrace := int64(0)
 	idle := 0 // how many cycles in succession we had not wokeup somebody
 	delay := uint32(0)
@@ -3798,6 +3945,15 @@ func sysmon() {
 			injectglist(forcegc.g)
 			unlock(&forcegc.lock)
 		}
+		// check if the cgroup CPU limit changed
+		if lastmaxprocs+maxprocsUpdatePeriod < now && atomic.Load(&updatemaxprocs.idle) != 0 {
+			lastmaxprocs = now
+			lock(&updatemaxprocs.lock)
+			updatemaxprocs.idle = 0
+			updatemaxprocs.g.schedlink = 0
+			injectglist(updatemaxprocs.g)
+			unlock(&updatemaxprocs.lock)
+		}
 		// scavenge heap once in a while
 		if lastscavenge+scavengelimit/2 < now {
 			mheap_.scavenge(int32(nscavenge), uint64(now), uint64(scavengelimit))
@@ -3923,6 +4079,12 @@ func preemptone(_p_ *p) bool {
 	// Setting gp->stackguard0 to StackPreempt folds
 	// preemption into the normal stack overflow check.
 	gp.stackguard0 = stackPreempt
+
+	// Request an async preemption of this M, in case gp is
+	// in a loop that makes no calls.
+	if preemptMSupported && debug.asyncpreemptoff == 0 {
+		preemptM(mp)
+	}
 	return true
 }
 
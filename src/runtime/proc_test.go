@@ -336,6 +336,20 @@ func TestPreemptionGC(t *testing.T) {
 	atomic.StoreUint32(&stop, 1)
 }
 
+func TestAsyncPreempt(t *testing.T) {
+	// Test that a goroutine in a loop with no function calls
+	// can be preempted asynchronously, both by the scheduler
+	// and by a GC stopping the world.
+	if !runtime.PreemptMSupported {
+		t.Skip("asynchronous preemption not supported on " + runtime.GOOS + "/" + runtime.GOARCH)
+	}
+	output := runTestProg(t, "testprog", "AsyncPreempt")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
+
 func TestGCFairness(t *testing.T) {
 	output := runTestProg(t, "testprog", "GCFairness")
 	want := "OK\n"
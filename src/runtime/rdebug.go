@@ -20,3 +20,8 @@ func setPanicOnFault(new bool) (old bool) {
 	_g_.paniconfault = new
 	return old
 }
+
+//go:linkname runtime_debug_modinfo runtime/debug.modinfo
+func runtime_debug_modinfo() string {
+	return modinfo
+}
@@ -319,7 +319,9 @@ type dbgVar struct {
 // already have an initial value.
 var debug struct {
 	allocfreetrace   int32
+	asyncpreemptoff  int32
 	cgocheck         int32
+	cpuquotaoff      int32
 	efence           int32
 	gccheckmark      int32
 	gcpacertrace     int32
@@ -336,7 +338,9 @@ var debug struct {
 
 var dbgvars = []dbgVar{
 	{"allocfreetrace", &debug.allocfreetrace},
+	{"asyncpreemptoff", &debug.asyncpreemptoff},
 	{"cgocheck", &debug.cgocheck},
+	{"cpuquotaoff", &debug.cpuquotaoff},
 	{"efence", &debug.efence},
 	{"gccheckmark", &debug.gccheckmark},
 	{"gcpacertrace", &debug.gcpacertrace},
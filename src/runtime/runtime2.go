@@ -348,6 +348,7 @@ type g struct {
 	preemptscan    bool     // preempted g does scan for gc
 	gcscandone     bool     // g has scanned stack; protected by _Gscan bit in status
 	gcscanvalid    bool     // false at start of gc cycle, true if G has not run since last scan; TODO: remove?
+	asyncSafePoint bool     // set if g is stopped at an asynchronous safe point; the innermost frames are scanned conservatively
 	throwsplit     bool     // must not split stack
 	raceignore     int8     // ignore race detection events
 	sysblocktraced bool     // StartTrace has emitted EvGoInSyscall about this goroutine
@@ -368,6 +369,12 @@ type g struct {
 	labels         unsafe.Pointer // profiler labels
 	timer          *timer         // cached timer for time.Sleep
 
+	// Per-G scheduling latency tracking. Only a sample of
+	// transitions to _Grunnable are timed; see casgstatus.
+	tracking      bool  // whether we're tracking this G for sched latency statistics
+	trackingSeq   uint8 // used to decide whether to track this G
+	runnableStamp int64 // timestamp of when the G last became runnable, only used when tracking
+
 	// Per-G GC state
 
 	// gcAssistBytes is this G's GC assist credit in terms of
@@ -436,6 +443,15 @@ type m struct {
 	syscalltick   uint32
 	thread        uintptr // thread handle
 
+	// preemptGen counts the number of completed preemption
+	// signals. This is used to detect when a preemption is
+	// requested, but fails. Accessed atomically.
+	preemptGen uint32
+
+	// signalPending is whether a preemption signal is pending
+	// on this M. Accessed atomically.
+	signalPending uint32
+
 	// these are here because they are too large to be on the stack
 	// of low-level NOSPLIT functions.
 	libcall   libcall
@@ -567,6 +583,11 @@ type schedt struct {
 
 	procresizetime int64 // nanotime() of last change to gomaxprocs
 	totaltime      int64 // ∫gomaxprocs dt up to procresizetime
+
+	// customGOMAXPROCS is set if GOMAXPROCS was set by the
+	// environment or runtime.GOMAXPROCS, which disables updates
+	// from the cgroup CPU limit.
+	customGOMAXPROCS bool
 }
 
 // The m.locked word holds two pieces of state counting active calls to LockOSThread/lockOSThread.
@@ -638,6 +659,12 @@ type forcegcstate struct {
 	idle uint32
 }
 
+type updatemaxprocsstate struct {
+	lock mutex
+	g    *g
+	idle uint32
+}
+
 // startup_random_data holds random bytes initialized at startup. These come from
 // the ELF AT_RANDOM auxiliary vector (vdso_linux_amd64.go or os_linux_386.go).
 var startupRandomData []byte
@@ -718,6 +745,8 @@ var (
 	sched       schedt
 	newprocs    int32
 
+	updatemaxprocs updatemaxprocsstate
+
 	// Information about what cpu features are available.
 	// Set on startup in asm_{x86,amd64}.s.
 	cpuid_ecx         uint32
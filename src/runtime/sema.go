@@ -46,6 +46,10 @@ type semaRoot struct {
 // Prime to not correlate with any user patterns.
 const semTabSize = 251
 
+// mutexWaitTime is the total time in nanoseconds that goroutines
+// have spent blocked on a contended sync.Mutex.
+var mutexWaitTime uint64
+
 var semtable [semTabSize]struct {
 	root semaRoot
 	pad  [sys.CacheLineSize - unsafe.Sizeof(semaRoot{})]byte
@@ -114,6 +118,10 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags) {
 	//	(waiter descriptor is dequeued by signaler)
 	s := acquireSudog()
 	root := semroot(addr)
+	var waitStart int64
+	if profile&semaMutexProfile != 0 {
+		waitStart = nanotime()
+	}
 	t0 := int64(0)
 	s.releasetime = 0
 	s.acquiretime = 0
@@ -149,6 +157,9 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags) {
 	if s.releasetime > 0 {
 		blockevent(s.releasetime-t0, 3)
 	}
+	if waitStart != 0 {
+		atomic.Xadd64(&mutexWaitTime, nanotime()-waitStart)
+	}
 	releaseSudog(s)
 }
 
@@ -1113,6 +1113,12 @@ func shrinkstack(gp *g) {
 	if debug.gcshrinkstackoff > 0 {
 		return
 	}
+	if gp.asyncSafePoint {
+		// The innermost frames of an asynchronously preempted
+		// goroutine have no stack maps, so pointers into the
+		// stack held there can't be adjusted by copystack.
+		return
+	}
 	if gp.startpc == gcBgMarkWorkerPC {
 		// We're not allowed to shrink the gcBgMarkWorker
 		// stack (see gcBgMarkWorker for explanation).
@@ -276,6 +276,8 @@ func call1073741824(typ, fn, arg unsafe.Pointer, n, retoffset uint32)
 
 func systemstack_switch()
 
+var no_pointers_stackmap uint64 // defined in assembly, for NO_LOCAL_POINTERS macro
+
 func prefetcht0(addr uintptr)
 func prefetcht1(addr uintptr)
 func prefetcht2(addr uintptr)
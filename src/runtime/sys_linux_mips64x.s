@@ -37,6 +37,7 @@
 #define SYS_mincore		5026
 #define SYS_gettid		5178
 #define SYS_tkill		5192
+#define SYS_tgkill		5225
 #define SYS_futex		5194
 #define SYS_sched_getaffinity	5196
 #define SYS_exit_group		5205
@@ -154,6 +155,20 @@ TEXT runtime·raiseproc(SB),NOSPLIT,$-8
 	SYSCALL
 	RET
 
+TEXT runtime·getpid(SB),NOSPLIT|NOFRAME,$0-8
+	MOVV	$SYS_getpid, R2
+	SYSCALL
+	MOVV	R2, ret+0(FP)
+	RET
+
+TEXT runtime·tgkill(SB),NOSPLIT|NOFRAME,$0-24
+	MOVV	tgid+0(FP), R4
+	MOVV	tid+8(FP), R5
+	MOVV	sig+16(FP), R6
+	MOVV	$SYS_tgkill, R2
+	SYSCALL
+	RET
+
 TEXT runtime·setitimer(SB),NOSPLIT,$-8-24
 	MOVW	mode+0(FP), R4
 	MOVV	new+8(FP), R5
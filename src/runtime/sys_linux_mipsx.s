@@ -37,6 +37,7 @@
 #define SYS_mincore		        4217
 #define SYS_gettid		        4222
 #define SYS_tkill		        4236
+#define SYS_tgkill		        4266
 #define SYS_futex		        4238
 #define SYS_sched_getaffinity	4240
 #define SYS_exit_group		    4246
@@ -157,6 +158,20 @@ TEXT runtime·raiseproc(SB),NOSPLIT,$0
 	SYSCALL
 	RET
 
+TEXT runtime·getpid(SB),NOSPLIT,$0-4
+	MOVW	$SYS_getpid, R2
+	SYSCALL
+	MOVW	R2, ret+0(FP)
+	RET
+
+TEXT runtime·tgkill(SB),NOSPLIT,$0-12
+	MOVW	tgid+0(FP), R4
+	MOVW	tid+4(FP), R5
+	MOVW	sig+8(FP), R6
+	MOVW	$SYS_tgkill, R2
+	SYSCALL
+	RET
+
 TEXT runtime·setitimer(SB),NOSPLIT,$0-12
 	MOVW	mode+0(FP), R4
 	MOVW	new+4(FP), R5
@@ -39,6 +39,7 @@
 #define SYS_mincore		206
 #define SYS_gettid		207
 #define SYS_tkill		208
+#define SYS_tgkill		250
 #define SYS_futex		221
 #define SYS_sched_getaffinity	223
 #define SYS_exit_group		234
@@ -141,6 +142,18 @@ TEXT runtime·raiseproc(SB),NOSPLIT|NOFRAME,$0
 	SYSCALL	$SYS_kill
 	RET
 
+TEXT runtime·getpid(SB),NOSPLIT|NOFRAME,$0-8
+	SYSCALL	$SYS_getpid
+	MOVD	R3, ret+0(FP)
+	RET
+
+TEXT runtime·tgkill(SB),NOSPLIT|NOFRAME,$0-24
+	MOVD	tgid+0(FP), R3
+	MOVD	tid+8(FP), R4
+	MOVD	sig+16(FP), R5
+	SYSCALL	$SYS_tgkill
+	RET
+
 TEXT runtime·setitimer(SB),NOSPLIT|NOFRAME,$0-24
 	MOVW	mode+0(FP), R3
 	MOVD	new+8(FP), R4
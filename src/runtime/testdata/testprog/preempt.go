@@ -0,0 +1,56 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+func init() {
+	register("AsyncPreempt", AsyncPreempt)
+}
+
+func AsyncPreempt() {
+	// Run with just 1 GOMAXPROCS so the runtime is required to
+	// use scheduler preemption.
+	runtime.GOMAXPROCS(1)
+	// Disable GC so we have complete control of what we're testing.
+	debug.SetGCPercent(-1)
+
+	// Start a goroutine with no sync safe-points.
+	var ready uint32
+	go func() {
+		for {
+			atomic.StoreUint32(&ready, 1)
+		}
+	}()
+
+	// Start a goroutine that holds heap pointers in its frame and
+	// also has no sync safe-points.
+	var ready2 uint32
+	go func() {
+		x := new([64]int)
+		for i := 0; ; i++ {
+			x[i%len(x)]++
+			atomic.StoreUint32(&ready2, 1)
+		}
+	}()
+
+	// Wait for the goroutines to stop passing through sync
+	// safe-points. This requires the spinning goroutines to be
+	// preempted so that this goroutine can run again.
+	for atomic.LoadUint32(&ready) == 0 || atomic.LoadUint32(&ready2) == 0 {
+		runtime.Gosched()
+	}
+
+	// Run a GC, which will have to stop the goroutines for STW and
+	// for stack scanning. If this doesn't work, the test will
+	// deadlock and timeout.
+	runtime.GC()
+
+	println("OK")
+}
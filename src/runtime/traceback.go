@@ -44,9 +44,11 @@ var (
 	mstartPC             uintptr
 	rt0_goPC             uintptr
 	sigpanicPC           uintptr
+	asyncPreemptPC       uintptr
 	runfinqPC            uintptr
 	bgsweepPC            uintptr
 	forcegchelperPC      uintptr
+	updatemaxprocsPC     uintptr
 	timerprocPC          uintptr
 	gcBgMarkWorkerPC     uintptr
 	systemstack_switchPC uintptr
@@ -71,9 +73,11 @@ func tracebackinit() {
 	mstartPC = funcPC(mstart)
 	rt0_goPC = funcPC(rt0_go)
 	sigpanicPC = funcPC(sigpanic)
+	asyncPreemptPC = funcPC(asyncPreempt)
 	runfinqPC = funcPC(runfinq)
 	bgsweepPC = funcPC(bgsweep)
 	forcegchelperPC = funcPC(forcegchelper)
+	updatemaxprocsPC = funcPC(updatemaxprocshelper)
 	timerprocPC = funcPC(timerproc)
 	gcBgMarkWorkerPC = funcPC(gcBgMarkWorker)
 	systemstack_switchPC = funcPC(systemstack_switch)
@@ -181,6 +185,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 		frame.lr = lr0
 	}
 	waspanic := false
+	injectedCall := false
 	cgoCtxt := gp.cgoCtxt
 	printing := pcbuf == nil && callback == nil
 	_defer := gp._defer
@@ -346,7 +351,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 			} else {
 				// backup to CALL instruction to read inlining info (same logic as below)
 				tracepc := frame.pc
-				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !waspanic {
+				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !injectedCall {
 					tracepc--
 				}
 				inldata := funcdata(f, _FUNCDATA_InlTree)
@@ -393,7 +398,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 				//		/home/rsc/go/src/runtime/x.go:23 +0xf
 				//
 				tracepc := frame.pc // back up to CALL instruction for funcline.
-				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !waspanic {
+				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !injectedCall {
 					tracepc--
 				}
 				file, line := funcline(f, tracepc)
@@ -455,6 +460,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 		}
 
 		waspanic = f.entry == sigpanicPC
+		injectedCall = waspanic || f.entry == asyncPreemptPC
 
 		// Do not unwind past the bottom of the stack.
 		if !flr.valid() {
@@ -470,8 +476,8 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 		frame.argmap = nil
 
 		// On link register architectures, sighandler saves the LR on stack
-		// before faking a call to sigpanic.
-		if usesLR && waspanic {
+		// before faking a call.
+		if usesLR && injectedCall {
 			x := *(*uintptr)(unsafe.Pointer(frame.sp))
 			frame.sp += sys.MinFrameSize
 			if GOARCH == "arm64" {
@@ -852,6 +858,7 @@ func isSystemGoroutine(gp *g) bool {
 	return pc == runfinqPC && !fingRunning ||
 		pc == bgsweepPC ||
 		pc == forcegchelperPC ||
+		pc == updatemaxprocsPC ||
 		pc == timerprocPC ||
 		pc == gcBgMarkWorkerPC
 }
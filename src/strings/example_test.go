@@ -214,6 +214,17 @@ func ExampleTrimSpace() {
 	// Output: a lone gopher
 }
 
+func ExampleBuilder() {
+	var b strings.Builder
+	for i := 3; i > 0; i-- {
+		fmt.Fprintf(&b, "%d...", i)
+	}
+	b.WriteString("ignition")
+	fmt.Println(b.String())
+
+	// Output: 3...2...1...ignition
+}
+
 func ExampleNewReplacer() {
 	r := strings.NewReplacer("<", "&lt;", ">", "&gt;")
 	fmt.Println(r.Replace("This is <b>HTML</b>!"))
@@ -269,20 +269,6 @@ func makeGenericReplacer(oldnew []string) *genericReplacer {
 	return r
 }
 
-type appendSliceWriter []byte
-
-// Write writes to the buffer to satisfy io.Writer.
-func (w *appendSliceWriter) Write(p []byte) (int, error) {
-	*w = append(*w, p...)
-	return len(p), nil
-}
-
-// WriteString writes to the buffer without string->[]byte->string allocations.
-func (w *appendSliceWriter) WriteString(s string) (int, error) {
-	*w = append(*w, s...)
-	return len(s), nil
-}
-
 type stringWriterIface interface {
 	WriteString(string) (int, error)
 }
@@ -304,9 +290,10 @@ func getStringWriter(w io.Writer) stringWriterIface {
 }
 
 func (r *genericReplacer) Replace(s string) string {
-	buf := make(appendSliceWriter, 0, len(s))
-	r.WriteString(&buf, s)
-	return string(buf)
+	var b Builder
+	b.Grow(len(s))
+	r.WriteString(&b, s)
+	return b.String()
 }
 
 func (r *genericReplacer) WriteString(w io.Writer, s string) (n int, err error) {
@@ -363,7 +350,7 @@ func makeSingleStringReplacer(pattern string, value string) *singleStringReplace
 }
 
 func (r *singleStringReplacer) Replace(s string) string {
-	var buf []byte
+	var b Builder
 	i, matched := 0, false
 	for {
 		match := r.finder.next(s[i:])
@@ -371,15 +358,16 @@ func (r *singleStringReplacer) Replace(s string) string {
 			break
 		}
 		matched = true
-		buf = append(buf, s[i:i+match]...)
-		buf = append(buf, r.value...)
+		b.Grow(match + len(r.value))
+		b.WriteString(s[i : i+match])
+		b.WriteString(r.value)
 		i += match + len(r.finder.pattern)
 	}
 	if !matched {
 		return s
 	}
-	buf = append(buf, s[i:]...)
-	return string(buf)
+	b.WriteString(s[i:])
+	return b.String()
 }
 
 func (r *singleStringReplacer) WriteString(w io.Writer, s string) (n int, err error) {
@@ -472,19 +460,18 @@ func (r *byteStringReplacer) Replace(s string) string {
 	if !anyChanges {
 		return s
 	}
-	buf := make([]byte, newSize)
-	bi := buf
+	var buf Builder
+	buf.Grow(newSize)
+	last := 0
 	for i := 0; i < len(s); i++ {
-		b := s[i]
-		if r[b] != nil {
-			n := copy(bi, r[b])
-			bi = bi[n:]
-		} else {
-			bi[0] = b
-			bi = bi[1:]
+		if rep := r[s[i]]; rep != nil {
+			buf.WriteString(s[last:i])
+			buf.Write(rep)
+			last = i + 1
 		}
 	}
-	return string(buf)
+	buf.WriteString(s[last:])
+	return buf.String()
 }
 
 func (r *byteStringReplacer) WriteString(w io.Writer, s string) (n int, err error) {
@@ -7,7 +7,6 @@
 package parse
 
 import (
-	"bytes"
 	"fmt"
 	"strconv"
 	"strings"
@@ -94,9 +93,9 @@ func (l *ListNode) tree() *Tree {
 }
 
 func (l *ListNode) String() string {
-	b := new(bytes.Buffer)
+	var b strings.Builder
 	for _, n := range l.Nodes {
-		fmt.Fprint(b, n)
+		fmt.Fprint(&b, n)
 	}
 	return b.String()
 }